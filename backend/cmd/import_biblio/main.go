@@ -1,240 +1,413 @@
+// Command import_biblio imports the bibliography export of a SLiMS (Senayan) catalog
+// as books and papers.
+//
+// Every row is remembered by its SLiMS biblio ID (or, when the export has none, its
+// first item code), so running the import again only adds the rows that are new or
+// failed before. Rows are saved in transactions of -batch rows; a row that fails is
+// rolled back on its own and reported at the end.
+//
+//	go run ./cmd/import_biblio -input biblio.csv -files /var/www/slims -dry-run
+//	go run ./cmd/import_biblio -input biblio.csv -map id=biblio_id,file=Lampiran
+//
+// Imported items are not added to the search index; run cmd/reindex afterwards.
 package main
 
 import (
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/database"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-type Book struct {
-	Title         string
-	Author        string
-	Publisher     *string
-	PublishedYear *int
-	ISBN          *string
-	Subject       *string
-	Language      *string
-	Pages         *string
-	Summary       *string
-	FileURL       *string
-	CoverImageURL *string
-}
-
-type Paper struct {
-	Title         string
-	Author        string
-	Advisor       *string
-	University    *string
-	Department    *string
-	Year          *int
-	ISSN          *string
-	Journal       *string
-	Volume        *int
-	Issue         *int
-	Pages         *string
-	DOI           *string
-	Abstract      *string
-	Keywords      *string
-	FileURL       *string
-	CoverImageURL *string
+// options are the command-line flags
+type options struct {
+	input     string
+	columns   services.SLiMSColumns
+	itemType  string
+	filesDir  string
+	uploadDir string
+	batchSize int
+	dryRun    bool
+	createdBy uint
+	delimiter string
 }
 
-type BookAuthor struct {
-	BookID     uint
-	AuthorName string
-}
-
-type PaperAuthor struct {
-	PaperID    uint
-	AuthorName string
+// row is a line of the export and what became of it
+type row struct {
+	line     int
+	record   services.SLiMSRecord
+	status   string // created, skipped or failed
+	message  string
+	warnings []string
+	copied   []string // files copied into the upload directory for the row
 }
 
 func main() {
-	// Get DB connection info from environment variables, with sensible defaults
-	user := os.Getenv("DB_USER")
-	if user == "" {
-		user = "root"
-	}
-	password := os.Getenv("DB_PASSWORD")
-	if password == "" {
-		password = "rootpassword"
+	opts := options{columns: services.DefaultSLiMSColumns()}
+	flag.StringVar(&opts.input, "input", "", "path of the SLiMS bibliography export (CSV)")
+	flag.Func("map", "column mapping as field=column pairs separated by commas, e.g. id=biblio_id,file=Lampiran", opts.columns.Set)
+	flag.StringVar(&opts.itemType, "type", "auto", "import rows as book, paper or auto (theses and articles by their GMD become papers)")
+	flag.StringVar(&opts.filesDir, "files", "", "SLiMS installation or files directory holding cover images and attachments")
+	flag.StringVar(&opts.uploadDir, "uploads", "uploads", "upload directory of the API server")
+	flag.IntVar(&opts.batchSize, "batch", 100, "rows saved per transaction")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "check the rows and report what would be imported without saving anything")
+	flag.UintVar(&opts.createdBy, "created-by", 0, "ID of the user the imported items are created by")
+	flag.StringVar(&opts.delimiter, "delimiter", ",", "field delimiter of the export")
+	flag.Parse()
+
+	if opts.input == "" {
+		flag.Usage()
+		os.Exit(2)
 	}
-	host := os.Getenv("DB_HOST")
-	if host == "" {
-		host = "127.0.0.1"
+	if opts.itemType != "auto" && opts.itemType != "book" && opts.itemType != "paper" {
+		log.Fatalf("Invalid -type %q, expected book, paper or auto", opts.itemType)
 	}
-	port := os.Getenv("DB_PORT")
-	if port == "" {
-		port = "3307"
+	if opts.batchSize < 1 {
+		log.Fatal("-batch must be at least 1")
 	}
-	dbname := os.Getenv("DB_NAME")
-	if dbname == "" {
-		dbname = "e_repository_db"
+	if len([]rune(opts.delimiter)) != 1 {
+		log.Fatal("-delimiter must be a single character")
 	}
-	dsn := user + ":" + password + "@tcp(" + host + ":" + port + ")/" + dbname + "?charset=utf8mb4&parseTime=True&loc=Local"
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+
+	rows, err := readRows(opts)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	file, err := os.Open("../senayan_biblio_export.csv")
+	config := configs.LoadConfig()
+	if err := database.Connect(config); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	db := database.GetDB().Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Warn)})
+	if err := db.AutoMigrate(&models.ImportedRecord{}); err != nil {
+		log.Fatal("Failed to migrate imported records:", err)
+	}
+
+	importRows(db, rows, opts)
+	if printSummary(rows, opts) > 0 {
+		os.Exit(1)
+	}
+}
+
+// readRows reads the export and maps each row to a book or paper
+func readRows(opts options) ([]*row, error) {
+	file, err := os.Open(opts.input)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
-	reader.Comma = ','
+	reader.Comma = []rune(opts.delimiter)[0]
 	reader.LazyQuotes = true
-	records, err := reader.ReadAll()
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to read the header of %s: %w", opts.input, err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	if missing := opts.columns.Missing(header); len(missing) > 0 {
+		return nil, fmt.Errorf("%s has no column %s; map the fields to its columns with -map", opts.input, strings.Join(missing, ", "))
 	}
 
-	header := records[0]
-	for i, row := range records[1:] {
-		rowMap := make(map[string]string)
-		for j, col := range row {
-			if j < len(header) {
-				key := strings.TrimSpace(header[j])
-				rowMap[key] = col
-			}
+	var rows []*row
+	for line := 2; ; line++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		r := &row{line: line}
+		rows = append(rows, r)
+		if err != nil {
+			r.status, r.message = "failed", err.Error()
+			continue
 		}
 
-		title := getOrDefault(rowMap, "Judul", "Unknown Title")
-		penulisTambahan := cleanBrackets(getOrDefault(rowMap, "Penulis Tambahan", "Unknown Author"))
-		authors := splitAuthors(penulisTambahan, "")
-		mainAuthor := "Unknown Author"
-		if len(authors) > 0 {
-			mainAuthor = authors[0]
-		}
-		publisher := getOrDefaultPtr(rowMap, "Penerbit")
-		tahun := parseIntPtr(rowMap["Tahun"])
-		isbn := getOrDefaultPtr(rowMap, "ISBN")
-		subject := cleanBracketsPtr(getOrDefaultPtr(rowMap, "Subjek"))
-		language := getOrDefaultPtr(rowMap, "Bahasa")
-		cover := getOrDefaultPtr(rowMap, "Cover")
-		cover = normalizeCoverImageURL(cover)
-
-		pages := getOrDefaultPtr(rowMap, "Jumlah Halaman dan Dimensi")
-
-		// Heuristic: if Penerbit is present, treat as Book; else Paper
-		if publisher != nil && *publisher != "" {
-			book := Book{
-				Title:         title,
-				Author:        mainAuthor,
-				Publisher:     publisher,
-				PublishedYear: tahun,
-				ISBN:          isbn,
-				Subject:       subject,
-				Language:      language,
-				Pages:         pages,
-				CoverImageURL: cover,
-			}
-			result := db.Table("books").Create(&book)
-			if result.Error != nil {
-				log.Printf("[Row %d] Failed to insert book: %v", i+2, result.Error)
-				continue
+		fields := make(map[string]string, len(header))
+		for i, value := range values {
+			if i < len(header) {
+				fields[header[i]] = value
 			}
-			for _, author := range authors {
-				ba := BookAuthor{BookID: getLastInsertID(db), AuthorName: author}
-				db.Table("book_authors").Create(&ba)
+		}
+		if r.record, err = services.SLiMSRecordFromRow(fields, opts.columns, opts.itemType); err != nil {
+			r.status, r.message = "failed", err.Error()
+		}
+	}
+	return rows, nil
+}
+
+// importRows saves the rows in batches, one transaction per batch
+func importRows(db *gorm.DB, rows []*row, opts options) {
+	var createdBy *uint
+	if opts.createdBy != 0 {
+		createdBy = &opts.createdBy
+	}
+
+	seen := make(map[string]int)
+	var pending []*row
+	for _, r := range rows {
+		if r.status != "" {
+			continue
+		}
+		if line, ok := seen[r.record.Key]; ok {
+			r.status, r.message = "skipped", fmt.Sprintf("same biblio record as line %d", line)
+			continue
+		}
+		seen[r.record.Key] = r.line
+
+		itemType, itemID, err := services.ImportedItemID(db, services.SLiMSSource, r.record.Key)
+		switch {
+		case err != nil:
+			r.status, r.message = "failed", err.Error()
+		case itemID != 0:
+			r.status, r.message = "skipped", fmt.Sprintf("already imported as %s %d", itemType, itemID)
+		default:
+			pending = append(pending, r)
+		}
+	}
+
+	for start := 0; start < len(pending); start += opts.batchSize {
+		batch := pending[start:min(start+opts.batchSize, len(pending))]
+		if opts.dryRun {
+			for _, r := range batch {
+				attachFiles(r, opts)
+				r.status = "created"
 			}
+			continue
+		}
+		importBatch(db, batch, createdBy, opts)
+		log.Printf("Imported %d of %d rows", start+len(batch), len(pending))
+	}
+}
+
+// importBatch saves a batch of rows in one transaction. A failing row is rolled back
+// to a savepoint so the rest of the batch is still saved.
+func importBatch(db *gorm.DB, batch []*row, createdBy *uint, opts options) {
+	tx := db.Begin()
+	if tx.Error != nil {
+		for _, r := range batch {
+			r.status, r.message = "failed", tx.Error.Error()
+		}
+		return
+	}
+
+	var books, papers int
+	for _, r := range batch {
+		attachFiles(r, opts)
+		tx.SavePoint("row")
+		if _, err := services.InsertSLiMSRecord(tx, &r.record, createdBy); err != nil {
+			tx.RollbackTo("row")
+			r.status, r.message = "failed", err.Error()
+			removeCopies(r)
+			continue
+		}
+		r.status = "created"
+		if r.record.ItemType == "book" {
+			books++
 		} else {
-			paper := Paper{
-				Title:         title,
-				Author:        mainAuthor,
-				Year:          tahun,
-				Pages:         pages,
-				CoverImageURL: cover,
-			}
-			result := db.Table("papers").Create(&paper)
-			if result.Error != nil {
-				log.Printf("[Row %d] Failed to insert paper: %v", i+2, result.Error)
-				continue
-			}
-			for _, author := range authors {
-				pa := PaperAuthor{PaperID: getLastInsertID(db), AuthorName: author}
-				db.Table("paper_authors").Create(&pa)
+			papers++
+		}
+	}
+
+	counters := map[string]int{"total_books": books, "total_papers": papers}
+	for name, count := range counters {
+		if count > 0 {
+			tx.Model(&models.Counter{}).Where("name = ?", name).UpdateColumn("count", gorm.Expr("count + ?", count))
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		for _, r := range batch {
+			if r.status == "created" {
+				r.status, r.message = "failed", "transaction failed: "+err.Error()
+				removeCopies(r)
 			}
 		}
 	}
-	fmt.Println("Import complete!")
 }
 
-func getOrDefault(m map[string]string, key, def string) string {
-	if v, ok := m[key]; ok && v != "" {
-		return v
+// attachFiles copies the row's cover image and attachment from the SLiMS files
+// directory into the upload directory. A file that cannot be found is a warning;
+// the row is imported without it. In a dry run the files are only looked up.
+func attachFiles(r *row, opts options) {
+	folder := r.record.ItemType + "s"
+	files := []struct {
+		name    string
+		subdirs []string
+		target  string
+		url     **string
+	}{
+		{r.record.CoverFile, []string{"images/docs", "docs", ""}, "covers", coverURL(r)},
+		{r.record.AttachFile, []string{"repository", "files", ""}, folder, fileURL(r)},
 	}
-	return def
-}
 
-func getOrDefaultPtr(m map[string]string, key string) *string {
-	if v, ok := m[key]; ok && v != "" {
-		return &v
+	for _, file := range files {
+		if file.name == "" {
+			continue
+		}
+		if strings.HasPrefix(file.name, "http://") || strings.HasPrefix(file.name, "https://") {
+			url := file.name
+			*file.url = &url
+			continue
+		}
+		if opts.filesDir == "" {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s not attached, no -files directory given", file.name))
+			continue
+		}
+		source := locateFile(opts.filesDir, file.name, file.subdirs)
+		if source == "" {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s not found in %s", file.name, opts.filesDir))
+			continue
+		}
+		if opts.dryRun {
+			continue
+		}
+
+		name := uploadName(file.name)
+		target := filepath.Join(opts.uploadDir, file.target, name)
+		if err := copyFile(source, target); err != nil {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s not attached: %v", file.name, err))
+			continue
+		}
+		r.copied = append(r.copied, target)
+		url := fmt.Sprintf("/uploads/%s/%s", file.target, name)
+		*file.url = &url
 	}
-	return nil
 }
 
-func parseIntPtr(s string) *int {
-	if s == "" {
-		return nil
-	}
-	var i int
-	_, err := fmt.Sscanf(s, "%d", &i)
-	if err != nil {
-		return nil
+// coverURL returns the cover image field of the row's book or paper
+func coverURL(r *row) **string {
+	if r.record.Book != nil {
+		return &r.record.Book.CoverImageURL
 	}
-	return &i
+	return &r.record.Paper.CoverImageURL
 }
 
-func splitAuthors(main, additional string) []string {
-	var authors []string
-	for _, a := range strings.Split(main, ";") {
-		a = strings.TrimSpace(cleanBrackets(a))
-		if a != "" {
-			authors = append(authors, a)
-		}
+// fileURL returns the file field of the row's book or paper
+func fileURL(r *row) **string {
+	if r.record.Book != nil {
+		return &r.record.Book.FileURL
 	}
-	for _, a := range strings.Split(additional, ";") {
-		a = strings.TrimSpace(cleanBrackets(a))
-		if a != "" {
-			authors = append(authors, a)
+	return &r.record.Paper.FileURL
+}
+
+// locateFile finds a file SLiMS refers to by name in the subdirectories it keeps
+// covers and attachments in
+func locateFile(dir, name string, subdirs []string) string {
+	name = filepath.Clean("/" + filepath.FromSlash(name))[1:] // no escaping the directory
+	for _, subdir := range subdirs {
+		path := filepath.Join(dir, subdir, name)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
 		}
 	}
-	return authors
+	return ""
 }
 
-func cleanBrackets(s string) string {
-	return strings.ReplaceAll(strings.ReplaceAll(s, "<", ""), ">", "")
+// unsafeFileChars matches the characters replaced in uploaded file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// uploadName returns a unique upload file name for a SLiMS file, as the upload
+// handlers name files
+func uploadName(name string) string {
+	return fmt.Sprintf("%d_%s", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(filepath.Base(name), "_"))
 }
 
-func cleanBracketsPtr(s *string) *string {
-	if s == nil {
-		return nil
+// copyFile copies source to target, creating the target's directory
+func copyFile(source, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
 	}
-	clean := cleanBrackets(*s)
-	return &clean
-}
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
 
-func normalizeCoverImageURL(cover *string) *string {
-	if cover == nil || *cover == "" {
-		return nil
+	out, err := os.Create(target)
+	if err != nil {
+		return err
 	}
-	if strings.HasPrefix(*cover, "/") || strings.HasPrefix(*cover, "http") {
-		return cover
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	return out.Close()
+}
+
+// removeCopies deletes the files copied for a row that was not saved
+func removeCopies(r *row) {
+	for _, path := range r.copied {
+		os.Remove(path)
 	}
-	url := "/uploads/covers/" + *cover
-	return &url
+	r.copied = nil
 }
 
-func getLastInsertID(db *gorm.DB) uint {
-	var id uint
-	db.Raw("SELECT LAST_INSERT_ID()").Scan(&id)
-	return id
+// printSummary reports the outcome of every skipped or failed row and the totals.
+// It returns the number of failed rows.
+func printSummary(rows []*row, opts options) int {
+	counts := map[string]int{}
+	byType := map[string]int{}
+	for _, r := range rows {
+		counts[r.status]++
+		if r.status == "created" {
+			byType[r.record.ItemType]++
+		}
+	}
+
+	verb := "Created"
+	if opts.dryRun {
+		verb = "Would create"
+		fmt.Println("Dry run, nothing was saved.")
+	}
+	for _, status := range []string{"skipped", "failed"} {
+		if counts[status] == 0 {
+			continue
+		}
+		fmt.Printf("\n%s rows:\n", strings.ToUpper(status[:1])+status[1:])
+		for _, r := range rows {
+			if r.status == status {
+				fmt.Printf("  line %d %q: %s\n", r.line, r.record.Title(), r.message)
+			}
+		}
+	}
+
+	var warned []*row
+	for _, r := range rows {
+		if len(r.warnings) > 0 && r.status == "created" {
+			warned = append(warned, r)
+		}
+	}
+	if len(warned) > 0 {
+		fmt.Println("\nFiles not attached:")
+		for _, r := range warned {
+			fmt.Printf("  line %d: %s\n", r.line, strings.Join(r.warnings, "; "))
+		}
+	}
+
+	fmt.Printf("\n%s %d books and %d papers from %d rows; %d skipped, %d failed.\n",
+		verb, byType["book"], byType["paper"], len(rows), counts["skipped"], counts["failed"])
+	if !opts.dryRun && counts["created"] > 0 {
+		fmt.Println("Run cmd/reindex to add the imported items to the search index.")
+	}
+	return counts["failed"]
 }
@@ -8,6 +8,8 @@ import (
 	"e-repository-api/internal/database"
 	"e-repository-api/internal/handlers"
 	"e-repository-api/internal/middleware"
+	"e-repository-api/internal/search"
+	"e-repository-api/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,11 +28,78 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	// Split legacy keyword/subject strings into the keyword vocabulary
+	if err := services.RunDataMigration(database.GetDB(), "backfill_keywords", services.BackfillKeywords); err != nil {
+		log.Fatal("Failed to backfill keywords:", err)
+	}
+
+	// Unlink co-authors that were wrongly linked to the uploader's account
+	if err := services.RunDataMigration(database.GetDB(), "unlink_mismatched_authors", services.UnlinkMismatchedAuthors); err != nil {
+		log.Fatal("Failed to repair author links:", err)
+	}
+
+	// Link existing author entries to authority records
+	if err := services.BackfillAuthorRecords(database.GetDB()); err != nil {
+		log.Fatal("Failed to backfill author records:", err)
+	}
+
 	// Seed initial data
 	if err := database.SeedData(); err != nil {
 		log.Fatal("Failed to seed data:", err)
 	}
 
+	// Open the search index, filling it in the background when it is new
+	searchIndex, created, err := search.OpenBleve(config.Search.IndexPath)
+	if err != nil {
+		log.Fatal("Failed to open search index:", err)
+	}
+	search.SetDefault(searchIndex)
+	if created {
+		go func() {
+			indexed, err := services.Reindex(database.GetDB(), searchIndex)
+			if err != nil {
+				log.Printf("Failed to build search index: %v", err)
+				return
+			}
+			log.Printf("Built search index with %d items", indexed)
+		}()
+	}
+
+	// Retry search index updates that failed during edits
+	services.RunPeriodically("RetryIndexUpdates", config.Jobs.IndexRetryInterval, func() error {
+		return services.RetryIndexUpdates(database.GetDB())
+	})
+
+	// Recompute related-item recommendations in the background
+	services.RunPeriodically("ComputeRelatedItems", config.Jobs.RelatedItemsInterval, func() error {
+		return services.ComputeRelatedItems(database.GetDB())
+	})
+
+	// Email members about overdue loans
+	services.RunPeriodically("SendOverdueReminders", config.Jobs.OverdueRemindersInterval, func() error {
+		return services.SendOverdueReminders(database.GetDB())
+	})
+
+	// Expire uncollected holds and tell members when their holds are ready
+	services.RunPeriodically("ProcessHolds", config.Jobs.HoldsInterval, func() error {
+		return services.ProcessHolds(database.GetDB())
+	})
+
+	// Extract the text of uploaded PDFs for full-text search
+	services.RunPeriodically("ExtractFullText", config.Jobs.FullTextInterval, func() error {
+		return services.ExtractFullText(database.GetDB())
+	})
+
+	// Rebuild the dictionary of catalog words behind "did you mean" suggestions
+	services.RunPeriodically("BuildSpellingDictionary", config.Jobs.SpellingInterval, func() error {
+		return services.BuildSpellingDictionary(database.GetDB())
+	})
+
+	// Send new-item digests for saved searches
+	services.RunPeriodically("SendSavedSearchAlerts", config.Jobs.SavedSearchesInterval, func() error {
+		return services.SendSavedSearchAlerts(database.GetDB(), config.Server.BaseURL)
+	})
+
 	// Initialize Gin
 	r := gin.Default()
 
@@ -62,6 +131,25 @@ func main() {
 	authorHandler := handlers.NewAuthorHandler(database.GetDB())
 	statsHandler := handlers.NewStatsHandler(database.GetDB())
 	metadataHandler := handlers.NewMetadataHandler()
+	keywordHandler := handlers.NewKeywordHandler(database.GetDB())
+	authorshipHandler := handlers.NewAuthorshipHandler(database.GetDB())
+	readingListHandler := handlers.NewReadingListHandler(database.GetDB(), config)
+	savedSearchHandler := handlers.NewSavedSearchHandler(database.GetDB())
+	relatedHandler := handlers.NewRelatedHandler(database.GetDB(), config)
+	searchHandler := handlers.NewSearchHandler(database.GetDB(), config)
+	reviewHandler := handlers.NewReviewHandler(database.GetDB())
+	copyHandler := handlers.NewCopyHandler(database.GetDB())
+	circulationHandler := handlers.NewCirculationHandler(database.GetDB())
+	holdHandler := handlers.NewHoldHandler(database.GetDB())
+	stockTakeHandler := handlers.NewStockTakeHandler(database.GetDB())
+	oaiHandler := handlers.NewOAIHandler(database.GetDB(), config)
+	citationHandler := handlers.NewCitationHandler(database.GetDB())
+	itemMetaHandler := handlers.NewItemMetaHandler(database.GetDB(), config)
+	feedHandler := handlers.NewFeedHandler(database.GetDB(), config)
+
+	// OAI-PMH endpoint for harvesters
+	r.GET("/oai", oaiHandler.Handle)
+	r.POST("/oai", oaiHandler.Handle)
 
 	// API routes
 	api := r.Group("/api")
@@ -85,15 +173,26 @@ func main() {
 		public := api.Group("/v1")
 		{
 			public.GET("/books", bookHandler.GetBooks)
-			public.GET("/books/:id", bookHandler.GetBook)
+			public.GET("/books/:id", middleware.OptionalAuthMiddleware(config), bookHandler.GetBook)
 			public.GET("/papers", paperHandler.GetPapers)
-			public.GET("/papers/:id", paperHandler.GetPaper)
+			public.GET("/papers/:id", middleware.OptionalAuthMiddleware(config), paperHandler.GetPaper)
+			public.GET("/search", searchHandler.Search)
+			public.GET("/search/suggest", searchHandler.Suggest)
 			public.GET("/departments", authHandler.GetDepartments)
 			authors := public.Group("/authors")
 			{
 				authors.GET("/search", authorHandler.SearchAuthors)
 				authors.GET("/:name/works", authorHandler.GetAuthorWorks)
 			}
+			keywords := public.Group("/keywords")
+			{
+				keywords.GET("/autocomplete", keywordHandler.AutocompleteKeywords)
+				keywords.GET("/:id", keywordHandler.GetKeyword)
+			}
+			public.GET("/reading-lists/shared/:token", readingListHandler.GetSharedReadingList)
+			public.GET("/reading-lists/shared/:token/export", readingListHandler.ExportSharedReadingList)
+			public.GET("/saved-searches/unsubscribe/:token", savedSearchHandler.UnsubscribePage)
+			public.POST("/saved-searches/unsubscribe/:token", savedSearchHandler.Unsubscribe)
 			public.GET("/users/count", statsHandler.GetUserCount)
 			public.GET("/downloads/count", statsHandler.GetDownloadCount)
 			public.GET("/users-per-month", statsHandler.GetUsersPerMonth)
@@ -106,14 +205,32 @@ func main() {
 			public.GET("/users/:id/downloads-per-month", statsHandler.GetUserDownloadsPerMonthById)
 			public.GET("/books/:id/stats", statsHandler.GetBookStats)
 			public.GET("/papers/:id/stats", statsHandler.GetPaperStats)
+			public.GET("/books/:id/related", relatedHandler.GetRelatedBooks)
+			public.GET("/papers/:id/related", relatedHandler.GetRelatedPapers)
+			public.GET("/books/:id/reviews", middleware.OptionalAuthMiddleware(config), reviewHandler.GetBookReviews)
+			public.GET("/papers/:id/reviews", middleware.OptionalAuthMiddleware(config), reviewHandler.GetPaperReviews)
 			public.GET("/books-per-month", statsHandler.GetBooksPerMonth)
 			public.GET("/papers-per-month", statsHandler.GetPapersPerMonth)
 
 			// Public download and citation routes
 			public.GET("/books/:id/download", bookHandler.DownloadBook)
-			public.POST("/books/:id/cite", bookHandler.CiteBook)
+			public.GET("/books/:id/cite", middleware.OptionalAuthMiddleware(config), bookHandler.CiteBook)
+			public.POST("/books/:id/cite", middleware.OptionalAuthMiddleware(config), bookHandler.CiteBook)
 			public.GET("/papers/:id/download", paperHandler.DownloadPaper)
-			public.POST("/papers/:id/cite", paperHandler.CitePaper)
+			public.GET("/papers/:id/cite", middleware.OptionalAuthMiddleware(config), paperHandler.CitePaper)
+			public.POST("/papers/:id/cite", middleware.OptionalAuthMiddleware(config), paperHandler.CitePaper)
+			public.GET("/citations/export", middleware.OptionalAuthMiddleware(config), citationHandler.ExportCitations)
+			public.POST("/citations/export", middleware.OptionalAuthMiddleware(config), citationHandler.ExportCitations)
+
+			// Discovery metadata and crawler landing pages
+			public.GET("/books/:id/meta", itemMetaHandler.GetBookMeta)
+			public.GET("/papers/:id/meta", itemMetaHandler.GetPaperMeta)
+			public.GET("/books/:id/landing", itemMetaHandler.BookLandingPage)
+			public.GET("/papers/:id/landing", itemMetaHandler.PaperLandingPage)
+
+			// Atom and RSS feeds of new books and papers
+			public.GET("/feeds/atom", feedHandler.AtomFeed)
+			public.GET("/feeds/rss", feedHandler.RSSFeed)
 
 			// Metadata extraction routes
 			public.POST("/metadata/extract", metadataHandler.ExtractMetadata)
@@ -138,18 +255,70 @@ func main() {
 				user.PUT("/books/:id", bookHandler.UpdateUserBook)
 				user.DELETE("/books/:id", bookHandler.DeleteUserBook)
 				user.GET("/books/:id/download", bookHandler.DownloadBook)
+				user.GET("/books/:id/cite", bookHandler.CiteBook)
 				user.POST("/books/:id/cite", bookHandler.CiteBook)
 
 				// User paper routes
 				user.POST("/papers", paperHandler.CreateUserPaper)
+				user.POST("/papers/import/preview", paperHandler.PreviewPaperImport)
+				user.POST("/papers/import", paperHandler.ImportPapers)
 				user.GET("/papers", paperHandler.GetUserPapers)
 				user.PUT("/papers/:id", paperHandler.UpdateUserPaper)
 				user.DELETE("/papers/:id", paperHandler.DeleteUserPaper)
 				user.GET("/papers/:id/download", paperHandler.DownloadPaper)
+				user.GET("/papers/:id/cite", paperHandler.CitePaper)
 				user.POST("/papers/:id/cite", paperHandler.CitePaper)
 				user.GET("/citations-per-month", statsHandler.GetUserCitationsPerMonth)
 				user.GET("/stats", statsHandler.GetUserStats)
 				user.GET("/downloads-per-month", statsHandler.GetUserDownloadsPerMonth)
+
+				// Authorship claim routes
+				user.GET("/authorship/suggestions", authorshipHandler.GetSuggestions)
+				user.GET("/authorship/claims", authorshipHandler.GetMyClaims)
+				user.POST("/authorship/claims", authorshipHandler.CreateClaim)
+				user.DELETE("/authorship/claims/:id", authorshipHandler.CancelClaim)
+				user.GET("/authorship/claims/incoming", authorshipHandler.GetIncomingClaims)
+				user.POST("/authorship/claims/:id/approve", authorshipHandler.ApproveClaim)
+				user.POST("/authorship/claims/:id/reject", authorshipHandler.RejectClaim)
+
+				// Bookmark and reading list routes
+				user.GET("/bookmarks", readingListHandler.GetBookmarks)
+				user.POST("/bookmarks", readingListHandler.AddBookmark)
+				user.DELETE("/bookmarks/:type/:id", readingListHandler.RemoveBookmark)
+				user.GET("/reading-lists", readingListHandler.GetReadingLists)
+				user.POST("/reading-lists", readingListHandler.CreateReadingList)
+				user.GET("/reading-lists/:id", readingListHandler.GetReadingList)
+				user.PUT("/reading-lists/:id", readingListHandler.UpdateReadingList)
+				user.DELETE("/reading-lists/:id", readingListHandler.DeleteReadingList)
+				user.POST("/reading-lists/:id/share-token", readingListHandler.RegenerateShareToken)
+				user.GET("/reading-lists/:id/export", readingListHandler.ExportReadingList)
+				user.POST("/reading-lists/:id/entries", readingListHandler.AddReadingListEntry)
+				user.PUT("/reading-lists/:id/entries/:entryId", readingListHandler.UpdateReadingListEntry)
+				user.DELETE("/reading-lists/:id/entries/:entryId", readingListHandler.RemoveReadingListEntry)
+				user.GET("/saved-searches", savedSearchHandler.GetSavedSearches)
+				user.POST("/saved-searches", savedSearchHandler.CreateSavedSearch)
+				user.GET("/saved-searches/:id", savedSearchHandler.GetSavedSearch)
+				user.PUT("/saved-searches/:id", savedSearchHandler.UpdateSavedSearch)
+				user.DELETE("/saved-searches/:id", savedSearchHandler.DeleteSavedSearch)
+				user.GET("/notifications", savedSearchHandler.GetNotifications)
+				user.PUT("/notifications/read-all", savedSearchHandler.MarkAllNotificationsRead)
+				user.PUT("/notifications/:id/read", savedSearchHandler.MarkNotificationRead)
+
+				// Review and rating routes
+				user.POST("/reviews", reviewHandler.CreateReview)
+				user.PUT("/reviews/:id", reviewHandler.UpdateReview)
+				user.DELETE("/reviews/:id", reviewHandler.DeleteReview)
+				user.POST("/reviews/:id/replies", reviewHandler.ReplyToReview)
+				user.POST("/reviews/:id/report", reviewHandler.ReportReview)
+
+				// Loan routes
+				user.GET("/loans", circulationHandler.GetMyLoans)
+				user.POST("/loans/:id/renew", circulationHandler.RenewMyLoan)
+
+				// Hold routes
+				user.GET("/holds", holdHandler.GetMyHolds)
+				user.POST("/holds", holdHandler.PlaceHold)
+				user.DELETE("/holds/:id", holdHandler.CancelMyHold)
 			}
 		}
 
@@ -172,10 +341,72 @@ func main() {
 			admin.POST("/books", bookHandler.CreateBook)
 			admin.PUT("/books/:id", bookHandler.UpdateBook)
 			admin.DELETE("/books/:id", bookHandler.DeleteBook)
+			admin.POST("/books/import/marc", bookHandler.ImportMARC)
+			admin.GET("/books/export/marc", bookHandler.ExportMARC)
 			admin.GET("/papers", paperHandler.GetPapers)
 			admin.POST("/papers", paperHandler.CreatePaper)
 			admin.PUT("/papers/:id", paperHandler.UpdatePaper)
 			admin.DELETE("/papers/:id", paperHandler.DeletePaper)
+
+			// Admin physical holdings (copies)
+			admin.GET("/books/:id/copies", copyHandler.GetBookCopies)
+			admin.POST("/books/:id/copies", copyHandler.CreateCopy)
+			admin.GET("/copies", copyHandler.GetCopies)
+			admin.GET("/copies/barcode/:barcode", copyHandler.GetCopyByBarcode)
+			admin.GET("/copies/:id", copyHandler.GetCopy)
+			admin.PUT("/copies/:id", copyHandler.UpdateCopy)
+			admin.DELETE("/copies/:id", copyHandler.DeleteCopy)
+
+			// Admin circulation
+			admin.POST("/circulation/checkout", circulationHandler.Checkout)
+			admin.POST("/circulation/checkin", circulationHandler.Checkin)
+			admin.GET("/circulation/loans", circulationHandler.GetLoans)
+			admin.POST("/circulation/loans/:id/renew", circulationHandler.RenewLoan)
+			admin.POST("/circulation/loans/:id/fine", circulationHandler.SettleFine)
+			admin.GET("/circulation/members/:member", circulationHandler.GetMemberCirculation)
+			admin.GET("/circulation/rules", circulationHandler.GetLoanRules)
+			admin.PUT("/circulation/rules/:userType", circulationHandler.UpdateLoanRule)
+			admin.GET("/circulation/holidays", circulationHandler.GetHolidays)
+			admin.POST("/circulation/holidays", circulationHandler.CreateHoliday)
+			admin.DELETE("/circulation/holidays/:id", circulationHandler.DeleteHoliday)
+			admin.GET("/circulation/holds", holdHandler.GetHolds)
+			admin.GET("/circulation/holds/pull-list", holdHandler.GetPullList)
+			admin.DELETE("/circulation/holds/:id", holdHandler.CancelHold)
+
+			// Admin stock-take
+			admin.GET("/stock-takes", stockTakeHandler.GetStockTakes)
+			admin.POST("/stock-takes", stockTakeHandler.OpenStockTake)
+			admin.GET("/stock-takes/:id", stockTakeHandler.GetStockTake)
+			admin.GET("/stock-takes/:id/items", stockTakeHandler.GetStockTakeItems)
+			admin.POST("/stock-takes/:id/scans", stockTakeHandler.RecordScans)
+			admin.POST("/stock-takes/:id/close", stockTakeHandler.CloseStockTake)
+			admin.GET("/stock-takes/:id/export", stockTakeHandler.ExportStockTake)
+
+			// Admin keyword vocabulary management
+			admin.PUT("/keywords/:id", keywordHandler.UpdateKeyword)
+			admin.DELETE("/keywords/:id/group", keywordHandler.RemoveKeywordFromGroup)
+			admin.POST("/keyword-groups", keywordHandler.CreateKeywordGroup)
+			admin.PUT("/keyword-groups/:id", keywordHandler.UpdateKeywordGroup)
+
+			// Admin authorship claim review
+			admin.GET("/authorship/claims", authorshipHandler.GetIncomingClaims)
+			admin.POST("/authorship/claims/:id/approve", authorshipHandler.ApproveClaim)
+			admin.POST("/authorship/claims/:id/reject", authorshipHandler.RejectClaim)
+
+			// Admin author authority records
+			admin.GET("/authors/:id", authorHandler.GetAuthor)
+			admin.PUT("/authors/:id", authorHandler.UpdateAuthor)
+			admin.POST("/authors/:id/variants", authorHandler.AddAuthorVariant)
+			admin.GET("/authors/:id/duplicates", authorHandler.GetAuthorDuplicates)
+			admin.POST("/authors/:id/merge", authorHandler.MergeAuthors)
+			admin.POST("/authors/:id/split", authorHandler.SplitAuthor)
+
+			// Admin review moderation
+			admin.GET("/reviews", reviewHandler.GetModerationQueue)
+			admin.PUT("/reviews/:id/moderation", reviewHandler.ModerateReview)
+			admin.DELETE("/reviews/:id", reviewHandler.DeleteReview)
+			admin.GET("/review-reports", reviewHandler.GetReviewReports)
+			admin.PUT("/review-reports/:id", reviewHandler.ResolveReviewReport)
 		}
 	}
 
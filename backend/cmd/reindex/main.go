@@ -0,0 +1,66 @@
+// Command reindex rebuilds the search index from the database.
+//
+// The new index is built next to the current one and swapped in when complete. The
+// API server keeps the index open, so stop it before running this and start it again
+// afterwards.
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/database"
+	"e-repository-api/internal/search"
+	"e-repository-api/internal/services"
+)
+
+func main() {
+	config := configs.LoadConfig()
+	if err := database.Connect(config); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	path := config.Search.IndexPath
+	buildPath := path + ".new"
+	if err := os.RemoveAll(buildPath); err != nil {
+		log.Fatal("Failed to remove unfinished index:", err)
+	}
+
+	index, _, err := search.OpenBleve(buildPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	started := time.Now()
+	indexed, err := services.Reindex(database.GetDB(), index)
+	if closeErr := index.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(buildPath)
+		log.Fatal("Failed to build search index:", err)
+	}
+
+	// Swap the new index in, keeping the old one until the new one is in place
+	oldPath := path + ".old"
+	if err := os.RemoveAll(oldPath); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Rename(path, oldPath); err != nil {
+			log.Fatal("Failed to move the old index aside:", err)
+		}
+	}
+	if err := os.Rename(buildPath, path); err != nil {
+		log.Fatal("Failed to move the new index into place:", err)
+	}
+	os.RemoveAll(oldPath)
+
+	// Index updates that failed before the rebuild started are in the new index
+	if err := services.ClearIndexUpdates(database.GetDB(), started); err != nil {
+		log.Printf("Failed to clear queued index updates: %v", err)
+	}
+
+	log.Printf("Indexed %d books and papers into %s", indexed, path)
+}
@@ -1,8 +1,11 @@
 package configs
 
 import (
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +15,9 @@ type Config struct {
 	Server   ServerConfig
 	JWT      JWTConfig
 	Upload   UploadConfig
+	Jobs     JobsConfig
+	Search   SearchConfig
+	OAI      OAIConfig
 }
 
 type DatabaseConfig struct {
@@ -23,8 +29,9 @@ type DatabaseConfig struct {
 }
 
 type ServerConfig struct {
-	Port    string
-	BaseURL string
+	Port        string
+	BaseURL     string
+	FrontendURL string // the address of the web app, for links to item pages
 }
 
 type JWTConfig struct {
@@ -36,10 +43,40 @@ type UploadConfig struct {
 	MaxUploadSize int64
 }
 
+// SearchConfig holds the location of the on-disk search index
+type SearchConfig struct {
+	IndexPath string
+}
+
+// OAIConfig describes the repository to OAI-PMH harvesters
+type OAIConfig struct {
+	RepositoryName string
+	// RepositoryIdentifier is the namespace of OAI item identifiers, usually the domain
+	RepositoryIdentifier string
+	AdminEmail           string
+}
+
+// JobsConfig holds the intervals of background jobs
+type JobsConfig struct {
+	RelatedItemsInterval     time.Duration
+	OverdueRemindersInterval time.Duration
+	HoldsInterval            time.Duration
+	FullTextInterval         time.Duration
+	SpellingInterval         time.Duration
+	SavedSearchesInterval    time.Duration
+	IndexRetryInterval       time.Duration
+}
+
 func LoadConfig() *Config {
 	// Load .env file if it exists
 	godotenv.Load()
 
+	baseURL := getEnv("BASE_URL", "http://localhost:8080")
+	repositoryIdentifier := "localhost"
+	if parsed, err := url.Parse(baseURL); err == nil && parsed.Hostname() != "" {
+		repositoryIdentifier = parsed.Hostname()
+	}
+
 	maxUploadSize, _ := strconv.ParseInt(getEnv("MAX_UPLOAD_SIZE", "52428800"), 10, 64) // 50MB default
 
 	return &Config{
@@ -51,8 +88,9 @@ func LoadConfig() *Config {
 			Name:     getEnv("DB_NAME", "e_repository_db"),
 		},
 		Server: ServerConfig{
-			Port:    getEnv("PORT", "8080"),
-			BaseURL: getEnv("BASE_URL", "http://localhost:8080"),
+			Port:        getEnv("PORT", "8080"),
+			BaseURL:     baseURL,
+			FrontendURL: strings.TrimRight(getEnv("FRONTEND_URL", "http://localhost:3000"), "/"),
 		},
 		JWT: JWTConfig{
 			Secret: getEnv("JWT_SECRET", "your_secure_jwt_secret_key_here"),
@@ -61,6 +99,23 @@ func LoadConfig() *Config {
 			Path:          getEnv("UPLOAD_PATH", "./uploads"),
 			MaxUploadSize: maxUploadSize,
 		},
+		Search: SearchConfig{
+			IndexPath: getEnv("SEARCH_INDEX_PATH", "./data/search.bleve"),
+		},
+		OAI: OAIConfig{
+			RepositoryName:       getEnv("OAI_REPOSITORY_NAME", "E-Repository"),
+			RepositoryIdentifier: getEnv("OAI_REPOSITORY_IDENTIFIER", repositoryIdentifier),
+			AdminEmail:           getEnv("OAI_ADMIN_EMAIL", "admin@"+repositoryIdentifier),
+		},
+		Jobs: JobsConfig{
+			RelatedItemsInterval:     getEnvDuration("RELATED_ITEMS_INTERVAL", 6*time.Hour),
+			OverdueRemindersInterval: getEnvDuration("OVERDUE_REMINDERS_INTERVAL", 24*time.Hour),
+			HoldsInterval:            getEnvDuration("HOLDS_INTERVAL", 10*time.Minute),
+			FullTextInterval:         getEnvDuration("FULLTEXT_INTERVAL", 5*time.Minute),
+			SpellingInterval:         getEnvDuration("SPELLING_INTERVAL", time.Hour),
+			SavedSearchesInterval:    getEnvDuration("SAVED_SEARCHES_INTERVAL", time.Hour),
+			IndexRetryInterval:       getEnvDuration("INDEX_RETRY_INTERVAL", 5*time.Minute),
+		},
 	}
 }
 
@@ -70,3 +125,11 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration reads a duration such as "30m" or "6h" from the environment
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
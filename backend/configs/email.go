@@ -18,6 +18,17 @@ type EmailConfig struct {
 
 // NewEmailConfig creates a new email configuration
 func NewEmailConfig() *EmailConfig {
+	config, err := LoadEmailConfig()
+	if err != nil {
+		panic(fmt.Sprintf("Invalid email configuration: %v", err))
+	}
+
+	return config
+}
+
+// LoadEmailConfig reads the email configuration, returning an error instead of
+// panicking when it is incomplete (for background jobs that can run without email)
+func LoadEmailConfig() (*EmailConfig, error) {
 	config := &EmailConfig{
 		SMTPHost:       getEnv("SMTP_HOST", "smtp.gmail.com"),
 		SMTPPort:       getEnv("SMTP_PORT", "587"),
@@ -29,10 +40,10 @@ func NewEmailConfig() *EmailConfig {
 	}
 
 	if err := config.validate(); err != nil {
-		panic(fmt.Sprintf("Invalid email configuration: %v", err))
+		return nil, err
 	}
 
-	return config
+	return config, nil
 }
 
 // validate checks if the email configuration is valid
@@ -76,9 +76,36 @@ func Migrate() error {
 		&models.PaperAuthor{},
 		&models.ActivityLog{},
 		&models.Counter{},
+		&models.DataMigration{},
 		&models.FileUpload{},
 		&models.Download{},
 		&models.PasswordResetToken{},
+		&models.KeywordGroup{},
+		&models.Keyword{},
+		&models.AuthorshipClaim{},
+		&models.Author{},
+		&models.AuthorVariant{},
+		&models.UserBook{},
+		&models.UserPaper{},
+		&models.ReadingList{},
+		&models.ReadingListEntry{},
+		&models.RelatedItem{},
+		&models.Review{},
+		&models.ReviewReport{},
+		&models.Copy{},
+		&models.LoanRule{},
+		&models.Holiday{},
+		&models.Loan{},
+		&models.Hold{},
+		&models.StockTake{},
+		&models.StockTakeItem{},
+		&models.ItemText{},
+		&models.ItemPage{},
+		&models.PendingIndexUpdate{},
+		&models.SavedSearch{},
+		&models.Notification{},
+		&models.DeletedItem{},
+		&models.ImportedRecord{},
 	)
 
 	if err != nil {
@@ -152,6 +179,23 @@ func SeedData() error {
 		}
 	}
 
+	// Seed default loan rules (editable by librarians afterwards)
+	loanRules := []models.LoanRule{
+		{UserType: "student", MaxLoans: 3, LoanDays: 14, MaxRenewals: 1, FinePerDay: 1000},
+		{UserType: "lecturer", MaxLoans: 10, LoanDays: 30, MaxRenewals: 2, FinePerDay: 1000},
+	}
+
+	for _, rule := range loanRules {
+		var existingCount int64
+		DB.Model(&models.LoanRule{}).Where("user_type = ?", rule.UserType).Count(&existingCount)
+
+		if existingCount == 0 {
+			if err := DB.Create(&rule).Error; err != nil {
+				log.Printf("Failed to create loan rule %s: %v", rule.UserType, err)
+			}
+		}
+	}
+
 	log.Println("Database seeding completed")
 	return nil
 }
@@ -180,5 +224,32 @@ type BookAuthor = models.BookAuthor
 type PaperAuthor = models.PaperAuthor
 type ActivityLog = models.ActivityLog
 type Counter = models.Counter
+type DataMigration = models.DataMigration
 type FileUpload = models.FileUpload
 type Download = models.Download
+type Keyword = models.Keyword
+type KeywordGroup = models.KeywordGroup
+type AuthorshipClaim = models.AuthorshipClaim
+type Author = models.Author
+type AuthorVariant = models.AuthorVariant
+type UserBook = models.UserBook
+type UserPaper = models.UserPaper
+type ReadingList = models.ReadingList
+type ReadingListEntry = models.ReadingListEntry
+type RelatedItem = models.RelatedItem
+type Review = models.Review
+type ReviewReport = models.ReviewReport
+type Copy = models.Copy
+type LoanRule = models.LoanRule
+type Holiday = models.Holiday
+type Loan = models.Loan
+type Hold = models.Hold
+type StockTake = models.StockTake
+type StockTakeItem = models.StockTakeItem
+type ItemText = models.ItemText
+type ItemPage = models.ItemPage
+type PendingIndexUpdate = models.PendingIndexUpdate
+type SavedSearch = models.SavedSearch
+type Notification = models.Notification
+type DeletedItem = models.DeletedItem
+type ImportedRecord = models.ImportedRecord
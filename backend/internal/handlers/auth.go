@@ -17,6 +17,7 @@ import (
 	"e-repository-api/configs"
 	"e-repository-api/internal/database"
 	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
 	"e-repository-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -857,6 +858,14 @@ func (h *AuthHandler) GetAllUsers(c *gin.Context) {
 			searchTerm, searchTerm, searchTerm)
 	}
 
+	// Sorting, newest first by default
+	order, err := services.UserSorts.Parse(req.Sort, "created_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = query.Session(&gorm.Session{})
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -866,11 +875,27 @@ func (h *AuthHandler) GetAllUsers(c *gin.Context) {
 
 	// Get paginated results
 	var users []database.User
-	offset := (req.Page - 1) * req.Limit
-	if err := query.Offset(offset).Limit(req.Limit).Find(&users).Error; err != nil {
+	page := listPage{sort: order, cursor: req.Cursor, page: req.Page, limit: req.Limit}
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := paged.Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
+	fetched := len(users)
+	if fetched > req.Limit {
+		users = users[:req.Limit]
+	}
+	var nextCursor *string
+	if len(users) > 0 {
+		if nextCursor, err = page.next(query, fetched, users[len(users)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+	}
 
 	// Remove sensitive information
 	for i := range users {
@@ -883,6 +908,7 @@ func (h *AuthHandler) GetAllUsers(c *gin.Context) {
 		"limit":       req.Limit,
 		"total_pages": int(math.Ceil(float64(total) / float64(req.Limit))),
 		"data":        users,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -986,10 +1012,20 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	// Members must return their loans and settle fines before their account goes
+	if services.HasOpenCirculation(h.db, user.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "User has books on loan or unpaid fines"})
+		return
+	}
+
 	log.Printf("[Admin User Delete] Deleting user ID: %s, Name: %s, Email: %s", id, user.Name, user.Email)
 
 	// Start a transaction to ensure data consistency
 	tx := h.db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -1025,6 +1061,14 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 	log.Printf("[Admin User Delete] Deleted %d downloads by user", downloadCount)
 
 	// 3. Delete all books created by this user and their associated files
+	// Books with physical copies belong to the library collection, so they are kept
+	// and only detached from the uploader
+	if err := tx.Model(&models.Book{}).Where("created_by = ? AND id IN (?)", user.ID, tx.Model(&models.Copy{}).Select("book_id")).
+		Update("created_by", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detach user's catalogued books"})
+		return
+	}
 	var books []models.Book
 	if err := tx.Where("created_by = ?", user.ID).Find(&books).Error; err != nil {
 		tx.Rollback()
@@ -1045,6 +1089,14 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 			utils.DeleteFileIfUnreferenced(tx, "books", "cover_image_url", *book.CoverImageURL, book.ID)
 		}
 
+		// Record the deletion and remove its keyword links, reviews, full text and other
+		// records, before its categories are unlinked
+		if err := services.DeleteItemRecords(tx, "book", book.ID); err != nil {
+			tx.Rollback()
+			deleteError(c, err, "Failed to delete book records")
+			return
+		}
+
 		// Delete book authors
 		if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookAuthor{}).Error; err != nil {
 			tx.Rollback()
@@ -1089,6 +1141,14 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 			utils.DeleteFileIfUnreferenced(tx, "papers", "cover_image_url", *paper.CoverImageURL, paper.ID)
 		}
 
+		// Record the deletion and remove its keyword links, reviews, full text and other
+		// records, before its categories are unlinked
+		if err := services.DeleteItemRecords(tx, "paper", paper.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete paper records"})
+			return
+		}
+
 		// Delete paper authors
 		if err := tx.Where("paper_id = ?", paper.ID).Delete(&models.PaperAuthor{}).Error; err != nil {
 			tx.Rollback()
@@ -1118,7 +1178,54 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 		utils.DeleteFileIfUnreferenced(tx, "users", "profile_picture_url", *user.ProfilePictureURL, user.ID)
 	}
 
-	// 6. Delete the user
+	// 6. Unlink the user from author entries and records and drop their authorship claims
+	for _, table := range []string{"book_authors", "paper_authors"} {
+		if err := tx.Table(table).Where("user_id = ?", user.ID).Update("user_id", nil).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink author entries"})
+			return
+		}
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.AuthorshipClaim{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete authorship claims"})
+		return
+	}
+	if err := tx.Model(&models.Author{}).Where("user_id = ?", user.ID).Update("user_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink author records"})
+		return
+	}
+
+	// 7. Delete the user's bookmarks and reading lists
+	if err := services.DeleteUserSavedItems(tx, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reading lists"})
+		return
+	}
+
+	// 8. Delete the user's reviews and abuse reports
+	if err := services.DeleteUserReviews(tx, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reviews"})
+		return
+	}
+
+	// 9. Delete the user's loan history
+	if err := services.DeleteUserLoans(tx, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete loan history"})
+		return
+	}
+
+	// 10. Cancel the user's holds, passing copies set aside for them on
+	if err := services.CancelUserHolds(tx, user.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel holds"})
+		return
+	}
+
+	// 11. Delete the user
 	if err := tx.Delete(&user).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
@@ -1132,6 +1239,14 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	// Drop the deleted items from the search index
+	for _, book := range books {
+		services.RemoveFromIndex(h.db, "book", book.ID)
+	}
+	for _, paper := range papers {
+		services.RemoveFromIndex(h.db, "paper", paper.ID)
+	}
+
 	log.Printf("[Admin User Delete] Successfully deleted user ID: %s with %d books, %d papers, %d citations, %d downloads",
 		id, len(books), len(papers), citationCount, downloadCount)
 
@@ -1174,6 +1289,10 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 
 	// Start a transaction for the entire bulk operation
 	tx := h.db.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		return
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -1187,6 +1306,7 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 		Citations int
 		Downloads int
 	}
+	var deletedItems []services.ItemRef
 
 	// Process each user
 	for _, userID := range req.UserIDs {
@@ -1197,6 +1317,12 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 			return
 		}
 
+		if services.HasOpenCirculation(tx, user.ID) {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("User with ID %d has books on loan or unpaid fines", userID)})
+			return
+		}
+
 		log.Printf("[Admin Bulk User Delete] Deleting user ID: %d, Name: %s, Email: %s", userID, user.Name, user.Email)
 
 		// 1. Delete all citations by this user
@@ -1228,6 +1354,14 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 		totalDeleted.Downloads += int(downloadCount)
 
 		// 3. Delete all books created by this user and their associated files
+		// Books with physical copies belong to the library collection, so they are kept
+		// and only detached from the uploader
+		if err := tx.Model(&models.Book{}).Where("created_by = ? AND id IN (?)", user.ID, tx.Model(&models.Copy{}).Select("book_id")).
+			Update("created_by", nil).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detach user's catalogued books"})
+			return
+		}
 		var books []models.Book
 		if err := tx.Where("created_by = ?", user.ID).Find(&books).Error; err != nil {
 			tx.Rollback()
@@ -1246,6 +1380,14 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 				utils.DeleteFileIfUnreferenced(tx, "books", "cover_image_url", *book.CoverImageURL, book.ID)
 			}
 
+			// Record the deletion and remove its keyword links, reviews, full text and other
+			// records, before its categories are unlinked
+			if err := services.DeleteItemRecords(tx, "book", book.ID); err != nil {
+				tx.Rollback()
+				deleteError(c, err, "Failed to delete book records")
+				return
+			}
+
 			// Delete book authors
 			if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookAuthor{}).Error; err != nil {
 				tx.Rollback()
@@ -1259,6 +1401,9 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book categories"})
 				return
 			}
+
+			deletedItems = append(deletedItems, services.ItemRef{Type: "book", ID: book.ID})
+
 		}
 
 		// Delete all books
@@ -1288,6 +1433,14 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 				utils.DeleteFileIfUnreferenced(tx, "papers", "cover_image_url", *paper.CoverImageURL, paper.ID)
 			}
 
+			// Record the deletion and remove its keyword links, reviews, full text and other
+			// records, before its categories are unlinked
+			if err := services.DeleteItemRecords(tx, "paper", paper.ID); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete paper records"})
+				return
+			}
+
 			// Delete paper authors
 			if err := tx.Where("paper_id = ?", paper.ID).Delete(&models.PaperAuthor{}).Error; err != nil {
 				tx.Rollback()
@@ -1301,6 +1454,8 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete paper categories"})
 				return
 			}
+
+			deletedItems = append(deletedItems, services.ItemRef{Type: "paper", ID: paper.ID})
 		}
 
 		// Delete all papers
@@ -1316,7 +1471,54 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 			utils.DeleteFileIfUnreferenced(tx, "users", "profile_picture_url", *user.ProfilePictureURL, user.ID)
 		}
 
-		// 6. Delete the user
+		// 6. Unlink the user from author entries and records and drop their authorship claims
+		for _, table := range []string{"book_authors", "paper_authors"} {
+			if err := tx.Table(table).Where("user_id = ?", user.ID).Update("user_id", nil).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink author entries"})
+				return
+			}
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.AuthorshipClaim{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete authorship claims"})
+			return
+		}
+		if err := tx.Model(&models.Author{}).Where("user_id = ?", user.ID).Update("user_id", nil).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink author records"})
+			return
+		}
+
+		// 7. Delete the user's bookmarks and reading lists
+		if err := services.DeleteUserSavedItems(tx, user.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reading lists"})
+			return
+		}
+
+		// 8. Delete the user's reviews and abuse reports
+		if err := services.DeleteUserReviews(tx, user.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reviews"})
+			return
+		}
+
+		// 9. Delete the user's loan history
+		if err := services.DeleteUserLoans(tx, user.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete loan history"})
+			return
+		}
+
+		// 10. Cancel the user's holds, passing copies set aside for them on
+		if err := services.CancelUserHolds(tx, user.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel holds"})
+			return
+		}
+
+		// 11. Delete the user
 		if err := tx.Delete(&user).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
@@ -1332,6 +1534,11 @@ func (h *AuthHandler) BulkDeleteUsers(c *gin.Context) {
 		return
 	}
 
+	// Drop the deleted items from the search index
+	for _, item := range deletedItems {
+		services.RemoveFromIndex(h.db, item.Type, item.ID)
+	}
+
 	log.Printf("[Admin Bulk User Delete] Successfully deleted %d users with %d books, %d papers, %d citations, %d downloads",
 		totalDeleted.Users, totalDeleted.Books, totalDeleted.Papers, totalDeleted.Citations, totalDeleted.Downloads)
 
@@ -1385,6 +1592,12 @@ func (h *AuthHandler) GetPublicUser(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+
+	// Count every work the user authored, not only the ones they uploaded
+	var bookCount, paperCount int64
+	h.db.Raw("SELECT COUNT(*) FROM ("+userBookIDsSQL+") AS t", user.ID, user.ID).Scan(&bookCount)
+	h.db.Raw("SELECT COUNT(*) FROM ("+userPaperIDsSQL+") AS t", user.ID, user.ID).Scan(&paperCount)
+
 	c.JSON(http.StatusOK, gin.H{
 		"id":                  user.ID,
 		"name":                user.Name,
@@ -1393,5 +1606,7 @@ func (h *AuthHandler) GetPublicUser(c *gin.Context) {
 		"department":          user.Department,
 		"profile_picture_url": user.ProfilePictureURL,
 		"user_type":           user.UserType,
+		"book_count":          bookCount,
+		"paper_count":         paperCount,
 	})
 }
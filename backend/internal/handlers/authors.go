@@ -3,8 +3,13 @@ package handlers
 import (
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
+	"strings"
 
 	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,11 +17,36 @@ import (
 
 // AuthorResponse represents the response for author search
 type AuthorResponse struct {
-	Name       string `json:"name"`
-	BookCount  int64  `json:"bookCount"`
-	PaperCount int64  `json:"paperCount"`
+	ID         uint     `json:"id"`
+	Name       string   `json:"name"`
+	Variants   []string `json:"variants" gorm:"-"`
+	BookCount  int64    `json:"bookCount"`
+	PaperCount int64    `json:"paperCount"`
 }
 
+// AuthorDuplicate is an authority record that may describe the same person as another one
+type AuthorDuplicate struct {
+	AuthorResponse
+	Score float64 `json:"score"`
+}
+
+// authorEntryResponse is a book_authors or paper_authors entry attributed to an authority record
+type authorEntryResponse struct {
+	ID         uint   `json:"id"`
+	ItemID     uint   `json:"item_id"`
+	Title      string `json:"title"`
+	AuthorName string `json:"author_name"`
+}
+
+// authorCountsSelect selects authority record columns plus the number of books and
+// papers attributed to each record
+const authorCountsSelect = `authors.id, authors.preferred_name AS name,
+	(SELECT COUNT(DISTINCT ba.book_id) FROM book_authors ba WHERE ba.author_id = authors.id) AS book_count,
+	(SELECT COUNT(DISTINCT pa.paper_id) FROM paper_authors pa WHERE pa.author_id = authors.id) AS paper_count`
+
+// orcidPattern matches an ORCID iD such as 0000-0002-1825-0097
+var orcidPattern = regexp.MustCompile(`^\d{4}-\d{4}-\d{4}-\d{3}[\dX]$`)
+
 // AuthorHandler handles author-related requests
 type AuthorHandler struct {
 	db *gorm.DB
@@ -28,8 +58,14 @@ func NewAuthorHandler(db *gorm.DB) *AuthorHandler {
 }
 
 // GetAuthorWorks handles requests to get all works by an author
+// The name is resolved to its authority record, so works published under any
+// variant of the name are returned.
 func (h *AuthorHandler) GetAuthorWorks(c *gin.Context) {
 	authorName := c.Param("name")
+	if authorName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Author name is required"})
+		return
+	}
 
 	// URL decode the author name
 	decodedName, err := url.QueryUnescape(authorName)
@@ -38,32 +74,42 @@ func (h *AuthorHandler) GetAuthorWorks(c *gin.Context) {
 		return
 	}
 
-	// Get books by author using book_authors table
+	author, err := h.findAuthorByName(decodedName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve author"})
+		return
+	}
+
+	// Names without an authority record fall back to the exact author string
+	bookIDs := h.db.Table("book_authors").Select("book_id").Where("author_name = ?", decodedName)
+	paperIDs := h.db.Table("paper_authors").Select("paper_id").Where("author_name = ?", decodedName)
+	if author != nil {
+		bookIDs = h.db.Table("book_authors").Select("book_id").Where("author_id = ?", author.ID)
+		paperIDs = h.db.Table("paper_authors").Select("paper_id").Where("author_id = ?", author.ID)
+	}
+
 	var books []models.Book
-	if err := h.db.Joins("JOIN book_authors ON books.id = book_authors.book_id").
-		Where("book_authors.author_name = ?", decodedName).
-		Find(&books).Error; err != nil {
+	if err := h.db.Where("id IN (?)", bookIDs).Find(&books).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
 		return
 	}
 
-	// Get papers by author using paper_authors table
 	var papers []models.Paper
-	if err := h.db.Joins("JOIN paper_authors ON papers.id = paper_authors.paper_id").
-		Where("paper_authors.author_name = ?", decodedName).
-		Find(&papers).Error; err != nil {
+	if err := h.db.Where("id IN (?)", paperIDs).Find(&papers).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch papers"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"name":   decodedName,
+		"author": author,
 		"books":  books,
 		"papers": papers,
 	})
 }
 
 // SearchAuthors handles requests to search for authors
+// Results are authority records, matched by preferred name, any name variant or identifier.
 func (h *AuthorHandler) SearchAuthors(c *gin.Context) {
 	query := c.Query("query")
 
@@ -73,102 +119,539 @@ func (h *AuthorHandler) SearchAuthors(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search query"})
 		return
 	}
+	decodedQuery = strings.TrimSpace(decodedQuery)
+
+	// Only list records that still have works attributed to them
+	filter := h.db.Where("(EXISTS (SELECT 1 FROM book_authors ba WHERE ba.author_id = authors.id) OR EXISTS (SELECT 1 FROM paper_authors pa WHERE pa.author_id = authors.id))")
+	if decodedQuery != "" {
+		like := "%" + decodedQuery + "%"
+		variants := h.db.Table("author_variants").Select("author_id").Where("name LIKE ?", like)
+		if key := utils.NameKey(decodedQuery); key != "" {
+			variants = variants.Or("normalized LIKE ?", "%"+key+"%")
+		}
+		filter = filter.Where(h.db.Where("authors.preferred_name LIKE ?", like).
+			Or("authors.id IN (?)", variants).
+			Or("authors.nidn = ? OR authors.orcid = ? OR authors.sinta_id = ?", decodedQuery, decodedQuery, decodedQuery))
+	}
+
+	authors, err := h.authorSummaries(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authors": authors,
+	})
+}
+
+// GetAuthorDetail handles requests to get author details
+func (h *AuthorHandler) GetAuthorDetail(c *gin.Context) {
+	h.GetAuthorWorks(c)
+}
+
+// GetAuthor handles GET /admin/authors/:id
+// Returns the authority record with its variants and every author entry attributed to it.
+func (h *AuthorHandler) GetAuthor(c *gin.Context) {
+	id, ok := paramID(c, "id", "author")
+	if !ok {
+		return
+	}
+	var author models.Author
+	if err := h.db.Preload("Variants").Preload("User").First(&author, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	bookEntries := make([]authorEntryResponse, 0)
+	if err := h.db.Table("book_authors ba").
+		Select("ba.id, ba.book_id AS item_id, b.title, ba.author_name").
+		Joins("JOIN books b ON b.id = ba.book_id").
+		Where("ba.author_id = ?", author.ID).
+		Order("b.title ASC").Scan(&bookEntries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch book entries"})
+		return
+	}
 
-	// Get authors from books
-	var bookAuthors []struct {
-		AuthorName string
-		Count      int64
+	paperEntries := make([]authorEntryResponse, 0)
+	if err := h.db.Table("paper_authors pa").
+		Select("pa.id, pa.paper_id AS item_id, p.title, pa.author_name").
+		Joins("JOIN papers p ON p.id = pa.paper_id").
+		Where("pa.author_id = ?", author.ID).
+		Order("p.title ASC").Scan(&paperEntries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch paper entries"})
+		return
 	}
-	if err := h.db.Model(&models.BookAuthor{}).
-		Select("author_name, COUNT(*) as count").
-		Where("author_name LIKE ?", "%"+decodedQuery+"%").
-		Group("author_name").
-		Find(&bookAuthors).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search book authors"})
+
+	c.JSON(http.StatusOK, gin.H{
+		"author":        author,
+		"book_entries":  bookEntries,
+		"paper_entries": paperEntries,
+	})
+}
+
+// UpdateAuthor handles PUT /admin/authors/:id (preferred name, identifiers and linked account)
+// An empty identifier string clears it, and user_id 0 unlinks the account.
+func (h *AuthorHandler) UpdateAuthor(c *gin.Context) {
+	id, ok := paramID(c, "id", "author")
+	if !ok {
+		return
+	}
+	var author models.Author
+	if err := h.db.First(&author, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
 		return
 	}
 
-	// Get authors from papers
-	var paperAuthors []struct {
-		AuthorName string
-		Count      int64
+	var req struct {
+		PreferredName *string `json:"preferred_name"`
+		NIDN          *string `json:"nidn"`
+		ORCID         *string `json:"orcid"`
+		SintaID       *string `json:"sinta_id"`
+		UserID        *uint   `json:"user_id"`
 	}
-	if err := h.db.Model(&models.PaperAuthor{}).
-		Select("author_name, COUNT(*) as count").
-		Where("author_name LIKE ?", "%"+decodedQuery+"%").
-		Group("author_name").
-		Find(&paperAuthors).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search paper authors"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Combine results
-	authors := make(map[string]AuthorResponse)
-	for _, ba := range bookAuthors {
-		authors[ba.AuthorName] = AuthorResponse{
-			Name:      ba.AuthorName,
-			BookCount: ba.Count,
+	if req.NIDN != nil {
+		author.NIDN = blankToNil(*req.NIDN)
+	}
+	if req.ORCID != nil {
+		author.ORCID = blankToNil(strings.ToUpper(*req.ORCID))
+		if author.ORCID != nil && !orcidPattern.MatchString(*author.ORCID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ORCID must look like 0000-0002-1825-0097"})
+			return
+		}
+	}
+	if req.SintaID != nil {
+		author.SintaID = blankToNil(*req.SintaID)
+	}
+
+	for column, value := range map[string]*string{"nidn": author.NIDN, "orcid": author.ORCID, "sinta_id": author.SintaID} {
+		if value == nil {
+			continue
+		}
+		var count int64
+		if err := h.db.Model(&models.Author{}).Where(column+" = ? AND id <> ?", *value, author.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another author already has this " + strings.ToUpper(strings.ReplaceAll(column, "_", " ")) + "; merge the records instead"})
+			return
 		}
 	}
-	for _, pa := range paperAuthors {
-		if author, exists := authors[pa.AuthorName]; exists {
-			author.PaperCount = pa.Count
-			authors[pa.AuthorName] = author
+
+	if req.UserID != nil {
+		if *req.UserID == 0 {
+			author.UserID = nil
 		} else {
-			authors[pa.AuthorName] = AuthorResponse{
-				Name:       pa.AuthorName,
-				PaperCount: pa.Count,
+			var count int64
+			if err := h.db.Model(&models.User{}).Where("id = ?", *req.UserID).Count(&count).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+				return
+			}
+			if count == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "User not found"})
+				return
 			}
+			author.UserID = req.UserID
 		}
 	}
 
-	// Convert map to slice
-	authorList := make([]AuthorResponse, 0, len(authors))
-	for _, author := range authors {
-		authorList = append(authorList, author)
+	var newVariant *models.AuthorVariant
+	if req.PreferredName != nil {
+		name := strings.TrimSpace(*req.PreferredName)
+		normalized := utils.NameKey(name)
+		if normalized == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Preferred name is required"})
+			return
+		}
+
+		// The preferred name must be one of the record's own variants
+		var variants []models.AuthorVariant
+		if err := h.db.Where("normalized = ?", normalized).Limit(1).Find(&variants).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+			return
+		}
+		if len(variants) > 0 && variants[0].AuthorID != author.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "This name belongs to another author; merge the records instead"})
+			return
+		}
+		if len(variants) == 0 {
+			newVariant = &models.AuthorVariant{AuthorID: author.ID, Name: name, Normalized: normalized}
+		}
+		author.PreferredName = name
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"authors": authorList,
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if newVariant != nil {
+			if err := tx.Create(newVariant).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&author).Error
 	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+		return
+	}
+
+	h.db.Preload("Variants").First(&author, author.ID)
+	c.JSON(http.StatusOK, author)
 }
 
-// GetAuthorDetail handles requests to get author details
-func (h *AuthorHandler) GetAuthorDetail(c *gin.Context) {
-	authorName := c.Param("name")
-	if authorName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Author name is required"})
+// AddAuthorVariant handles POST /admin/authors/:id/variants
+func (h *AuthorHandler) AddAuthorVariant(c *gin.Context) {
+	id, ok := paramID(c, "id", "author")
+	if !ok {
+		return
+	}
+	var author models.Author
+	if err := h.db.First(&author, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
 		return
 	}
 
-	// URL decode the author name
-	decodedName, err := url.QueryUnescape(authorName)
+	var req struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	normalized := utils.NameKey(name)
+	if normalized == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variant name is required"})
+		return
+	}
+
+	var existing []models.AuthorVariant
+	h.db.Where("normalized = ?", normalized).Limit(1).Find(&existing)
+	if len(existing) > 0 {
+		if existing[0].AuthorID != author.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "This name belongs to another author; merge the records instead"})
+			return
+		}
+		c.JSON(http.StatusOK, existing[0])
+		return
+	}
+
+	variant := models.AuthorVariant{AuthorID: author.ID, Name: name, Normalized: normalized}
+	if err := h.db.Create(&variant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add name variant"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, variant)
+}
+
+// GetAuthorDuplicates handles GET /admin/authors/:id/duplicates
+// Lists other authority records whose names are similar enough to be the same person.
+func (h *AuthorHandler) GetAuthorDuplicates(c *gin.Context) {
+	id, ok := paramID(c, "id", "author")
+	if !ok {
+		return
+	}
+	var author models.Author
+	if err := h.db.Preload("Variants").First(&author, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	names := []string{author.PreferredName}
+	for _, variant := range author.Variants {
+		names = append(names, variant.Name)
+	}
+
+	// Narrow the candidates in SQL by any significant name token, then score in Go
+	seen := make(map[string]bool)
+	var patterns []string
+	for _, name := range names {
+		for _, token := range utils.NameTokens(name) {
+			if len(token) >= 3 && !seen[token] {
+				seen[token] = true
+				patterns = append(patterns, "%"+token+"%")
+			}
+		}
+	}
+	duplicates := make([]AuthorDuplicate, 0)
+	if len(patterns) == 0 {
+		c.JSON(http.StatusOK, gin.H{"duplicates": duplicates})
+		return
+	}
+
+	variantFilter := h.db.Table("author_variants").Select("author_id").Where("normalized LIKE ?", patterns[0])
+	for _, pattern := range patterns[1:] {
+		variantFilter = variantFilter.Or("normalized LIKE ?", pattern)
+	}
+	candidates, err := h.authorSummaries(h.db.Where("authors.id <> ? AND authors.id IN (?)", author.ID, variantFilter))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author name"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch candidate authors"})
 		return
 	}
 
-	// Get books by author
-	var books []models.Book
-	if err := h.db.Joins("JOIN book_authors ON books.id = book_authors.book_id").
-		Where("book_authors.author_name = ?", decodedName).
-		Find(&books).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+	for _, candidate := range candidates {
+		best := 0.0
+		for _, name := range names {
+			for _, other := range append([]string{candidate.Name}, candidate.Variants...) {
+				if score := services.AuthorNameSimilarity(name, other); score > best {
+					best = score
+				}
+			}
+		}
+		if best >= services.AuthorMatchThreshold {
+			duplicates = append(duplicates, AuthorDuplicate{AuthorResponse: candidate, Score: best})
+		}
+	}
+	sort.SliceStable(duplicates, func(i, j int) bool { return duplicates[i].Score > duplicates[j].Score })
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": duplicates})
+}
+
+// MergeAuthors handles POST /admin/authors/:id/merge
+// Moves the variants and works of the given records into this one and deletes them.
+// Identifiers and the linked account are kept from the merged records when this one has none.
+func (h *AuthorHandler) MergeAuthors(c *gin.Context) {
+	id, ok := paramID(c, "id", "author")
+	if !ok {
+		return
+	}
+	var target models.Author
+	if err := h.db.First(&target, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
 		return
 	}
 
-	// Get papers by author
-	var papers []models.Paper
-	if err := h.db.Joins("JOIN paper_authors ON papers.id = paper_authors.paper_id").
-		Where("paper_authors.author_name = ?", decodedName).
-		Find(&papers).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch papers"})
+	var req struct {
+		AuthorIDs []uint `json:"author_ids" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"name":   decodedName,
-		"books":  books,
-		"papers": papers,
+	var sourceIDs []uint
+	for _, id := range req.AuthorIDs {
+		if id != target.ID {
+			sourceIDs = append(sourceIDs, id)
+		}
+	}
+	if len(sourceIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Select at least one other author to merge"})
+		return
+	}
+
+	var sources []models.Author
+	if err := h.db.Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil || len(sources) != len(sourceIDs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "One or more authors not found"})
+		return
+	}
+
+	for _, source := range sources {
+		if target.NIDN == nil {
+			target.NIDN = source.NIDN
+		}
+		if target.ORCID == nil {
+			target.ORCID = source.ORCID
+		}
+		if target.SintaID == nil {
+			target.SintaID = source.SintaID
+		}
+		if target.UserID == nil {
+			target.UserID = source.UserID
+		}
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"book_authors", "paper_authors"} {
+			if err := tx.Table(table).Where("author_id IN ?", sourceIDs).Update("author_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.AuthorVariant{}).Where("author_id IN ?", sourceIDs).Update("author_id", target.ID).Error; err != nil {
+			return err
+		}
+		// Delete the merged records before saving their identifiers on the target
+		if err := tx.Delete(&models.Author{}, sourceIDs).Error; err != nil {
+			return err
+		}
+		return tx.Save(&target).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge authors"})
+		return
+	}
+
+	h.db.Preload("Variants").First(&target, target.ID)
+	c.JSON(http.StatusOK, target)
+}
+
+// SplitAuthor handles POST /admin/authors/:id/split
+// Moves the selected variants, the works published under them and any explicitly
+// selected author entries to a new authority record. Selecting entries without
+// variants separates different people who share the same name.
+func (h *AuthorHandler) SplitAuthor(c *gin.Context) {
+	id, ok := paramID(c, "id", "author")
+	if !ok {
+		return
+	}
+	var source models.Author
+	if err := h.db.Preload("Variants").First(&source, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	var req struct {
+		PreferredName  string `json:"preferred_name" binding:"required"`
+		VariantIDs     []uint `json:"variant_ids"`
+		BookAuthorIDs  []uint `json:"book_author_ids"`
+		PaperAuthorIDs []uint `json:"paper_author_ids"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.PreferredName)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Preferred name is required"})
+		return
+	}
+	if len(req.VariantIDs)+len(req.BookAuthorIDs)+len(req.PaperAuthorIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Select the variants or author entries to split off"})
+		return
+	}
+
+	selected := make(map[uint]bool)
+	for _, id := range req.VariantIDs {
+		selected[id] = true
+	}
+	movedNames := make(map[string]bool)
+	for _, variant := range source.Variants {
+		if selected[variant.ID] {
+			movedNames[variant.Normalized] = true
+		}
+	}
+	if len(movedNames) != len(selected) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Variants must belong to the author being split"})
+		return
+	}
+	if len(movedNames) > 0 && len(movedNames) == len(source.Variants) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one variant must stay with the original author"})
+		return
+	}
+
+	author := models.Author{PreferredName: name}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&author).Error; err != nil {
+			return err
+		}
+		if len(req.VariantIDs) > 0 {
+			if err := tx.Model(&models.AuthorVariant{}).Where("id IN ?", req.VariantIDs).Update("author_id", author.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, src := range []struct {
+			table    string
+			entryIDs []uint
+		}{{"book_authors", req.BookAuthorIDs}, {"paper_authors", req.PaperAuthorIDs}} {
+			var entries []struct {
+				ID         uint
+				AuthorName string
+			}
+			if err := tx.Table(src.table).Select("id, author_name").Where("author_id = ?", source.ID).Scan(&entries).Error; err != nil {
+				return err
+			}
+
+			move := append([]uint{}, src.entryIDs...)
+			for _, entry := range entries {
+				if movedNames[utils.NameKey(entry.AuthorName)] {
+					move = append(move, entry.ID)
+				}
+			}
+			if len(move) == 0 {
+				continue
+			}
+			if err := tx.Table(src.table).Where("id IN ? AND author_id = ?", move, source.ID).Update("author_id", author.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
 	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to split author"})
+		return
+	}
+
+	h.db.Preload("Variants").First(&author, author.ID)
+	c.JSON(http.StatusCreated, author)
+}
+
+// authorSummaries loads the authority records matched by filter with their name
+// variants and work counts
+func (h *AuthorHandler) authorSummaries(filter *gorm.DB) ([]AuthorResponse, error) {
+	authors := make([]AuthorResponse, 0)
+	if err := filter.Table("authors").Select(authorCountsSelect).
+		Order("authors.preferred_name ASC").Scan(&authors).Error; err != nil {
+		return nil, err
+	}
+	if len(authors) == 0 {
+		return authors, nil
+	}
+
+	ids := make([]uint, len(authors))
+	for i, author := range authors {
+		ids[i] = author.ID
+	}
+	var variants []models.AuthorVariant
+	if err := h.db.Where("author_id IN ?", ids).Order("name ASC").Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	byAuthor := make(map[uint][]string)
+	for _, variant := range variants {
+		byAuthor[variant.AuthorID] = append(byAuthor[variant.AuthorID], variant.Name)
+	}
+	for i := range authors {
+		authors[i].Variants = byAuthor[authors[i].ID]
+		if authors[i].Variants == nil {
+			authors[i].Variants = []string{}
+		}
+	}
+	return authors, nil
+}
+
+// findAuthorByName returns the authority record that has name as a variant, or nil
+func (h *AuthorHandler) findAuthorByName(name string) (*models.Author, error) {
+	normalized := utils.NameKey(name)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	var variants []models.AuthorVariant
+	if err := h.db.Where("normalized = ?", normalized).Limit(1).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	if len(variants) == 0 {
+		return nil, nil
+	}
+
+	var author models.Author
+	if err := h.db.Preload("Variants").First(&author, variants[0].AuthorID).Error; err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+// blankToNil trims s and returns nil when nothing is left
+func blankToNil(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
 }
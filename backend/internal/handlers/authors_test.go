@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
 	"e-repository-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -44,8 +45,8 @@ func TestGetAuthorWorks(t *testing.T) {
 		AuthorName: "Test Author",
 	}
 
-	db.Create(&bookAuthor)
-	db.Create(&paperAuthor)
+	services.CreateBookAuthor(db, &bookAuthor)
+	services.CreatePaperAuthor(db, &paperAuthor)
 
 	// Test cases
 	tests := []struct {
@@ -140,10 +141,10 @@ func TestSearchAuthors(t *testing.T) {
 	}
 
 	for i := range bookAuthors {
-		db.Create(&bookAuthors[i])
+		services.CreateBookAuthor(db, &bookAuthors[i])
 	}
 	for i := range paperAuthors {
-		db.Create(&paperAuthors[i])
+		services.CreatePaperAuthor(db, &paperAuthors[i])
 	}
 
 	// Test cases
@@ -219,3 +220,39 @@ func setupTestDB(t *testing.T) *gorm.DB {
 
 	return db
 }
+
+func TestAuthorHandlersParseIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewAuthorHandler(db)
+
+	for _, id := range []string{"1 OR 1=1", "x", "-1"} {
+		params := gin.Params{{Key: "id", Value: id}}
+		assert.Equal(t, http.StatusBadRequest, getHandler(handler.GetAuthor, 1, "admin", params, "").Code, id)
+		assert.Equal(t, http.StatusBadRequest, getHandler(handler.GetAuthorDuplicates, 1, "admin", params, "").Code, id)
+		for _, post := range []gin.HandlerFunc{handler.UpdateAuthor, handler.AddAuthorVariant, handler.MergeAuthors, handler.SplitAuthor} {
+			assert.Equal(t, http.StatusBadRequest, callHandler(post, 1, "admin", params, gin.H{}).Code, id)
+		}
+	}
+	assert.Equal(t, http.StatusNotFound, getHandler(handler.GetAuthor, 1, "admin", idParam(999999), "").Code)
+}
+
+func TestSplitAuthorRequiresName(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewAuthorHandler(db)
+
+	author := models.Author{PreferredName: "Budi Santoso"}
+	assert.NoError(t, db.Create(&author).Error)
+	variants := []models.AuthorVariant{
+		{AuthorID: author.ID, Name: "Budi Santoso", Normalized: utils.NameKey("Budi Santoso")},
+		{AuthorID: author.ID, Name: "B. Santoso", Normalized: utils.NameKey("B. Santoso")},
+	}
+	assert.NoError(t, db.Create(&variants).Error)
+
+	w := callHandler(handler.SplitAuthor, 1, "admin", idParam(author.ID), gin.H{"preferred_name": "   ", "variant_ids": []uint{variants[1].ID}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var count int64
+	db.Model(&models.Author{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
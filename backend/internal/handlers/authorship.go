@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// authorLinker decides which user account and authority record each author entry
+// of an item is linked to
+type authorLinker struct {
+	uploaderID   *uint
+	uploaderName string
+	existing     map[string]linkedAuthor
+}
+
+// linkedAuthor holds the links of an author entry as it was before the item was re-saved
+type linkedAuthor struct {
+	UserID   *uint
+	AuthorID *uint
+}
+
+// newAuthorLinker loads the current author links of an item (if it already exists)
+// and the uploader's name, so that re-saving an item keeps approved links and
+// authority assignments, and a new item only links the entry that matches the uploader
+func newAuthorLinker(db *gorm.DB, itemType string, itemID uint, uploaderID *uint) *authorLinker {
+	linker := &authorLinker{uploaderID: uploaderID, existing: make(map[string]linkedAuthor)}
+
+	if uploaderID != nil {
+		var uploader models.User
+		if err := db.Select("id", "name").First(&uploader, *uploaderID).Error; err == nil {
+			linker.uploaderName = uploader.Name
+		}
+	}
+
+	if itemID != 0 {
+		var entries []struct {
+			AuthorName string
+			UserID     *uint
+			AuthorID   *uint
+		}
+		db.Table(itemType+"_authors").Select("author_name, user_id, author_id").
+			Where(itemType+"_id = ?", itemID).Scan(&entries)
+		for _, entry := range entries {
+			linker.existing[strings.ToLower(strings.TrimSpace(entry.AuthorName))] = linkedAuthor{UserID: entry.UserID, AuthorID: entry.AuthorID}
+		}
+	}
+
+	return linker
+}
+
+// userIDFor returns the account an author entry with the given name belongs to
+func (l *authorLinker) userIDFor(authorName string) *uint {
+	if entry, ok := l.existing[strings.ToLower(strings.TrimSpace(authorName))]; ok && entry.UserID != nil {
+		return entry.UserID
+	}
+	if l.uploaderID != nil && services.AuthorNameSimilarity(authorName, l.uploaderName) >= services.AuthorMatchThreshold {
+		return l.uploaderID
+	}
+	return nil
+}
+
+// authorIDFor returns the authority record the entry was assigned to before the item
+// was re-saved. nil lets CreateBookAuthor or CreatePaperAuthor resolve it from the name.
+func (l *authorLinker) authorIDFor(authorName string) *uint {
+	return l.existing[strings.ToLower(strings.TrimSpace(authorName))].AuthorID
+}
+
+// errAuthorEntryLinked is returned when approving a claim for an entry that got linked meanwhile
+var errAuthorEntryLinked = errors.New("author entry already linked")
+
+// AuthorshipSuggestion is an unlinked author entry that may belong to the current user
+type AuthorshipSuggestion struct {
+	ItemType    string  `json:"item_type"`
+	ItemID      uint    `json:"item_id"`
+	AuthorID    uint    `json:"author_id"`
+	AuthorName  string  `json:"author_name"`
+	Title       string  `json:"title"`
+	Year        *int    `json:"year"`
+	Score       float64 `json:"score"`
+	ClaimStatus *string `json:"claim_status"`
+}
+
+// AuthorshipHandler handles authorship claim requests
+type AuthorshipHandler struct {
+	db *gorm.DB
+}
+
+// NewAuthorshipHandler creates a new authorship handler
+func NewAuthorshipHandler(db *gorm.DB) *AuthorshipHandler {
+	return &AuthorshipHandler{db: db}
+}
+
+// GetSuggestions handles GET /user/authorship/suggestions
+// Suggests unlinked author entries whose name is similar to the user's name.
+func (h *AuthorshipHandler) GetSuggestions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userObj := user.(models.User)
+
+	// Narrow the candidates in SQL by any significant name token, then score in Go
+	var patterns []string
+	for _, token := range utils.NameTokens(userObj.Name) {
+		if len(token) >= 3 {
+			patterns = append(patterns, "%"+token+"%")
+		}
+	}
+	if len(patterns) == 0 {
+		c.JSON(http.StatusOK, gin.H{"suggestions": []AuthorshipSuggestion{}})
+		return
+	}
+
+	suggestions := make([]AuthorshipSuggestion, 0)
+	for _, src := range []struct{ itemType, yearColumn string }{{"book", "published_year"}, {"paper", "year"}} {
+		query := h.db.Table(src.itemType + "_authors ia").
+			Select("ia.id AS author_id, ia.author_name, i.id AS item_id, i.title, i." + src.yearColumn + " AS year").
+			Joins("JOIN " + src.itemType + "s i ON i.id = ia." + src.itemType + "_id").
+			Where("ia.user_id IS NULL")
+
+		nameFilter := h.db.Where("ia.author_name LIKE ?", patterns[0])
+		for _, pattern := range patterns[1:] {
+			nameFilter = nameFilter.Or("ia.author_name LIKE ?", pattern)
+		}
+
+		var candidates []AuthorshipSuggestion
+		if err := query.Where(nameFilter).Limit(500).Scan(&candidates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search author entries"})
+			return
+		}
+
+		for _, candidate := range candidates {
+			score := services.AuthorNameSimilarity(candidate.AuthorName, userObj.Name)
+			if score < services.AuthorMatchThreshold {
+				continue
+			}
+			candidate.ItemType = src.itemType
+			candidate.Score = score
+			suggestions = append(suggestions, candidate)
+		}
+	}
+
+	// Mark entries the user has already claimed
+	var claims []models.AuthorshipClaim
+	h.db.Where("user_id = ?", userObj.ID).Find(&claims)
+	type entryKey struct {
+		itemType   string
+		itemID     uint
+		authorName string
+	}
+	claimed := make(map[entryKey]string)
+	for _, claim := range claims {
+		claimed[entryKey{claim.ItemType, claim.ItemID, claim.AuthorName}] = claim.Status
+	}
+	for i := range suggestions {
+		if status, ok := claimed[entryKey{suggestions[i].ItemType, suggestions[i].ItemID, suggestions[i].AuthorName}]; ok {
+			suggestions[i].ClaimStatus = &status
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// CreateClaim handles POST /user/authorship/claims
+func (h *AuthorshipHandler) CreateClaim(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	var req struct {
+		ItemType string  `json:"item_type" binding:"required,oneof=book paper"`
+		AuthorID uint    `json:"author_id" binding:"required"`
+		Note     *string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var entry struct {
+		ID         uint
+		ItemID     uint
+		UserID     *uint
+		AuthorName string
+	}
+	if err := h.db.Table(req.ItemType+"_authors").
+		Select("id, "+req.ItemType+"_id AS item_id, user_id, author_name").
+		Where("id = ?", req.AuthorID).Take(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author entry not found"})
+		return
+	}
+	if entry.UserID != nil {
+		if *entry.UserID == uid {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already linked to this author entry"})
+		} else {
+			c.JSON(http.StatusConflict, gin.H{"error": "This author entry is already linked to another account"})
+		}
+		return
+	}
+
+	var pending int64
+	h.db.Model(&models.AuthorshipClaim{}).
+		Where("user_id = ? AND item_type = ? AND item_id = ? AND author_name = ? AND status = ?", uid, req.ItemType, entry.ItemID, entry.AuthorName, "pending").
+		Count(&pending)
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a pending claim for this author entry"})
+		return
+	}
+
+	claim := models.AuthorshipClaim{
+		UserID:        uid,
+		ItemType:      req.ItemType,
+		ItemID:        entry.ItemID,
+		AuthorEntryID: entry.ID,
+		AuthorName:    entry.AuthorName,
+		Status:        "pending",
+		Note:          req.Note,
+	}
+	if err := h.db.Create(&claim).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create authorship claim"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, claim)
+}
+
+// GetMyClaims handles GET /user/authorship/claims
+func (h *AuthorshipHandler) GetMyClaims(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	claims := make([]models.AuthorshipClaim, 0)
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&claims).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authorship claims"})
+		return
+	}
+
+	c.JSON(http.StatusOK, claims)
+}
+
+// GetIncomingClaims handles GET /user/authorship/claims/incoming and GET /admin/authorship/claims
+// Admins see every claim; other users see claims on items they uploaded.
+func (h *AuthorshipHandler) GetIncomingClaims(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	query := h.db.Model(&models.AuthorshipClaim{}).Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name", "email", "user_type", "nim_nidn")
+	})
+	if c.GetString("user_role") != "admin" {
+		query = query.Where(
+			h.db.Where("item_type = 'book' AND item_id IN (?)", h.db.Table("books").Select("id").Where("created_by = ?", userID)).
+				Or("item_type = 'paper' AND item_id IN (?)", h.db.Table("papers").Select("id").Where("created_by = ?", userID)),
+		)
+	}
+	query = query.Where("status = ?", c.DefaultQuery("status", "pending"))
+
+	claims := make([]models.AuthorshipClaim, 0)
+	if err := query.Order("created_at ASC").Find(&claims).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch authorship claims"})
+		return
+	}
+
+	c.JSON(http.StatusOK, claims)
+}
+
+// ApproveClaim handles POST /user/authorship/claims/:id/approve
+func (h *AuthorshipHandler) ApproveClaim(c *gin.Context) {
+	h.reviewClaim(c, "approved")
+}
+
+// RejectClaim handles POST /user/authorship/claims/:id/reject
+func (h *AuthorshipHandler) RejectClaim(c *gin.Context) {
+	h.reviewClaim(c, "rejected")
+}
+
+// CancelClaim handles DELETE /user/authorship/claims/:id (withdraw a pending claim)
+func (h *AuthorshipHandler) CancelClaim(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	claimID, ok := paramID(c, "id", "claim")
+	if !ok {
+		return
+	}
+	var claim models.AuthorshipClaim
+	if err := h.db.First(&claim, claimID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Authorship claim not found"})
+		return
+	}
+	if claim.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to withdraw this claim"})
+		return
+	}
+	if claim.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only pending claims can be withdrawn"})
+		return
+	}
+
+	if err := h.db.Delete(&claim).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw authorship claim"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Authorship claim withdrawn"})
+}
+
+// reviewClaim approves or rejects a pending claim. Admins may review any claim;
+// uploaders may review claims on their own items made by other users.
+func (h *AuthorshipHandler) reviewClaim(c *gin.Context, status string) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+	isAdmin := c.GetString("user_role") == "admin"
+
+	claimID, ok := paramID(c, "id", "claim")
+	if !ok {
+		return
+	}
+	var claim models.AuthorshipClaim
+	if err := h.db.First(&claim, claimID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Authorship claim not found"})
+		return
+	}
+	if claim.Status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Claim has already been reviewed"})
+		return
+	}
+
+	if !isAdmin {
+		var createdBy *uint
+		h.db.Table(claim.ItemType+"s").Select("created_by").Where("id = ?", claim.ItemID).Scan(&createdBy)
+		if createdBy == nil || *createdBy != uid || claim.UserID == uid {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to review this claim"})
+			return
+		}
+	}
+
+	now := time.Now()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if status == "approved" {
+			// Author entries are recreated whenever an item is edited, so match by name
+			// rather than entry ID. Only unlinked entries are updated, so two approvals
+			// can't race.
+			result := tx.Table(claim.ItemType+"_authors").
+				Where(claim.ItemType+"_id = ? AND author_name = ? AND user_id IS NULL", claim.ItemID, claim.AuthorName).
+				Update("user_id", claim.UserID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errAuthorEntryLinked
+			}
+
+			// Other pending claims on the same entry can no longer succeed
+			if err := tx.Model(&models.AuthorshipClaim{}).
+				Where("item_type = ? AND item_id = ? AND author_name = ? AND status = ? AND id <> ?", claim.ItemType, claim.ItemID, claim.AuthorName, "pending", claim.ID).
+				Updates(map[string]interface{}{"status": "rejected", "reviewed_by": uid, "reviewed_at": now}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&claim).Updates(map[string]interface{}{"status": status, "reviewed_by": uid, "reviewed_at": now}).Error
+	})
+	if err == errAuthorEntryLinked {
+		c.JSON(http.StatusConflict, gin.H{"error": "This author entry is already linked to an account"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review authorship claim"})
+		return
+	}
+
+	h.db.First(&claim, claim.ID)
+	c.JSON(http.StatusOK, claim)
+}
@@ -13,6 +13,7 @@ import (
 
 	"e-repository-api/configs"
 	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
 	"e-repository-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -155,6 +156,9 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 		book.CoverImageURL = &coverImageURL
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "book", book.ID, book.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -174,15 +178,23 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 		bookAuthor := models.BookAuthor{
 			BookID:     book.ID,
 			AuthorName: authorName,
-			UserID:     book.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&bookAuthor).Error; err != nil {
+		if err := services.CreateBookAuthor(tx, &bookAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book authors"})
 			return
 		}
 	}
 
+	// Link subjects to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "book", book.ID, book.Subject, book.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save book subjects"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -190,6 +202,9 @@ func (h *BookHandler) CreateBook(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "book", book.ID)
+
 	// Update book count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_books").UpdateColumn("count", gorm.Expr("count + 1"))
 
@@ -326,6 +341,9 @@ func (h *BookHandler) CreateUserBook(c *gin.Context) {
 		book.CoverImageURL = &coverImageURL
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "book", book.ID, book.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -345,15 +363,23 @@ func (h *BookHandler) CreateUserBook(c *gin.Context) {
 		bookAuthor := models.BookAuthor{
 			BookID:     book.ID,
 			AuthorName: authorName,
-			UserID:     book.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&bookAuthor).Error; err != nil {
+		if err := services.CreateBookAuthor(tx, &bookAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book authors"})
 			return
 		}
 	}
 
+	// Link subjects to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "book", book.ID, book.Subject, book.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save book subjects"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -361,6 +387,9 @@ func (h *BookHandler) CreateUserBook(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "book", book.ID)
+
 	// Update book count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_books").UpdateColumn("count", gorm.Expr("count + 1"))
 
@@ -371,6 +400,8 @@ func (h *BookHandler) CreateUserBook(c *gin.Context) {
 }
 
 // GetBooks handles book listing with pagination and search
+// sort takes a field of services.BookSorts and asc or desc, e.g. downloads:desc. Pages are
+// selected by page number or by the next_cursor returned with the previous page.
 func (h *BookHandler) GetBooks(c *gin.Context) {
 	var req models.SearchRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -396,8 +427,14 @@ func (h *BookHandler) GetBooks(c *gin.Context) {
 
 	query := h.db.Model(&models.Book{}).Unscoped()
 
-	// Search functionality
-	if q != "" {
+	// Search functionality. Full-text mode also matches the text of the uploaded file.
+	fullText := q != "" && c.Query("fulltext") == "true"
+	if fullText {
+		searchTerm := "%" + strings.ToLower(q) + "%"
+		query = query.Joins("LEFT JOIN (?) AS fulltext_matches ON fulltext_matches.item_id = books.id", services.FullTextScores(h.db, "book", q)).
+			Where("fulltext_matches.item_id IS NOT NULL OR LOWER(title) LIKE ? OR LOWER(author) LIKE ? OR LOWER(summary) LIKE ? OR LOWER(isbn) LIKE ? OR CAST(published_year AS CHAR) LIKE ?",
+				searchTerm, searchTerm, searchTerm, searchTerm, searchTerm)
+	} else if q != "" {
 		searchTerm := "%" + strings.ToLower(q) + "%"
 		query = query.Where("LOWER(title) LIKE ? OR LOWER(author) LIKE ? OR LOWER(summary) LIKE ? OR LOWER(isbn) LIKE ? OR CAST(published_year AS CHAR) LIKE ?",
 			searchTerm, searchTerm, searchTerm, searchTerm, searchTerm)
@@ -415,11 +452,42 @@ func (h *BookHandler) GetBooks(c *gin.Context) {
 		query = query.Where("published_year = ?", *req.Year)
 	}
 
+	// Keyword filter (also matches grouped synonyms and translations)
+	if req.Keyword != "" {
+		bookIDs, err := services.KeywordItemIDs(h.db, "book", req.Keyword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve keyword"})
+			return
+		}
+		query = query.Where("books.id IN ?", bookIDs)
+	}
+
 	// After year filter
 	if createdBy := c.Query("created_by"); createdBy != "" {
 		query = query.Where("created_by = ?", createdBy)
 	}
 
+	// Works the user uploaded or is a linked author of
+	if authoredBy := c.Query("authored_by"); authoredBy != "" {
+		query = query.Where("books.id IN ("+userBookIDsSQL+")", authoredBy, authoredBy)
+	}
+
+	// Sorting: best full-text matches first, otherwise newest first
+	defaultSort := "created_at:desc"
+	if fullText {
+		defaultSort = "relevance:desc"
+	}
+	order, err := services.BookSorts.Parse(req.Sort, defaultSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if order.Field == "relevance" && !fullText {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "relevance sort requires fulltext=true"})
+		return
+	}
+	query = query.Session(&gorm.Session{})
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -429,27 +497,41 @@ func (h *BookHandler) GetBooks(c *gin.Context) {
 
 	// Get paginated results
 	var books []models.Book
-	offset := (req.Page - 1) * req.Limit
-
-	// Handle sorting
-	if req.Sort != "" {
-		sortParts := strings.Split(req.Sort, ":")
-		if len(sortParts) == 2 {
-			field := sortParts[0]
-			direction := strings.ToUpper(sortParts[1])
-			if direction == "DESC" || direction == "ASC" {
-				query = query.Order(fmt.Sprintf("%s %s", field, direction))
-			}
-		}
-	} else {
-		// Default sorting by created_at desc
-		query = query.Order("created_at DESC")
+	page := listPage{sort: order, cursor: req.Cursor, page: req.Page, limit: req.Limit}
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	if err := query.Preload("Authors").Offset(offset).Limit(req.Limit).Find(&books).Error; err != nil {
+	if err := paged.Preload("Authors").Find(&books).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get books"})
 		return
 	}
+	fetched := len(books)
+	if fetched > req.Limit {
+		books = books[:req.Limit]
+	}
+	var nextCursor *string
+	if len(books) > 0 {
+		if nextCursor, err = page.next(query, fetched, books[len(books)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get books"})
+			return
+		}
+	}
+
+	// Matching pages of the uploaded files, with highlighted snippets
+	var textMatches map[uint][]services.TextMatch
+	if fullText {
+		ids := make([]uint, len(books))
+		for i, book := range books {
+			ids[i] = book.ID
+		}
+		var err error
+		if textMatches, err = services.FullTextMatches(h.db, "book", ids, q, 3); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get full-text matches"})
+			return
+		}
+	}
 
 	// Format each book to include authors array like GetBook
 	var formattedBooks []gin.H
@@ -469,6 +551,8 @@ func (h *BookHandler) GetBooks(c *gin.Context) {
 			"file_url":        book.FileURL,
 			"cover_image_url": book.CoverImageURL,
 			"created_by":      book.CreatedBy,
+			"rating_average":  book.RatingAverage,
+			"rating_count":    book.RatingCount,
 			"created_at":      book.CreatedAt,
 			"updated_at":      book.UpdatedAt,
 		}
@@ -493,6 +577,13 @@ func (h *BookHandler) GetBooks(c *gin.Context) {
 				item["cover_image_url"] = coverURL
 			}
 		}
+		if fullText {
+			matches := textMatches[book.ID]
+			if matches == nil {
+				matches = []services.TextMatch{}
+			}
+			item["fulltext_matches"] = matches
+		}
 		formattedBooks = append(formattedBooks, item)
 	}
 
@@ -502,6 +593,7 @@ func (h *BookHandler) GetBooks(c *gin.Context) {
 		"limit":       req.Limit,
 		"total_pages": int(math.Ceil(float64(total) / float64(req.Limit))),
 		"data":        formattedBooks,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -562,6 +654,28 @@ func (h *BookHandler) GetUserBooks(c *gin.Context) {
 		query = query.Where("published_year = ?", *req.Year)
 	}
 
+	// Keyword filter (also matches grouped synonyms and translations)
+	if req.Keyword != "" {
+		bookIDs, err := services.KeywordItemIDs(h.db, "book", req.Keyword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve keyword"})
+			return
+		}
+		query = query.Where("books.id IN ?", bookIDs)
+	}
+
+	// Sorting, newest first by default. There is no full-text search here to rank by.
+	order, err := services.BookSorts.Parse(req.Sort, "created_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if order.Field == "relevance" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "relevance sort requires a full-text search"})
+		return
+	}
+	query = query.Session(&gorm.Session{})
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -571,11 +685,27 @@ func (h *BookHandler) GetUserBooks(c *gin.Context) {
 
 	// Get paginated results
 	var books []models.Book
-	offset := (req.Page - 1) * req.Limit
-	if err := query.Preload("Authors").Offset(offset).Limit(req.Limit).Find(&books).Error; err != nil {
+	page := listPage{sort: order, cursor: req.Cursor, page: req.Page, limit: req.Limit}
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := paged.Preload("Authors").Find(&books).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get books"})
 		return
 	}
+	fetched := len(books)
+	if fetched > req.Limit {
+		books = books[:req.Limit]
+	}
+	var nextCursor *string
+	if len(books) > 0 {
+		if nextCursor, err = page.next(query, fetched, books[len(books)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get books"})
+			return
+		}
+	}
 
 	// Format each book to include authors array like GetBook
 	var formattedBooks []gin.H
@@ -595,6 +725,8 @@ func (h *BookHandler) GetUserBooks(c *gin.Context) {
 			"file_url":        book.FileURL,
 			"cover_image_url": book.CoverImageURL,
 			"created_by":      book.CreatedBy,
+			"rating_average":  book.RatingAverage,
+			"rating_count":    book.RatingCount,
 			"created_at":      book.CreatedAt,
 			"updated_at":      book.UpdatedAt,
 		}
@@ -628,6 +760,7 @@ func (h *BookHandler) GetUserBooks(c *gin.Context) {
 		"limit":       req.Limit,
 		"total_pages": int(math.Ceil(float64(total) / float64(req.Limit))),
 		"data":        formattedBooks,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -658,6 +791,8 @@ func (h *BookHandler) GetBook(c *gin.Context) {
 		"file_url":        book.FileURL,
 		"cover_image_url": book.CoverImageURL,
 		"created_by":      book.CreatedBy,
+		"rating_average":  book.RatingAverage,
+		"rating_count":    book.RatingCount,
 		"created_at":      book.CreatedAt,
 		"updated_at":      book.UpdatedAt,
 	}
@@ -687,6 +822,20 @@ func (h *BookHandler) GetBook(c *gin.Context) {
 		}
 	}
 
+	// Physical copy counts by status
+	availability, err := services.GetBookAvailability(h.db, book.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get book availability"})
+		return
+	}
+	response["availability"] = availability
+
+	// Bookmark flag for logged-in users (set by OptionalAuthMiddleware)
+	response["is_bookmarked"] = false
+	if userID, exists := c.Get("user_id"); exists {
+		response["is_bookmarked"] = services.IsBookmarked(h.db, userID.(uint), "book", book.ID)
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -840,6 +989,9 @@ func (h *BookHandler) UpdateUserBook(c *gin.Context) {
 		book.CoverImageURL = &coverImageURL
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "book", book.ID, book.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -866,15 +1018,23 @@ func (h *BookHandler) UpdateUserBook(c *gin.Context) {
 		bookAuthor := models.BookAuthor{
 			BookID:     book.ID,
 			AuthorName: authorName,
-			UserID:     book.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&bookAuthor).Error; err != nil {
+		if err := services.CreateBookAuthor(tx, &bookAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book authors"})
 			return
 		}
 	}
 
+	// Link subjects to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "book", book.ID, book.Subject, book.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save book subjects"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -882,6 +1042,9 @@ func (h *BookHandler) UpdateUserBook(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "book", book.ID)
+
 	// Load authors for response
 	h.db.Preload("Authors").First(&book, book.ID)
 
@@ -910,6 +1073,34 @@ func (h *BookHandler) DeleteUserBook(c *gin.Context) {
 		return
 	}
 
+	// Physical copies must be withdrawn before the catalog record is removed
+	if services.HasCopies(h.db, book.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Book still has physical copies; delete them first"})
+		return
+	}
+	active, err := services.HasActiveHolds(h.db, book.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check holds"})
+		return
+	}
+	if active {
+		c.JSON(http.StatusConflict, gin.H{"error": "Members still have holds on this book; cancel them first"})
+		return
+	}
+
+	// Delete the book with its keyword links, reviews, full text and other records in
+	// one transaction, so a failure leaves it intact
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.DeleteItemRecords(tx, "book", book.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&book).Error
+	})
+	if err != nil {
+		deleteError(c, err, "Failed to delete book")
+		return
+	}
+
 	// Delete file if exists and not referenced by other books
 	if book.FileURL != nil {
 		utils.DeleteFileIfUnreferenced(h.db, "books", "file_url", *book.FileURL, book.ID)
@@ -919,11 +1110,8 @@ func (h *BookHandler) DeleteUserBook(c *gin.Context) {
 		utils.DeleteFileIfUnreferenced(h.db, "books", "cover_image_url", *book.CoverImageURL, book.ID)
 	}
 
-	// Delete from database
-	if err := h.db.Delete(&book).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
-		return
-	}
+	// Drop it from the search index
+	services.RemoveFromIndex(h.db, "book", book.ID)
 
 	// Update book count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_books").UpdateColumn("count", gorm.Expr("count - 1"))
@@ -1143,6 +1331,9 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		book.CoverImageURL = &coverImageURL
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "book", book.ID, book.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -1169,15 +1360,23 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		bookAuthor := models.BookAuthor{
 			BookID:     book.ID,
 			AuthorName: authorName,
-			UserID:     book.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&bookAuthor).Error; err != nil {
+		if err := services.CreateBookAuthor(tx, &bookAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book authors"})
 			return
 		}
 	}
 
+	// Link subjects to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "book", book.ID, book.Subject, book.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save book subjects"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -1185,6 +1384,9 @@ func (h *BookHandler) UpdateBook(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "book", book.ID)
+
 	// Load authors for response
 	h.db.Preload("Authors").First(&book, book.ID)
 
@@ -1202,6 +1404,34 @@ func (h *BookHandler) DeleteBook(c *gin.Context) {
 		return
 	}
 
+	// Physical copies must be withdrawn before the catalog record is removed
+	if services.HasCopies(h.db, book.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Book still has physical copies; delete them first"})
+		return
+	}
+	active, err := services.HasActiveHolds(h.db, book.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check holds"})
+		return
+	}
+	if active {
+		c.JSON(http.StatusConflict, gin.H{"error": "Members still have holds on this book; cancel them first"})
+		return
+	}
+
+	// Delete the book with its keyword links, reviews, full text and other records in
+	// one transaction, so a failure leaves it intact
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.DeleteItemRecords(tx, "book", book.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&book).Error
+	})
+	if err != nil {
+		deleteError(c, err, "Failed to delete book")
+		return
+	}
+
 	// Delete file if exists and not referenced by other books
 	if book.FileURL != nil {
 		utils.DeleteFileIfUnreferenced(h.db, "books", "file_url", *book.FileURL, book.ID)
@@ -1211,11 +1441,8 @@ func (h *BookHandler) DeleteBook(c *gin.Context) {
 		utils.DeleteFileIfUnreferenced(h.db, "books", "cover_image_url", *book.CoverImageURL, book.ID)
 	}
 
-	// Delete from database
-	if err := h.db.Delete(&book).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
-		return
-	}
+	// Drop it from the search index
+	services.RemoveFromIndex(h.db, "book", book.ID)
 
 	// Update book count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_books").UpdateColumn("count", gorm.Expr("count - 1"))
@@ -1259,7 +1486,8 @@ func (h *BookHandler) GetAuthorWorks(c *gin.Context) {
 	c.JSON(http.StatusOK, books)
 }
 
-// CiteBook handles citation logging for a book
+// CiteBook handles GET and POST /books/:id/cite?format=apa
+// It returns the citation of the book in the requested format; a POST also logs it as cited.
 func (h *BookHandler) CiteBook(c *gin.Context) {
 	id := c.Param("id")
 	var book models.Book
@@ -1270,22 +1498,6 @@ func (h *BookHandler) CiteBook(c *gin.Context) {
 		return
 	}
 
-	// Check if user is authenticated (optional for public citations)
-	userIDVal, exists := c.Get("user_id")
-	var userID *uint
-	if exists {
-		uid := userIDVal.(uint)
-		userID = &uid
-	}
-
-	// Log the citation
-	citation := models.Citation{
-		UserID:   userID,
-		ItemID:   book.ID,
-		ItemType: "book",
-		CitedAt:  time.Now(),
-	}
-	h.db.Create(&citation)
-
-	c.JSON(http.StatusOK, gin.H{"message": "Citation logged"})
+	refs := []services.ItemRef{{Type: "book", ID: book.ID}}
+	writeCitations(c, h.db, refs, c.DefaultQuery("format", "apa"), fmt.Sprintf("book-%d", book.ID))
 }
@@ -0,0 +1,617 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LoanResponse is a loan with its overdue state and the fine accrued so far
+type LoanResponse struct {
+	models.Loan
+	IsOverdue   bool    `json:"is_overdue"`
+	OverdueDays int     `json:"overdue_days"`
+	AccruedFine float64 `json:"accrued_fine"`
+	// TrappedFor is the hold a returned copy was set aside for, only set on check-in
+	TrappedFor *models.Hold `json:"trapped_for,omitempty"`
+}
+
+// CirculationHandler handles loans, returns, renewals and fines of physical copies
+type CirculationHandler struct {
+	db *gorm.DB
+}
+
+// NewCirculationHandler creates a new circulation handler
+func NewCirculationHandler(db *gorm.DB) *CirculationHandler {
+	return &CirculationHandler{db: db}
+}
+
+// Checkout handles POST /admin/circulation/checkout (lend a copy to a member by NIM/NIDN)
+func (h *CirculationHandler) Checkout(c *gin.Context) {
+	staffID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Barcode string `json:"barcode" binding:"required"`
+		Member  string `json:"member" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var bookCopy models.Copy
+	if err := h.db.Where("barcode = ?", strings.TrimSpace(req.Barcode)).First(&bookCopy).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Copy not found"})
+		return
+	}
+	member, ok := h.findMember(c, req.Member)
+	if !ok {
+		return
+	}
+
+	loan, err := services.Checkout(h.db, bookCopy.ID, *member, staffID.(uint))
+	if err != nil {
+		circulationError(c, err, "Failed to check out copy")
+		return
+	}
+
+	h.respondWithLoan(c, http.StatusCreated, loan.ID)
+}
+
+// Checkin handles POST /admin/circulation/checkin (return a copy by barcode)
+func (h *CirculationHandler) Checkin(c *gin.Context) {
+	staffID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Barcode string `json:"barcode" binding:"required"`
+		Damaged bool   `json:"damaged"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var loan models.Loan
+	if err := h.db.Joins("JOIN copies ON copies.id = loans.copy_id").
+		Where("copies.barcode = ? AND loans.returned_at IS NULL", strings.TrimSpace(req.Barcode)).
+		First(&loan).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No open loan for this copy"})
+		return
+	}
+
+	hold, err := services.Checkin(h.db, &loan, staffID.(uint), req.Damaged)
+	if err != nil {
+		circulationError(c, err, "Failed to check in copy")
+		return
+	}
+
+	response, err := h.loadLoanResponse(loan.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load loan"})
+		return
+	}
+	if hold != nil {
+		// The copy goes to the hold shelf instead of back on the shelf
+		h.db.Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "name", "email", "nim_nidn")
+		}).First(hold, hold.ID)
+		response.TrappedFor = hold
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RenewLoan handles POST /admin/circulation/loans/:id/renew
+func (h *CirculationHandler) RenewLoan(c *gin.Context) {
+	id, ok := paramID(c, "id", "loan")
+	if !ok {
+		return
+	}
+
+	var loan models.Loan
+	if err := h.db.First(&loan, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan not found"})
+		return
+	}
+	h.renew(c, &loan)
+}
+
+// RenewMyLoan handles POST /user/loans/:id/renew
+func (h *CirculationHandler) RenewMyLoan(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, ok := paramID(c, "id", "loan")
+	if !ok {
+		return
+	}
+
+	var loan models.Loan
+	if err := h.db.Where("user_id = ?", userID).First(&loan, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan not found"})
+		return
+	}
+	h.renew(c, &loan)
+}
+
+// renew extends a loan and writes the updated loan
+func (h *CirculationHandler) renew(c *gin.Context, loan *models.Loan) {
+	if err := services.Renew(h.db, loan); err != nil {
+		circulationError(c, err, "Failed to renew loan")
+		return
+	}
+	h.respondWithLoan(c, http.StatusOK, loan.ID)
+}
+
+// SettleFine handles POST /admin/circulation/loans/:id/fine (record a fine as paid or waived)
+func (h *CirculationHandler) SettleFine(c *gin.Context) {
+	id, ok := paramID(c, "id", "loan")
+	if !ok {
+		return
+	}
+
+	var loan models.Loan
+	if err := h.db.First(&loan, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan not found"})
+		return
+	}
+	if loan.FineStatus != "unpaid" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Loan has no unpaid fine"})
+		return
+	}
+
+	var req struct {
+		Action string `json:"action" binding:"required,oneof=pay waive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := map[string]string{"pay": "paid", "waive": "waived"}[req.Action]
+	if err := h.db.Model(&loan).Updates(map[string]interface{}{
+		"fine_status":     status,
+		"fine_settled_at": time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to settle fine"})
+		return
+	}
+
+	h.respondWithLoan(c, http.StatusOK, loan.ID)
+}
+
+// GetLoans handles GET /admin/circulation/loans
+// Filters: status (active, overdue, returned or unpaid) and member (NIM/NIDN). sort takes
+// a field of services.LoanSorts, due_date:asc by default.
+func (h *CirculationHandler) GetLoans(c *gin.Context) {
+	order, err := services.LoanSorts.Parse(c.Query("sort"), "due_date:asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 20, 100)
+
+	query := h.db.Model(&models.Loan{})
+	switch c.Query("status") {
+	case "active":
+		query = query.Where("returned_at IS NULL")
+	case "overdue":
+		query = query.Where("returned_at IS NULL AND due_date < ?", time.Now().Format(utils.DateLayout))
+	case "returned":
+		query = query.Where("returned_at IS NOT NULL")
+	case "unpaid":
+		query = query.Where("fine_status = 'unpaid'")
+	}
+	if member := strings.TrimSpace(c.Query("member")); member != "" {
+		query = query.Where("user_id IN (?)", h.db.Model(&models.User{}).Select("id").Where("nim_nidn = ?", member))
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	query.Count(&total)
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var loans []models.Loan
+	if err := h.preloadLoan(paged).Find(&loans).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loans"})
+		return
+	}
+	fetched := len(loans)
+	if fetched > page.limit {
+		loans = loans[:page.limit]
+	}
+	var nextCursor *string
+	if len(loans) > 0 {
+		if nextCursor, err = page.next(query, fetched, loans[len(loans)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loans"})
+			return
+		}
+	}
+	data, err := h.loanResponses(loans)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute fines"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"page":        page.page,
+		"limit":       page.limit,
+		"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+		"next_cursor": nextCursor,
+		"data":        data,
+	})
+}
+
+// GetMemberCirculation handles GET /admin/circulation/members/:member (by NIM/NIDN)
+func (h *CirculationHandler) GetMemberCirculation(c *gin.Context) {
+	member, ok := h.findMember(c, c.Param("member"))
+	if !ok {
+		return
+	}
+	h.memberCirculation(c, member)
+}
+
+// GetMyLoans handles GET /user/loans
+func (h *CirculationHandler) GetMyLoans(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var member models.User
+	if err := h.db.First(&member, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	h.memberCirculation(c, &member)
+}
+
+// memberCirculation writes a member's loan rule, open loans, outstanding fines and a
+// page of their loan history, latest return first unless sort selects another field of
+// services.LoanSorts
+func (h *CirculationHandler) memberCirculation(c *gin.Context, member *models.User) {
+	order, err := services.LoanSorts.Parse(c.Query("sort"), "returned_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 20, 100)
+
+	rule, err := services.LoanRuleFor(h.db, member.UserType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "No loan rule for this member type"})
+		return
+	}
+
+	var active []models.Loan
+	if err := h.preloadLoan(h.db.Where("user_id = ? AND returned_at IS NULL", member.ID)).
+		Order("due_date ASC").Find(&active).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loans"})
+		return
+	}
+
+	history := h.db.Model(&models.Loan{}).Where("user_id = ? AND returned_at IS NOT NULL", member.ID).Session(&gorm.Session{})
+	var total int64
+	history.Count(&total)
+	paged, err := page.apply(history)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var returned []models.Loan
+	if err := h.preloadLoan(paged).Find(&returned).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loan history"})
+		return
+	}
+	fetched := len(returned)
+	if fetched > page.limit {
+		returned = returned[:page.limit]
+	}
+	var nextCursor *string
+	if len(returned) > 0 {
+		if nextCursor, err = page.next(history, fetched, returned[len(returned)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loan history"})
+			return
+		}
+	}
+
+	activeData, err := h.loanResponses(active)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute fines"})
+		return
+	}
+	historyData, err := h.loanResponses(returned)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute fines"})
+		return
+	}
+
+	// Outstanding fines: unpaid fines of returned loans plus what open loans have accrued
+	var unpaid struct{ Total float64 }
+	h.db.Model(&models.Loan{}).Select("COALESCE(SUM(fine_amount), 0) AS total").
+		Where("user_id = ? AND fine_status = 'unpaid'", member.ID).Scan(&unpaid)
+	outstanding := unpaid.Total
+	for _, loan := range activeData {
+		outstanding += loan.AccruedFine
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"member": gin.H{
+			"id":        member.ID,
+			"name":      member.Name,
+			"email":     member.Email,
+			"user_type": member.UserType,
+			"nim_nidn":  member.NIMNIDN,
+		},
+		"rule":              rule,
+		"active":            activeData,
+		"outstanding_fines": outstanding,
+		"history": gin.H{
+			"total":       total,
+			"page":        page.page,
+			"limit":       page.limit,
+			"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+			"next_cursor": nextCursor,
+			"data":        historyData,
+		},
+	})
+}
+
+// GetLoanRules handles GET /admin/circulation/rules
+func (h *CirculationHandler) GetLoanRules(c *gin.Context) {
+	rules := make([]models.LoanRule, 0)
+	if err := h.db.Order("user_type ASC").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loan rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// UpdateLoanRule handles PUT /admin/circulation/rules/:userType
+func (h *CirculationHandler) UpdateLoanRule(c *gin.Context) {
+	var rule models.LoanRule
+	if err := h.db.Where("user_type = ?", c.Param("userType")).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan rule not found"})
+		return
+	}
+
+	var req struct {
+		MaxLoans    *int     `json:"max_loans" binding:"omitempty,min=0"`
+		LoanDays    *int     `json:"loan_days" binding:"omitempty,min=1"`
+		MaxRenewals *int     `json:"max_renewals" binding:"omitempty,min=0"`
+		FinePerDay  *float64 `json:"fine_per_day" binding:"omitempty,min=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.MaxLoans != nil {
+		rule.MaxLoans = *req.MaxLoans
+	}
+	if req.LoanDays != nil {
+		rule.LoanDays = *req.LoanDays
+	}
+	if req.MaxRenewals != nil {
+		rule.MaxRenewals = *req.MaxRenewals
+	}
+	if req.FinePerDay != nil {
+		rule.FinePerDay = *req.FinePerDay
+	}
+	if err := h.db.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update loan rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// GetHolidays handles GET /admin/circulation/holidays (upcoming, or a whole ?year=)
+func (h *CirculationHandler) GetHolidays(c *gin.Context) {
+	query := h.db.Model(&models.Holiday{})
+	if year, err := strconv.Atoi(c.Query("year")); err == nil {
+		query = query.Where("date >= ? AND date < ?", strconv.Itoa(year)+"-01-01", strconv.Itoa(year+1)+"-01-01")
+	} else {
+		query = query.Where("date >= ?", time.Now().Format(utils.DateLayout))
+	}
+
+	holidays := make([]models.Holiday, 0)
+	if err := query.Order("date ASC").Find(&holidays).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holidays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holidays)
+}
+
+// CreateHoliday handles POST /admin/circulation/holidays
+func (h *CirculationHandler) CreateHoliday(c *gin.Context) {
+	var req struct {
+		Date string `json:"date" binding:"required"`
+		Name string `json:"name" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	date, err := utils.ParseOptionalDate(&req.Date)
+	if err != nil || date == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date must be in YYYY-MM-DD format"})
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.Holiday{}).Where("date = ?", date.Format(utils.DateLayout)).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A holiday already exists on this date"})
+		return
+	}
+
+	holiday := models.Holiday{Date: *date, Name: strings.TrimSpace(req.Name)}
+	if err := h.db.Create(&holiday).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create holiday"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, holiday)
+}
+
+// DeleteHoliday handles DELETE /admin/circulation/holidays/:id
+func (h *CirculationHandler) DeleteHoliday(c *gin.Context) {
+	id, ok := paramID(c, "id", "holiday")
+	if !ok {
+		return
+	}
+
+	result := h.db.Delete(&models.Holiday{}, id)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete holiday"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Holiday not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Holiday deleted successfully"})
+}
+
+// findMember looks up a library member by NIM/NIDN, writing a 404 when there is none
+func (h *CirculationHandler) findMember(c *gin.Context, nimNIDN string) (*models.User, bool) {
+	var member models.User
+	if err := h.db.Where("nim_nidn = ?", strings.TrimSpace(nimNIDN)).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return nil, false
+	}
+	return &member, true
+}
+
+// preloadLoan loads the copy, book and member shown with a loan
+func (h *CirculationHandler) preloadLoan(query *gorm.DB) *gorm.DB {
+	return query.Preload("Copy.Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "author", "isbn")
+	}).Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name", "email", "user_type", "nim_nidn")
+	})
+}
+
+// respondWithLoan reloads a loan and writes it with its fine
+func (h *CirculationHandler) respondWithLoan(c *gin.Context, status int, loanID uint) {
+	response, err := h.loadLoanResponse(loanID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load loan"})
+		return
+	}
+
+	c.JSON(status, response)
+}
+
+// loadLoanResponse reloads a loan with its fine
+func (h *CirculationHandler) loadLoanResponse(loanID uint) (*LoanResponse, error) {
+	var loan models.Loan
+	if err := h.preloadLoan(h.db).First(&loan, loanID).Error; err != nil {
+		return nil, err
+	}
+	data, err := h.loanResponses([]models.Loan{loan})
+	if err != nil {
+		return nil, err
+	}
+	return &data[0], nil
+}
+
+// loanResponses adds the overdue state and accrued fine to loans (with User preloaded)
+func (h *CirculationHandler) loanResponses(loans []models.Loan) ([]LoanResponse, error) {
+	responses := make([]LoanResponse, 0, len(loans))
+	if len(loans) == 0 {
+		return responses, nil
+	}
+
+	var rules []models.LoanRule
+	if err := h.db.Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	finePerDay := make(map[string]float64, len(rules))
+	for _, rule := range rules {
+		finePerDay[rule.UserType] = rule.FinePerDay
+	}
+
+	earliest := loans[0].DueDate
+	for _, loan := range loans {
+		if loan.DueDate.Before(earliest) {
+			earliest = loan.DueDate
+		}
+	}
+	holidays, err := services.LoadHolidays(h.db, earliest)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, loan := range loans {
+		response := LoanResponse{Loan: loan, AccruedFine: loan.FineAmount}
+		if loan.ReturnedAt == nil {
+			response.OverdueDays = services.ChargeableOverdueDays(loan.DueDate, now, holidays)
+			response.IsOverdue = loan.DueDate.Format(utils.DateLayout) < now.Format(utils.DateLayout)
+			if loan.User != nil {
+				response.AccruedFine = float64(response.OverdueDays) * finePerDay[loan.User.UserType]
+			}
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// circulationError maps circulation rule violations to 409 Conflict responses
+func circulationError(c *gin.Context, err error, fallback string) {
+	for _, ruleErr := range []error{
+		services.ErrCopyUnavailable,
+		services.ErrLoanLimitReached,
+		services.ErrMemberHasOverdue,
+		services.ErrMemberHasUnpaidFines,
+		services.ErrLoanReturned,
+		services.ErrLoanOverdue,
+		services.ErrRenewalLimitReached,
+		services.ErrLoanChanged,
+		services.ErrCopyOnHold,
+		services.ErrBookHasHolds,
+		services.ErrNoCopies,
+		services.ErrCopyOnShelf,
+		services.ErrHoldExists,
+		services.ErrAlreadyBorrowed,
+		services.ErrHoldClosed,
+	} {
+		if errors.Is(err, ruleErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": strings.ToUpper(ruleErr.Error()[:1]) + ruleErr.Error()[1:]})
+			return
+		}
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
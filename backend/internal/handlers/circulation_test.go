@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// createLoanRule sets the loan rule of students for a test
+func createLoanRule(t *testing.T, db *gorm.DB, maxLoans, maxRenewals int) {
+	rule := models.LoanRule{UserType: "student", MaxLoans: maxLoans, LoanDays: 14, MaxRenewals: maxRenewals, FinePerDay: 1000}
+	if err := db.Create(&rule).Error; err != nil {
+		t.Fatalf("Failed to create loan rule: %v", err)
+	}
+}
+
+// createBorrower creates a student with a NIM that circulation can look up
+func createBorrower(t *testing.T, db *gorm.DB, email, nim string) models.User {
+	member := createMember(t, db, email, "Borrower "+nim, "student")
+	assert.NoError(t, db.Model(&member).Update("nim_nidn", nim).Error)
+	member.NIMNIDN = utils.StringPtr(nim)
+	return member
+}
+
+// createShelvedCopies creates a book with available copies of the given barcodes
+func createShelvedCopies(t *testing.T, db *gorm.DB, barcodes ...string) (models.Book, []models.Copy) {
+	book := models.Book{Title: "Sistem Operasi", Author: "Budi Santoso"}
+	assert.NoError(t, db.Create(&book).Error)
+	copies := make([]models.Copy, 0, len(barcodes))
+	for _, barcode := range barcodes {
+		bookCopy := models.Copy{BookID: book.ID, Barcode: barcode, Status: "available"}
+		assert.NoError(t, db.Create(&bookCopy).Error)
+		copies = append(copies, bookCopy)
+	}
+	return book, copies
+}
+
+// decodeLoan reads a loan response
+func decodeLoan(t *testing.T, body []byte) LoanResponse {
+	var response LoanResponse
+	assert.NoError(t, json.Unmarshal(body, &response))
+	return response
+}
+
+func TestCirculationCheckout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewCirculationHandler(db)
+
+	createLoanRule(t, db, 1, 1)
+	member := createBorrower(t, db, "borrower@example.com", "STU100")
+	other := createBorrower(t, db, "other@example.com", "STU200")
+	_, copies := createShelvedCopies(t, db, "C0001", "C0002")
+
+	w := callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0001", "member": "STU100"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	loan := decodeLoan(t, w.Body.Bytes())
+	assert.Equal(t, member.ID, loan.UserID)
+	assert.Nil(t, loan.ReturnedAt)
+	db.First(&copies[0], copies[0].ID)
+	assert.Equal(t, "on_loan", copies[0].Status)
+
+	// A copy on loan can't be lent again
+	w = callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0001", "member": "STU200"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// The student rule allows one loan at a time
+	w = callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0002", "member": "STU100"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Members with an overdue loan can't borrow more
+	overdue := models.Loan{CopyID: copies[1].ID, UserID: other.ID, LoanedAt: time.Now().AddDate(0, 0, -20),
+		DueDate: time.Now().AddDate(0, 0, -6), FineStatus: "none"}
+	assert.NoError(t, db.Create(&overdue).Error)
+	_, shelved := createShelvedCopies(t, db, "C0003")
+	w = callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": shelved[0].Barcode, "member": "STU200"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0001", "member": "UNKNOWN"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCirculationCheckin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewCirculationHandler(db)
+
+	createLoanRule(t, db, 3, 1)
+	member := createBorrower(t, db, "borrower@example.com", "STU100")
+	waiting := createBorrower(t, db, "waiting@example.com", "STU200")
+	book, copies := createShelvedCopies(t, db, "C0001", "C0002")
+
+	// Returned on time: no fine and the copy goes back on the shelf
+	w := callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0001", "member": "STU100"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = callHandler(handler.Checkin, 1, "admin", nil, gin.H{"barcode": "C0001"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	loan := decodeLoan(t, w.Body.Bytes())
+	assert.NotNil(t, loan.ReturnedAt)
+	assert.Equal(t, "none", loan.FineStatus)
+	db.First(&copies[0], copies[0].ID)
+	assert.Equal(t, "available", copies[0].Status)
+
+	// A copy scanned twice is only checked in once
+	w = callHandler(handler.Checkin, 1, "admin", nil, gin.H{"barcode": "C0001"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Returned late: holidays are not charged, and the copy is trapped for the next hold
+	late := models.Loan{CopyID: copies[1].ID, UserID: member.ID, LoanedAt: time.Now().AddDate(0, 0, -19),
+		DueDate: time.Now().AddDate(0, 0, -5), FineStatus: "none"}
+	assert.NoError(t, db.Create(&late).Error)
+	assert.NoError(t, db.Model(&copies[1]).Update("status", "on_loan").Error)
+	assert.NoError(t, db.Create(&models.Holiday{Date: time.Now().AddDate(0, 0, -3), Name: "Libur"}).Error)
+	hold := models.Hold{BookID: book.ID, UserID: waiting.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&hold).Error)
+
+	w = callHandler(handler.Checkin, 1, "admin", nil, gin.H{"barcode": "C0002"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	loan = decodeLoan(t, w.Body.Bytes())
+	assert.Equal(t, 4000.0, loan.FineAmount)
+	assert.Equal(t, "unpaid", loan.FineStatus)
+	if assert.NotNil(t, loan.TrappedFor) {
+		assert.Equal(t, hold.ID, loan.TrappedFor.ID)
+	}
+	db.First(&hold, hold.ID)
+	assert.Equal(t, "ready", hold.Status)
+	if assert.NotNil(t, hold.CopyID) {
+		assert.Equal(t, copies[1].ID, *hold.CopyID)
+	}
+
+	// The unpaid fine blocks new loans until it is settled
+	w = callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0001", "member": "STU100"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = callHandler(handler.SettleFine, 1, "admin", idParam(late.ID), gin.H{"action": "pay"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0001", "member": "STU100"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestCirculationRenew(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewCirculationHandler(db)
+
+	createLoanRule(t, db, 3, 1)
+	member := createBorrower(t, db, "borrower@example.com", "STU100")
+	other := createBorrower(t, db, "other@example.com", "STU200")
+	book, copies := createShelvedCopies(t, db, "C0001", "C0002")
+
+	w := callHandler(handler.Checkout, 1, "admin", nil, gin.H{"barcode": "C0001", "member": "STU100"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	loan := decodeLoan(t, w.Body.Bytes())
+
+	// Members renew only their own loans
+	w = callHandler(handler.RenewMyLoan, other.ID, "user", idParam(loan.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = callHandler(handler.RenewMyLoan, member.ID, "user", idParam(loan.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, decodeLoan(t, w.Body.Bytes()).RenewalCount)
+
+	// The student rule allows one renewal
+	w = callHandler(handler.RenewLoan, 1, "admin", idParam(loan.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// A renewal based on a stale read doesn't renew the loan twice
+	assert.NoError(t, db.Model(&models.LoanRule{}).Where("user_type = 'student'").Update("max_renewals", 3).Error)
+	stale := loan.Loan
+	assert.ErrorIs(t, services.Renew(db, &stale), services.ErrLoanChanged)
+	var renewed models.Loan
+	db.First(&renewed, loan.ID)
+	assert.Equal(t, 1, renewed.RenewalCount)
+
+	// Loans are not renewed while others wait for the book
+	hold := models.Hold{BookID: book.ID, UserID: other.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&hold).Error)
+	w = callHandler(handler.RenewLoan, 1, "admin", idParam(loan.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Overdue loans are not renewed
+	overdue := models.Loan{CopyID: copies[1].ID, UserID: member.ID, LoanedAt: time.Now().AddDate(0, 0, -20),
+		DueDate: time.Now().AddDate(0, 0, -6), FineStatus: "none"}
+	assert.NoError(t, db.Create(&overdue).Error)
+	w = callHandler(handler.RenewLoan, 1, "admin", idParam(overdue.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = callHandler(handler.RenewLoan, 1, "admin", gin.Params{{Key: "id", Value: "1 OR 1=1"}}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
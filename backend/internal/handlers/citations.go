@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxCitationExportItems is how many items a batch citation export may hold
+const maxCitationExportItems = 200
+
+// CitationHandler handles citation exports of several items at once
+type CitationHandler struct {
+	db *gorm.DB
+}
+
+// NewCitationHandler creates a new citation handler
+func NewCitationHandler(db *gorm.DB) *CitationHandler {
+	return &CitationHandler{db: db}
+}
+
+// ExportCitations handles GET and POST /citations/export?items=book:1,paper:2&format=ris
+// Only a POST logs the items as cited.
+func (h *CitationHandler) ExportCitations(c *gin.Context) {
+	var refs []services.ItemRef
+	seen := make(map[services.ItemRef]bool)
+	for _, value := range strings.Split(c.Query("items"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		itemType, rawID, _ := strings.Cut(value, ":")
+		id, err := strconv.ParseUint(rawID, 10, 64)
+		if (itemType != "book" && itemType != "paper") || err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid item %q, expected book:<id> or paper:<id>", value)})
+			return
+		}
+		ref := services.ItemRef{Type: itemType, ID: uint(id)}
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one item is required"})
+		return
+	}
+	if len(refs) > maxCitationExportItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d items can be exported at once", maxCitationExportItems)})
+		return
+	}
+
+	writeCitations(c, h.db, refs, c.DefaultQuery("format", "bibtex"), "citations")
+}
+
+// writeCitations formats the referenced items as citations and writes the document. With
+// ?download=true it is sent as a file attachment. A GET only reads, so that crawlers and
+// link previews don't inflate the citation counts; the items are logged as cited when
+// the citation is requested with a POST, the "cite" action of the web app.
+func writeCitations(c *gin.Context, db *gorm.DB, refs []services.ItemRef, format, fileName string) {
+	items, err := services.LoadCitationItems(db, refs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch items"})
+		return
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Items not found"})
+		return
+	}
+
+	body, contentType, extension, err := services.FormatCitations(items, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Request.Method == http.MethodPost {
+		logCitations(c, db, items)
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", fileName, extension))
+	}
+	c.Data(http.StatusOK, contentType, []byte(body))
+}
+
+// logCitations records the items as cited; the user is optional for public citations
+func logCitations(c *gin.Context, db *gorm.DB, items []services.CitationItem) {
+	var userID *uint
+	if value, exists := c.Get("user_id"); exists {
+		uid := value.(uint)
+		userID = &uid
+	}
+	citations := make([]models.Citation, len(items))
+	for i, item := range items {
+		citations[i] = models.Citation{UserID: userID, ItemID: item.ID, ItemType: item.Type, CitedAt: time.Now()}
+	}
+	if err := db.Create(&citations).Error; err != nil {
+		log.Printf("[Citations] Failed to log %d citations: %v", len(citations), err)
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"e-repository-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCiteLogsOnlyOnPost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewBookHandler(db, getTestConfig())
+	book, _ := createShelvedCopies(t, db)
+
+	cited := func() int64 {
+		var count int64
+		db.Model(&models.Citation{}).Where("item_type = 'book' AND item_id = ?", book.ID).Count(&count)
+		return count
+	}
+
+	// Reading a citation, as crawlers and link previews do, is not citing
+	w := getHandler(handler.CiteBook, 0, "", idParam(book.ID), "format=bibtex")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Sistem Operasi")
+	assert.Equal(t, int64(0), cited())
+
+	w = callHandler(handler.CiteBook, 0, "", idParam(book.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(1), cited())
+
+	w = getHandler(NewCitationHandler(db).ExportCitations, 0, "", nil, "items=book:1,book:x")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// copyRequest holds the editable fields of a physical copy. Blank strings clear
+// optional fields; on_loan is set by circulation only.
+type copyRequest struct {
+	Barcode         *string  `json:"barcode"`
+	CallNumber      *string  `json:"call_number"`
+	Location        *string  `json:"location"`
+	AcquisitionDate *string  `json:"acquisition_date"`
+	Source          *string  `json:"source"`
+	Price           *float64 `json:"price" binding:"omitempty,min=0"`
+	Status          *string  `json:"status" binding:"omitempty,oneof=available lost damaged"`
+	Notes           *string  `json:"notes"`
+}
+
+// apply copies the provided fields onto a copy record
+func (req copyRequest) apply(bookCopy *models.Copy) error {
+	if req.Barcode != nil {
+		bookCopy.Barcode = strings.TrimSpace(*req.Barcode)
+	}
+	if req.CallNumber != nil {
+		bookCopy.CallNumber = blankToNil(*req.CallNumber)
+	}
+	if req.Location != nil {
+		bookCopy.Location = blankToNil(*req.Location)
+	}
+	if req.AcquisitionDate != nil {
+		date, err := utils.ParseOptionalDate(req.AcquisitionDate)
+		if err != nil {
+			return err
+		}
+		bookCopy.AcquisitionDate = date
+	}
+	if req.Source != nil {
+		bookCopy.Source = blankToNil(*req.Source)
+	}
+	if req.Price != nil {
+		bookCopy.Price = req.Price
+	}
+	if req.Status != nil {
+		bookCopy.Status = *req.Status
+	}
+	if req.Notes != nil {
+		bookCopy.Notes = blankToNil(*req.Notes)
+	}
+	return nil
+}
+
+// CopyHandler manages the physical copies (eksemplar) of books
+type CopyHandler struct {
+	db *gorm.DB
+}
+
+// NewCopyHandler creates a new copy handler
+func NewCopyHandler(db *gorm.DB) *CopyHandler {
+	return &CopyHandler{db: db}
+}
+
+// GetBookCopies handles GET /admin/books/:id/copies
+func (h *CopyHandler) GetBookCopies(c *gin.Context) {
+	bookID, ok := paramID(c, "id", "book")
+	if !ok {
+		return
+	}
+	var book models.Book
+	if err := h.db.Select("id").First(&book, bookID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	copies := make([]models.Copy, 0)
+	if err := h.db.Where("book_id = ?", book.ID).Order("barcode ASC").Find(&copies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch copies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, copies)
+}
+
+// CreateCopy handles POST /admin/books/:id/copies
+func (h *CopyHandler) CreateCopy(c *gin.Context) {
+	bookID, ok := paramID(c, "id", "book")
+	if !ok {
+		return
+	}
+	var book models.Book
+	if err := h.db.Select("id").First(&book, bookID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	var req copyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bookCopy := models.Copy{BookID: book.ID, Status: "available"}
+	if err := req.apply(&bookCopy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Acquisition date must be in YYYY-MM-DD format"})
+		return
+	}
+	if bookCopy.Barcode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Barcode is required"})
+		return
+	}
+	if h.barcodeTaken(bookCopy.Barcode, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another copy already has this barcode"})
+		return
+	}
+
+	if err := h.db.Create(&bookCopy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create copy"})
+		return
+	}
+
+	// A new copy goes straight to the first member waiting for the book
+	if _, err := services.TrapCopy(h.db, bookCopy.ID); err != nil {
+		log.Printf("[Copy Create] Failed to trap copy %d for a hold: %v", bookCopy.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, bookCopy)
+}
+
+// GetCopies handles GET /admin/copies
+// Filters: barcode (prefix), status, location (substring) and book_id. sort takes a field
+// of services.CopySorts, barcode:asc by default.
+func (h *CopyHandler) GetCopies(c *gin.Context) {
+	order, err := services.CopySorts.Parse(c.Query("sort"), "barcode:asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 20, 100)
+
+	query := h.db.Model(&models.Copy{})
+	if barcode := strings.TrimSpace(c.Query("barcode")); barcode != "" {
+		query = query.Where("barcode LIKE ?", barcode+"%")
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if location := strings.TrimSpace(c.Query("location")); location != "" {
+		query = query.Where("location LIKE ?", "%"+location+"%")
+	}
+	if bookID := c.Query("book_id"); bookID != "" {
+		query = query.Where("book_id = ?", bookID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	query.Count(&total)
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	copies := make([]models.Copy, 0)
+	if err := paged.Preload("Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "author", "isbn")
+	}).Find(&copies).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch copies"})
+		return
+	}
+	fetched := len(copies)
+	if fetched > page.limit {
+		copies = copies[:page.limit]
+	}
+	var nextCursor *string
+	if len(copies) > 0 {
+		if nextCursor, err = page.next(query, fetched, copies[len(copies)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch copies"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"page":        page.page,
+		"limit":       page.limit,
+		"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+		"next_cursor": nextCursor,
+		"data":        copies,
+	})
+}
+
+// GetCopyByBarcode handles GET /admin/copies/barcode/:barcode (scanner lookup)
+func (h *CopyHandler) GetCopyByBarcode(c *gin.Context) {
+	var bookCopy models.Copy
+	if err := h.db.Preload("Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "author", "isbn")
+	}).Where("barcode = ?", strings.TrimSpace(c.Param("barcode"))).First(&bookCopy).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Copy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, bookCopy)
+}
+
+// GetCopy handles GET /admin/copies/:id
+func (h *CopyHandler) GetCopy(c *gin.Context) {
+	copyID, ok := paramID(c, "id", "copy")
+	if !ok {
+		return
+	}
+	var bookCopy models.Copy
+	if err := h.db.Preload("Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "author", "isbn")
+	}).First(&bookCopy, copyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Copy not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, bookCopy)
+}
+
+// UpdateCopy handles PUT /admin/copies/:id
+func (h *CopyHandler) UpdateCopy(c *gin.Context) {
+	copyID, ok := paramID(c, "id", "copy")
+	if !ok {
+		return
+	}
+	var bookCopy models.Copy
+	if err := h.db.First(&bookCopy, copyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Copy not found"})
+		return
+	}
+
+	var req copyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Status != nil && bookCopy.Status == "on_loan" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Copy is on loan; check it in before changing its status"})
+		return
+	}
+	if req.Status != nil && *req.Status != bookCopy.Status && services.TrappedHold(h.db, bookCopy.ID) != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Copy is on the hold shelf; cancel the hold before changing its status"})
+		return
+	}
+	if err := req.apply(&bookCopy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Acquisition date must be in YYYY-MM-DD format"})
+		return
+	}
+	if bookCopy.Barcode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Barcode is required"})
+		return
+	}
+	if h.barcodeTaken(bookCopy.Barcode, bookCopy.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another copy already has this barcode"})
+		return
+	}
+
+	if err := h.db.Save(&bookCopy).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update copy"})
+		return
+	}
+
+	// A repaired or found copy fills the next hold; losing the last one ends the queue
+	if bookCopy.Status == "available" {
+		if _, err := services.TrapCopy(h.db, bookCopy.ID); err != nil {
+			log.Printf("[Copy Update] Failed to trap copy %d for a hold: %v", bookCopy.ID, err)
+		}
+	} else if err := services.CancelOrphanedHolds(h.db, bookCopy.BookID); err != nil {
+		log.Printf("[Copy Update] Failed to cancel holds of book %d: %v", bookCopy.BookID, err)
+	}
+
+	c.JSON(http.StatusOK, bookCopy)
+}
+
+// DeleteCopy handles DELETE /admin/copies/:id (withdraw a copy from the collection)
+func (h *CopyHandler) DeleteCopy(c *gin.Context) {
+	copyID, ok := paramID(c, "id", "copy")
+	if !ok {
+		return
+	}
+	var bookCopy models.Copy
+	if err := h.db.First(&bookCopy, copyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Copy not found"})
+		return
+	}
+	if bookCopy.Status == "on_loan" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Copy is on loan; check it in before withdrawing it"})
+		return
+	}
+	if services.TrappedHold(h.db, bookCopy.ID) != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Copy is on the hold shelf; cancel the hold before withdrawing it"})
+		return
+	}
+
+	// Copies that have been lent are kept so their loan history stays intact
+	var loans int64
+	h.db.Model(&models.Loan{}).Where("copy_id = ?", bookCopy.ID).Count(&loans)
+	if loans > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Copy has circulation history; mark it lost or damaged instead"})
+		return
+	}
+
+	// Closed holds and stock-take reports keep their record without the copy
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Hold{}).Where("copy_id = ?", bookCopy.ID).Update("copy_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.StockTakeItem{}).Where("copy_id = ?", bookCopy.ID).Update("copy_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&bookCopy).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete copy"})
+		return
+	}
+	if err := services.CancelOrphanedHolds(h.db, bookCopy.BookID); err != nil {
+		log.Printf("[Copy Delete] Failed to cancel holds of book %d: %v", bookCopy.BookID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Copy deleted successfully"})
+}
+
+// barcodeTaken reports whether a barcode is used by a copy other than exceptID
+func (h *CopyHandler) barcodeTaken(barcode string, exceptID uint) bool {
+	var count int64
+	h.db.Model(&models.Copy{}).Where("barcode = ? AND id <> ?", barcode, exceptID).Count(&count)
+	return count > 0
+}
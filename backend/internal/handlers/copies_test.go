@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// createCopy adds a copy through the handler and returns it
+func createCopy(t *testing.T, handler *CopyHandler, bookID uint, barcode string) models.Copy {
+	w := callHandler(handler.CreateCopy, 1, "admin", idParam(bookID), gin.H{"barcode": barcode, "location": "Rak A1"})
+	if !assert.Equal(t, http.StatusCreated, w.Code) {
+		t.FailNow()
+	}
+	var bookCopy models.Copy
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &bookCopy))
+	return bookCopy
+}
+
+func TestCopyBarcodes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewCopyHandler(db)
+
+	book := models.Book{Title: "Algoritma", Author: "Budi Santoso"}
+	assert.NoError(t, db.Create(&book).Error)
+
+	first := createCopy(t, handler, book.ID, "B0001")
+	assert.Equal(t, "available", first.Status)
+	second := createCopy(t, handler, book.ID, " B0002 ")
+	assert.Equal(t, "B0002", second.Barcode)
+
+	// Barcodes are unique across the collection
+	w := callHandler(handler.CreateCopy, 1, "admin", idParam(book.ID), gin.H{"barcode": "B0001"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = callHandler(handler.UpdateCopy, 1, "admin", idParam(second.ID), gin.H{"barcode": "B0001"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// A copy keeps its own barcode when other fields change
+	w = callHandler(handler.UpdateCopy, 1, "admin", idParam(first.ID), gin.H{"barcode": "B0001", "location": "Rak B2"})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = callHandler(handler.CreateCopy, 1, "admin", idParam(book.ID), gin.H{"barcode": "  "})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = callHandler(handler.GetCopyByBarcode, 1, "admin", gin.Params{{Key: "barcode", Value: "B0002"}}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = callHandler(handler.GetCopy, 1, "admin", gin.Params{{Key: "id", Value: "1 OR 1=1"}}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCopyStatusTransitions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewCopyHandler(db)
+
+	book := models.Book{Title: "Statistika", Author: "Ani Wijaya"}
+	assert.NoError(t, db.Create(&book).Error)
+	lent := createCopy(t, handler, book.ID, "S0001")
+	shelved := createCopy(t, handler, book.ID, "S0002")
+
+	// Only circulation puts a copy on loan
+	w := callHandler(handler.UpdateCopy, 1, "admin", idParam(shelved.ID), gin.H{"status": "on_loan"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.NoError(t, db.Model(&lent).Update("status", "on_loan").Error)
+	w = callHandler(handler.UpdateCopy, 1, "admin", idParam(lent.ID), gin.H{"status": "lost"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = callHandler(handler.DeleteCopy, 1, "admin", idParam(lent.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Shelved copies can be lost, damaged and found again
+	for _, status := range []string{"damaged", "lost", "available"} {
+		w = callHandler(handler.UpdateCopy, 1, "admin", idParam(shelved.ID), gin.H{"status": status})
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.First(&shelved, shelved.ID)
+		assert.Equal(t, status, shelved.Status)
+	}
+
+	availability, err := services.GetBookAvailability(db, book.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, availability.Total)
+	assert.Equal(t, 1, availability.Available)
+	assert.Equal(t, 1, availability.OnLoan)
+}
+
+func TestDeleteBookWithCopies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewCopyHandler(db)
+	bookHandler := NewBookHandler(db, getTestConfig())
+
+	book := models.Book{Title: "Kalkulus", Author: "Budi Santoso"}
+	assert.NoError(t, db.Create(&book).Error)
+	bookCopy := createCopy(t, handler, book.ID, "K0001")
+	assert.True(t, services.HasCopies(db, book.ID))
+
+	// The catalog record stays while the library holds copies
+	w := callHandler(bookHandler.DeleteBook, 1, "admin", idParam(book.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = callHandler(handler.DeleteCopy, 1, "admin", idParam(bookCopy.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, services.HasCopies(db, book.ID))
+
+	w = callHandler(bookHandler.DeleteBook, 1, "admin", idParam(book.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCopyListPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewCopyHandler(db)
+
+	createShelvedCopies(t, db, "L0003", "L0001", "L0002")
+
+	type copyPage struct {
+		Total      int64         `json:"total"`
+		NextCursor *string       `json:"next_cursor"`
+		Data       []models.Copy `json:"data"`
+	}
+	barcodes := func(page copyPage) []string {
+		result := make([]string, 0, len(page.Data))
+		for _, bookCopy := range page.Data {
+			result = append(result, bookCopy.Barcode)
+		}
+		return result
+	}
+
+	// The cursor of a page continues where it ended
+	w := getHandler(handler.GetCopies, 1, "admin", nil, "limit=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var first copyPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	assert.Equal(t, int64(3), first.Total)
+	assert.Equal(t, []string{"L0001", "L0002"}, barcodes(first))
+	if !assert.NotNil(t, first.NextCursor) {
+		t.FailNow()
+	}
+
+	w = getHandler(handler.GetCopies, 1, "admin", nil, "limit=2&cursor="+*first.NextCursor)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var second copyPage
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+	assert.Equal(t, []string{"L0003"}, barcodes(second))
+	assert.Nil(t, second.NextCursor)
+
+	w = getHandler(handler.GetCopies, 1, "admin", nil, "sort=barcode:desc&page=2&limit=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+	assert.Equal(t, []string{"L0001"}, barcodes(second))
+
+	// A cursor only continues the sort it was issued for
+	w = getHandler(handler.GetCopies, 1, "admin", nil, "sort=barcode:desc&cursor="+*first.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = getHandler(handler.GetCopies, 1, "admin", nil, "sort=shelf")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
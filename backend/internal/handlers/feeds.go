@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/search"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FeedHandler serves Atom and RSS feeds of newly added books and papers
+type FeedHandler struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+// NewFeedHandler creates a new feed handler
+func NewFeedHandler(db *gorm.DB, config *configs.Config) *FeedHandler {
+	return &FeedHandler{db: db, config: config}
+}
+
+// AtomFeed handles GET /feeds/atom
+func (h *FeedHandler) AtomFeed(c *gin.Context) {
+	h.serveFeed(c, "application/atom+xml; charset=utf-8", services.Feed.Atom)
+}
+
+// RSSFeed handles GET /feeds/rss
+func (h *FeedHandler) RSSFeed(c *gin.Context) {
+	h.serveFeed(c, "application/rss+xml; charset=utf-8", services.Feed.RSS)
+}
+
+// serveFeed lists the newest items matching the search query q and the filters of
+// the unified search (type, category, faculty, department, author ID and so on), at
+// most limit of them. Responses carry an ETag and Last-Modified so that feed readers
+// polling with If-None-Match or If-Modified-Since get 304 Not Modified.
+func (h *FeedHandler) serveFeed(c *gin.Context, contentType string, render func(services.Feed) ([]byte, error)) {
+	filters, err := searchFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	limit := services.FeedDefaultSize
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(limit, services.FeedMaxSize)
+	}
+	q := strings.TrimSpace(c.Query("q"))
+
+	refs, err := services.NewestItems(h.db, q, filters, limit)
+	var syntaxErr *search.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": syntaxErr.Error(), "position": syntaxErr.Position})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+	links := services.ItemLinks{ServerURL: h.config.Server.BaseURL, FrontendURL: h.config.Server.FrontendURL}
+	entries, err := services.LoadFeedEntries(h.db, refs, links)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+
+	// The web app's search page takes the same parameters as the feed
+	page := c.Request.URL.Query()
+	page.Del("limit")
+	feed := services.Feed{
+		Title:       feedTitle(q, filters),
+		Description: "Books and papers newly added to the E-Repository",
+		SelfURL:     h.config.Server.BaseURL + c.Request.URL.RequestURI(),
+		PageURL:     h.config.Server.FrontendURL + "/search?" + page.Encode(),
+		Entries:     entries,
+	}
+	body, err := render(feed)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render feed"})
+		return
+	}
+
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	lastModified := feed.LastModified()
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// notModified reports whether the client's copy of a response is current. As in RFC
+// 9110, If-Modified-Since is only considered when the request has no If-None-Match.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+	// HTTP dates have whole seconds
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// feedTitle describes what a feed lists, e.g. "New papers in Fakultas Ilmu Komputer"
+func feedTitle(q string, filters services.SearchFilters) string {
+	title := "New books and papers"
+	if len(filters.Types) == 1 {
+		title = "New " + filters.Types[0] + "s"
+	}
+	var scope []string
+	scope = append(scope, filters.Categories...)
+	scope = append(scope, filters.Faculties...)
+	scope = append(scope, filters.Departments...)
+	if len(scope) > 0 {
+		title += " in " + strings.Join(scope, ", ")
+	}
+	if q != "" {
+		title += ` matching "` + q + `"`
+	}
+	return "E-Repository: " + title
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HoldResponse is a hold with its place in the book's queue (0 once it is ready)
+type HoldResponse struct {
+	models.Hold
+	Position int `json:"position"`
+}
+
+// HoldHandler handles the reservation queue for checked-out books
+type HoldHandler struct {
+	db *gorm.DB
+}
+
+// NewHoldHandler creates a new hold handler
+func NewHoldHandler(db *gorm.DB) *HoldHandler {
+	return &HoldHandler{db: db}
+}
+
+// PlaceHold handles POST /user/holds
+func (h *HoldHandler) PlaceHold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		BookID uint `json:"book_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var book models.Book
+	if err := h.db.Select("id").First(&book, req.BookID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	hold, err := services.PlaceHold(h.db, userID.(uint), book.ID)
+	if err != nil {
+		circulationError(c, err, "Failed to place hold")
+		return
+	}
+
+	if err := h.preloadHold(h.db).First(hold, hold.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load hold"})
+		return
+	}
+	data, err := h.holdResponses([]models.Hold{*hold})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load hold"})
+		return
+	}
+	c.JSON(http.StatusCreated, data[0])
+}
+
+// GetMyHolds handles GET /user/holds (active holds with queue positions, then recent closed ones)
+func (h *HoldHandler) GetMyHolds(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var holds []models.Hold
+	if err := h.preloadHold(h.db).Where("user_id = ?", userID).
+		Order("FIELD(status, 'waiting', 'ready') DESC, created_at DESC").Limit(50).
+		Find(&holds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+		return
+	}
+
+	data, err := h.holdResponses(holds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// CancelMyHold handles DELETE /user/holds/:id
+func (h *HoldHandler) CancelMyHold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, ok := paramID(c, "id", "hold")
+	if !ok {
+		return
+	}
+
+	var hold models.Hold
+	if err := h.db.Where("user_id = ?", userID).First(&hold, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hold not found"})
+		return
+	}
+	h.cancel(c, &hold)
+}
+
+// CancelHold handles DELETE /admin/circulation/holds/:id
+func (h *HoldHandler) CancelHold(c *gin.Context) {
+	id, ok := paramID(c, "id", "hold")
+	if !ok {
+		return
+	}
+
+	var hold models.Hold
+	if err := h.db.First(&hold, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hold not found"})
+		return
+	}
+	h.cancel(c, &hold)
+}
+
+// cancel closes a hold, passing a trapped copy on to the next member
+func (h *HoldHandler) cancel(c *gin.Context, hold *models.Hold) {
+	if err := services.CancelHold(h.db, hold); err != nil {
+		circulationError(c, err, "Failed to cancel hold")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Hold cancelled successfully"})
+}
+
+// GetHolds handles GET /admin/circulation/holds
+// Filters: status (default waiting and ready) and book_id. Holds are listed in queue order
+// unless sort selects another field of services.HoldSorts.
+func (h *HoldHandler) GetHolds(c *gin.Context) {
+	order, err := services.HoldSorts.Parse(c.Query("sort"), "queue:asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 20, 100)
+
+	query := h.db.Model(&models.Hold{})
+	if status := c.Query("status"); status != "" {
+		if !services.ValidHoldStatus(status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, expected one of " + strings.Join(services.HoldStatuses, ", ")})
+			return
+		}
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN ?", []string{"waiting", "ready"})
+	}
+	if value := c.Query("book_id"); value != "" {
+		bookID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+			return
+		}
+		query = query.Where("book_id = ?", bookID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+		return
+	}
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var holds []models.Hold
+	if err := h.preloadHold(paged).Find(&holds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+		return
+	}
+	fetched := len(holds)
+	if fetched > page.limit {
+		holds = holds[:page.limit]
+	}
+	var nextCursor *string
+	if len(holds) > 0 {
+		if nextCursor, err = page.next(query, fetched, holds[len(holds)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+			return
+		}
+	}
+
+	data, err := h.holdResponses(holds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"page":        page.page,
+		"limit":       page.limit,
+		"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+		"next_cursor": nextCursor,
+		"data":        data,
+	})
+}
+
+// GetPullList handles GET /admin/circulation/holds/pull-list
+// Lists the copies set aside for holds in shelf order, so they can be pulled in one walk.
+func (h *HoldHandler) GetPullList(c *gin.Context) {
+	holds := make([]models.Hold, 0)
+	if err := h.preloadHold(h.db.Joins("JOIN copies ON copies.id = holds.copy_id")).
+		Where("holds.status = 'ready'").
+		Order("copies.location ASC, copies.call_number ASC, copies.barcode ASC").
+		Find(&holds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pull list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holds)
+}
+
+// preloadHold loads the book, copy and member shown with a hold
+func (h *HoldHandler) preloadHold(query *gorm.DB) *gorm.DB {
+	return query.Preload("Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "author", "isbn")
+	}).Preload("Copy").Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name", "email", "user_type", "nim_nidn")
+	})
+}
+
+// holdResponses adds the queue positions of holds
+func (h *HoldHandler) holdResponses(holds []models.Hold) ([]HoldResponse, error) {
+	data := make([]HoldResponse, 0, len(holds))
+	for _, hold := range holds {
+		position, err := services.HoldPosition(h.db, hold)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, HoldResponse{Hold: hold, Position: position})
+	}
+	return data, nil
+}
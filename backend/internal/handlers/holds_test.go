@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHoldCancelRollsCopyOver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewHoldHandler(db)
+
+	first := createMember(t, db, "first@example.com", "First Reader", "student")
+	second := createMember(t, db, "second@example.com", "Second Reader", "student")
+	book, copies := createShelvedCopies(t, db, "H0001")
+
+	firstHold := models.Hold{BookID: book.ID, UserID: first.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&firstHold).Error)
+	secondHold := models.Hold{BookID: book.ID, UserID: second.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&secondHold).Error)
+
+	trapped, err := services.TrapCopy(db, copies[0].ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, trapped) {
+		assert.Equal(t, firstHold.ID, trapped.ID)
+	}
+
+	// Members cancel only their own holds
+	w := callHandler(handler.CancelMyHold, second.ID, "user", idParam(firstHold.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Cancelling a ready hold passes its copy to the next member in the queue
+	w = callHandler(handler.CancelMyHold, first.ID, "user", idParam(firstHold.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&firstHold, firstHold.ID)
+	assert.Equal(t, "cancelled", firstHold.Status)
+	db.First(&secondHold, secondHold.ID)
+	assert.Equal(t, "ready", secondHold.Status)
+	if assert.NotNil(t, secondHold.CopyID) {
+		assert.Equal(t, copies[0].ID, *secondHold.CopyID)
+	}
+
+	// A closed hold can't be cancelled again
+	w = callHandler(handler.CancelHold, 1, "admin", idParam(firstHold.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = callHandler(handler.CancelHold, 1, "admin", gin.Params{{Key: "id", Value: "1 OR 1=1"}}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExpireHolds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+
+	first := createMember(t, db, "first@example.com", "First Reader", "student")
+	second := createMember(t, db, "second@example.com", "Second Reader", "student")
+	book, copies := createShelvedCopies(t, db, "H0001")
+
+	firstHold := models.Hold{BookID: book.ID, UserID: first.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&firstHold).Error)
+	secondHold := models.Hold{BookID: book.ID, UserID: second.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&secondHold).Error)
+	_, err := services.TrapCopy(db, copies[0].ID)
+	assert.NoError(t, err)
+
+	// Holds still within their pickup deadline are kept
+	expired, err := services.ExpireHolds(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	// An uncollected copy rolls over to the next member
+	assert.NoError(t, db.Model(&firstHold).Update("pickup_deadline", time.Now().AddDate(0, 0, -1)).Error)
+	expired, err = services.ExpireHolds(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	db.First(&firstHold, firstHold.ID)
+	assert.Equal(t, "expired", firstHold.Status)
+	assert.NotNil(t, firstHold.ClosedAt)
+	db.First(&secondHold, secondHold.ID)
+	assert.Equal(t, "ready", secondHold.Status)
+
+	// With nobody left in the queue the copy goes back on the shelf
+	assert.NoError(t, db.Model(&secondHold).Update("pickup_deadline", time.Now().AddDate(0, 0, -1)).Error)
+	expired, err = services.ExpireHolds(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Nil(t, services.TrappedHold(db, copies[0].ID))
+	db.First(&copies[0], copies[0].ID)
+	assert.Equal(t, "available", copies[0].Status)
+}
+
+func TestClearBookHolds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+
+	member := createMember(t, db, "member@example.com", "Reader", "student")
+	book, _ := createShelvedCopies(t, db)
+
+	closed := models.Hold{BookID: book.ID, UserID: member.ID, Status: "cancelled"}
+	assert.NoError(t, db.Create(&closed).Error)
+	active := models.Hold{BookID: book.ID, UserID: member.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&active).Error)
+
+	// Active holds are never deleted with the book
+	assert.ErrorIs(t, services.ClearBookHolds(db, book.ID), services.ErrBookHasHolds)
+	bookHandler := NewBookHandler(db, getTestConfig())
+	w := callHandler(bookHandler.DeleteBook, 1, "admin", idParam(book.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	assert.NoError(t, services.CancelHold(db, &active))
+	assert.NoError(t, services.ClearBookHolds(db, book.ID))
+	var remaining int64
+	db.Model(&models.Hold{}).Where("book_id = ?", book.ID).Count(&remaining)
+	assert.Equal(t, int64(0), remaining)
+}
+
+func TestPlaceHoldAndListHolds(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewHoldHandler(db)
+
+	member := createMember(t, db, "reader@example.com", "Reader", "student")
+	book, copies := createShelvedCopies(t, db, "H0101")
+	assert.NoError(t, db.Model(&copies[0]).Update("status", "on_loan").Error)
+
+	w := callHandler(handler.PlaceHold, member.ID, "user", nil, gin.H{"book_id": book.ID})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"position":1`)
+
+	// A second hold on the same book is refused
+	w = callHandler(handler.PlaceHold, member.ID, "user", nil, gin.H{"book_id": book.ID})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var count int64
+	db.Model(&models.Hold{}).Where("user_id = ?", member.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	w = getHandler(handler.GetHolds, 1, "admin", nil, "book_id="+fmt.Sprint(book.ID))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+
+	w = getHandler(handler.GetHolds, 1, "admin", nil, "status=pending")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = getHandler(handler.GetHolds, 1, "admin", nil, "book_id=1%20OR%201=1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ItemMetaHandler serves the discovery metadata of books and papers, for the web app
+// to put in its pages and as landing pages search engine crawlers can read
+type ItemMetaHandler struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+// NewItemMetaHandler creates a new item metadata handler
+func NewItemMetaHandler(db *gorm.DB, config *configs.Config) *ItemMetaHandler {
+	return &ItemMetaHandler{db: db, config: config}
+}
+
+// GetBookMeta handles GET /books/:id/meta
+func (h *ItemMetaHandler) GetBookMeta(c *gin.Context) {
+	if meta, language, ok := h.bookMeta(c); ok {
+		c.JSON(http.StatusOK, gin.H{"language": language, "highwire": meta.Highwire, "dublin_core": meta.DublinCore, "json_ld": meta.JSONLD})
+	}
+}
+
+// GetPaperMeta handles GET /papers/:id/meta
+func (h *ItemMetaHandler) GetPaperMeta(c *gin.Context) {
+	if meta, language, ok := h.paperMeta(c); ok {
+		c.JSON(http.StatusOK, gin.H{"language": language, "highwire": meta.Highwire, "dublin_core": meta.DublinCore, "json_ld": meta.JSONLD})
+	}
+}
+
+// BookLandingPage handles GET /books/:id/landing, a minimal HTML page with the
+// book's metadata for crawlers
+func (h *ItemMetaHandler) BookLandingPage(c *gin.Context) {
+	if meta, language, ok := h.bookMeta(c); ok {
+		h.renderLandingPage(c, meta, language)
+	}
+}
+
+// PaperLandingPage handles GET /papers/:id/landing, a minimal HTML page with the
+// paper's metadata for crawlers such as Google Scholar's
+func (h *ItemMetaHandler) PaperLandingPage(c *gin.Context) {
+	if meta, language, ok := h.paperMeta(c); ok {
+		h.renderLandingPage(c, meta, language)
+	}
+}
+
+// links returns the addresses the metadata links to
+func (h *ItemMetaHandler) links() services.ItemLinks {
+	return services.ItemLinks{ServerURL: h.config.Server.BaseURL, FrontendURL: h.config.Server.FrontendURL}
+}
+
+// bookMeta loads the book of the request and builds its metadata, writing an error
+// response when it cannot
+func (h *ItemMetaHandler) bookMeta(c *gin.Context) (services.ScholarlyMeta, string, bool) {
+	id, ok := paramID(c, "id", "book")
+	if !ok {
+		return services.ScholarlyMeta{}, "", false
+	}
+	var book models.Book
+	orderedAuthors := func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }
+	if err := h.db.Preload("Authors", orderedAuthors).Preload("Subjects").First(&book, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return services.ScholarlyMeta{}, "", false
+	}
+	return services.BookScholarlyMeta(book, h.links()), services.LanguageCode(book.Language), true
+}
+
+// paperMeta loads the paper of the request and builds its metadata, writing an error
+// response when it cannot
+func (h *ItemMetaHandler) paperMeta(c *gin.Context) (services.ScholarlyMeta, string, bool) {
+	id, ok := paramID(c, "id", "paper")
+	if !ok {
+		return services.ScholarlyMeta{}, "", false
+	}
+	var paper models.Paper
+	orderedAuthors := func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }
+	if err := h.db.Preload("Authors", orderedAuthors).Preload("KeywordTerms").First(&paper, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Paper not found"})
+		return services.ScholarlyMeta{}, "", false
+	}
+	return services.PaperScholarlyMeta(paper, h.links()), services.LanguageCode(paper.Language), true
+}
+
+// renderLandingPage writes the landing page of an item
+func (h *ItemMetaHandler) renderLandingPage(c *gin.Context, meta services.ScholarlyMeta, language string) {
+	tmpl, err := template.ParseFiles("templates/item_landing.html")
+	if err != nil {
+		log.Printf("[ItemMeta] Failed to parse landing page template: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render page"})
+		return
+	}
+
+	if language == "" {
+		language = "id"
+	}
+	data := struct {
+		Meta     services.ScholarlyMeta
+		Language string
+	}{Meta: meta, Language: language}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		log.Printf("[ItemMeta] Failed to render landing page: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render page"})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemMetaParsesIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewItemMetaHandler(db, getTestConfig())
+	book, _ := createShelvedCopies(t, db)
+
+	w := getHandler(handler.GetBookMeta, 0, "", idParam(book.ID), "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Sistem Operasi")
+
+	for _, id := range []string{"1 OR 1=1", "x", "-1"} {
+		w = getHandler(handler.GetBookMeta, 0, "", gin.Params{{Key: "id", Value: id}}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, id)
+		w = getHandler(handler.PaperLandingPage, 0, "", gin.Params{{Key: "id", Value: id}}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, id)
+	}
+
+	w = getHandler(handler.GetPaperMeta, 0, "", idParam(999999), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ItemSummary is a compact view of a book or paper used in bookmarks, reading lists
+// and recommendations
+type ItemSummary struct {
+	ItemType      string  `json:"item_type"`
+	ID            uint    `json:"id"`
+	Title         string  `json:"title"`
+	Author        string  `json:"author"`
+	Year          *int    `json:"year"`
+	CoverImageURL *string `json:"cover_image_url"`
+}
+
+// loadItemSummaries loads summaries of the referenced books and papers.
+// References to items that no longer exist are missing from the result.
+func loadItemSummaries(db *gorm.DB, baseURL string, refs []services.ItemRef) (map[services.ItemRef]*ItemSummary, error) {
+	var bookIDs, paperIDs []uint
+	for _, ref := range refs {
+		if ref.Type == "book" {
+			bookIDs = append(bookIDs, ref.ID)
+		} else {
+			paperIDs = append(paperIDs, ref.ID)
+		}
+	}
+
+	items := make(map[services.ItemRef]*ItemSummary)
+	if len(bookIDs) > 0 {
+		var books []models.Book
+		if err := db.Select("id", "title", "author", "published_year", "cover_image_url").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			items[services.ItemRef{Type: "book", ID: book.ID}] = &ItemSummary{
+				ItemType: "book", ID: book.ID, Title: book.Title, Author: book.Author,
+				Year: book.PublishedYear, CoverImageURL: fullURL(baseURL, book.CoverImageURL),
+			}
+		}
+	}
+	if len(paperIDs) > 0 {
+		var papers []models.Paper
+		if err := db.Select("id", "title", "author", "year", "cover_image_url").Where("id IN ?", paperIDs).Find(&papers).Error; err != nil {
+			return nil, err
+		}
+		for _, paper := range papers {
+			items[services.ItemRef{Type: "paper", ID: paper.ID}] = &ItemSummary{
+				ItemType: "paper", ID: paper.ID, Title: paper.Title, Author: paper.Author,
+				Year: paper.Year, CoverImageURL: fullURL(baseURL, paper.CoverImageURL),
+			}
+		}
+	}
+	return items, nil
+}
+
+// fullURL prefixes a stored upload path with the server base URL
+func fullURL(baseURL string, path *string) *string {
+	if path == nil || strings.HasPrefix(*path, "http") {
+		return path
+	}
+	url := baseURL + *path
+	return &url
+}
+
+// itemExists reports whether a book or paper exists
+func itemExists(db *gorm.DB, itemType string, itemID uint) bool {
+	var count int64
+	if itemType == "book" {
+		db.Model(&models.Book{}).Where("id = ?", itemID).Count(&count)
+	} else {
+		db.Model(&models.Paper{}).Where("id = ?", itemID).Count(&count)
+	}
+	return count > 0
+}
+
+// deleteError writes the response to a failed book or paper deletion: holds other
+// members are waiting on block it with 409 Conflict, anything else is a server error
+func deleteError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, services.ErrBookHasHolds) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Members still have holds on this book; cancel them first"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
+
+// paramID parses a numeric path parameter, writing a 400 response naming what the ID
+// is of when it is not one. IDs are parsed before they reach First, which treats other
+// strings as SQL conditions.
+func paramID(c *gin.Context, name, what string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + what + " ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// countItemRows counts the rows of a model that belong to an item
+func countItemRows(db *gorm.DB, model interface{}, itemType string, itemID uint) int64 {
+	var count int64
+	db.Model(model).Where("item_type = ? AND item_id = ?", itemType, itemID).Count(&count)
+	return count
+}
+
+func TestDeleteBookRemovesItemRecords(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewBookHandler(db, getTestConfig())
+
+	member := createMember(t, db, "reader@example.com", "Reader", "student")
+	book, _ := createShelvedCopies(t, db)
+	assert.NoError(t, db.Create(&models.Review{UserID: member.ID, ItemType: "book", ItemID: book.ID, Rating: utils.IntPtr(5)}).Error)
+	assert.NoError(t, db.Create(&models.ItemText{ItemType: "book", ItemID: book.ID, FileURL: "/uploads/book.pdf", Status: "extracted"}).Error)
+	hold := models.Hold{BookID: book.ID, UserID: member.ID, Status: "waiting"}
+	assert.NoError(t, db.Create(&hold).Error)
+	other, _ := createShelvedCopies(t, db)
+	assert.NoError(t, db.Create(&[]models.RelatedItem{
+		{ItemType: "book", ItemID: book.ID, RelatedType: "book", RelatedID: other.ID, Score: 1},
+		{ItemType: "book", ItemID: other.ID, RelatedType: "book", RelatedID: book.ID, Score: 1},
+	}).Error)
+
+	// A blocked deletion leaves the book's records in place
+	w := callHandler(handler.DeleteBook, 1, "admin", idParam(book.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, int64(1), countItemRows(db, &models.Review{}, "book", book.ID))
+	assert.Equal(t, int64(0), countItemRows(db, &models.DeletedItem{}, "book", book.ID))
+
+	assert.NoError(t, db.Model(&hold).Update("status", "cancelled").Error)
+	w = callHandler(handler.DeleteBook, 1, "admin", idParam(book.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), countItemRows(db, &models.Review{}, "book", book.ID))
+	assert.Equal(t, int64(0), countItemRows(db, &models.ItemText{}, "book", book.ID))
+	assert.Equal(t, int64(1), countItemRows(db, &models.DeletedItem{}, "book", book.ID))
+	var related int64
+	db.Model(&models.RelatedItem{}).Count(&related)
+	assert.Equal(t, int64(0), related)
+	var holds int64
+	db.Model(&models.Hold{}).Where("book_id = ?", book.ID).Count(&holds)
+	assert.Equal(t, int64(0), holds)
+}
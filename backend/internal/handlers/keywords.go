@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// KeywordResponse represents a vocabulary entry with its usage count
+type KeywordResponse struct {
+	ID         uint    `json:"id"`
+	Term       string  `json:"term"`
+	Language   *string `json:"language"`
+	GroupID    *uint   `json:"group_id"`
+	UsageCount int64   `json:"usage_count"`
+}
+
+// KeywordHandler handles keyword vocabulary requests
+type KeywordHandler struct {
+	db *gorm.DB
+}
+
+// NewKeywordHandler creates a new keyword handler
+func NewKeywordHandler(db *gorm.DB) *KeywordHandler {
+	return &KeywordHandler{db: db}
+}
+
+// keywordUsageSelect selects keyword columns plus the number of items linked to each keyword
+const keywordUsageSelect = `keywords.id, keywords.term, keywords.language, keywords.group_id,
+	(SELECT COUNT(*) FROM book_keywords bk WHERE bk.keyword_id = keywords.id) +
+	(SELECT COUNT(*) FROM paper_keywords pk WHERE pk.keyword_id = keywords.id) AS usage_count`
+
+// AutocompleteKeywords handles GET /keywords/autocomplete?q=
+func (h *KeywordHandler) AutocompleteKeywords(c *gin.Context) {
+	q := services.NormalizeKeyword(c.Query("q"))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	query := h.db.Table("keywords").Select(keywordUsageSelect)
+	if q != "" {
+		// Match the start of the term or the start of any word inside it
+		query = query.Where("keywords.normalized LIKE ? OR keywords.normalized LIKE ?", q+"%", "% "+q+"%")
+	}
+	if lang := c.Query("language"); lang != "" {
+		query = query.Where("keywords.language = ?", lang)
+	}
+
+	suggestions := make([]KeywordResponse, 0)
+	if err := query.Order("usage_count DESC, keywords.term ASC").Limit(limit).Scan(&suggestions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch keyword suggestions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// GetKeyword handles GET /keywords/:id and lists every item tagged with the keyword or its synonyms
+func (h *KeywordHandler) GetKeyword(c *gin.Context) {
+	id, ok := paramID(c, "id", "keyword")
+	if !ok {
+		return
+	}
+	var keyword models.Keyword
+	if err := h.db.Preload("Group").First(&keyword, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Keyword not found"})
+		return
+	}
+
+	keywordIDs, err := services.ExpandKeywordIDs(h.db, []uint{keyword.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve synonyms"})
+		return
+	}
+
+	synonyms := make([]KeywordResponse, 0)
+	if err := h.db.Table("keywords").Select(keywordUsageSelect).
+		Where("keywords.id IN ? AND keywords.id <> ?", keywordIDs, keyword.ID).
+		Order("keywords.term ASC").Scan(&synonyms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch synonyms"})
+		return
+	}
+
+	var books []models.Book
+	if err := h.db.Preload("Authors").
+		Where("id IN (?)", h.db.Table("book_keywords").Select("book_id").Where("keyword_id IN ?", keywordIDs)).
+		Order("created_at DESC").Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+
+	var papers []models.Paper
+	if err := h.db.Preload("Authors").
+		Where("id IN (?)", h.db.Table("paper_keywords").Select("paper_id").Where("keyword_id IN ?", keywordIDs)).
+		Order("created_at DESC").Find(&papers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch papers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"keyword":  keyword,
+		"synonyms": synonyms,
+		"books":    books,
+		"papers":   papers,
+	})
+}
+
+// UpdateKeyword handles PUT /admin/keywords/:id (term spelling and language)
+func (h *KeywordHandler) UpdateKeyword(c *gin.Context) {
+	id, ok := paramID(c, "id", "keyword")
+	if !ok {
+		return
+	}
+	var keyword models.Keyword
+	if err := h.db.First(&keyword, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Keyword not found"})
+		return
+	}
+
+	var req struct {
+		Term     string  `json:"term"`
+		Language *string `json:"language" binding:"omitempty,oneof=id en"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if term := strings.TrimSpace(req.Term); term != "" {
+		normalized := services.NormalizeKeyword(term)
+		if normalized != keyword.Normalized {
+			var count int64
+			if err := h.db.Model(&models.Keyword{}).Where("normalized = ? AND id <> ?", normalized, keyword.ID).Count(&count).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update keyword"})
+				return
+			}
+			if count > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Another keyword with this term already exists; group them instead"})
+				return
+			}
+		}
+		keyword.Term = term
+		keyword.Normalized = normalized
+	}
+	if req.Language != nil {
+		keyword.Language = req.Language
+	}
+
+	if err := h.db.Save(&keyword).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update keyword"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keyword)
+}
+
+// keywordGroupRequest is the payload for creating or extending a synonym group
+type keywordGroupRequest struct {
+	PreferredTerm string `json:"preferred_term"`
+	KeywordIDs    []uint `json:"keyword_ids"`
+}
+
+// CreateKeywordGroup handles POST /admin/keyword-groups
+func (h *KeywordHandler) CreateKeywordGroup(c *gin.Context) {
+	var req keywordGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.PreferredTerm) == "" || len(req.KeywordIDs) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A preferred term and at least two keywords are required"})
+		return
+	}
+
+	group := models.KeywordGroup{PreferredTerm: strings.TrimSpace(req.PreferredTerm)}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&group).Error; err != nil {
+			return err
+		}
+		return h.assignToGroup(tx, group.ID, req.KeywordIDs)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create keyword group"})
+		return
+	}
+
+	h.db.Preload("Keywords").First(&group, group.ID)
+	c.JSON(http.StatusCreated, group)
+}
+
+// UpdateKeywordGroup handles PUT /admin/keyword-groups/:id (rename and add keywords)
+func (h *KeywordHandler) UpdateKeywordGroup(c *gin.Context) {
+	id, ok := paramID(c, "id", "keyword group")
+	if !ok {
+		return
+	}
+	var group models.KeywordGroup
+	if err := h.db.First(&group, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Keyword group not found"})
+		return
+	}
+
+	var req keywordGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if term := strings.TrimSpace(req.PreferredTerm); term != "" {
+			group.PreferredTerm = term
+			if err := tx.Save(&group).Error; err != nil {
+				return err
+			}
+		}
+		return h.assignToGroup(tx, group.ID, req.KeywordIDs)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update keyword group"})
+		return
+	}
+
+	h.db.Preload("Keywords").First(&group, group.ID)
+	c.JSON(http.StatusOK, group)
+}
+
+// RemoveKeywordFromGroup handles DELETE /admin/keywords/:id/group
+func (h *KeywordHandler) RemoveKeywordFromGroup(c *gin.Context) {
+	id, ok := paramID(c, "id", "keyword")
+	if !ok {
+		return
+	}
+	var keyword models.Keyword
+	if err := h.db.First(&keyword, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Keyword not found"})
+		return
+	}
+	if keyword.GroupID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Keyword is not in a group"})
+		return
+	}
+
+	groupID := *keyword.GroupID
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&keyword).Update("group_id", nil).Error; err != nil {
+			return err
+		}
+		// A group with fewer than two members no longer joins anything
+		var remaining int64
+		if err := tx.Model(&models.Keyword{}).Where("group_id = ?", groupID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining < 2 {
+			if err := tx.Model(&models.Keyword{}).Where("group_id = ?", groupID).Update("group_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.KeywordGroup{}, groupID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove keyword from group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Keyword removed from group"})
+}
+
+// assignToGroup moves keywords (and any group they already belong to) into groupID
+func (h *KeywordHandler) assignToGroup(tx *gorm.DB, groupID uint, keywordIDs []uint) error {
+	if len(keywordIDs) == 0 {
+		return nil
+	}
+
+	// Merge existing groups of the selected keywords into the target group
+	var oldGroupIDs []uint
+	if err := tx.Model(&models.Keyword{}).
+		Where("id IN ? AND group_id IS NOT NULL AND group_id <> ?", keywordIDs, groupID).
+		Distinct("group_id").Pluck("group_id", &oldGroupIDs).Error; err != nil {
+		return err
+	}
+	if len(oldGroupIDs) > 0 {
+		if err := tx.Model(&models.Keyword{}).Where("group_id IN ?", oldGroupIDs).Update("group_id", groupID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.KeywordGroup{}, oldGroupIDs).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.Keyword{}).Where("id IN ?", keywordIDs).Update("group_id", groupID).Error
+}
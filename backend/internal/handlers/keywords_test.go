@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"e-repository-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeywordHandlersParseIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewKeywordHandler(db)
+
+	for _, id := range []string{"1 OR 1=1", "x", "-1"} {
+		params := gin.Params{{Key: "id", Value: id}}
+		assert.Equal(t, http.StatusBadRequest, getHandler(handler.GetKeyword, 0, "", params, "").Code, id)
+		assert.Equal(t, http.StatusBadRequest, callHandler(handler.UpdateKeyword, 1, "admin", params, gin.H{"term": "x"}).Code, id)
+		assert.Equal(t, http.StatusBadRequest, callHandler(handler.UpdateKeywordGroup, 1, "admin", params, gin.H{}).Code, id)
+		assert.Equal(t, http.StatusBadRequest, callHandler(handler.RemoveKeywordFromGroup, 1, "admin", params, nil).Code, id)
+	}
+	assert.Equal(t, http.StatusNotFound, getHandler(handler.GetKeyword, 0, "", idParam(999999), "").Code)
+}
+
+func TestRemoveKeywordFromGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewKeywordHandler(db)
+
+	group := models.KeywordGroup{PreferredTerm: "Basis data"}
+	assert.NoError(t, db.Create(&group).Error)
+	keywords := []models.Keyword{
+		{Term: "Basis data", Normalized: "basis data", GroupID: &group.ID},
+		{Term: "Database", Normalized: "database", GroupID: &group.ID},
+		{Term: "Pangkalan data", Normalized: "pangkalan data", GroupID: &group.ID},
+	}
+	assert.NoError(t, db.Create(&keywords).Error)
+
+	// The group keeps two members
+	w := callHandler(handler.RemoveKeywordFromGroup, 1, "admin", idParam(keywords[2].ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&models.KeywordGroup{}).Where("id = ?", group.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// A group left with one member is dissolved
+	w = callHandler(handler.RemoveKeywordFromGroup, 1, "admin", idParam(keywords[1].ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&models.KeywordGroup{}).Where("id = ?", group.ID).Count(&count)
+	assert.Zero(t, count)
+	db.Model(&models.Keyword{}).Where("group_id IS NOT NULL").Count(&count)
+	assert.Zero(t, count)
+
+	w = callHandler(handler.RemoveKeywordFromGroup, 1, "admin", idParam(keywords[0].ID), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"strconv"
+
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listPage is the sort and position of a list request. Without a cursor the page number
+// selects the rows; with one, the rows after the cursor are returned and page is ignored.
+type listPage struct {
+	sort   services.Sort
+	cursor string
+	page   int
+	limit  int
+}
+
+// apply orders query and selects the rows of the page, plus one more to tell whether
+// another page follows. query should be a session so that it can be reused afterwards.
+func (p listPage) apply(query *gorm.DB) (*gorm.DB, error) {
+	query = p.sort.Order(query)
+	if p.cursor != "" {
+		after, err := p.sort.After(query, p.cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = after
+	} else {
+		query = query.Offset((p.page - 1) * p.limit)
+	}
+	return query.Limit(p.limit + 1), nil
+}
+
+// next returns the cursor of the following page, or nil when fetched holds no more rows
+// than the page. query is the filtered listing query and lastID the last row shown.
+func (p listPage) next(query *gorm.DB, fetched int, lastID uint) (*string, error) {
+	if fetched <= p.limit {
+		return nil, nil
+	}
+	cursor, err := p.sort.Cursor(query, lastID)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// pageQuery reads the page, limit and cursor query parameters of a list request sorted
+// by order. The limit defaults to defaultLimit and may not exceed maxLimit.
+func pageQuery(c *gin.Context, order services.Sort, defaultLimit, maxLimit int) listPage {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if limit < 1 || limit > maxLimit {
+		limit = defaultLimit
+	}
+	return listPage{sort: order, cursor: c.Query("cursor"), page: page, limit: limit}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/marc"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxMARCExportBooks is how many books one MARC export may hold
+const maxMARCExportBooks = 1000
+
+// ImportMARC handles POST /admin/books/import/marc with a MARC21 (ISO 2709) or
+// MARCXML file, creating a book for every record
+func (h *BookHandler) ImportMARC(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A MARC file is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	records, err := marc.Parse(data)
+	if err != nil && len(records) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var createdBy *uint
+	if userID, exists := c.Get("user_id"); exists {
+		if uid, ok := userID.(uint); ok {
+			createdBy = &uid
+		}
+	}
+
+	results := services.ImportMARCRecords(h.db, records, createdBy)
+	summary := gin.H{"created": 0, "skipped": 0, "failed": 0}
+	for _, result := range results {
+		summary[result.Status] = summary[result.Status].(int) + 1
+	}
+
+	response := gin.H{"records": len(records), "summary": summary, "results": results}
+	if err != nil {
+		// The records before a damaged one are still imported
+		response["warning"] = fmt.Sprintf("Reading stopped after record %d: %v", len(records), err)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportMARC handles GET /admin/books/export/marc?ids=1,2,3&format=marcxml
+// format is marc (ISO 2709, the default) or marcxml.
+func (h *BookHandler) ExportMARC(c *gin.Context) {
+	var ids []uint
+	for _, value := range strings.Split(c.Query("ids"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid book ID %q", value)})
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one book ID is required"})
+		return
+	}
+	if len(ids) > maxMARCExportBooks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d books can be exported at once", maxMARCExportBooks)})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "marc"))
+	if format != "marc" && format != "marcxml" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, expected marc or marcxml"})
+		return
+	}
+
+	var books []models.Book
+	orderedAuthors := func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }
+	if err := h.db.Preload("Authors", orderedAuthors).Where("id IN ?", ids).Order("id ASC").Find(&books).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch books"})
+		return
+	}
+	if len(books) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Books not found"})
+		return
+	}
+
+	records := make([]*marc.Record, len(books))
+	for i, book := range books {
+		records[i] = services.BookToMARC(book, h.config.Server.BaseURL)
+	}
+
+	var buf bytes.Buffer
+	var err error
+	contentType, extension := "application/marc", "mrc"
+	if format == "marcxml" {
+		contentType, extension = "application/marcxml+xml", "xml"
+		err = marc.WriteXML(&buf, records)
+	} else {
+		err = marc.Write(&buf, records)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, marc.ErrInvalidRecord) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{"error": "Failed to encode records: " + err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"books.%s\"", extension))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
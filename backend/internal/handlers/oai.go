@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OAIHandler serves the OAI-PMH endpoint harvesters collect the catalog from
+type OAIHandler struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+// NewOAIHandler creates a new OAI-PMH handler
+func NewOAIHandler(db *gorm.DB, config *configs.Config) *OAIHandler {
+	return &OAIHandler{db: db, config: config}
+}
+
+// Handle handles GET and POST /oai
+// Protocol errors are part of the XML response, which is always sent with status 200.
+func (h *OAIHandler) Handle(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request arguments"})
+		return
+	}
+
+	repository := services.OAIRepository{
+		Name:       h.config.OAI.RepositoryName,
+		BaseURL:    h.config.Server.BaseURL + "/oai",
+		ServerURL:  h.config.Server.BaseURL,
+		Identifier: h.config.OAI.RepositoryIdentifier,
+		AdminEmail: h.config.OAI.AdminEmail,
+	}
+	response, err := services.HandleOAI(h.db, repository, c.Request.Form)
+	if err != nil {
+		log.Printf("[OAI] Failed to answer %s: %v", c.Request.Form.Get("verb"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to answer OAI-PMH request"})
+		return
+	}
+
+	body, err := xml.Marshal(response)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode OAI-PMH response"})
+		return
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
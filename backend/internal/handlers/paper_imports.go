@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxPaperImportEntries is how many entries one reference file may hold
+	maxPaperImportEntries = 500
+	// maxPaperImportSize is the largest reference file accepted, in bytes
+	maxPaperImportSize = 5 << 20
+)
+
+// paperImportPreview is an entry of a reference file as shown before it is created
+type paperImportPreview struct {
+	Index    int                 `json:"index"`
+	Entry    services.PaperEntry `json:"entry"`
+	Valid    bool                `json:"valid"`
+	Errors   []string            `json:"errors"`
+	Warnings []string            `json:"warnings"`
+}
+
+// PreviewPaperImport handles POST /user/papers/import/preview with a BibTeX, RIS or
+// CSV file. Nothing is saved; the entries come back with their validation errors and
+// duplicate warnings so the user can choose which ones to create.
+func (h *PaperHandler) PreviewPaperImport(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A .bib, .ris or .csv file is required"})
+		return
+	}
+	if header.Size > maxPaperImportSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The file is larger than %d MB", maxPaperImportSize>>20)})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	format, entries, err := services.ParsePaperEntries(header.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to parse file: %v", err)})
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The file has no entries"})
+		return
+	}
+	if len(entries) > maxPaperImportEntries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d entries can be imported at once", maxPaperImportEntries)})
+		return
+	}
+
+	duplicates, err := services.PaperDuplicateWarnings(h.db, entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for duplicates"})
+		return
+	}
+
+	previews := make([]paperImportPreview, len(entries))
+	valid, duplicated := 0, 0
+	for i, entry := range entries {
+		problems, warnings := services.ValidatePaperEntry(entry)
+		previews[i] = paperImportPreview{
+			Index:    i,
+			Entry:    entry,
+			Valid:    len(problems) == 0,
+			Errors:   append([]string{}, problems...),
+			Warnings: append(warnings, duplicates[i]...),
+		}
+		if previews[i].Warnings == nil {
+			previews[i].Warnings = []string{}
+		}
+		if previews[i].Valid {
+			valid++
+		}
+		if len(duplicates[i]) > 0 {
+			duplicated++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"format":  format,
+		"entries": previews,
+		"summary": gin.H{
+			"total":      len(entries),
+			"valid":      valid,
+			"invalid":    len(entries) - valid,
+			"duplicates": duplicated,
+		},
+	})
+}
+
+// ImportPapers handles POST /user/papers/import with the previewed entries the user
+// chose to create, possibly edited. Entries that still fail validation are reported
+// and the others are created as the user's papers.
+func (h *PaperHandler) ImportPapers(c *gin.Context) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	userID := value.(uint)
+
+	var req struct {
+		Entries []services.PaperEntry `json:"entries" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Select at least one entry to import"})
+		return
+	}
+	if len(req.Entries) > maxPaperImportEntries {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d entries can be imported at once", maxPaperImportEntries)})
+		return
+	}
+
+	type failedEntry struct {
+		Index  int      `json:"index"`
+		Title  string   `json:"title"`
+		Errors []string `json:"errors"`
+	}
+	created := []models.Paper{}
+	failed := []failedEntry{}
+
+	for i, entry := range req.Entries {
+		if problems, _ := services.ValidatePaperEntry(entry); len(problems) > 0 {
+			failed = append(failed, failedEntry{Index: i, Title: entry.Title, Errors: problems})
+			continue
+		}
+
+		paper, authors := entry.Paper()
+		paper.CreatedBy = &userID
+		if err := h.createImportedPaper(&paper, authors); err != nil {
+			log.Printf("[PaperImport] Failed to create %q for user %d: %v", paper.Title, userID, err)
+			failed = append(failed, failedEntry{Index: i, Title: entry.Title, Errors: []string{"failed to save the paper"}})
+			continue
+		}
+		created = append(created, paper)
+	}
+
+	status := http.StatusCreated
+	if len(created) == 0 {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{
+		"created": created,
+		"failed":  failed,
+		"summary": gin.H{"created": len(created), "failed": len(failed)},
+	})
+}
+
+// createImportedPaper saves an imported paper with its authors, linking the entry
+// that matches the uploader to their account as CreateUserPaper does
+func (h *PaperHandler) createImportedPaper(paper *models.Paper, authors []string) error {
+	linker := newAuthorLinker(h.db, "paper", 0, paper.CreatedBy)
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		return services.InsertImportedPaper(tx, paper, authors, linker.userIDFor)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Keep the search index up to date
+	services.IndexItem(h.db, "paper", paper.ID)
+
+	// Update paper count
+	h.db.Model(&models.Counter{}).Where("name = ?", "total_papers").UpdateColumn("count", gorm.Expr("count + 1"))
+
+	h.db.Preload("Authors").First(paper, paper.ID)
+	return nil
+}
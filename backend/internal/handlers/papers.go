@@ -13,6 +13,7 @@ import (
 
 	"e-repository-api/configs"
 	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
 	"e-repository-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -175,6 +176,9 @@ func (h *PaperHandler) CreatePaper(c *gin.Context) {
 		paper.CoverImageURL = &coverImageURL
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "paper", paper.ID, paper.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -194,15 +198,23 @@ func (h *PaperHandler) CreatePaper(c *gin.Context) {
 		paperAuthor := models.PaperAuthor{
 			PaperID:    paper.ID,
 			AuthorName: authorName,
-			UserID:     paper.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&paperAuthor).Error; err != nil {
+		if err := services.CreatePaperAuthor(tx, &paperAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create paper authors"})
 			return
 		}
 	}
 
+	// Link keywords to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "paper", paper.ID, paper.Keywords, paper.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save paper keywords"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -210,6 +222,9 @@ func (h *PaperHandler) CreatePaper(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "paper", paper.ID)
+
 	// Update paper count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_papers").UpdateColumn("count", gorm.Expr("count + 1"))
 
@@ -368,6 +383,9 @@ func (h *PaperHandler) CreateUserPaper(c *gin.Context) {
 		paper.CoverImageURL = &coverImageURL
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "paper", paper.ID, paper.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -387,15 +405,23 @@ func (h *PaperHandler) CreateUserPaper(c *gin.Context) {
 		paperAuthor := models.PaperAuthor{
 			PaperID:    paper.ID,
 			AuthorName: authorName,
-			UserID:     paper.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&paperAuthor).Error; err != nil {
+		if err := services.CreatePaperAuthor(tx, &paperAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create paper authors"})
 			return
 		}
 	}
 
+	// Link keywords to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "paper", paper.ID, paper.Keywords, paper.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save paper keywords"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -403,6 +429,9 @@ func (h *PaperHandler) CreateUserPaper(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "paper", paper.ID)
+
 	// Update paper count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_papers").UpdateColumn("count", gorm.Expr("count + 1"))
 
@@ -413,6 +442,8 @@ func (h *PaperHandler) CreateUserPaper(c *gin.Context) {
 }
 
 // GetPapers handles paper listing with pagination and search
+// sort takes a field of services.PaperSorts and asc or desc, e.g. downloads:desc. Pages are
+// selected by page number or by the next_cursor returned with the previous page.
 func (h *PaperHandler) GetPapers(c *gin.Context) {
 	var req models.SearchRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -438,8 +469,14 @@ func (h *PaperHandler) GetPapers(c *gin.Context) {
 
 	query := h.db.Model(&models.Paper{}).Unscoped()
 
-	// Search functionality
-	if q != "" {
+	// Search functionality. Full-text mode also matches the text of the uploaded file.
+	fullText := q != "" && c.Query("fulltext") == "true"
+	if fullText {
+		searchTerm := "%" + strings.ToLower(q) + "%"
+		query = query.Joins("LEFT JOIN (?) AS fulltext_matches ON fulltext_matches.item_id = papers.id", services.FullTextScores(h.db, "paper", q)).
+			Where("fulltext_matches.item_id IS NOT NULL OR LOWER(title) LIKE ? OR LOWER(author) LIKE ? OR LOWER(abstract) LIKE ? OR LOWER(keywords) LIKE ? OR LOWER(issn) LIKE ? OR LOWER(doi) LIKE ? OR CAST(year AS CHAR) LIKE ?",
+				searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm)
+	} else if q != "" {
 		searchTerm := "%" + strings.ToLower(q) + "%"
 		query = query.Where("LOWER(title) LIKE ? OR LOWER(author) LIKE ? OR LOWER(abstract) LIKE ? OR LOWER(keywords) LIKE ? OR LOWER(issn) LIKE ? OR LOWER(doi) LIKE ? OR CAST(year AS CHAR) LIKE ?",
 			searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm, searchTerm)
@@ -457,11 +494,42 @@ func (h *PaperHandler) GetPapers(c *gin.Context) {
 		query = query.Where("year = ?", *req.Year)
 	}
 
+	// Keyword filter (also matches grouped synonyms and translations)
+	if req.Keyword != "" {
+		paperIDs, err := services.KeywordItemIDs(h.db, "paper", req.Keyword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve keyword"})
+			return
+		}
+		query = query.Where("papers.id IN ?", paperIDs)
+	}
+
 	// After year filter
 	if createdBy := c.Query("created_by"); createdBy != "" {
 		query = query.Where("created_by = ?", createdBy)
 	}
 
+	// Works the user uploaded or is a linked author of
+	if authoredBy := c.Query("authored_by"); authoredBy != "" {
+		query = query.Where("papers.id IN ("+userPaperIDsSQL+")", authoredBy, authoredBy)
+	}
+
+	// Sorting: best full-text matches first, otherwise newest first
+	defaultSort := "created_at:desc"
+	if fullText {
+		defaultSort = "relevance:desc"
+	}
+	order, err := services.PaperSorts.Parse(req.Sort, defaultSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if order.Field == "relevance" && !fullText {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "relevance sort requires fulltext=true"})
+		return
+	}
+	query = query.Session(&gorm.Session{})
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -471,27 +539,41 @@ func (h *PaperHandler) GetPapers(c *gin.Context) {
 
 	// Get paginated results
 	var papers []models.Paper
-	offset := (req.Page - 1) * req.Limit
-
-	// Handle sorting
-	if req.Sort != "" {
-		sortParts := strings.Split(req.Sort, ":")
-		if len(sortParts) == 2 {
-			field := sortParts[0]
-			direction := strings.ToUpper(sortParts[1])
-			if direction == "DESC" || direction == "ASC" {
-				query = query.Order(fmt.Sprintf("%s %s", field, direction))
-			}
-		}
-	} else {
-		// Default sorting by created_at desc
-		query = query.Order("created_at DESC")
+	page := listPage{sort: order, cursor: req.Cursor, page: req.Page, limit: req.Limit}
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	if err := query.Preload("Authors").Offset(offset).Limit(req.Limit).Find(&papers).Error; err != nil {
+	if err := paged.Preload("Authors").Find(&papers).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get papers"})
 		return
 	}
+	fetched := len(papers)
+	if fetched > req.Limit {
+		papers = papers[:req.Limit]
+	}
+	var nextCursor *string
+	if len(papers) > 0 {
+		if nextCursor, err = page.next(query, fetched, papers[len(papers)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get papers"})
+			return
+		}
+	}
+
+	// Matching pages of the uploaded files, with highlighted snippets
+	var textMatches map[uint][]services.TextMatch
+	if fullText {
+		ids := make([]uint, len(papers))
+		for i, paper := range papers {
+			ids[i] = paper.ID
+		}
+		var err error
+		if textMatches, err = services.FullTextMatches(h.db, "paper", ids, q, 3); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get full-text matches"})
+			return
+		}
+	}
 
 	// Format each paper to include authors array like GetPaper
 	var formattedPapers []gin.H
@@ -516,6 +598,8 @@ func (h *PaperHandler) GetPapers(c *gin.Context) {
 			"file_url":        paper.FileURL,
 			"cover_image_url": paper.CoverImageURL,
 			"created_by":      paper.CreatedBy,
+			"rating_average":  paper.RatingAverage,
+			"rating_count":    paper.RatingCount,
 			"created_at":      paper.CreatedAt,
 			"updated_at":      paper.UpdatedAt,
 			"language":        paper.Language,
@@ -541,6 +625,13 @@ func (h *PaperHandler) GetPapers(c *gin.Context) {
 				item["cover_image_url"] = coverURL
 			}
 		}
+		if fullText {
+			matches := textMatches[paper.ID]
+			if matches == nil {
+				matches = []services.TextMatch{}
+			}
+			item["fulltext_matches"] = matches
+		}
 		formattedPapers = append(formattedPapers, item)
 	}
 
@@ -550,6 +641,7 @@ func (h *PaperHandler) GetPapers(c *gin.Context) {
 		"limit":       req.Limit,
 		"total_pages": int(math.Ceil(float64(total) / float64(req.Limit))),
 		"data":        formattedPapers,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -585,6 +677,8 @@ func (h *PaperHandler) GetPaper(c *gin.Context) {
 		"file_url":        paper.FileURL,
 		"cover_image_url": paper.CoverImageURL,
 		"created_by":      paper.CreatedBy,
+		"rating_average":  paper.RatingAverage,
+		"rating_count":    paper.RatingCount,
 		"created_at":      paper.CreatedAt,
 		"updated_at":      paper.UpdatedAt,
 		"language":        paper.Language,
@@ -615,6 +709,12 @@ func (h *PaperHandler) GetPaper(c *gin.Context) {
 		}
 	}
 
+	// Bookmark flag for logged-in users (set by OptionalAuthMiddleware)
+	response["is_bookmarked"] = false
+	if userID, exists := c.Get("user_id"); exists {
+		response["is_bookmarked"] = services.IsBookmarked(h.db, userID.(uint), "paper", paper.ID)
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -770,6 +870,9 @@ func (h *PaperHandler) UpdatePaper(c *gin.Context) {
 		paper.CoverImageURL = &coverImageURL
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "paper", paper.ID, paper.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -796,15 +899,23 @@ func (h *PaperHandler) UpdatePaper(c *gin.Context) {
 		paperAuthor := models.PaperAuthor{
 			PaperID:    paper.ID,
 			AuthorName: authorName,
-			UserID:     paper.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&paperAuthor).Error; err != nil {
+		if err := services.CreatePaperAuthor(tx, &paperAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create paper authors"})
 			return
 		}
 	}
 
+	// Link keywords to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "paper", paper.ID, paper.Keywords, paper.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save paper keywords"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -812,6 +923,9 @@ func (h *PaperHandler) UpdatePaper(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "paper", paper.ID)
+
 	// Load authors for response
 	h.db.Preload("Authors").First(&paper, paper.ID)
 
@@ -829,6 +943,19 @@ func (h *PaperHandler) DeletePaper(c *gin.Context) {
 		return
 	}
 
+	// Delete the paper with its keyword links, reviews, full text and other records in
+	// one transaction, so a failure leaves it intact
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.DeleteItemRecords(tx, "paper", paper.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&paper).Error
+	})
+	if err != nil {
+		deleteError(c, err, "Failed to delete paper")
+		return
+	}
+
 	// Delete file if exists and not referenced by other papers
 	if paper.FileURL != nil {
 		utils.DeleteFileIfUnreferenced(h.db, "papers", "file_url", *paper.FileURL, paper.ID)
@@ -838,11 +965,8 @@ func (h *PaperHandler) DeletePaper(c *gin.Context) {
 		utils.DeleteFileIfUnreferenced(h.db, "papers", "cover_image_url", *paper.CoverImageURL, paper.ID)
 	}
 
-	// Delete from database
-	if err := h.db.Delete(&paper).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete paper"})
-		return
-	}
+	// Drop it from the search index
+	services.RemoveFromIndex(h.db, "paper", paper.ID)
 
 	// Update paper count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_papers").UpdateColumn("count", gorm.Expr("count - 1"))
@@ -981,6 +1105,28 @@ func (h *PaperHandler) GetUserPapers(c *gin.Context) {
 		query = query.Where("year = ?", *req.Year)
 	}
 
+	// Keyword filter (also matches grouped synonyms and translations)
+	if req.Keyword != "" {
+		paperIDs, err := services.KeywordItemIDs(h.db, "paper", req.Keyword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve keyword"})
+			return
+		}
+		query = query.Where("papers.id IN ?", paperIDs)
+	}
+
+	// Sorting, newest first by default. There is no full-text search here to rank by.
+	order, err := services.PaperSorts.Parse(req.Sort, "created_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if order.Field == "relevance" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "relevance sort requires a full-text search"})
+		return
+	}
+	query = query.Session(&gorm.Session{})
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -990,11 +1136,27 @@ func (h *PaperHandler) GetUserPapers(c *gin.Context) {
 
 	// Get paginated results
 	var papers []models.Paper
-	offset := (req.Page - 1) * req.Limit
-	if err := query.Preload("Authors").Offset(offset).Limit(req.Limit).Find(&papers).Error; err != nil {
+	page := listPage{sort: order, cursor: req.Cursor, page: req.Page, limit: req.Limit}
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := paged.Preload("Authors").Find(&papers).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get papers"})
 		return
 	}
+	fetched := len(papers)
+	if fetched > req.Limit {
+		papers = papers[:req.Limit]
+	}
+	var nextCursor *string
+	if len(papers) > 0 {
+		if nextCursor, err = page.next(query, fetched, papers[len(papers)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get papers"})
+			return
+		}
+	}
 
 	// Format each paper to include authors array like GetPaper
 	var formattedPapers []gin.H
@@ -1019,6 +1181,8 @@ func (h *PaperHandler) GetUserPapers(c *gin.Context) {
 			"file_url":        paper.FileURL,
 			"cover_image_url": paper.CoverImageURL,
 			"created_by":      paper.CreatedBy,
+			"rating_average":  paper.RatingAverage,
+			"rating_count":    paper.RatingCount,
 			"created_at":      paper.CreatedAt,
 			"updated_at":      paper.UpdatedAt,
 			"language":        paper.Language,
@@ -1053,6 +1217,7 @@ func (h *PaperHandler) GetUserPapers(c *gin.Context) {
 		"limit":       req.Limit,
 		"total_pages": int(math.Ceil(float64(total) / float64(req.Limit))),
 		"data":        formattedPapers,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -1232,6 +1397,9 @@ func (h *PaperHandler) UpdateUserPaper(c *gin.Context) {
 		log.Printf("[User Paper Update] New cover saved: %s", coverImageURL)
 	}
 
+	// Work out which accounts the author entries belong to
+	linker := newAuthorLinker(h.db, "paper", paper.ID, paper.CreatedBy)
+
 	// Start a transaction
 	tx := h.db.Begin()
 	if tx.Error != nil {
@@ -1258,15 +1426,23 @@ func (h *PaperHandler) UpdateUserPaper(c *gin.Context) {
 		paperAuthor := models.PaperAuthor{
 			PaperID:    paper.ID,
 			AuthorName: authorName,
-			UserID:     paper.CreatedBy,
+			UserID:     linker.userIDFor(authorName),
+			AuthorID:   linker.authorIDFor(authorName),
 		}
-		if err := tx.Create(&paperAuthor).Error; err != nil {
+		if err := services.CreatePaperAuthor(tx, &paperAuthor); err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create paper authors"})
 			return
 		}
 	}
 
+	// Link keywords to the keyword vocabulary
+	if err := services.SyncItemKeywords(tx, "paper", paper.ID, paper.Keywords, paper.Language); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save paper keywords"})
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -1274,6 +1450,9 @@ func (h *PaperHandler) UpdateUserPaper(c *gin.Context) {
 		return
 	}
 
+	// Keep the search index up to date
+	services.IndexItem(h.db, "paper", paper.ID)
+
 	// Load authors for response
 	h.db.Preload("Authors").First(&paper, paper.ID)
 
@@ -1304,6 +1483,19 @@ func (h *PaperHandler) DeleteUserPaper(c *gin.Context) {
 
 	log.Printf("[User Paper Delete] Deleting paper ID: %s, Title: %s", id, paper.Title)
 
+	// Delete the paper with its keyword links, reviews, full text and other records in
+	// one transaction, so a failure leaves it intact
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.DeleteItemRecords(tx, "paper", paper.ID); err != nil {
+			return err
+		}
+		return tx.Delete(&paper).Error
+	})
+	if err != nil {
+		deleteError(c, err, "Failed to delete paper")
+		return
+	}
+
 	// Delete file if exists and not referenced by other papers
 	if paper.FileURL != nil {
 		log.Printf("[User Paper Delete] Checking file deletion for: %s", *paper.FileURL)
@@ -1315,11 +1507,8 @@ func (h *PaperHandler) DeleteUserPaper(c *gin.Context) {
 		utils.DeleteFileIfUnreferenced(h.db, "papers", "cover_image_url", *paper.CoverImageURL, paper.ID)
 	}
 
-	// Delete from database
-	if err := h.db.Delete(&paper).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete paper"})
-		return
-	}
+	// Drop it from the search index
+	services.RemoveFromIndex(h.db, "paper", paper.ID)
 
 	// Update paper count
 	h.db.Model(&models.Counter{}).Where("name = ?", "total_papers").UpdateColumn("count", gorm.Expr("count - 1"))
@@ -1328,7 +1517,8 @@ func (h *PaperHandler) DeleteUserPaper(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Paper deleted successfully"})
 }
 
-// CitePaper handles citation logging for a paper
+// CitePaper handles GET and POST /papers/:id/cite?format=apa
+// It returns the citation of the paper in the requested format; a POST also logs it as cited.
 func (h *PaperHandler) CitePaper(c *gin.Context) {
 	id := c.Param("id")
 	var paper models.Paper
@@ -1339,22 +1529,6 @@ func (h *PaperHandler) CitePaper(c *gin.Context) {
 		return
 	}
 
-	// Check if user is authenticated (optional for public citations)
-	userIDVal, exists := c.Get("user_id")
-	var userID *uint
-	if exists {
-		uid := userIDVal.(uint)
-		userID = &uid
-	}
-
-	// Log the citation
-	citation := models.Citation{
-		UserID:   userID,
-		ItemID:   paper.ID,
-		ItemType: "paper",
-		CitedAt:  time.Now(),
-	}
-	h.db.Create(&citation)
-
-	c.JSON(http.StatusOK, gin.H{"message": "Citation logged"})
+	refs := []services.ItemRef{{Type: "paper", ID: paper.ID}}
+	writeCitations(c, h.db, refs, c.DefaultQuery("format", "apa"), fmt.Sprintf("paper-%d", paper.ID))
 }
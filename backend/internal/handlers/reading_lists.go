@@ -0,0 +1,546 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReadingListSummary is a reading list with the number of items in it
+type ReadingListSummary struct {
+	models.ReadingList
+	EntryCount int64 `json:"entry_count"`
+}
+
+// ReadingListEntryResponse is a reading list entry with the item it points to
+type ReadingListEntryResponse struct {
+	models.ReadingListEntry
+	Item *ItemSummary `json:"item"`
+}
+
+// fileNameUnsafe matches characters that are replaced in export file names
+var fileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// ReadingListHandler handles bookmark and reading list requests
+type ReadingListHandler struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+// NewReadingListHandler creates a new reading list handler
+func NewReadingListHandler(db *gorm.DB, config *configs.Config) *ReadingListHandler {
+	return &ReadingListHandler{db: db, config: config}
+}
+
+// GetBookmarks handles GET /user/bookmarks
+func (h *ReadingListHandler) GetBookmarks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var bookmarks []struct {
+		ItemType string
+		ItemID   uint
+	}
+	if err := h.db.Raw(`SELECT 'book' AS item_type, book_id AS item_id, created_at FROM user_books WHERE user_id = ?
+		UNION ALL
+		SELECT 'paper' AS item_type, paper_id AS item_id, created_at FROM user_papers WHERE user_id = ?
+		ORDER BY created_at DESC`, userID, userID).Scan(&bookmarks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookmarks"})
+		return
+	}
+
+	refs := make([]services.ItemRef, len(bookmarks))
+	for i, bookmark := range bookmarks {
+		refs[i] = services.ItemRef{Type: bookmark.ItemType, ID: bookmark.ItemID}
+	}
+	items, err := loadItemSummaries(h.db, h.config.Server.BaseURL, refs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookmarked items"})
+		return
+	}
+
+	books, papers := make([]ItemSummary, 0), make([]ItemSummary, 0)
+	for _, ref := range refs {
+		if item, ok := items[ref]; ok {
+			if ref.Type == "book" {
+				books = append(books, *item)
+			} else {
+				papers = append(papers, *item)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"books": books, "papers": papers})
+}
+
+// AddBookmark handles POST /user/bookmarks
+func (h *ReadingListHandler) AddBookmark(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	var req struct {
+		ItemType string `json:"item_type" binding:"required,oneof=book paper"`
+		ItemID   uint   `json:"item_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !itemExists(h.db, req.ItemType, req.ItemID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	var bookmark interface{} = &models.UserBook{UserID: uid, BookID: req.ItemID}
+	if req.ItemType == "paper" {
+		bookmark = &models.UserPaper{UserID: uid, PaperID: req.ItemID}
+	}
+	if err := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(bookmark).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add bookmark"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Bookmark added", "is_bookmarked": true})
+}
+
+// RemoveBookmark handles DELETE /user/bookmarks/:type/:id
+func (h *ReadingListHandler) RemoveBookmark(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	itemID := c.Param("id")
+	var err error
+	switch c.Param("type") {
+	case "book":
+		err = h.db.Where("user_id = ? AND book_id = ?", userID, itemID).Delete(&models.UserBook{}).Error
+	case "paper":
+		err = h.db.Where("user_id = ? AND paper_id = ?", userID, itemID).Delete(&models.UserPaper{}).Error
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Item type must be book or paper"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove bookmark"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bookmark removed", "is_bookmarked": false})
+}
+
+// GetReadingLists handles GET /user/reading-lists
+func (h *ReadingListHandler) GetReadingLists(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	lists := make([]ReadingListSummary, 0)
+	if err := h.db.Model(&models.ReadingList{}).
+		Select("reading_lists.*, (SELECT COUNT(*) FROM reading_list_entries e WHERE e.list_id = reading_lists.id) AS entry_count").
+		Where("user_id = ?", userID).
+		Order("updated_at DESC").Scan(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading lists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+// readingListRequest is the payload for creating or updating a reading list
+type readingListRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Visibility  *string `json:"visibility" binding:"omitempty,oneof=private link"`
+}
+
+// CreateReadingList handles POST /user/reading-lists
+func (h *ReadingListHandler) CreateReadingList(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req readingListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "List name is required"})
+		return
+	}
+
+	token, err := utils.GenerateVerificationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate share token"})
+		return
+	}
+
+	list := models.ReadingList{
+		UserID:      userID.(uint),
+		Name:        strings.TrimSpace(*req.Name),
+		Description: req.Description,
+		Visibility:  "private",
+		ShareToken:  token,
+	}
+	if req.Visibility != nil {
+		list.Visibility = *req.Visibility
+	}
+	if err := h.db.Create(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reading list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// GetReadingList handles GET /user/reading-lists/:id
+func (h *ReadingListHandler) GetReadingList(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+	h.respondWithEntries(c, list)
+}
+
+// GetSharedReadingList handles GET /reading-lists/shared/:token
+func (h *ReadingListHandler) GetSharedReadingList(c *gin.Context) {
+	list, ok := h.sharedList(c)
+	if !ok {
+		return
+	}
+	h.respondWithEntries(c, list)
+}
+
+// UpdateReadingList handles PUT /user/reading-lists/:id
+func (h *ReadingListHandler) UpdateReadingList(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	var req readingListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "List name is required"})
+			return
+		}
+		list.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		list.Description = req.Description
+	}
+	if req.Visibility != nil {
+		list.Visibility = *req.Visibility
+	}
+
+	if err := h.db.Save(list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// RegenerateShareToken handles POST /user/reading-lists/:id/share-token
+// Issuing a new token revokes links shared earlier.
+func (h *ReadingListHandler) RegenerateShareToken(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	token, err := utils.GenerateVerificationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate share token"})
+		return
+	}
+	list.ShareToken = token
+	if err := h.db.Model(list).Update("share_token", token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update share token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteReadingList handles DELETE /user/reading-lists/:id
+func (h *ReadingListHandler) DeleteReadingList(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", list.ID).Delete(&models.ReadingListEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete reading list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reading list deleted successfully"})
+}
+
+// AddReadingListEntry handles POST /user/reading-lists/:id/entries
+func (h *ReadingListHandler) AddReadingListEntry(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ItemType string  `json:"item_type" binding:"required,oneof=book paper"`
+		ItemID   uint    `json:"item_id" binding:"required"`
+		Note     *string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !itemExists(h.db, req.ItemType, req.ItemID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.ReadingListEntry{}).Where("list_id = ? AND item_type = ? AND item_id = ?", list.ID, req.ItemType, req.ItemID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Item is already in this reading list"})
+		return
+	}
+
+	// New entries go to the end of the list
+	var last struct{ Position int }
+	h.db.Model(&models.ReadingListEntry{}).Select("COALESCE(MAX(position), 0) AS position").Where("list_id = ?", list.ID).Scan(&last)
+
+	entry := models.ReadingListEntry{
+		ListID:   list.ID,
+		ItemType: req.ItemType,
+		ItemID:   req.ItemID,
+		Note:     req.Note,
+		Position: last.Position + 1,
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(list).Update("updated_at", time.Now()).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to reading list"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// UpdateReadingListEntry handles PUT /user/reading-lists/:id/entries/:entryId (note and position)
+func (h *ReadingListHandler) UpdateReadingListEntry(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	var entry models.ReadingListEntry
+	if err := h.db.Where("id = ? AND list_id = ?", c.Param("entryId"), list.ID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading list entry not found"})
+		return
+	}
+
+	var req struct {
+		Note     *string `json:"note"`
+		Position *int    `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Note != nil {
+		entry.Note = req.Note
+	}
+	if req.Position != nil {
+		entry.Position = *req.Position
+	}
+
+	if err := h.db.Save(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reading list entry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// RemoveReadingListEntry handles DELETE /user/reading-lists/:id/entries/:entryId
+func (h *ReadingListHandler) RemoveReadingListEntry(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+
+	result := h.db.Where("id = ? AND list_id = ?", c.Param("entryId"), list.ID).Delete(&models.ReadingListEntry{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove reading list entry"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading list entry not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Item removed from reading list"})
+}
+
+// ExportReadingList handles GET /user/reading-lists/:id/export?format=bibtex|ris|apa
+func (h *ReadingListHandler) ExportReadingList(c *gin.Context) {
+	list, ok := h.ownedList(c)
+	if !ok {
+		return
+	}
+	h.export(c, list)
+}
+
+// ExportSharedReadingList handles GET /reading-lists/shared/:token/export?format=bibtex|ris|apa
+func (h *ReadingListHandler) ExportSharedReadingList(c *gin.Context) {
+	list, ok := h.sharedList(c)
+	if !ok {
+		return
+	}
+	h.export(c, list)
+}
+
+// export writes the items of a list as a citation file
+func (h *ReadingListHandler) export(c *gin.Context, list *models.ReadingList) {
+	entries, err := h.entries(list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list entries"})
+		return
+	}
+
+	refs := make([]services.ItemRef, len(entries))
+	for i, entry := range entries {
+		refs[i] = services.ItemRef{Type: entry.ItemType, ID: entry.ItemID}
+	}
+	items, err := services.LoadCitationItems(h.db, refs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list items"})
+		return
+	}
+
+	body, contentType, extension, err := services.FormatCitations(items, c.DefaultQuery("format", "bibtex"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fileName := strings.Trim(fileNameUnsafe.ReplaceAllString(strings.ToLower(list.Name), "-"), "-")
+	if fileName == "" {
+		fileName = "reading-list-" + strconv.FormatUint(uint64(list.ID), 10)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", fileName, extension))
+	c.Data(http.StatusOK, contentType, []byte(body))
+}
+
+// respondWithEntries writes a list together with its entries and their items
+func (h *ReadingListHandler) respondWithEntries(c *gin.Context, list *models.ReadingList) {
+	entries, err := h.entries(list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list entries"})
+		return
+	}
+
+	refs := make([]services.ItemRef, len(entries))
+	for i, entry := range entries {
+		refs[i] = services.ItemRef{Type: entry.ItemType, ID: entry.ItemID}
+	}
+	items, err := loadItemSummaries(h.db, h.config.Server.BaseURL, refs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list items"})
+		return
+	}
+
+	response := make([]ReadingListEntryResponse, len(entries))
+	for i, entry := range entries {
+		response[i] = ReadingListEntryResponse{ReadingListEntry: entry, Item: items[refs[i]]}
+	}
+
+	var owner models.User
+	h.db.Select("id", "name").First(&owner, list.UserID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"list":    list,
+		"owner":   gin.H{"id": owner.ID, "name": owner.Name},
+		"entries": response,
+	})
+}
+
+// entries returns the entries of a list in display order
+func (h *ReadingListHandler) entries(listID uint) ([]models.ReadingListEntry, error) {
+	var entries []models.ReadingListEntry
+	err := h.db.Where("list_id = ?", listID).Order("position ASC, id ASC").Find(&entries).Error
+	return entries, err
+}
+
+// ownedList loads the list in the :id parameter if it belongs to the current user,
+// writing an error response otherwise
+func (h *ReadingListHandler) ownedList(c *gin.Context) (*models.ReadingList, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var list models.ReadingList
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Reading list not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reading list"})
+		}
+		return nil, false
+	}
+	return &list, true
+}
+
+// sharedList loads the list in the :token parameter if it is shared by link,
+// writing an error response otherwise
+func (h *ReadingListHandler) sharedList(c *gin.Context) (*models.ReadingList, bool) {
+	var list models.ReadingList
+	if err := h.db.Where("share_token = ? AND visibility = ?", c.Param("token"), "link").First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading list not found"})
+		return nil, false
+	}
+	return &list, true
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RelatedItemResponse is a recommended book or paper with the signals it was chosen for
+type RelatedItemResponse struct {
+	ItemSummary
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// RelatedHandler serves the related-item recommendations computed by the background job
+type RelatedHandler struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+// NewRelatedHandler creates a new related item handler
+func NewRelatedHandler(db *gorm.DB, config *configs.Config) *RelatedHandler {
+	return &RelatedHandler{db: db, config: config}
+}
+
+// GetRelatedBooks handles GET /books/:id/related
+func (h *RelatedHandler) GetRelatedBooks(c *gin.Context) {
+	h.related(c, "book")
+}
+
+// GetRelatedPapers handles GET /papers/:id/related
+func (h *RelatedHandler) GetRelatedPapers(c *gin.Context) {
+	h.related(c, "paper")
+}
+
+// related writes the cached recommendations of an item
+func (h *RelatedHandler) related(c *gin.Context, itemType string) {
+	id, ok := paramID(c, "id", itemType)
+	if !ok {
+		return
+	}
+
+	var count int64
+	if err := h.db.Table(itemType+"s").Where("id = ?", id).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related items"})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": strings.ToUpper(itemType[:1]) + itemType[1:] + " not found"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "6"))
+	if err != nil || limit <= 0 {
+		limit = 6
+	}
+	if limit > services.RelatedItemsPerItem {
+		limit = services.RelatedItemsPerItem
+	}
+
+	var rows []models.RelatedItem
+	if err := h.db.Where("item_type = ? AND item_id = ?", itemType, id).
+		Order("score DESC").Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related items"})
+		return
+	}
+
+	refs := make([]services.ItemRef, len(rows))
+	for i, row := range rows {
+		refs[i] = services.ItemRef{Type: row.RelatedType, ID: row.RelatedID}
+	}
+	summaries, err := loadItemSummaries(h.db, h.config.Server.BaseURL, refs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch related items"})
+		return
+	}
+
+	// Items deleted since the last run are skipped until the cache is recomputed
+	related := make([]RelatedItemResponse, 0, limit)
+	var computedAt *time.Time
+	for i, row := range rows {
+		summary, ok := summaries[refs[i]]
+		if !ok {
+			continue
+		}
+		related = append(related, RelatedItemResponse{
+			ItemSummary: *summary,
+			Score:       row.Score,
+			Reasons:     strings.Split(row.Reasons, ","),
+		})
+		computedAt = &rows[i].ComputedAt
+		if len(related) == limit {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"related":     related,
+		"computed_at": computedAt,
+	})
+}
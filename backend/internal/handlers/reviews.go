@@ -0,0 +1,721 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reviewReportFlagThreshold is the number of open abuse reports after which a visible
+// review is flagged for moderation
+const reviewReportFlagThreshold = 3
+
+// ReviewerSummary is the public view of the user who wrote a review or reply
+type ReviewerSummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// ReviewResponse is a review or reply with the replies threaded below it
+type ReviewResponse struct {
+	ID            uint              `json:"id"`
+	ParentID      *uint             `json:"parent_id"`
+	Rating        *int              `json:"rating,omitempty"`
+	Body          *string           `json:"body"`
+	IsAuthorReply bool              `json:"is_author_reply"`
+	Status        string            `json:"status"`
+	Reviewer      ReviewerSummary   `json:"reviewer"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Replies       []*ReviewResponse `json:"replies"`
+}
+
+// RatingSummary aggregates the visible star ratings of an item
+type RatingSummary struct {
+	Average      float64        `json:"average"`
+	Count        int            `json:"count"`
+	Distribution map[string]int `json:"distribution"`
+}
+
+// ReviewHandler handles reviews, ratings and their moderation
+type ReviewHandler struct {
+	db *gorm.DB
+}
+
+// NewReviewHandler creates a new review handler
+func NewReviewHandler(db *gorm.DB) *ReviewHandler {
+	return &ReviewHandler{db: db}
+}
+
+// GetBookReviews handles GET /books/:id/reviews
+func (h *ReviewHandler) GetBookReviews(c *gin.Context) {
+	h.listReviews(c, "book")
+}
+
+// GetPaperReviews handles GET /papers/:id/reviews
+func (h *ReviewHandler) GetPaperReviews(c *gin.Context) {
+	h.listReviews(c, "paper")
+}
+
+// listReviews writes the rating summary and a page of threaded reviews of an item.
+// Hidden reviews are only shown to their writer and to admins.
+func (h *ReviewHandler) listReviews(c *gin.Context, itemType string) {
+	itemID, ok := paramID(c, "id", itemType)
+	if !ok {
+		return
+	}
+	if !itemExists(h.db, itemType, itemID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": strings.ToUpper(itemType[:1]) + itemType[1:] + " not found"})
+		return
+	}
+
+	// sort takes a field of services.ReviewSorts; the older names still work
+	sortParam := c.Query("sort")
+	switch sortParam {
+	case "newest":
+		sortParam = "created_at:desc"
+	case "oldest":
+		sortParam = "created_at:asc"
+	case "highest":
+		sortParam = "rating:desc"
+	case "lowest":
+		sortParam = "rating:asc"
+	}
+	order, err := services.ReviewSorts.Parse(sortParam, "created_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 10, 50)
+
+	visible := func(db *gorm.DB) *gorm.DB {
+		if c.GetString("user_role") == "admin" {
+			return db
+		}
+		if userID, exists := c.Get("user_id"); exists {
+			return db.Where("status <> 'hidden' OR user_id = ?", userID)
+		}
+		return db.Where("status <> 'hidden'")
+	}
+
+	query := h.db.Model(&models.Review{}).Where("item_type = ? AND item_id = ? AND parent_id IS NULL", itemType, itemID).
+		Scopes(visible).Session(&gorm.Session{})
+	var total int64
+	query.Count(&total)
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var roots []models.Review
+	if err := paged.Find(&roots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	fetched := len(roots)
+	if fetched > page.limit {
+		roots = roots[:page.limit]
+	}
+	var nextCursor *string
+	if len(roots) > 0 {
+		if nextCursor, err = page.next(query, fetched, roots[len(roots)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+			return
+		}
+	}
+
+	rootIDs := make([]uint, len(roots))
+	for i, root := range roots {
+		rootIDs[i] = root.ID
+	}
+	threadIDs, err := services.ReviewThreadIDs(h.db, rootIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	var replies []models.Review
+	if len(threadIDs) > len(rootIDs) {
+		if err := h.db.Where("id IN ? AND parent_id IS NOT NULL", threadIDs).Scopes(visible).
+			Order("created_at ASC").Find(&replies).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+			return
+		}
+	}
+
+	data, err := h.threadReviews(roots, replies)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	summary, err := h.ratingSummary(itemType, itemID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rating summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary":     summary,
+		"total":       total,
+		"page":        page.page,
+		"limit":       page.limit,
+		"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+		"next_cursor": nextCursor,
+		"data":        data,
+	})
+}
+
+// CreateReview handles POST /user/reviews (one rated review per user and item)
+func (h *ReviewHandler) CreateReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	var req struct {
+		ItemType string  `json:"item_type" binding:"required,oneof=book paper"`
+		ItemID   uint    `json:"item_id" binding:"required"`
+		Rating   int     `json:"rating" binding:"required,min=1,max=5"`
+		Body     *string `json:"body" binding:"omitempty,max=5000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !itemExists(h.db, req.ItemType, req.ItemID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.Review{}).Where("user_id = ? AND item_type = ? AND item_id = ? AND parent_id IS NULL", uid, req.ItemType, req.ItemID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this item"})
+		return
+	}
+
+	review := models.Review{
+		UserID:   uid,
+		ItemType: req.ItemType,
+		ItemID:   req.ItemID,
+		Rating:   &req.Rating,
+		Body:     blankToNil(utils.StringValue(req.Body)),
+		Status:   "visible",
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+		return services.RefreshItemRating(tx, review.ItemType, review.ItemID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// UpdateReview handles PUT /user/reviews/:id (own reviews and replies)
+func (h *ReviewHandler) UpdateReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	reviewID, ok := paramID(c, "id", "review")
+	if !ok {
+		return
+	}
+	var review models.Review
+	if err := h.db.First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if review.UserID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to edit this review"})
+		return
+	}
+
+	var req struct {
+		Rating *int    `json:"rating" binding:"omitempty,min=1,max=5"`
+		Body   *string `json:"body" binding:"omitempty,max=5000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Rating != nil {
+		if review.ParentID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Replies cannot carry a rating"})
+			return
+		}
+		updates["rating"] = *req.Rating
+	}
+	if req.Body != nil {
+		body := blankToNil(utils.StringValue(req.Body))
+		if body == nil && review.ParentID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reply text is required"})
+			return
+		}
+		updates["body"] = body
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No changes provided"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&review).Updates(updates).Error; err != nil {
+			return err
+		}
+		if review.ParentID != nil {
+			return nil
+		}
+		return services.RefreshItemRating(tx, review.ItemType, review.ItemID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
+		return
+	}
+
+	h.db.First(&review, review.ID)
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview handles DELETE /user/reviews/:id and DELETE /admin/reviews/:id
+// Deleting a review also deletes the replies below it.
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	reviewID, ok := paramID(c, "id", "review")
+	if !ok {
+		return
+	}
+	var review models.Review
+	if err := h.db.First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if review.UserID != userID.(uint) && c.GetString("user_role") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized to delete this review"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.DeleteReviews(tx, []uint{review.ID}); err != nil {
+			return err
+		}
+		return services.RefreshItemRating(tx, review.ItemType, review.ItemID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully"})
+}
+
+// ReplyToReview handles POST /user/reviews/:id/replies
+// Replies may be written by the item's authors, by the writer of the review that
+// started the thread, and by admins.
+func (h *ReviewHandler) ReplyToReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	reviewID, ok := paramID(c, "id", "review")
+	if !ok {
+		return
+	}
+	var parent models.Review
+	if err := h.db.First(&parent, reviewID).Error; err != nil || parent.Status == "hidden" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	var req struct {
+		Body string `json:"body" binding:"required,max=5000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body := blankToNil(req.Body)
+	if body == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reply text is required"})
+		return
+	}
+
+	isAuthor := services.IsItemAuthor(h.db, uid, parent.ItemType, parent.ItemID)
+	if !isAuthor && c.GetString("user_role") != "admin" {
+		root := parent
+		for root.ParentID != nil {
+			if err := h.db.First(&root, *root.ParentID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+				return
+			}
+		}
+		if root.UserID != uid {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the item's authors and the reviewer can reply to this review"})
+			return
+		}
+	}
+
+	reply := models.Review{
+		UserID:        uid,
+		ItemType:      parent.ItemType,
+		ItemID:        parent.ItemID,
+		ParentID:      &parent.ID,
+		Body:          body,
+		IsAuthorReply: isAuthor,
+		Status:        "visible",
+	}
+	if err := h.db.Create(&reply).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reply"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+// ReportReview handles POST /user/reviews/:id/report
+// A visible review is flagged for moderation once enough users have reported it.
+func (h *ReviewHandler) ReportReview(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	uid := userID.(uint)
+
+	reviewID, ok := paramID(c, "id", "review")
+	if !ok {
+		return
+	}
+	var review models.Review
+	if err := h.db.First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if review.UserID == uid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own review"})
+		return
+	}
+
+	var req struct {
+		Reason string  `json:"reason" binding:"required,oneof=spam abuse off_topic other"`
+		Note   *string `json:"note" binding:"omitempty,max=1000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var count int64
+	h.db.Model(&models.ReviewReport{}).Where("review_id = ? AND user_id = ? AND status = 'open'", review.ID, uid).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this review"})
+		return
+	}
+
+	report := models.ReviewReport{
+		ReviewID: review.ID,
+		UserID:   uid,
+		Reason:   req.Reason,
+		Note:     blankToNil(utils.StringValue(req.Note)),
+		Status:   "open",
+	}
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		var open int64
+		if err := tx.Model(&models.ReviewReport{}).Where("review_id = ? AND status = 'open'", review.ID).Count(&open).Error; err != nil {
+			return err
+		}
+		if open >= reviewReportFlagThreshold && review.Status == "visible" {
+			return tx.Model(&review).Update("status", "flagged").Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to report review"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Review reported", "report": report})
+}
+
+// GetModerationQueue handles GET /admin/reviews
+// Defaults to flagged reviews; ?status= selects visible, flagged or hidden and
+// ?reported=true restricts to reviews with open reports. Recently changed reviews come
+// first unless sort selects another field of services.ReviewSorts.
+func (h *ReviewHandler) GetModerationQueue(c *gin.Context) {
+	order, err := services.ReviewSorts.Parse(c.Query("sort"), "updated_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 20, 100)
+
+	query := h.db.Model(&models.Review{})
+	if status := c.DefaultQuery("status", "flagged"); status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	if c.Query("reported") == "true" {
+		query = query.Where("id IN (?)", h.db.Model(&models.ReviewReport{}).Select("review_id").Where("status = 'open'"))
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	query.Count(&total)
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reviews := make([]models.Review, 0)
+	if err := paged.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name", "email")
+	}).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	fetched := len(reviews)
+	if fetched > page.limit {
+		reviews = reviews[:page.limit]
+	}
+	var nextCursor *string
+	if len(reviews) > 0 {
+		if nextCursor, err = page.next(query, fetched, reviews[len(reviews)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"page":        page.page,
+		"limit":       page.limit,
+		"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+		"next_cursor": nextCursor,
+		"data":        reviews,
+	})
+}
+
+// ModerateReview handles PUT /admin/reviews/:id/moderation (show, flag or hide a review)
+// Hiding a review resolves its open reports; showing it again dismisses them.
+func (h *ReviewHandler) ModerateReview(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	reviewID, ok := paramID(c, "id", "review")
+	if !ok {
+		return
+	}
+	var review models.Review
+	if err := h.db.First(&review, reviewID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	var req struct {
+		Status string  `json:"status" binding:"required,oneof=visible flagged hidden"`
+		Note   *string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&review).Updates(map[string]interface{}{
+			"status":          req.Status,
+			"moderated_by":    adminID,
+			"moderated_at":    now,
+			"moderation_note": blankToNil(utils.StringValue(req.Note)),
+		}).Error; err != nil {
+			return err
+		}
+
+		reportStatus := map[string]string{"hidden": "resolved", "visible": "dismissed"}[req.Status]
+		if reportStatus != "" {
+			if err := tx.Model(&models.ReviewReport{}).Where("review_id = ? AND status = 'open'", review.ID).
+				Updates(map[string]interface{}{"status": reportStatus, "resolved_by": adminID, "resolved_at": now}).Error; err != nil {
+				return err
+			}
+		}
+
+		if review.ParentID != nil {
+			return nil
+		}
+		return services.RefreshItemRating(tx, review.ItemType, review.ItemID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate review"})
+		return
+	}
+
+	h.db.First(&review, review.ID)
+	c.JSON(http.StatusOK, review)
+}
+
+// GetReviewReports handles GET /admin/review-reports (defaults to open reports)
+func (h *ReviewHandler) GetReviewReports(c *gin.Context) {
+	reports := make([]models.ReviewReport, 0)
+	if err := h.db.Where("status = ?", c.DefaultQuery("status", "open")).
+		Preload("Review").Order("created_at ASC").Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// ResolveReviewReport handles PUT /admin/review-reports/:id (resolve or dismiss a report)
+func (h *ReviewHandler) ResolveReviewReport(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	reportID, ok := paramID(c, "id", "review report")
+	if !ok {
+		return
+	}
+	var report models.ReviewReport
+	if err := h.db.First(&report, reportID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review report not found"})
+		return
+	}
+	if report.Status != "open" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Report has already been handled"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status" binding:"required,oneof=resolved dismissed"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.Model(&report).Updates(map[string]interface{}{
+		"status":      req.Status,
+		"resolved_by": adminID,
+		"resolved_at": time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review report"})
+		return
+	}
+
+	h.db.First(&report, report.ID)
+	c.JSON(http.StatusOK, report)
+}
+
+// threadReviews nests replies below the reviews they answer. Replies are expected in
+// creation order, so a parent is always placed before its replies; replies whose
+// parent was filtered out are dropped.
+func (h *ReviewHandler) threadReviews(roots, replies []models.Review) ([]*ReviewResponse, error) {
+	userIDs := make([]uint, 0, len(roots)+len(replies))
+	for _, review := range roots {
+		userIDs = append(userIDs, review.UserID)
+	}
+	for _, review := range replies {
+		userIDs = append(userIDs, review.UserID)
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		if err := h.db.Select("id", "name").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+
+	nodes := make(map[uint]*ReviewResponse, len(roots)+len(replies))
+	toResponse := func(review models.Review) *ReviewResponse {
+		node := &ReviewResponse{
+			ID:            review.ID,
+			ParentID:      review.ParentID,
+			Rating:        review.Rating,
+			Body:          review.Body,
+			IsAuthorReply: review.IsAuthorReply,
+			Status:        review.Status,
+			Reviewer:      ReviewerSummary{ID: review.UserID, Name: names[review.UserID]},
+			CreatedAt:     review.CreatedAt,
+			UpdatedAt:     review.UpdatedAt,
+			Replies:       make([]*ReviewResponse, 0),
+		}
+		nodes[review.ID] = node
+		return node
+	}
+
+	data := make([]*ReviewResponse, 0, len(roots))
+	for _, review := range roots {
+		data = append(data, toResponse(review))
+	}
+	for _, review := range replies {
+		if parent, ok := nodes[*review.ParentID]; ok {
+			parent.Replies = append(parent.Replies, toResponse(review))
+		}
+	}
+	return data, nil
+}
+
+// ratingSummary computes the average, count and per-star distribution of an item's
+// visible ratings
+func (h *ReviewHandler) ratingSummary(itemType string, itemID uint) (RatingSummary, error) {
+	var rows []struct {
+		Rating int
+		Count  int
+	}
+	if err := h.db.Model(&models.Review{}).Select("rating, COUNT(*) AS count").
+		Where("item_type = ? AND item_id = ? AND parent_id IS NULL AND rating IS NOT NULL AND status <> 'hidden'", itemType, itemID).
+		Group("rating").Scan(&rows).Error; err != nil {
+		return RatingSummary{}, err
+	}
+
+	summary := RatingSummary{Distribution: map[string]int{"1": 0, "2": 0, "3": 0, "4": 0, "5": 0}}
+	sum := 0
+	for _, row := range rows {
+		summary.Distribution[strconv.Itoa(row.Rating)] = row.Count
+		summary.Count += row.Count
+		sum += row.Rating * row.Count
+	}
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(sum)/float64(summary.Count)*100) / 100
+	}
+	return summary, nil
+}
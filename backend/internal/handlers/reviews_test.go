@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"e-repository-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// idParam returns the :id path parameter of a request
+func idParam(id uint) gin.Params {
+	return gin.Params{{Key: "id", Value: fmt.Sprint(id)}}
+}
+
+// createReview writes a review through the handler and returns it
+func createReview(t *testing.T, handler *ReviewHandler, userID, bookID uint, rating int) models.Review {
+	w := callHandler(handler.CreateReview, userID, "user", nil, gin.H{"item_type": "book", "item_id": bookID, "rating": rating, "body": "Review"})
+	if !assert.Equal(t, http.StatusCreated, w.Code) {
+		t.FailNow()
+	}
+	var review models.Review
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &review))
+	return review
+}
+
+func TestReviewRatingAggregates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewReviewHandler(db)
+
+	first := createMember(t, db, "first@example.com", "First Reader", "student")
+	second := createMember(t, db, "second@example.com", "Second Reader", "student")
+	book := models.Book{Title: "Pemrograman Go", Author: "Budi Santoso"}
+	assert.NoError(t, db.Create(&book).Error)
+
+	createReview(t, handler, first.ID, book.ID, 5)
+	review := createReview(t, handler, second.ID, book.ID, 2)
+
+	db.First(&book, book.ID)
+	assert.Equal(t, 3.5, book.RatingAverage)
+	assert.Equal(t, 2, book.RatingCount)
+
+	// One rated review per user and item
+	w := callHandler(handler.CreateReview, first.ID, "user", nil, gin.H{"item_type": "book", "item_id": book.ID, "rating": 1})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Editing a rating refreshes the aggregate
+	w = callHandler(handler.UpdateReview, second.ID, "user", idParam(review.ID), gin.H{"rating": 4})
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&book, book.ID)
+	assert.Equal(t, 4.5, book.RatingAverage)
+
+	// Only the writer may edit a review
+	w = callHandler(handler.UpdateReview, first.ID, "user", idParam(review.ID), gin.H{"rating": 1})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = callHandler(handler.DeleteReview, second.ID, "user", idParam(review.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&book, book.ID)
+	assert.Equal(t, 5.0, book.RatingAverage)
+	assert.Equal(t, 1, book.RatingCount)
+}
+
+func TestReviewModeration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewReviewHandler(db)
+
+	writer := createMember(t, db, "writer@example.com", "Writer", "student")
+	other := createMember(t, db, "other@example.com", "Other Reader", "student")
+	admin := createMember(t, db, "moderator@example.com", "Moderator", "lecturer")
+	book := models.Book{Title: "Basis Data", Author: "Ani Wijaya"}
+	assert.NoError(t, db.Create(&book).Error)
+
+	createReview(t, handler, other.ID, book.ID, 4)
+	review := createReview(t, handler, writer.ID, book.ID, 1)
+
+	// Writers cannot report their own review
+	w := callHandler(handler.ReportReview, writer.ID, "user", idParam(review.ID), gin.H{"reason": "spam"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The review is flagged once enough users report it
+	for i := 0; i < reviewReportFlagThreshold; i++ {
+		reporter := createMember(t, db, fmt.Sprintf("reporter%d@example.com", i), "Reporter", "student")
+		w = callHandler(handler.ReportReview, reporter.ID, "user", idParam(review.ID), gin.H{"reason": "abuse"})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		db.First(&review, review.ID)
+		if i < reviewReportFlagThreshold-1 {
+			assert.Equal(t, "visible", review.Status)
+		}
+	}
+	assert.Equal(t, "flagged", review.Status)
+
+	// Hiding it resolves the reports and drops it from the rating
+	w = callHandler(handler.ModerateReview, admin.ID, "admin", idParam(review.ID), gin.H{"status": "hidden", "note": "Abusive"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var open int64
+	db.Model(&models.ReviewReport{}).Where("review_id = ? AND status = 'open'", review.ID).Count(&open)
+	assert.Equal(t, int64(0), open)
+	db.First(&book, book.ID)
+	assert.Equal(t, 4.0, book.RatingAverage)
+	assert.Equal(t, 1, book.RatingCount)
+
+	// Hidden reviews are only listed for their writer and admins
+	listed := func(userID uint, role string) int {
+		w := callHandler(handler.GetBookReviews, userID, role, idParam(book.ID), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Total int64 `json:"total"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return int(response.Total)
+	}
+	assert.Equal(t, 1, listed(0, ""))
+	assert.Equal(t, 1, listed(other.ID, "user"))
+	assert.Equal(t, 2, listed(writer.ID, "user"))
+	assert.Equal(t, 2, listed(admin.ID, "admin"))
+
+	// Hidden reviews cannot be replied to
+	w = callHandler(handler.ReplyToReview, writer.ID, "user", idParam(review.ID), gin.H{"body": "Why?"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReviewThreading(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewReviewHandler(db)
+
+	reviewer := createMember(t, db, "reviewer@example.com", "Reviewer", "student")
+	author := createMember(t, db, "author@example.com", "Budi Santoso", "lecturer")
+	stranger := createMember(t, db, "stranger@example.com", "Stranger", "student")
+	book := models.Book{Title: "Jaringan Komputer", Author: "Budi Santoso", CreatedBy: &author.ID}
+	assert.NoError(t, db.Create(&book).Error)
+
+	review := createReview(t, handler, reviewer.ID, book.ID, 3)
+
+	// The item's author replies, and the reviewer answers the reply
+	w := callHandler(handler.ReplyToReview, author.ID, "user", idParam(review.ID), gin.H{"body": "Thanks for reading"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var reply models.Review
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &reply))
+	assert.True(t, reply.IsAuthorReply)
+	assert.Nil(t, reply.Rating)
+
+	w = callHandler(handler.ReplyToReview, reviewer.ID, "user", idParam(reply.ID), gin.H{"body": "You're welcome"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Other users cannot join the thread
+	w = callHandler(handler.ReplyToReview, stranger.ID, "user", idParam(review.ID), gin.H{"body": "Me too"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Replies cannot carry a rating
+	w = callHandler(handler.UpdateReview, author.ID, "user", idParam(reply.ID), gin.H{"rating": 5})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = callHandler(handler.GetBookReviews, 0, "", idParam(book.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Total   int64            `json:"total"`
+		Summary RatingSummary    `json:"summary"`
+		Data    []ReviewResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(1), response.Total)
+	assert.Equal(t, 1, response.Summary.Distribution["3"])
+	if assert.Len(t, response.Data, 1) && assert.Len(t, response.Data[0].Replies, 1) {
+		assert.Equal(t, "Budi Santoso", response.Data[0].Replies[0].Reviewer.Name)
+		assert.Len(t, response.Data[0].Replies[0].Replies, 1)
+	}
+
+	// Deleting the review deletes its thread
+	w = callHandler(handler.DeleteReview, reviewer.ID, "user", idParam(review.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var remaining int64
+	db.Model(&models.Review{}).Where("item_type = 'book' AND item_id = ?", book.ID).Count(&remaining)
+	assert.Equal(t, int64(0), remaining)
+
+	// Review IDs must be numeric
+	w = callHandler(handler.DeleteReview, reviewer.ID, "user", gin.Params{{Key: "id", Value: "1 OR 1=1"}}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
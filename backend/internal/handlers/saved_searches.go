@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/search"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SavedSearchHandler handles saved searches and the notifications they leave
+type SavedSearchHandler struct {
+	db *gorm.DB
+}
+
+// NewSavedSearchHandler creates a new saved search handler
+func NewSavedSearchHandler(db *gorm.DB) *SavedSearchHandler {
+	return &SavedSearchHandler{db: db}
+}
+
+// savedSearchRequest is the payload for creating or updating a saved search
+type savedSearchRequest struct {
+	Name      *string                    `json:"name"`
+	Query     *string                    `json:"query"`
+	Filters   *models.SavedSearchFilters `json:"filters"`
+	Frequency *string                    `json:"frequency" binding:"omitempty,oneof=daily weekly"`
+	Channel   *string                    `json:"channel" binding:"omitempty,oneof=email in_app"`
+	Active    *bool                      `json:"active"`
+}
+
+// GetSavedSearches handles GET /user/saved-searches
+func (h *SavedSearchHandler) GetSavedSearches(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	searches := make([]models.SavedSearch, 0)
+	if err := h.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&searches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved searches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, searches)
+}
+
+// CreateSavedSearch handles POST /user/saved-searches
+// Alerts cover the items added from now on.
+func (h *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search name is required"})
+		return
+	}
+
+	token, err := utils.GenerateVerificationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate unsubscribe token"})
+		return
+	}
+
+	saved := models.SavedSearch{
+		UserID:           userID.(uint),
+		Frequency:        "daily",
+		Channel:          "email",
+		Active:           true,
+		UnsubscribeToken: token,
+		LastRunAt:        time.Now(),
+	}
+	if !h.apply(c, &saved, req) {
+		return
+	}
+	if err := h.db.Create(&saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create saved search"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+// GetSavedSearch handles GET /user/saved-searches/:id
+func (h *SavedSearchHandler) GetSavedSearch(c *gin.Context) {
+	saved, ok := h.ownedSearch(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// UpdateSavedSearch handles PUT /user/saved-searches/:id
+func (h *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	saved, ok := h.ownedSearch(c)
+	if !ok {
+		return
+	}
+
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search name is required"})
+		return
+	}
+	// Turning alerts back on starts from now rather than sending what was missed
+	if req.Active != nil && *req.Active && !saved.Active {
+		saved.LastRunAt = time.Now()
+	}
+	if !h.apply(c, saved, req) {
+		return
+	}
+
+	if err := h.db.Save(saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update saved search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteSavedSearch handles DELETE /user/saved-searches/:id
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	saved, ok := h.ownedSearch(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Delete(saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// UnsubscribePage handles GET /saved-searches/unsubscribe/:token
+// It is the link in digest emails, so it works without logging in. Mail scanners and
+// link previews open such links too, so the page only asks for confirmation and the
+// alerts are turned off by the POST it submits.
+func (h *SavedSearchHandler) UnsubscribePage(c *gin.Context) {
+	saved, ok := h.findByUnsubscribeToken(c)
+	if !ok {
+		return
+	}
+	h.renderUnsubscribePage(c, saved, false)
+}
+
+// Unsubscribe handles POST /saved-searches/unsubscribe/:token
+// The search is kept with its alerts turned off.
+func (h *SavedSearchHandler) Unsubscribe(c *gin.Context) {
+	saved, ok := h.findByUnsubscribeToken(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Model(saved).Update("active", false).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+
+	h.renderUnsubscribePage(c, saved, true)
+}
+
+// findByUnsubscribeToken loads the saved search of the token in the path
+func (h *SavedSearchHandler) findByUnsubscribeToken(c *gin.Context) (*models.SavedSearch, bool) {
+	var saved models.SavedSearch
+	if err := h.db.Where("unsubscribe_token = ?", c.Param("token")).First(&saved).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return nil, false
+	}
+	return &saved, true
+}
+
+// renderUnsubscribePage writes the page that confirms, or asks to confirm, turning off
+// the alerts of a saved search
+func (h *SavedSearchHandler) renderUnsubscribePage(c *gin.Context, saved *models.SavedSearch, unsubscribed bool) {
+	tmpl, err := template.ParseFiles("templates/unsubscribe.html")
+	if err != nil {
+		log.Printf("[SavedSearch] Failed to parse unsubscribe page template: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render page"})
+		return
+	}
+
+	data := struct {
+		Name         string
+		Unsubscribed bool
+	}{Name: saved.Name, Unsubscribed: unsubscribed}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		log.Printf("[SavedSearch] Failed to render unsubscribe page: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render page"})
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+// GetNotifications handles GET /user/notifications?unread=true
+func (h *SavedSearchHandler) GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	order, err := services.NotificationSorts.Parse(c.Query("sort"), "created_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 20, 100)
+
+	query := h.db.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total, unread int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	h.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread)
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	notifications := make([]models.Notification, 0)
+	if err := paged.Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	fetched := len(notifications)
+	if fetched > page.limit {
+		notifications = notifications[:page.limit]
+	}
+	var nextCursor *string
+	if len(notifications) > 0 {
+		if nextCursor, err = page.next(query, fetched, notifications[len(notifications)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"unread":      unread,
+		"page":        page.page,
+		"limit":       page.limit,
+		"next_cursor": nextCursor,
+		"data":        notifications,
+	})
+}
+
+// MarkNotificationRead handles PUT /user/notifications/:id/read
+func (h *SavedSearchHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var notification models.Notification
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := h.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead handles PUT /user/notifications/read-all
+func (h *SavedSearchHandler) MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := h.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "updated": result.RowsAffected})
+}
+
+// apply copies the fields of a request onto a saved search after validating the query
+// and filters, writing an error response when they are invalid
+func (h *SavedSearchHandler) apply(c *gin.Context, saved *models.SavedSearch, req savedSearchRequest) bool {
+	if req.Query != nil {
+		query := strings.TrimSpace(*req.Query)
+		if _, err := search.Parse(query); err != nil {
+			var syntaxErr *search.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": syntaxErr.Error(), "position": syntaxErr.Position})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			}
+			return false
+		}
+		saved.Query = query
+	}
+	if req.Filters != nil {
+		if _, err := services.SearchFiltersFrom(*req.Filters); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+		saved.Filters = *req.Filters
+	}
+	if req.Name != nil {
+		saved.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Frequency != nil {
+		saved.Frequency = *req.Frequency
+	}
+	if req.Channel != nil {
+		saved.Channel = *req.Channel
+	}
+	if req.Active != nil {
+		saved.Active = *req.Active
+	}
+	return true
+}
+
+// ownedSearch loads the authenticated user's saved search in the :id parameter,
+// writing an error response when there is none
+func (h *SavedSearchHandler) ownedSearch(c *gin.Context) (*models.SavedSearch, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var saved models.SavedSearch
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&saved).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch saved search"})
+		}
+		return nil, false
+	}
+	return &saved, true
+}
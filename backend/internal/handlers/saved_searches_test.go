@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"e-repository-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsubscribeNeedsConfirmation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewSavedSearchHandler(db)
+
+	member := createMember(t, db, "reader@example.com", "Reader", "student")
+	saved := models.SavedSearch{UserID: member.ID, Name: "Basis data", Query: "basis data", Active: true, UnsubscribeToken: "token-123"}
+	assert.NoError(t, db.Create(&saved).Error)
+	token := gin.Params{{Key: "token", Value: saved.UnsubscribeToken}}
+
+	// Opening the link, as mail scanners do, keeps the alerts on
+	getHandler(handler.UnsubscribePage, 0, "", token, "")
+	db.First(&saved, saved.ID)
+	assert.True(t, saved.Active)
+
+	// Confirming the page turns them off
+	callHandler(handler.Unsubscribe, 0, "", token, nil)
+	db.First(&saved, saved.ID)
+	assert.False(t, saved.Active)
+
+	w := getHandler(handler.UnsubscribePage, 0, "", gin.Params{{Key: "token", Value: "unknown"}}, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = callHandler(handler.Unsubscribe, 0, "", gin.Params{{Key: "token", Value: "unknown"}}, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
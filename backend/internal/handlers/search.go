@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/search"
+	"e-repository-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SearchResultResponse is a book or paper found by a search with its relevance score
+type SearchResultResponse struct {
+	ItemSummary
+	Score float64 `json:"score"`
+}
+
+// SearchHandler handles the unified search across books and papers
+type SearchHandler struct {
+	db     *gorm.DB
+	config *configs.Config
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(db *gorm.DB, config *configs.Config) *SearchHandler {
+	return &SearchHandler{db: db, config: config}
+}
+
+// Search handles GET /search
+// The query supports field qualifiers, "phrases", AND/OR/NOT, grouping, year ranges and
+// wildcards, e.g. title:"sistem informasi" AND author:santoso or year:2019..2023 -keywords:covid.
+// Filters: type (book, paper or both comma-separated) and the repeatable facet
+// selections year_range, category, language, faculty, department and author (ID).
+// When few items match, did_you_mean offers the query with misspelled words corrected.
+// total_approximate is set when a broad query matched more items than are counted.
+func (h *SearchHandler) Search(c *gin.Context) {
+	var req models.SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Support both 'query' and 'q' as search parameters
+	q := req.Query
+	if q == "" {
+		q = c.Query("q")
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10
+	}
+	if req.Limit > 100 {
+		req.Limit = 100
+	}
+
+	filters, err := searchFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := services.Search(h.db, q, filters, (req.Page-1)*req.Limit, req.Limit)
+	var syntaxErr *search.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": syntaxErr.Error(), "position": syntaxErr.Position})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	refs := make([]services.ItemRef, len(result.Hits))
+	for i, hit := range result.Hits {
+		refs[i] = hit.ItemRef
+	}
+	summaries, err := loadItemSummaries(h.db, h.config.Server.BaseURL, refs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	data := make([]SearchResultResponse, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		if summary, ok := summaries[hit.ItemRef]; ok {
+			data = append(data, SearchResultResponse{ItemSummary: *summary, Score: hit.Score})
+		}
+	}
+
+	response := gin.H{
+		"total":             result.Total,
+		"total_approximate": result.Approximate,
+		"page":              req.Page,
+		"limit":             req.Limit,
+		"total_pages":       int(math.Ceil(float64(result.Total) / float64(req.Limit))),
+		"data":              data,
+		"facets":            result.Facets,
+	}
+	// Offer a spelling correction when the query finds little
+	if result.Total < services.SpellingResultThreshold {
+		if correction := services.SuggestSpelling(q); correction != "" {
+			response["did_you_mean"] = correction
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Suggest handles GET /search/suggest?q=prefix
+// It completes titles, author names and keywords as the user types, most popular first.
+func (h *SearchHandler) Suggest(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+	if limit > 20 {
+		limit = 20
+	}
+
+	suggestions, err := services.Suggest(h.db, c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get suggestions"})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// searchFilters reads the facet selections of a search from the query string: type
+// (book, paper or both comma-separated) and the repeatable year_range, category,
+// language, faculty, department and author (ID)
+func searchFilters(c *gin.Context) (services.SearchFilters, error) {
+	filters := services.SearchFilters{
+		Categories:  nonEmpty(c.QueryArray("category")),
+		Languages:   nonEmpty(c.QueryArray("language")),
+		Faculties:   nonEmpty(c.QueryArray("faculty")),
+		Departments: nonEmpty(c.QueryArray("department")),
+	}
+	for _, itemType := range nonEmpty(strings.Split(c.Query("type"), ",")) {
+		if itemType != "book" && itemType != "paper" {
+			return filters, errors.New("type must be book or paper")
+		}
+		filters.Types = append(filters.Types, itemType)
+	}
+	for _, value := range nonEmpty(c.QueryArray("year_range")) {
+		yearRange, err := services.ParseYearRange(value)
+		if err != nil {
+			return filters, err
+		}
+		filters.YearRanges = append(filters.YearRanges, yearRange)
+	}
+	for _, value := range nonEmpty(c.QueryArray("author")) {
+		authorID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filters, errors.New("Invalid author ID")
+		}
+		filters.AuthorIDs = append(filters.AuthorIDs, uint(authorID))
+	}
+	return filters, nil
+}
+
+// nonEmpty trims values and drops the blank ones
+func nonEmpty(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchRanksItemsWithEmptyFields(t *testing.T) {
+	db := setupTestDB(t)
+
+	// The title match has no abstract, DOI or ISSN; it must still outrank the
+	// abstract match rather than score NULL
+	titled := models.Paper{Title: "Jaringan Komputer Kampus", Author: "Budi Santoso"}
+	abstracted := models.Paper{Title: "Evaluasi Kinerja", Author: "Ani Wijaya", Abstract: utils.StringPtr("Pengukuran jaringan komputer")}
+	assert.NoError(t, db.Create(&titled).Error)
+	assert.NoError(t, db.Create(&abstracted).Error)
+
+	result, err := services.Search(db, "jaringan komputer", services.SearchFilters{Types: []string{"paper"}}, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.Total)
+	if assert.Len(t, result.Hits, 2) {
+		assert.Equal(t, titled.ID, result.Hits[0].ID)
+		assert.Greater(t, result.Hits[0].Score, result.Hits[1].Score)
+	}
+}
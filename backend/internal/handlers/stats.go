@@ -7,6 +7,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// userBookIDsSQL and userPaperIDsSQL select the works a user uploaded or is a linked
+// author of. Each takes the user ID twice.
+const (
+	userBookIDsSQL  = "SELECT id FROM books WHERE created_by = ? UNION SELECT book_id FROM book_authors WHERE user_id = ?"
+	userPaperIDsSQL = "SELECT id FROM papers WHERE created_by = ? UNION SELECT paper_id FROM paper_authors WHERE user_id = ?"
+)
+
 type StatsHandler struct {
 	db *gorm.DB
 }
@@ -103,13 +110,11 @@ func (h *StatsHandler) GetUserCitationsPerMonth(c *gin.Context) {
 	h.db.Raw(`
 		SELECT YEAR(c.cited_at) as year, MONTH(c.cited_at) as month, COUNT(*) as count
 		FROM citations c
-		LEFT JOIN books b ON c.item_id = b.id AND c.item_type = 'book'
-		LEFT JOIN papers p ON c.item_id = p.id AND c.item_type = 'paper'
 		WHERE c.cited_at >= DATE_SUB(CURDATE(), INTERVAL 12 MONTH)
-		AND ((c.item_type = 'book' AND b.created_by = ?) OR (c.item_type = 'paper' AND p.created_by = ?))
+		AND ((c.item_type = 'book' AND c.item_id IN (`+userBookIDsSQL+`)) OR (c.item_type = 'paper' AND c.item_id IN (`+userPaperIDsSQL+`)))
 		GROUP BY year, month
 		ORDER BY year, month
-	`, userID, userID).Scan(&results)
+	`, userID, userID, userID, userID).Scan(&results)
 	c.JSON(http.StatusOK, results)
 }
 
@@ -120,33 +125,13 @@ func (h *StatsHandler) GetUserStats(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
-	var totalBooks, totalPapers, totalDownloads, totalCitations int64
-	h.db.Table("books").Where("created_by = ?", userID).Count(&totalBooks)
-	h.db.Table("papers").Where("created_by = ?", userID).Count(&totalPapers)
-	h.db.Raw(`SELECT COUNT(*) FROM downloads d LEFT JOIN books b ON d.item_id = b.id AND d.item_type = 'book' LEFT JOIN papers p ON d.item_id = p.id AND d.item_type = 'paper' WHERE (d.item_type = 'book' AND b.created_by = ?) OR (d.item_type = 'paper' AND p.created_by = ?)`, userID, userID).Scan(&totalDownloads)
-	h.db.Raw(`SELECT COUNT(*) FROM citations c LEFT JOIN books b ON c.item_id = b.id AND c.item_type = 'book' LEFT JOIN papers p ON c.item_id = p.id AND c.item_type = 'paper' WHERE (c.item_type = 'book' AND b.created_by = ?) OR (c.item_type = 'paper' AND p.created_by = ?)`, userID, userID).Scan(&totalCitations)
-	c.JSON(http.StatusOK, gin.H{
-		"totalBooks":     totalBooks,
-		"totalPapers":    totalPapers,
-		"totalDownloads": totalDownloads,
-		"totalCitations": totalCitations,
-	})
+	c.JSON(http.StatusOK, h.userWorkStats(userID))
 }
 
 // GetUserStatsById returns stats for a user by ID (public)
 func (h *StatsHandler) GetUserStatsById(c *gin.Context) {
 	userID := c.Param("id")
-	var totalBooks, totalPapers, totalDownloads, totalCitations int64
-	h.db.Table("books").Where("created_by = ?", userID).Count(&totalBooks)
-	h.db.Table("papers").Where("created_by = ?", userID).Count(&totalPapers)
-	h.db.Raw(`SELECT COUNT(*) FROM downloads d LEFT JOIN books b ON d.item_id = b.id AND d.item_type = 'book' LEFT JOIN papers p ON d.item_id = p.id AND d.item_type = 'paper' WHERE (d.item_type = 'book' AND b.created_by = ?) OR (d.item_type = 'paper' AND p.created_by = ?)`, userID, userID).Scan(&totalDownloads)
-	h.db.Raw(`SELECT COUNT(*) FROM citations c LEFT JOIN books b ON c.item_id = b.id AND c.item_type = 'book' LEFT JOIN papers p ON c.item_id = p.id AND c.item_type = 'paper' WHERE (c.item_type = 'book' AND b.created_by = ?) OR (c.item_type = 'paper' AND p.created_by = ?)`, userID, userID).Scan(&totalCitations)
-	c.JSON(http.StatusOK, gin.H{
-		"totalBooks":     totalBooks,
-		"totalPapers":    totalPapers,
-		"totalDownloads": totalDownloads,
-		"totalCitations": totalCitations,
-	})
+	c.JSON(http.StatusOK, h.userWorkStats(userID))
 }
 
 // GetUserCitationsPerMonthById returns citation count per month for a user's works (public)
@@ -161,13 +146,11 @@ func (h *StatsHandler) GetUserCitationsPerMonthById(c *gin.Context) {
 	h.db.Raw(`
 		SELECT YEAR(c.cited_at) as year, MONTH(c.cited_at) as month, COUNT(*) as count
 		FROM citations c
-		LEFT JOIN books b ON c.item_id = b.id AND c.item_type = 'book'
-		LEFT JOIN papers p ON c.item_id = p.id AND c.item_type = 'paper'
 		WHERE c.cited_at >= DATE_SUB(CURDATE(), INTERVAL 12 MONTH)
-		AND ((c.item_type = 'book' AND b.created_by = ?) OR (c.item_type = 'paper' AND p.created_by = ?))
+		AND ((c.item_type = 'book' AND c.item_id IN (`+userBookIDsSQL+`)) OR (c.item_type = 'paper' AND c.item_id IN (`+userPaperIDsSQL+`)))
 		GROUP BY year, month
 		ORDER BY year, month
-	`, userID, userID).Scan(&results)
+	`, userID, userID, userID, userID).Scan(&results)
 	c.JSON(http.StatusOK, results)
 }
 
@@ -183,13 +166,11 @@ func (h *StatsHandler) GetUserDownloadsPerMonthById(c *gin.Context) {
 	h.db.Raw(`
 		SELECT YEAR(d.downloaded_at) as year, MONTH(d.downloaded_at) as month, COUNT(*) as count
 		FROM downloads d
-		LEFT JOIN books b ON d.item_id = b.id AND d.item_type = 'book'
-		LEFT JOIN papers p ON d.item_id = p.id AND d.item_type = 'paper'
 		WHERE d.downloaded_at >= DATE_SUB(CURDATE(), INTERVAL 12 MONTH)
-		AND ((d.item_type = 'book' AND b.created_by = ?) OR (d.item_type = 'paper' AND p.created_by = ?))
+		AND ((d.item_type = 'book' AND d.item_id IN (`+userBookIDsSQL+`)) OR (d.item_type = 'paper' AND d.item_id IN (`+userPaperIDsSQL+`)))
 		GROUP BY year, month
 		ORDER BY year, month
-	`, userID, userID).Scan(&results)
+	`, userID, userID, userID, userID).Scan(&results)
 	c.JSON(http.StatusOK, results)
 }
 
@@ -269,12 +250,26 @@ func (h *StatsHandler) GetUserDownloadsPerMonth(c *gin.Context) {
 	h.db.Raw(`
 		SELECT YEAR(d.downloaded_at) as year, MONTH(d.downloaded_at) as month, COUNT(*) as count
 		FROM downloads d
-		LEFT JOIN books b ON d.item_id = b.id AND d.item_type = 'book'
-		LEFT JOIN papers p ON d.item_id = p.id AND d.item_type = 'paper'
 		WHERE d.downloaded_at >= DATE_SUB(CURDATE(), INTERVAL 12 MONTH)
-		AND ((d.item_type = 'book' AND b.created_by = ?) OR (d.item_type = 'paper' AND p.created_by = ?))
+		AND ((d.item_type = 'book' AND d.item_id IN (`+userBookIDsSQL+`)) OR (d.item_type = 'paper' AND d.item_id IN (`+userPaperIDsSQL+`)))
 		GROUP BY year, month
 		ORDER BY year, month
-	`, userID, userID).Scan(&results)
+	`, userID, userID, userID, userID).Scan(&results)
 	c.JSON(http.StatusOK, results)
 }
+
+// userWorkStats counts the works a user uploaded or is a linked author of, and the
+// downloads and citations of those works
+func (h *StatsHandler) userWorkStats(userID interface{}) gin.H {
+	var totalBooks, totalPapers, totalDownloads, totalCitations int64
+	h.db.Raw("SELECT COUNT(*) FROM ("+userBookIDsSQL+") AS t", userID, userID).Scan(&totalBooks)
+	h.db.Raw("SELECT COUNT(*) FROM ("+userPaperIDsSQL+") AS t", userID, userID).Scan(&totalPapers)
+	h.db.Raw(`SELECT COUNT(*) FROM downloads d WHERE (d.item_type = 'book' AND d.item_id IN (`+userBookIDsSQL+`)) OR (d.item_type = 'paper' AND d.item_id IN (`+userPaperIDsSQL+`))`, userID, userID, userID, userID).Scan(&totalDownloads)
+	h.db.Raw(`SELECT COUNT(*) FROM citations c WHERE (c.item_type = 'book' AND c.item_id IN (`+userBookIDsSQL+`)) OR (c.item_type = 'paper' AND c.item_id IN (`+userPaperIDsSQL+`))`, userID, userID, userID, userID).Scan(&totalCitations)
+	return gin.H{
+		"totalBooks":     totalBooks,
+		"totalPapers":    totalPapers,
+		"totalDownloads": totalDownloads,
+		"totalCitations": totalCitations,
+	}
+}
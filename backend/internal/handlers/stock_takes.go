@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// StockTakeResponse is a stock-take session with its item counts
+type StockTakeResponse struct {
+	models.StockTake
+	Summary services.StockTakeSummary `json:"summary"`
+}
+
+// StockTakeHandler handles inventory stock-take sessions
+type StockTakeHandler struct {
+	db *gorm.DB
+}
+
+// NewStockTakeHandler creates a new stock-take handler
+func NewStockTakeHandler(db *gorm.DB) *StockTakeHandler {
+	return &StockTakeHandler{db: db}
+}
+
+// OpenStockTake handles POST /admin/stock-takes
+func (h *StockTakeHandler) OpenStockTake(c *gin.Context) {
+	staffID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		Name         string `json:"name" binding:"required,max=255"`
+		LocationFrom string `json:"location_from" binding:"required,max=255"`
+		LocationTo   string `json:"location_to" binding:"required,max=255"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, to := strings.TrimSpace(req.LocationFrom), strings.TrimSpace(req.LocationTo)
+	if strings.ToLower(from) > strings.ToLower(to) {
+		from, to = to, from
+	}
+
+	session, err := services.OpenStockTake(h.db, strings.TrimSpace(req.Name), from, to, staffID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open stock-take"})
+		return
+	}
+
+	h.respondWithSession(c, http.StatusCreated, session)
+}
+
+// GetStockTakes handles GET /admin/stock-takes (filter: status), newest first
+func (h *StockTakeHandler) GetStockTakes(c *gin.Context) {
+	order, err := services.StockTakeSorts.Parse(c.Query("sort"), "created_at:desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 20, 100)
+
+	query := h.db.Model(&models.StockTake{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	query.Count(&total)
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var sessions []models.StockTake
+	if err := paged.Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock-takes"})
+		return
+	}
+	fetched := len(sessions)
+	if fetched > page.limit {
+		sessions = sessions[:page.limit]
+	}
+	var nextCursor *string
+	if len(sessions) > 0 {
+		if nextCursor, err = page.next(query, fetched, sessions[len(sessions)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock-takes"})
+			return
+		}
+	}
+
+	data := make([]StockTakeResponse, 0, len(sessions))
+	for _, session := range sessions {
+		summary, err := services.SummarizeStockTake(h.db, session.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count stock-take items"})
+			return
+		}
+		data = append(data, StockTakeResponse{StockTake: session, Summary: summary})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"page":        page.page,
+		"limit":       page.limit,
+		"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+		"next_cursor": nextCursor,
+		"data":        data,
+	})
+}
+
+// GetStockTake handles GET /admin/stock-takes/:id
+func (h *StockTakeHandler) GetStockTake(c *gin.Context) {
+	session, ok := h.findSession(c)
+	if !ok {
+		return
+	}
+	h.respondWithSession(c, http.StatusOK, session)
+}
+
+// GetStockTakeItems handles GET /admin/stock-takes/:id/items
+// Filter: result (found, missing, wrong_location or unknown). Items are in shelf order.
+func (h *StockTakeHandler) GetStockTakeItems(c *gin.Context) {
+	session, ok := h.findSession(c)
+	if !ok {
+		return
+	}
+
+	order, err := services.StockTakeItemSorts.Parse(c.Query("sort"), "shelf:asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page := pageQuery(c, order, 50, 500)
+
+	query := h.db.Model(&models.StockTakeItem{}).Where("stock_take_id = ?", session.ID)
+	if result := c.Query("result"); result != "" {
+		query = query.Where("result = ?", result)
+	}
+	query = query.Joins("LEFT JOIN copies ON copies.id = stock_take_items.copy_id").Session(&gorm.Session{})
+
+	var total int64
+	query.Count(&total)
+
+	paged, err := page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items := make([]models.StockTakeItem, 0)
+	if err := paged.Preload("Copy.Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "author", "isbn")
+	}).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock-take items"})
+		return
+	}
+	fetched := len(items)
+	if fetched > page.limit {
+		items = items[:page.limit]
+	}
+	var nextCursor *string
+	if len(items) > 0 {
+		if nextCursor, err = page.next(query, fetched, items[len(items)-1].ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock-take items"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":       total,
+		"page":        page.page,
+		"limit":       page.limit,
+		"total_pages": int(math.Ceil(float64(total) / float64(page.limit))),
+		"next_cursor": nextCursor,
+		"data":        items,
+	})
+}
+
+// RecordScans handles POST /admin/stock-takes/:id/scans (a batch of scanned barcodes)
+func (h *StockTakeHandler) RecordScans(c *gin.Context) {
+	staffID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	session, ok := h.findSession(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Barcodes []string `json:"barcodes" binding:"required,min=1,max=1000"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := services.RecordScans(h.db, session, req.Barcodes, staffID.(uint))
+	if err != nil {
+		stockTakeError(c, err, "Failed to record scans")
+		return
+	}
+	summary, err := services.SummarizeStockTake(h.db, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count stock-take items"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"summary": summary,
+	})
+}
+
+// CloseStockTake handles POST /admin/stock-takes/:id/close
+func (h *StockTakeHandler) CloseStockTake(c *gin.Context) {
+	staffID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	session, ok := h.findSession(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		MarkMissingLost bool `json:"mark_missing_lost"`
+	}
+	// The body is optional; without it missing copies are left as they are
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.CloseStockTake(h.db, session, req.MarkMissingLost, staffID.(uint)); err != nil {
+		stockTakeError(c, err, "Failed to close stock-take")
+		return
+	}
+	if err := h.db.First(session, session.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load stock-take"})
+		return
+	}
+
+	h.respondWithSession(c, http.StatusOK, session)
+}
+
+// ExportStockTake handles GET /admin/stock-takes/:id/export (the report as CSV)
+func (h *StockTakeHandler) ExportStockTake(c *gin.Context) {
+	session, ok := h.findSession(c)
+	if !ok {
+		return
+	}
+
+	var items []models.StockTakeItem
+	query := h.db.Model(&models.StockTakeItem{}).Where("stock_take_id = ?", session.ID).
+		Order("FIELD(result, 'found', 'missing', 'wrong_location', 'unknown')")
+	if err := h.itemsInShelfOrder(query).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock-take items"})
+		return
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write([]string{"result", "barcode", "title", "call_number", "location", "copy_status", "scanned_at"})
+	for _, item := range items {
+		var title, callNumber, location, status, scannedAt string
+		if item.Copy != nil {
+			callNumber = utils.StringValue(item.Copy.CallNumber)
+			location = utils.StringValue(item.Copy.Location)
+			status = item.Copy.Status
+			if item.Copy.Book != nil {
+				title = item.Copy.Book.Title
+			}
+		}
+		if item.ScannedAt != nil {
+			scannedAt = item.ScannedAt.Format("2006-01-02 15:04:05")
+		}
+		writer.Write([]string{item.Result, item.Barcode, title, callNumber, location, status, scannedAt})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write report"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"stock-take-%d.csv\"", session.ID))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// findSession loads the session named by the :id parameter, writing a 400 or 404 when
+// there is none
+func (h *StockTakeHandler) findSession(c *gin.Context) (*models.StockTake, bool) {
+	id, ok := paramID(c, "id", "stock-take")
+	if !ok {
+		return nil, false
+	}
+
+	var session models.StockTake
+	if err := h.db.First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stock-take not found"})
+		return nil, false
+	}
+	return &session, true
+}
+
+// itemsInShelfOrder loads items with their copy and book, ordered as the copies stand on
+// the shelves (unknown barcodes last)
+func (h *StockTakeHandler) itemsInShelfOrder(query *gorm.DB) *gorm.DB {
+	return query.Preload("Copy.Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "author", "isbn")
+	}).Joins("LEFT JOIN copies ON copies.id = stock_take_items.copy_id").
+		Order("copies.id IS NULL, copies.location ASC, copies.call_number ASC, stock_take_items.barcode ASC")
+}
+
+// respondWithSession writes a session with its item counts
+func (h *StockTakeHandler) respondWithSession(c *gin.Context, status int, session *models.StockTake) {
+	summary, err := services.SummarizeStockTake(h.db, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count stock-take items"})
+		return
+	}
+
+	c.JSON(status, StockTakeResponse{StockTake: *session, Summary: summary})
+}
+
+// stockTakeError maps a closed session to 409 Conflict
+func stockTakeError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, services.ErrStockTakeClosed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stock-take session is closed"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
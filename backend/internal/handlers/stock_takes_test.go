@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
+	"e-repository-api/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// shelveCopy creates a copy at a shelf location
+func shelveCopy(t *testing.T, db *gorm.DB, bookID uint, barcode, location, callNumber, status string) models.Copy {
+	bookCopy := models.Copy{
+		BookID:     bookID,
+		Barcode:    barcode,
+		Location:   utils.StringPtr(location),
+		CallNumber: utils.StringPtr(callNumber),
+		Status:     status,
+	}
+	if err := db.Create(&bookCopy).Error; err != nil {
+		t.Fatalf("Failed to create copy: %v", err)
+	}
+	return bookCopy
+}
+
+// openStockTake opens a session through the handler and returns it
+func openStockTake(t *testing.T, handler *StockTakeHandler, from, to string) StockTakeResponse {
+	w := callHandler(handler.OpenStockTake, 1, "admin", nil, gin.H{"name": "Rak A", "location_from": from, "location_to": to})
+	if !assert.Equal(t, http.StatusCreated, w.Code) {
+		t.FailNow()
+	}
+	var response StockTakeResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestStockTakeLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewStockTakeHandler(db)
+
+	book := models.Book{Title: "Manajemen Proyek", Author: "Ani Wijaya"}
+	assert.NoError(t, db.Create(&book).Error)
+	shelved := shelveCopy(t, db, book.ID, "ST001", "Rak A1", "658.4 ANI", "available")
+	missing := shelveCopy(t, db, book.ID, "ST002", "Rak A2", "658.4 ANI", "available")
+	lent := shelveCopy(t, db, book.ID, "ST003", "Rak A1", "658.4 ANI", "on_loan")
+	misplaced := shelveCopy(t, db, book.ID, "ST004", "Rak C1", "658.4 ANI", "available")
+
+	// Only copies expected on the shelves of the range are counted
+	session := openStockTake(t, handler, "Rak A2", "Rak A1")
+	assert.Equal(t, "Rak A1", session.LocationFrom)
+	assert.Equal(t, "open", session.Status)
+	assert.Equal(t, 2, session.Summary.Expected)
+	assert.Equal(t, 2, session.Summary.Missing)
+
+	// Blank and repeated barcodes in a batch are skipped
+	w := callHandler(handler.RecordScans, 1, "admin", idParam(session.ID),
+		gin.H{"barcodes": []string{"ST001", " ST001 ", "", "ST003", "ST004", "UNKNOWN"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var scanned struct {
+		Results []services.ScanResult     `json:"results"`
+		Summary services.StockTakeSummary `json:"summary"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &scanned))
+	assert.Equal(t, []services.ScanResult{
+		{Barcode: "ST001", Result: "found"},
+		{Barcode: "ST003", Result: "found"},
+		{Barcode: "ST004", Result: "wrong_location"},
+		{Barcode: "UNKNOWN", Result: "unknown"},
+	}, scanned.Results)
+	assert.Equal(t, services.StockTakeSummary{Expected: 2, Found: 2, Missing: 1, WrongLocation: 1, Unknown: 1}, scanned.Summary)
+
+	// Barcodes scanned in an earlier batch are reported as duplicates
+	w = callHandler(handler.RecordScans, 1, "admin", idParam(session.ID), gin.H{"barcodes": []string{"ST001"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &scanned))
+	assert.Equal(t, []services.ScanResult{{Barcode: "ST001", Result: "found", Duplicate: true}}, scanned.Results)
+
+	// Closing marks the copies still missing as lost
+	w = callHandler(handler.CloseStockTake, 1, "admin", idParam(session.ID), gin.H{"mark_missing_lost": true})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var closed StockTakeResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &closed))
+	assert.Equal(t, "closed", closed.Status)
+	assert.Equal(t, 1, closed.MarkedLost)
+
+	for copyID, status := range map[uint]string{shelved.ID: "available", missing.ID: "lost", lent.ID: "on_loan", misplaced.ID: "available"} {
+		var bookCopy models.Copy
+		db.First(&bookCopy, copyID)
+		assert.Equal(t, status, bookCopy.Status, bookCopy.Barcode)
+	}
+
+	// A closed session takes no more scans and can't be closed again
+	w = callHandler(handler.RecordScans, 1, "admin", idParam(session.ID), gin.H{"barcodes": []string{"ST002"}})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = callHandler(handler.CloseStockTake, 1, "admin", idParam(session.ID), nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = callHandler(handler.GetStockTake, 1, "admin", gin.Params{{Key: "id", Value: "1 OR 1=1"}}, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestStockTakeItemsInShelfOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := setupTestDB(t)
+	handler := NewStockTakeHandler(db)
+
+	book := models.Book{Title: "Akuntansi Dasar", Author: "Budi Santoso"}
+	assert.NoError(t, db.Create(&book).Error)
+	shelveCopy(t, db, book.ID, "SO004", "Rak B2", "657 BUD", "available")
+	shelveCopy(t, db, book.ID, "SO002", "Rak B1", "657.2 BUD", "available")
+	shelveCopy(t, db, book.ID, "SO001", "Rak B1", "657 BUD", "available")
+	shelveCopy(t, db, book.ID, "SO003", "Rak B1", "657.4 BUD", "available")
+
+	session := openStockTake(t, handler, "Rak B1", "Rak B2")
+	w := callHandler(handler.RecordScans, 1, "admin", idParam(session.ID), gin.H{"barcodes": []string{"AAA-UNKNOWN", "SO003"}})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Items list by location and call number, with unknown barcodes last
+	w = callHandler(handler.GetStockTakeItems, 1, "admin", idParam(session.ID), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Total int64                  `json:"total"`
+		Data  []models.StockTakeItem `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(5), response.Total)
+	barcodes := make([]string, 0, len(response.Data))
+	for _, item := range response.Data {
+		barcodes = append(barcodes, item.Barcode)
+	}
+	assert.Equal(t, []string{"SO001", "SO002", "SO003", "SO004", "AAA-UNKNOWN"}, barcodes)
+	if assert.NotNil(t, response.Data[0].Copy) && assert.NotNil(t, response.Data[0].Copy.Book) {
+		assert.Equal(t, "Akuntansi Dasar", response.Data[0].Copy.Book.Title)
+	}
+}
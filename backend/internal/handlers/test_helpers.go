@@ -12,6 +12,7 @@ import (
 	"e-repository-api/configs"
 	"e-repository-api/internal/database"
 	"e-repository-api/internal/models"
+	"e-repository-api/internal/services"
 	"e-repository-api/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -145,17 +146,44 @@ func getTestConfig() *configs.Config {
 func cleanupTestData(db *gorm.DB) {
 	// Clean up test data in reverse order of foreign key dependencies
 	db.Exec("DELETE FROM downloads")
+	db.Exec("DELETE FROM citations")
 	db.Exec("DELETE FROM file_uploads")
 	db.Exec("DELETE FROM activity_logs")
 	db.Exec("DELETE FROM paper_authors")
 	db.Exec("DELETE FROM book_authors")
 	db.Exec("DELETE FROM paper_categories")
 	db.Exec("DELETE FROM book_categories")
+	db.Exec("DELETE FROM paper_keywords")
+	db.Exec("DELETE FROM book_keywords")
+	db.Exec("DELETE FROM reading_list_entries")
+	db.Exec("DELETE FROM reading_lists")
+	db.Exec("DELETE FROM review_reports")
+	db.Exec("DELETE FROM reviews")
+	db.Exec("DELETE FROM related_items")
+	db.Exec("DELETE FROM item_pages")
+	db.Exec("DELETE FROM item_texts")
+	db.Exec("DELETE FROM pending_index_updates")
+	db.Exec("DELETE FROM notifications")
+	db.Exec("DELETE FROM saved_searches")
+	db.Exec("DELETE FROM deleted_items")
+	db.Exec("DELETE FROM imported_records")
+	db.Exec("DELETE FROM stock_take_items")
+	db.Exec("DELETE FROM stock_takes")
+	db.Exec("DELETE FROM holds")
+	db.Exec("DELETE FROM loans")
+	db.Exec("DELETE FROM holidays")
+	db.Exec("DELETE FROM loan_rules")
+	db.Exec("DELETE FROM copies")
+	db.Exec("DELETE FROM authorship_claims")
 	db.Exec("DELETE FROM user_papers")
 	db.Exec("DELETE FROM user_books")
 	db.Exec("DELETE FROM papers")
 	db.Exec("DELETE FROM books")
 	db.Exec("DELETE FROM categories")
+	db.Exec("DELETE FROM keywords")
+	db.Exec("DELETE FROM keyword_groups")
+	db.Exec("DELETE FROM author_variants")
+	db.Exec("DELETE FROM authors")
 	db.Exec("DELETE FROM users")
 	db.Exec("DELETE FROM counters")
 }
@@ -344,6 +372,11 @@ func SeedTestData(db *gorm.DB) error {
 		}
 	}
 
+	// Nested author entries are created without an authority record
+	if err := services.BackfillAuthorRecords(db); err != nil {
+		return fmt.Errorf("failed to link test authors: %w", err)
+	}
+
 	return nil
 }
 
@@ -483,3 +516,57 @@ func createTestAdmin(t *testing.T, h *AuthHandler) (models.User, string) {
 
 	return admin, token
 }
+
+// callHandler calls a handler directly as a signed-in user (userID 0 for a guest), with
+// the given path parameters and JSON body
+func callHandler(handler gin.HandlerFunc, userID uint, role string, params gin.Params, body interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	var reader *bytes.Buffer
+	if body != nil {
+		jsonData, _ := json.Marshal(body)
+		reader = bytes.NewBuffer(jsonData)
+	} else {
+		reader = bytes.NewBuffer(nil)
+	}
+	c.Request = httptest.NewRequest(http.MethodPost, "/", reader)
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = params
+	if userID != 0 {
+		c.Set("user_id", userID)
+		c.Set("user_role", role)
+	}
+	handler(c)
+	return w
+}
+
+// getHandler calls a handler directly with a GET request carrying the query string
+func getHandler(handler gin.HandlerFunc, userID uint, role string, params gin.Params, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+	c.Params = params
+	if userID != 0 {
+		c.Set("user_id", userID)
+		c.Set("user_role", role)
+	}
+	handler(c)
+	return w
+}
+
+// createMember creates an approved user account for a test
+func createMember(t *testing.T, db *gorm.DB, email, name, userType string) models.User {
+	user := models.User{
+		Email:         email,
+		PasswordHash:  "hashed_password",
+		Name:          name,
+		Role:          "user",
+		UserType:      userType,
+		EmailVerified: true,
+		IsApproved:    true,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,205 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+	leaderLength      = 24
+	directoryEntry    = 12
+)
+
+// Reader reads ISO 2709 records one at a time
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a reader of the ISO 2709 records in r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, or io.EOF after the last one
+func (r *Reader) Read() (*Record, error) {
+	// Some exports put line breaks between records
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != '\n' && b != '\r' && b != ' ' {
+			r.r.UnreadByte()
+			break
+		}
+	}
+
+	prefix, err := r.r.Peek(5)
+	if err != nil {
+		return nil, fmt.Errorf("%w: truncated leader", ErrInvalidRecord)
+	}
+	length, ok := digits(prefix)
+	if !ok || length < leaderLength+1 {
+		return nil, fmt.Errorf("%w: bad record length %q", ErrInvalidRecord, prefix)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("%w: record shorter than its length %d", ErrInvalidRecord, length)
+	}
+	return decodeISO2709(data)
+}
+
+// ReadAll reads every ISO 2709 record in r
+func ReadAll(r io.Reader) ([]*Record, error) {
+	reader := NewReader(r)
+	var records []*Record
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
+
+// decodeISO2709 decodes one record: the leader, the directory of tag, length and
+// offset entries, then the fields they point into
+func decodeISO2709(data []byte) (*Record, error) {
+	if data[len(data)-1] != recordTerminator {
+		return nil, fmt.Errorf("%w: missing record terminator", ErrInvalidRecord)
+	}
+	base, ok := digits(data[12:17])
+	if !ok || base <= leaderLength || base > len(data) {
+		return nil, fmt.Errorf("%w: bad base address %q", ErrInvalidRecord, data[12:17])
+	}
+
+	record := &Record{Leader: string(data[:leaderLength])}
+	directory := data[leaderLength : base-1]
+	if len(directory)%directoryEntry != 0 {
+		return nil, fmt.Errorf("%w: bad directory length %d", ErrInvalidRecord, len(directory))
+	}
+	for i := 0; i < len(directory); i += directoryEntry {
+		entry := directory[i : i+directoryEntry]
+		tag := string(entry[:3])
+		length, ok1 := digits(entry[3:7])
+		start, ok2 := digits(entry[7:12])
+		if !ok1 || !ok2 || length == 0 || base+start+length > len(data) {
+			return nil, fmt.Errorf("%w: bad directory entry %q", ErrInvalidRecord, entry)
+		}
+		field := bytes.TrimSuffix(data[base+start:base+start+length], []byte{fieldTerminator})
+
+		if isControlTag(tag) {
+			record.AddControlField(tag, string(field))
+			continue
+		}
+		if len(field) < 2 {
+			return nil, fmt.Errorf("%w: field %s has no indicators", ErrInvalidRecord, tag)
+		}
+		dataField := DataField{Tag: tag, Ind1: field[0], Ind2: field[1]}
+		for _, part := range bytes.Split(field[2:], []byte{subfieldDelimiter}) {
+			if len(part) == 0 {
+				continue
+			}
+			dataField.Subfields = append(dataField.Subfields, Subfield{Code: part[0], Value: string(part[1:])})
+		}
+		record.DataFields = append(record.DataFields, dataField)
+	}
+	return record, nil
+}
+
+// digits parses a fixed-width number field, which unlike strconv.Atoi must not
+// carry a sign or spaces
+func digits(b []byte) (int, bool) {
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, len(b) > 0
+}
+
+// Write writes records in ISO 2709, encoded as UTF-8
+func Write(w io.Writer, records []*Record) error {
+	for _, record := range records {
+		data, err := encodeISO2709(record)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeISO2709 encodes a record, computing the lengths and offsets of the leader and
+// directory
+func encodeISO2709(record *Record) ([]byte, error) {
+	var directory, fields bytes.Buffer
+	addField := func(tag string, body []byte) error {
+		body = append(body, fieldTerminator)
+		if len(tag) != 3 || len(body) > 9999 || fields.Len() > 99999 {
+			return fmt.Errorf("%w: field %s cannot be encoded", ErrInvalidRecord, tag)
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", tag, len(body), fields.Len())
+		fields.Write(body)
+		return nil
+	}
+
+	for _, field := range record.ControlFields {
+		if err := addField(field.Tag, []byte(field.Value)); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range record.DataFields {
+		body := []byte{indicator(field.Ind1), indicator(field.Ind2)}
+		for _, subfield := range field.Subfields {
+			body = append(body, subfieldDelimiter, subfield.Code)
+			body = append(body, subfield.Value...)
+		}
+		if err := addField(field.Tag, body); err != nil {
+			return nil, err
+		}
+	}
+	directory.WriteByte(fieldTerminator)
+
+	base := leaderLength + directory.Len()
+	length := base + fields.Len() + 1
+	if length > 99999 {
+		return nil, fmt.Errorf("%w: record longer than 99999 bytes", ErrInvalidRecord)
+	}
+
+	leader := []byte(record.Leader)
+	if len(leader) != leaderLength {
+		leader = []byte(DefaultLeader)
+	}
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	leader[9] = 'a' // UCS/Unicode character coding
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", base))
+	copy(leader[20:24], "4500")
+
+	data := make([]byte, 0, length)
+	data = append(data, leader...)
+	data = append(data, directory.Bytes()...)
+	data = append(data, fields.Bytes()...)
+	return append(data, recordTerminator), nil
+}
+
+// indicator returns a blank for an unset indicator
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
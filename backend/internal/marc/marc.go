@@ -0,0 +1,122 @@
+// Package marc reads and writes MARC 21 bibliographic records, both in the ISO 2709
+// exchange format and as MARCXML.
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Record is a MARC record: a leader, control fields (001-009) and data fields
+type Record struct {
+	Leader        string
+	ControlFields []ControlField
+	DataFields    []DataField
+}
+
+// ControlField is a field without indicators or subfields, such as 001 or 008
+type ControlField struct {
+	Tag   string
+	Value string
+}
+
+// DataField is a field with two indicators and subfields
+type DataField struct {
+	Tag       string
+	Ind1      byte
+	Ind2      byte
+	Subfields []Subfield
+}
+
+// Subfield is a coded part of a data field, such as $a
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// DefaultLeader is the leader of a new record: a monographic language material ("am")
+// catalogued to AACR2, coded in Unicode. Writers fill in its lengths and addresses.
+const DefaultLeader = "00000nam a22000007a 4500"
+
+// ErrInvalidRecord is returned for data that is not a well-formed MARC record
+var ErrInvalidRecord = errors.New("invalid MARC record")
+
+// ControlField returns the value of the first control field with the tag
+func (r *Record) ControlField(tag string) string {
+	for _, field := range r.ControlFields {
+		if field.Tag == tag {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Fields returns the data fields with the tag in record order
+func (r *Record) Fields(tag string) []DataField {
+	var fields []DataField
+	for _, field := range r.DataFields {
+		if field.Tag == tag {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// AddControlField appends a control field
+func (r *Record) AddControlField(tag, value string) {
+	r.ControlFields = append(r.ControlFields, ControlField{Tag: tag, Value: value})
+}
+
+// AddDataField appends a data field, skipping subfields without a value. A field
+// left without subfields is not added.
+func (r *Record) AddDataField(tag string, ind1, ind2 byte, subfields ...Subfield) {
+	field := DataField{Tag: tag, Ind1: ind1, Ind2: ind2}
+	for _, subfield := range subfields {
+		if strings.TrimSpace(subfield.Value) != "" {
+			field.Subfields = append(field.Subfields, subfield)
+		}
+	}
+	if len(field.Subfields) > 0 {
+		r.DataFields = append(r.DataFields, field)
+	}
+}
+
+// Subfield returns the value of the first subfield with the code
+func (f DataField) Subfield(code byte) string {
+	for _, subfield := range f.Subfields {
+		if subfield.Code == code {
+			return subfield.Value
+		}
+	}
+	return ""
+}
+
+// SubfieldValues returns the values of the subfields with any of the codes in order
+func (f DataField) SubfieldValues(codes ...byte) []string {
+	var values []string
+	for _, subfield := range f.Subfields {
+		if bytes.IndexByte(codes, subfield.Code) >= 0 {
+			values = append(values, subfield.Value)
+		}
+	}
+	return values
+}
+
+// isControlTag reports whether a tag is a control field (001-009)
+func isControlTag(tag string) bool {
+	return strings.HasPrefix(tag, "00")
+}
+
+// Parse reads the records of a MARC file, detecting MARCXML by its leading "<"
+func Parse(data []byte) ([]*Record, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidRecord)
+	}
+	if trimmed[0] == '<' {
+		return ReadXML(bytes.NewReader(trimmed))
+	}
+	return ReadAll(bytes.NewReader(trimmed))
+}
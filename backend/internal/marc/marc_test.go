@@ -0,0 +1,78 @@
+package marc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fixtureRecord() *Record {
+	record := &Record{}
+	record.AddControlField("001", "12")
+	record.AddDataField("020", ' ', ' ', Subfield{'a', "9786020000000"})
+	record.AddDataField("100", '1', ' ', Subfield{'a', "Santoso, Budi"})
+	record.AddDataField("245", '1', '0', Subfield{'a', "Basis data"}, Subfield{'b', "teori dan praktik"})
+	record.AddDataField("650", ' ', '4', Subfield{'a', "Sistem basis data"}, Subfield{'x', ""})
+	return record
+}
+
+func TestISO2709RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, []*Record{fixtureRecord(), fixtureRecord()}))
+	assert.Equal(t, "a", buf.String()[9:10])
+
+	records, err := Parse(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	record := records[1]
+	assert.Equal(t, "12", record.ControlField("001"))
+	assert.Equal(t, "Santoso, Budi", record.Fields("100")[0].Subfield('a'))
+	title := record.Fields("245")[0]
+	assert.Equal(t, byte('1'), title.Ind1)
+	assert.Equal(t, []string{"Basis data", "teori dan praktik"}, title.SubfieldValues('a', 'b'))
+	// Empty subfields are not written
+	assert.Len(t, record.Fields("650")[0].Subfields, 1)
+}
+
+func TestISO2709Invalid(t *testing.T) {
+	_, err := Parse([]byte("00042nam a2200025   4500"))
+	assert.ErrorIs(t, err, ErrInvalidRecord)
+	_, err = Parse([]byte("   "))
+	assert.ErrorIs(t, err, ErrInvalidRecord)
+
+	// A signed directory offset would point before the field data
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf, []*Record{fixtureRecord()}))
+	data := buf.Bytes()
+	copy(data[leaderLength+7:leaderLength+directoryEntry], "-0001")
+	_, err = Parse(data)
+	assert.ErrorIs(t, err, ErrInvalidRecord)
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteXML(&buf, []*Record{fixtureRecord()}))
+	assert.Contains(t, buf.String(), `<collection xmlns="http://www.loc.gov/MARC21/slim">`)
+	assert.Contains(t, buf.String(), `<datafield tag="100" ind1="1" ind2=" ">`)
+
+	records, err := Parse(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "teori dan praktik", records[0].Fields("245")[0].Subfield('b'))
+}
+
+func TestReadXMLSingleRecord(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<marc:record xmlns:marc="http://www.loc.gov/MARC21/slim">
+  <marc:leader>00000nam a2200000 i 4500</marc:leader>
+  <marc:controlfield tag="001">7</marc:controlfield>
+  <marc:datafield tag="245" ind1="0" ind2="0"><marc:subfield code="a">Judul</marc:subfield></marc:datafield>
+</marc:record>`
+	records, err := ReadXML(strings.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, "7", records[0].ControlField("001"))
+	assert.Equal(t, "Judul", records[0].Fields("245")[0].Subfield('a'))
+}
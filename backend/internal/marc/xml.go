@@ -0,0 +1,126 @@
+package marc
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Namespace is the MARCXML (MARC 21 slim) namespace
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlCollection struct {
+	XMLName xml.Name    `xml:"collection"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Records []xmlRecord `xml:"record"`
+}
+
+type xmlRecord struct {
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ReadXML reads the MARCXML records in r. The records may be wrapped in a collection
+// or stand alone, with or without the MARC 21 slim namespace.
+func ReadXML(r io.Reader) ([]*Record, error) {
+	decoder := xml.NewDecoder(r)
+	var records []*Record
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return records, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+
+		var element xmlRecord
+		if err := decoder.DecodeElement(&element, &start); err != nil {
+			return records, fmt.Errorf("%w: record %d: %v", ErrInvalidRecord, len(records)+1, err)
+		}
+		record := &Record{Leader: element.Leader}
+		for _, field := range element.ControlFields {
+			record.AddControlField(field.Tag, field.Value)
+		}
+		for _, field := range element.DataFields {
+			dataField := DataField{Tag: field.Tag, Ind1: xmlIndicator(field.Ind1), Ind2: xmlIndicator(field.Ind2)}
+			for _, subfield := range field.Subfields {
+				if subfield.Code == "" {
+					continue
+				}
+				dataField.Subfields = append(dataField.Subfields, Subfield{Code: subfield.Code[0], Value: subfield.Value})
+			}
+			record.DataFields = append(record.DataFields, dataField)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: no record elements found", ErrInvalidRecord)
+	}
+	return records, nil
+}
+
+// WriteXML writes records as a MARCXML collection
+func WriteXML(w io.Writer, records []*Record) error {
+	collection := xmlCollection{Xmlns: Namespace}
+	for _, record := range records {
+		element := xmlRecord{Leader: record.Leader}
+		for _, field := range record.ControlFields {
+			element.ControlFields = append(element.ControlFields, xmlControlField{Tag: field.Tag, Value: field.Value})
+		}
+		for _, field := range record.DataFields {
+			dataField := xmlDataField{
+				Tag:  field.Tag,
+				Ind1: string(indicator(field.Ind1)),
+				Ind2: string(indicator(field.Ind2)),
+			}
+			for _, subfield := range field.Subfields {
+				dataField.Subfields = append(dataField.Subfields, xmlSubfield{Code: string(subfield.Code), Value: subfield.Value})
+			}
+			element.DataFields = append(element.DataFields, dataField)
+		}
+		collection.Records = append(collection.Records, element)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(collection); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// xmlIndicator returns the indicator byte of an attribute, blank when it is missing
+func xmlIndicator(value string) byte {
+	if value == "" {
+		return ' '
+	}
+	return value[0]
+}
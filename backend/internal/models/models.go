@@ -63,6 +63,8 @@ type Book struct {
 	FileURL       *string   `json:"file_url" gorm:"size:500"`
 	CoverImageURL *string   `json:"cover_image_url" gorm:"size:500"`
 	CreatedBy     *uint     `json:"created_by" gorm:"index"`
+	RatingAverage float64   `json:"rating_average" gorm:"type:decimal(3,2);default:0;index"`
+	RatingCount   int       `json:"rating_count" gorm:"default:0"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 
@@ -71,6 +73,27 @@ type Book struct {
 	Users      []User       `json:"users,omitempty" gorm:"many2many:user_books;"`
 	Authors    []BookAuthor `json:"authors,omitempty" gorm:"foreignKey:BookID"`
 	Categories []Category   `json:"categories,omitempty" gorm:"many2many:book_categories;"`
+	Subjects   []Keyword    `json:"subjects,omitempty" gorm:"many2many:book_keywords;"`
+	Copies     []Copy       `json:"copies,omitempty" gorm:"foreignKey:BookID"`
+}
+
+// Copy represents the copies table (a physical eksemplar of a book held by the library)
+type Copy struct {
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	BookID          uint       `json:"book_id" gorm:"not null;index:idx_copies_book_id"`
+	Barcode         string     `json:"barcode" gorm:"size:50;not null;uniqueIndex:idx_copies_barcode"`
+	CallNumber      *string    `json:"call_number" gorm:"size:100"`
+	Location        *string    `json:"location" gorm:"size:255;index:idx_copies_location"`
+	AcquisitionDate *time.Time `json:"acquisition_date" gorm:"type:date"`
+	Source          *string    `json:"source" gorm:"size:255"`
+	Price           *float64   `json:"price" gorm:"type:decimal(12,2)"`
+	Status          string     `json:"status" gorm:"type:enum('available','on_loan','lost','damaged');default:'available';index:idx_copies_status"`
+	Notes           *string    `json:"notes" gorm:"type:text"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relationships
+	Book *Book `json:"book,omitempty" gorm:"foreignKey:BookID"`
 }
 
 // Paper represents the papers table
@@ -94,9 +117,12 @@ type Paper struct {
 	FileURL       *string       `json:"file_url" gorm:"size:500"`
 	CoverImageURL *string       `json:"cover_image_url" gorm:"size:500"`
 	CreatedBy     *uint         `json:"created_by"`
+	RatingAverage float64       `json:"rating_average" gorm:"type:decimal(3,2);default:0;index"`
+	RatingCount   int           `json:"rating_count" gorm:"default:0"`
 	CreatedAt     time.Time     `json:"created_at"`
 	UpdatedAt     time.Time     `json:"updated_at"`
 	Authors       []PaperAuthor `json:"authors,omitempty"`
+	KeywordTerms  []Keyword     `json:"keyword_terms,omitempty" gorm:"many2many:paper_keywords;"`
 }
 
 // Category represents the categories table
@@ -113,18 +139,76 @@ type Category struct {
 	Papers []Paper `json:"papers,omitempty" gorm:"many2many:paper_categories;"`
 }
 
+// KeywordGroup represents the keyword_groups table
+// A group joins synonyms and translations (e.g. "machine learning" and
+// "pembelajaran mesin") so that filtering by one term matches all of them.
+type KeywordGroup struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	PreferredTerm string    `json:"preferred_term" gorm:"size:255;not null"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Relationships
+	Keywords []Keyword `json:"keywords,omitempty" gorm:"foreignKey:GroupID"`
+}
+
+// Keyword represents the keywords table (controlled keyword/subject vocabulary)
+type Keyword struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Term       string    `json:"term" gorm:"size:255;not null"`
+	Normalized string    `json:"normalized" gorm:"size:255;not null;uniqueIndex:idx_keywords_normalized"`
+	Language   *string   `json:"language" gorm:"size:10"`
+	GroupID    *uint     `json:"group_id" gorm:"index:idx_keywords_group_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relationships
+	Group  *KeywordGroup `json:"group,omitempty" gorm:"foreignKey:GroupID"`
+	Books  []Book        `json:"books,omitempty" gorm:"many2many:book_keywords;"`
+	Papers []Paper       `json:"papers,omitempty" gorm:"many2many:paper_keywords;"`
+}
+
+// Author represents the authors table
+// An authority record identifies one person across the name variants they are
+// published under ("Budi Santoso", "B. Santoso", "Santoso, Budi").
+type Author struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	PreferredName string    `json:"preferred_name" gorm:"type:varchar(255);not null;index:idx_authors_preferred_name"`
+	NIDN          *string   `json:"nidn" gorm:"column:nidn;type:varchar(20);uniqueIndex:idx_authors_nidn"`
+	ORCID         *string   `json:"orcid" gorm:"column:orcid;type:varchar(19);uniqueIndex:idx_authors_orcid"`
+	SintaID       *string   `json:"sinta_id" gorm:"type:varchar(20);uniqueIndex:idx_authors_sinta_id"`
+	UserID        *uint     `json:"user_id" gorm:"index:idx_authors_user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Relationships
+	Variants []AuthorVariant `json:"variants,omitempty" gorm:"foreignKey:AuthorID"`
+	User     *User           `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// AuthorVariant represents the author_variants table (a name form of an authority record)
+type AuthorVariant struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	AuthorID   uint      `json:"author_id" gorm:"not null;index:idx_author_variants_author_id"`
+	Name       string    `json:"name" gorm:"type:varchar(255);not null"`
+	Normalized string    `json:"normalized" gorm:"type:varchar(255);not null;uniqueIndex:idx_author_variants_normalized"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // BookAuthor represents the book_authors table
 type BookAuthor struct {
 	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	BookID     uint      `json:"book_id" gorm:"not null;index:idx_book_authors_book_id"`
 	UserID     *uint     `json:"user_id" gorm:"index:idx_book_authors_user_id"`
+	AuthorID   *uint     `json:"author_id" gorm:"index:idx_book_authors_author_id"`
 	AuthorName string    `json:"author_name" gorm:"type:varchar(255);not null;index:idx_book_authors_author_name"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 
 	// Relationships
-	Book *Book `json:"book,omitempty" gorm:"foreignKey:BookID"`
-	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Book   *Book   `json:"book,omitempty" gorm:"foreignKey:BookID"`
+	User   *User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Author *Author `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
 }
 
 // PaperAuthor represents the paper_authors table
@@ -132,13 +216,337 @@ type PaperAuthor struct {
 	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	PaperID    uint      `json:"paper_id" gorm:"not null;index:idx_paper_authors_paper_id"`
 	UserID     *uint     `json:"user_id" gorm:"index:idx_paper_authors_user_id"`
+	AuthorID   *uint     `json:"author_id" gorm:"index:idx_paper_authors_author_id"`
 	AuthorName string    `json:"author_name" gorm:"type:varchar(255);not null;index:idx_paper_authors_author_name"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 
 	// Relationships
-	Paper *Paper `json:"paper,omitempty" gorm:"foreignKey:PaperID"`
-	User  *User  `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Paper  *Paper  `json:"paper,omitempty" gorm:"foreignKey:PaperID"`
+	User   *User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Author *Author `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+}
+
+// AuthorshipClaim represents the authorship_claims table
+// A user asks to be linked to a book_authors or paper_authors entry, and an admin
+// or the uploader of the item approves or rejects the request.
+type AuthorshipClaim struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        uint       `json:"user_id" gorm:"not null;index:idx_authorship_claims_user_id"`
+	ItemType      string     `json:"item_type" gorm:"type:enum('book','paper');not null;index:idx_authorship_claims_item"`
+	ItemID        uint       `json:"item_id" gorm:"not null;index:idx_authorship_claims_item"`
+	AuthorEntryID uint       `json:"author_entry_id" gorm:"not null;index:idx_authorship_claims_entry"`
+	AuthorName    string     `json:"author_name" gorm:"type:varchar(255);not null"`
+	Status        string     `json:"status" gorm:"type:enum('pending','approved','rejected');default:'pending';index:idx_authorship_claims_status"`
+	Note          *string    `json:"note" gorm:"type:text"`
+	ReviewedBy    *uint      `json:"reviewed_by"`
+	ReviewedAt    *time.Time `json:"reviewed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// Relationships
+	User     *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Reviewer *User `json:"reviewer,omitempty" gorm:"foreignKey:ReviewedBy"`
+}
+
+// UserBook represents the user_books table (books a user bookmarked)
+type UserBook struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	BookID    uint      `json:"book_id" gorm:"primaryKey;index:idx_user_books_book_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserPaper represents the user_papers table (papers a user bookmarked)
+type UserPaper struct {
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	PaperID   uint      `json:"paper_id" gorm:"primaryKey;index:idx_user_papers_paper_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReadingList represents the reading_lists table
+// A list is private to its owner unless its visibility is "link", in which case
+// anyone with the share token can view and export it.
+type ReadingList struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint      `json:"user_id" gorm:"not null;index:idx_reading_lists_user_id"`
+	Name        string    `json:"name" gorm:"size:255;not null"`
+	Description *string   `json:"description" gorm:"type:text"`
+	Visibility  string    `json:"visibility" gorm:"type:enum('private','link');default:'private'"`
+	ShareToken  string    `json:"share_token" gorm:"size:64;not null;uniqueIndex:idx_reading_lists_share_token"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	User    *User              `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Entries []ReadingListEntry `json:"entries,omitempty" gorm:"foreignKey:ListID"`
+}
+
+// ReadingListEntry represents the reading_list_entries table (a book or paper in a list)
+type ReadingListEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ListID    uint      `json:"list_id" gorm:"not null;uniqueIndex:idx_reading_list_entries_item"`
+	ItemType  string    `json:"item_type" gorm:"type:enum('book','paper');not null;uniqueIndex:idx_reading_list_entries_item"`
+	ItemID    uint      `json:"item_id" gorm:"not null;uniqueIndex:idx_reading_list_entries_item"`
+	Note      *string   `json:"note" gorm:"type:text"`
+	Position  int       `json:"position" gorm:"default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RelatedItem represents the related_items table
+// Recommendations are recomputed periodically by a background job; each row links
+// an item to one related book or paper with its score and the signals behind it.
+type RelatedItem struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ItemType    string    `json:"item_type" gorm:"type:enum('book','paper');not null;index:idx_related_items_item"`
+	ItemID      uint      `json:"item_id" gorm:"not null;index:idx_related_items_item"`
+	RelatedType string    `json:"related_type" gorm:"type:enum('book','paper');not null"`
+	RelatedID   uint      `json:"related_id" gorm:"not null"`
+	Score       float64   `json:"score"`
+	Reasons     string    `json:"reasons" gorm:"size:100"`
+	ComputedAt  time.Time `json:"computed_at"`
+}
+
+// LoanRule represents the loan_rules table (circulation limits per user type)
+type LoanRule struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserType    string    `json:"user_type" gorm:"type:enum('student','lecturer');not null;uniqueIndex:idx_loan_rules_user_type"`
+	MaxLoans    int       `json:"max_loans" gorm:"not null"`
+	LoanDays    int       `json:"loan_days" gorm:"not null"`
+	MaxRenewals int       `json:"max_renewals" gorm:"not null"`
+	FinePerDay  float64   `json:"fine_per_day" gorm:"type:decimal(12,2);not null"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Holiday represents the holidays table (days the library is closed)
+// Due dates never fall on a holiday and no fines accrue on one.
+type Holiday struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Date      time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_holidays_date"`
+	Name      string    `json:"name" gorm:"size:255;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Loan represents the loans table (a copy checked out to a member)
+// A loan is open until ReturnedAt is set; its fine is fixed at check-in.
+type Loan struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CopyID         uint       `json:"copy_id" gorm:"not null;index:idx_loans_copy_id"`
+	UserID         uint       `json:"user_id" gorm:"not null;index:idx_loans_user_id"`
+	LoanedAt       time.Time  `json:"loaned_at" gorm:"not null"`
+	DueDate        time.Time  `json:"due_date" gorm:"type:date;not null;index:idx_loans_due_date"`
+	ReturnedAt     *time.Time `json:"returned_at" gorm:"index:idx_loans_returned_at"`
+	RenewalCount   int        `json:"renewal_count" gorm:"default:0"`
+	FineAmount     float64    `json:"fine_amount" gorm:"type:decimal(12,2);default:0"`
+	FineStatus     string     `json:"fine_status" gorm:"type:enum('none','unpaid','paid','waived');default:'none';index:idx_loans_fine_status"`
+	FineSettledAt  *time.Time `json:"fine_settled_at"`
+	CheckedOutBy   *uint      `json:"checked_out_by"`
+	CheckedInBy    *uint      `json:"checked_in_by"`
+	LastRemindedAt *time.Time `json:"last_reminded_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Copy *Copy `json:"copy,omitempty" gorm:"foreignKey:CopyID"`
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// Hold represents the holds table (a member queueing for a book whose copies are all out)
+// Waiting holds are served first come, first served. A returned copy is trapped for
+// the first waiting hold, which becomes ready until its pickup deadline.
+type Hold struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	BookID         uint       `json:"book_id" gorm:"not null;index:idx_holds_book_status"`
+	UserID         uint       `json:"user_id" gorm:"not null;index:idx_holds_user_id"`
+	Status         string     `json:"status" gorm:"type:enum('waiting','ready','fulfilled','cancelled','expired');default:'waiting';index:idx_holds_book_status"`
+	CopyID         *uint      `json:"copy_id" gorm:"index:idx_holds_copy_id"`
+	ReadyAt        *time.Time `json:"ready_at"`
+	PickupDeadline *time.Time `json:"pickup_deadline" gorm:"type:date"`
+	NotifiedAt     *time.Time `json:"notified_at"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	Book *Book `json:"book,omitempty" gorm:"foreignKey:BookID"`
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Copy *Copy `json:"copy,omitempty" gorm:"foreignKey:CopyID"`
+}
+
+// StockTake represents the stock_takes table (an inventory count of a shelf range)
+// Locations from LocationFrom to LocationTo inclusive, in collation order, are counted.
+type StockTake struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name         string     `json:"name" gorm:"size:255;not null"`
+	LocationFrom string     `json:"location_from" gorm:"size:255;not null"`
+	LocationTo   string     `json:"location_to" gorm:"size:255;not null"`
+	Status       string     `json:"status" gorm:"type:enum('open','closed');default:'open';index:idx_stock_takes_status"`
+	MarkedLost   int        `json:"marked_lost" gorm:"default:0"`
+	OpenedBy     uint       `json:"opened_by" gorm:"not null"`
+	ClosedBy     *uint      `json:"closed_by"`
+	ClosedAt     *time.Time `json:"closed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// StockTakeItem represents the stock_take_items table
+// Copies expected on the shelves are recorded as missing when the session opens and
+// become found when scanned. Scans of other copies are found (in range but not
+// expected, e.g. an unrecorded return), wrong_location or, with no copy, unknown.
+type StockTakeItem struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	StockTakeID uint       `json:"stock_take_id" gorm:"not null;uniqueIndex:idx_stock_take_items_barcode;index:idx_stock_take_items_result"`
+	Barcode     string     `json:"barcode" gorm:"size:50;not null;uniqueIndex:idx_stock_take_items_barcode"`
+	CopyID      *uint      `json:"copy_id" gorm:"index:idx_stock_take_items_copy_id"`
+	Expected    bool       `json:"expected" gorm:"default:false"`
+	Result      string     `json:"result" gorm:"type:enum('found','missing','wrong_location','unknown');not null;index:idx_stock_take_items_result"`
+	ScannedBy   *uint      `json:"scanned_by"`
+	ScannedAt   *time.Time `json:"scanned_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// Relationships
+	Copy *Copy `json:"copy,omitempty" gorm:"foreignKey:CopyID"`
+}
+
+// ItemText represents the item_texts table (full-text extraction state of an item's file)
+// An item is extracted once per uploaded file; FileURL records which file was read.
+type ItemText struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ItemType    string    `json:"item_type" gorm:"type:enum('book','paper');not null;uniqueIndex:idx_item_texts_item"`
+	ItemID      uint      `json:"item_id" gorm:"not null;uniqueIndex:idx_item_texts_item"`
+	FileURL     string    `json:"file_url" gorm:"size:500;not null"`
+	Status      string    `json:"status" gorm:"type:enum('extracted','empty','failed');not null"`
+	PageCount   int       `json:"page_count" gorm:"default:0"`
+	Error       *string   `json:"error" gorm:"type:text"`
+	ExtractedAt time.Time `json:"extracted_at"`
+}
+
+// ItemPage represents the item_pages table (the text of one page of an item's file)
+type ItemPage struct {
+	ID         uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	ItemType   string `json:"item_type" gorm:"type:enum('book','paper');not null;index:idx_item_pages_item"`
+	ItemID     uint   `json:"item_id" gorm:"not null;index:idx_item_pages_item"`
+	PageNumber int    `json:"page_number" gorm:"not null"`
+	Content    string `json:"content" gorm:"type:longtext;not null;index:idx_item_pages_content,class:FULLTEXT"`
+}
+
+// PendingIndexUpdate represents the pending_index_updates table (a book or paper whose
+// search index entry failed to update and is retried in the background)
+type PendingIndexUpdate struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ItemType  string    `json:"item_type" gorm:"type:enum('book','paper');not null;uniqueIndex:idx_pending_index_updates_item"`
+	ItemID    uint      `json:"item_id" gorm:"not null;uniqueIndex:idx_pending_index_updates_item"`
+	Attempts  int       `json:"attempts" gorm:"default:0"`
+	LastError string    `json:"last_error" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SavedSearch represents the saved_searches table (a search a user gets new-item alerts for)
+// Each run looks for items created since LastRunAt and sends them as a digest by email or
+// in-app notification. Unsubscribing from the emailed link turns the alerts off.
+type SavedSearch struct {
+	ID               uint               `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID           uint               `json:"user_id" gorm:"not null;index:idx_saved_searches_user_id"`
+	Name             string             `json:"name" gorm:"size:255;not null"`
+	Query            string             `json:"query" gorm:"type:text"`
+	Filters          SavedSearchFilters `json:"filters" gorm:"type:text;serializer:json"`
+	Frequency        string             `json:"frequency" gorm:"type:enum('daily','weekly');default:'daily'"`
+	Channel          string             `json:"channel" gorm:"type:enum('email','in_app');default:'email'"`
+	Active           bool               `json:"active" gorm:"default:true;index:idx_saved_searches_active"`
+	UnsubscribeToken string             `json:"-" gorm:"size:64;not null;uniqueIndex:idx_saved_searches_unsubscribe_token"`
+	LastRunAt        time.Time          `json:"last_run_at"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// SavedSearchFilters are the facet selections of a saved search, named as the
+// parameters of GET /search
+type SavedSearchFilters struct {
+	Types       []string `json:"type,omitempty"`
+	YearRanges  []string `json:"year_range,omitempty"`
+	Categories  []string `json:"category,omitempty"`
+	Languages   []string `json:"language,omitempty"`
+	Faculties   []string `json:"faculty,omitempty"`
+	Departments []string `json:"department,omitempty"`
+	AuthorIDs   []uint   `json:"author,omitempty"`
+}
+
+// Notification represents the notifications table (in-app messages to a user)
+type Notification struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"not null;index:idx_notifications_user_id"`
+	Title     string     `json:"title" gorm:"size:255;not null"`
+	Message   string     `json:"message" gorm:"type:text"`
+	Link      *string    `json:"link" gorm:"size:1000"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DeletedItem represents the deleted_items table (a record of a removed book or paper)
+// OAI-PMH harvesters learn of deletions from it. Sets holds the set specs the item
+// belonged to, separated and surrounded by spaces so a set can be matched with LIKE.
+type DeletedItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	ItemType  string    `json:"item_type" gorm:"type:enum('book','paper');not null;uniqueIndex:idx_deleted_items_item"`
+	ItemID    uint      `json:"item_id" gorm:"not null;uniqueIndex:idx_deleted_items_item"`
+	Sets      string    `json:"sets" gorm:"type:text"`
+	DeletedAt time.Time `json:"deleted_at" gorm:"index:idx_deleted_items_deleted_at"`
+}
+
+// ImportedRecord represents the imported_records table (the book or paper a record of
+// another catalog was imported as). Importers skip records already listed here, so an
+// import can be run again.
+type ImportedRecord struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Source    string    `json:"source" gorm:"size:50;not null;uniqueIndex:idx_imported_records_source"`
+	SourceID  string    `json:"source_id" gorm:"size:191;not null;uniqueIndex:idx_imported_records_source"`
+	ItemType  string    `json:"item_type" gorm:"type:enum('book','paper');not null;index:idx_imported_records_item"`
+	ItemID    uint      `json:"item_id" gorm:"not null;index:idx_imported_records_item"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Review represents the reviews table
+// A top-level review carries the star rating; replies (ParentID set) form a thread
+// under it. Hidden reviews are excluded from listings and rating aggregates, flagged
+// ones stay visible until a moderator decides.
+type Review struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         uint       `json:"user_id" gorm:"not null;index:idx_reviews_user_id"`
+	ItemType       string     `json:"item_type" gorm:"type:enum('book','paper');not null;index:idx_reviews_item"`
+	ItemID         uint       `json:"item_id" gorm:"not null;index:idx_reviews_item"`
+	ParentID       *uint      `json:"parent_id" gorm:"index:idx_reviews_parent_id"`
+	Rating         *int       `json:"rating"`
+	Body           *string    `json:"body" gorm:"type:text"`
+	IsAuthorReply  bool       `json:"is_author_reply" gorm:"default:false"`
+	Status         string     `json:"status" gorm:"type:enum('visible','flagged','hidden');default:'visible';index:idx_reviews_status"`
+	ModeratedBy    *uint      `json:"moderated_by"`
+	ModeratedAt    *time.Time `json:"moderated_at"`
+	ModerationNote *string    `json:"moderation_note" gorm:"type:text"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// ReviewReport represents the review_reports table (a user reporting an abusive review)
+type ReviewReport struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	ReviewID   uint       `json:"review_id" gorm:"not null;index:idx_review_reports_review_id"`
+	UserID     uint       `json:"user_id" gorm:"not null;index:idx_review_reports_user_id"`
+	Reason     string     `json:"reason" gorm:"type:enum('spam','abuse','off_topic','other');not null"`
+	Note       *string    `json:"note" gorm:"type:text"`
+	Status     string     `json:"status" gorm:"type:enum('open','resolved','dismissed');default:'open';index:idx_review_reports_status"`
+	ResolvedBy *uint      `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Relationships
+	Review *Review `json:"review,omitempty" gorm:"foreignKey:ReviewID"`
 }
 
 // ActivityLog represents the activity_logs table
@@ -164,6 +572,13 @@ type Counter struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// DataMigration represents the data_migrations table (a one-off data migration that
+// has already run)
+type DataMigration struct {
+	Name  string    `json:"name" gorm:"primaryKey;size:191"`
+	RanAt time.Time `json:"ran_at"`
+}
+
 // FileUpload represents the file_uploads table
 type FileUpload struct {
 	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -256,11 +671,13 @@ type SearchRequest struct {
 	Type     string `form:"type"`
 	Category string `form:"category"`
 	Year     *int   `form:"year"`
+	Keyword  string `form:"keyword"`
 	ISBN     string `form:"isbn"`
 	ISSN     string `form:"issn"`
 	Page     int    `form:"page"`
 	Limit    int    `form:"limit"`
 	Sort     string `form:"sort"`
+	Cursor   string `form:"cursor"`
 }
 
 type PaginatedResponse struct {
@@ -0,0 +1,378 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/lang/id"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// IndonesianAnalyzerName is the analyzer of Indonesian text: stopwords are removed and
+// words are stemmed with StemIndonesian
+const IndonesianAnalyzerName = "indonesian"
+
+// textAnalyzers are the analyzers a text field may have been indexed with, keyed by the
+// text language of the document
+var textAnalyzers = map[string]string{
+	"id": IndonesianAnalyzerName,
+	"en": en.AnalyzerName,
+	"":   standard.Name,
+}
+
+// schemaVersion changes whenever the mapping does; an index built with another version
+// is rebuilt from scratch
+const schemaVersion = "3"
+
+var schemaVersionKey = []byte("schema_version")
+
+// BleveIndex is an Index stored on disk with Bleve
+type BleveIndex struct {
+	index bleve.Index
+}
+
+// OpenBleve opens the index at path, creating an empty one when there is none yet or when
+// it was built with an older mapping. created reports whether the index is new and still
+// has to be filled.
+func OpenBleve(path string) (index *BleveIndex, created bool, err error) {
+	existing, err := bleve.Open(path)
+	if err == nil {
+		version, err := existing.GetInternal(schemaVersionKey)
+		if err == nil && string(version) == schemaVersion {
+			return &BleveIndex{index: existing}, false, nil
+		}
+		existing.Close()
+		if err := os.RemoveAll(path); err != nil {
+			return nil, false, fmt.Errorf("failed to remove outdated search index %s: %w", path, err)
+		}
+	} else if !errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		return nil, false, fmt.Errorf("failed to open search index %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create search index directory: %w", err)
+	}
+	indexMapping, err := newMapping()
+	if err != nil {
+		return nil, false, err
+	}
+	newIndex, err := bleve.New(path, indexMapping)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create search index %s: %w", path, err)
+	}
+	if err := newIndex.SetInternal(schemaVersionKey, []byte(schemaVersion)); err != nil {
+		newIndex.Close()
+		return nil, false, fmt.Errorf("failed to create search index %s: %w", path, err)
+	}
+	return &BleveIndex{index: newIndex}, true, nil
+}
+
+// newMapping maps documents to analyzed text fields for the title, authors, keywords
+// and abstract, and exact fields for identifiers and the facets. Each text language has
+// its own document mapping so Indonesian and English text is stemmed, and text in other
+// languages only split into lowercase words.
+func newMapping() (mapping.IndexMapping, error) {
+	indexMapping := bleve.NewIndexMapping()
+	err := indexMapping.AddCustomAnalyzer(IndonesianAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name, id.StopName, IndonesianStemmerName},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Indonesian analyzer: %w", err)
+	}
+
+	for language, analyzer := range textAnalyzers {
+		if language == "" {
+			indexMapping.DefaultMapping = documentMapping(analyzer)
+		} else {
+			indexMapping.AddDocumentMapping(language, documentMapping(analyzer))
+		}
+	}
+	indexMapping.DefaultAnalyzer = standard.Name
+	return indexMapping, nil
+}
+
+// documentMapping maps the fields of a document with its text fields analyzed by analyzer
+func documentMapping(analyzer string) *mapping.DocumentMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = analyzer
+	text.Store = false
+
+	exact := bleve.NewKeywordFieldMapping()
+	exact.Analyzer = keyword.Name
+	exact.Store = false
+
+	year := bleve.NewNumericFieldMapping()
+	year.Store = false
+
+	document := bleve.NewDocumentStaticMapping()
+	document.AddFieldMappingsAt("title", text)
+	document.AddFieldMappingsAt("authors", text)
+	document.AddFieldMappingsAt("keywords", text)
+	document.AddFieldMappingsAt("abstract", text)
+	document.AddFieldMappingsAt("identifiers", exact)
+	document.AddFieldMappingsAt("type", exact)
+	document.AddFieldMappingsAt("language", exact)
+	document.AddFieldMappingsAt("year", year)
+	return document
+}
+
+// Index adds or replaces documents in one batch
+func (b *BleveIndex) Index(docs ...Document) error {
+	batch := b.index.NewBatch()
+	for _, doc := range docs {
+		// Exact fields are matched case-insensitively
+		identifiers := make([]string, len(doc.Identifiers))
+		for i, identifier := range doc.Identifiers {
+			identifiers[i] = strings.ToLower(identifier)
+		}
+		doc.Identifiers = identifiers
+		doc.Language = strings.ToLower(doc.Language)
+
+		if err := batch.Index(DocumentID(doc.Type, doc.ID), doc); err != nil {
+			return err
+		}
+	}
+	return b.index.Batch(batch)
+}
+
+// Delete removes a document from the index
+func (b *BleveIndex) Delete(itemType string, id uint) error {
+	return b.index.Delete(DocumentID(itemType, id))
+}
+
+// Search returns the documents matching a parsed query. Matches in more heavily boosted
+// fields rank first, and the words of the query appearing as a phrase in the title rank
+// higher still. Words are analyzed the way each text language indexes them, and
+// stopwords such as "dan" or "the" do not have to match.
+func (b *BleveIndex) Search(q Query, offset, limit int) ([]Hit, error) {
+	if q == nil {
+		return nil, nil
+	}
+	root := b.compile(q)
+	if root == nil {
+		root = bleve.NewMatchAllQuery()
+	}
+	if terms := Terms(q); len(terms) > 1 {
+		if phrase := b.textQuery("title", strings.Join(terms, " "), true, TitleBoost); phrase != nil {
+			boosted := bleve.NewBooleanQuery()
+			boosted.AddMust(root)
+			boosted.AddShould(phrase)
+			root = boosted
+		}
+	}
+	return b.search(root, offset, limit)
+}
+
+// search runs a query and returns a page of its hits, best first
+func (b *BleveIndex) search(q query.Query, offset, limit int) ([]Hit, error) {
+	result, err := b.index.Search(bleve.NewSearchRequestOptions(q, limit, offset, false))
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(result.Hits))
+	for _, match := range result.Hits {
+		itemType, id, err := ParseDocumentID(match.ID)
+		if err != nil {
+			continue
+		}
+		hits = append(hits, Hit{Type: itemType, ID: id, Score: match.Score})
+	}
+	return hits, nil
+}
+
+// Count returns the number of indexed documents
+func (b *BleveIndex) Count() (uint64, error) {
+	return b.index.DocCount()
+}
+
+// Close closes the index files
+func (b *BleveIndex) Close() error {
+	return b.index.Close()
+}
+
+// indexFields maps query fields to the index fields they search
+var indexFields = map[string]string{
+	FieldTitle:    "title",
+	FieldAuthor:   "authors",
+	FieldAbstract: "abstract",
+	FieldKeywords: "keywords",
+	FieldISBN:     "identifiers",
+	FieldISSN:     "identifiers",
+	FieldDOI:      "identifiers",
+	FieldLanguage: "language",
+	FieldType:     "type",
+}
+
+// fieldBoosts weights matches by index field
+var fieldBoosts = map[string]float64{
+	"title":       TitleBoost,
+	"authors":     AuthorsBoost,
+	"keywords":    KeywordsBoost,
+	"abstract":    AbstractBoost,
+	"identifiers": IdentifierBoost,
+}
+
+// compile turns a parsed query into a Bleve query. It returns nil for a query that
+// matches every document, such as a lone stopword.
+func (b *BleveIndex) compile(q Query) query.Query {
+	switch q := q.(type) {
+	case Term:
+		if q.Field != "" {
+			return b.termQuery(indexFields[q.Field], q)
+		}
+		fields := []string{"title", "authors", "keywords", "abstract"}
+		if !q.Phrase {
+			fields = append(fields, "identifiers")
+		}
+		clauses := make([]query.Query, 0, len(fields))
+		for _, field := range fields {
+			clause := b.termQuery(field, q)
+			if clause == nil {
+				return nil
+			}
+			clauses = append(clauses, clause)
+		}
+		return bleve.NewDisjunctionQuery(clauses...)
+	case Range:
+		var from, to *float64
+		if q.From != nil {
+			value := float64(*q.From)
+			from = &value
+		}
+		if q.To != nil {
+			value := float64(*q.To)
+			to = &value
+		}
+		inclusive := true
+		yearRange := bleve.NewNumericRangeInclusiveQuery(from, to, &inclusive, &inclusive)
+		yearRange.SetField("year")
+		return yearRange
+	case And:
+		clauses := make([]query.Query, 0, len(q.Clauses))
+		for _, clause := range q.Clauses {
+			if compiled := b.compile(clause); compiled != nil {
+				clauses = append(clauses, compiled)
+			}
+		}
+		if len(clauses) == 0 {
+			return nil
+		}
+		return bleve.NewConjunctionQuery(clauses...)
+	case Or:
+		clauses := make([]query.Query, len(q.Clauses))
+		for i, clause := range q.Clauses {
+			if clauses[i] = b.compile(clause); clauses[i] == nil {
+				return nil
+			}
+		}
+		return bleve.NewDisjunctionQuery(clauses...)
+	case Not:
+		clause := b.compile(q.Clause)
+		if clause == nil {
+			return nil
+		}
+		excluded := bleve.NewBooleanQuery()
+		excluded.AddMust(bleve.NewMatchAllQuery())
+		excluded.AddMustNot(clause)
+		return excluded
+	}
+	return bleve.NewMatchNoneQuery()
+}
+
+// termQuery matches a term in one index field, or returns nil when the term is a stopword
+func (b *BleveIndex) termQuery(field string, term Term) query.Query {
+	boost := fieldBoosts[field]
+	if boost == 0 {
+		boost = 1
+	}
+	value := term.Value
+	exact := field == "identifiers" || field == "language" || field == "type"
+	if exact || term.Wildcard {
+		// Exact fields are indexed in lowercase, as are the words of text fields
+		value = strings.ToLower(value)
+	}
+
+	switch {
+	case term.Wildcard:
+		q := bleve.NewWildcardQuery(value)
+		q.SetField(field)
+		q.SetBoost(boost)
+		return q
+	case exact:
+		q := bleve.NewTermQuery(value)
+		q.SetField(field)
+		q.SetBoost(boost)
+		return q
+	default:
+		return b.textQuery(field, value, term.Phrase, boost)
+	}
+}
+
+// textQuery matches text in a text field the way each text language analyzes it, or
+// returns nil when some language drops the text entirely as stopwords
+func (b *BleveIndex) textQuery(field, text string, phrase bool, boost float64) query.Query {
+	languages := make([]string, 0, len(textAnalyzers))
+	for language := range textAnalyzers {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	var clauses []query.Query
+	seen := make(map[string]bool, len(languages))
+	for _, language := range languages {
+		name := textAnalyzers[language]
+		analyzer := b.index.Mapping().AnalyzerNamed(name)
+		if analyzer == nil {
+			continue
+		}
+		tokens := analyzer.Analyze([]byte(text))
+		if len(tokens) == 0 {
+			return nil
+		}
+		// Languages that analyze the text alike share one clause
+		terms := make([]string, len(tokens))
+		for i, token := range tokens {
+			terms[i] = string(token.Term)
+		}
+		key := strings.Join(terms, " ")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if phrase {
+			q := bleve.NewMatchPhraseQuery(text)
+			q.SetField(field)
+			q.SetBoost(boost)
+			q.Analyzer = name
+			clauses = append(clauses, q)
+		} else {
+			q := bleve.NewMatchQuery(text)
+			q.SetField(field)
+			q.SetBoost(boost)
+			q.Analyzer = name
+			// A word the analyzer splits up, such as covid-19, needs all its parts
+			q.SetOperator(query.MatchQueryOperatorAnd)
+			clauses = append(clauses, q)
+		}
+	}
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return bleve.NewDisjunctionQuery(clauses...)
+}
@@ -0,0 +1,184 @@
+package search
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentID(t *testing.T) {
+	assert.Equal(t, "paper:42", DocumentID("paper", 42))
+
+	itemType, id, err := ParseDocumentID("book:12")
+	assert.NoError(t, err)
+	assert.Equal(t, "book", itemType)
+	assert.Equal(t, uint(12), id)
+
+	for _, key := range []string{"book", "book:", "journal:1", "paper:x"} {
+		_, _, err := ParseDocumentID(key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestBleveIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.bleve")
+	index, created, err := OpenBleve(path)
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	assert.NoError(t, index.Index(
+		Document{Type: "book", ID: 1, Title: "Pengantar Basis Data", Authors: []string{"Budi Santoso"}},
+		Document{Type: "paper", ID: 1, Title: "Evaluasi Kinerja", Abstract: "Studi kasus basis data terdistribusi"},
+		Document{Type: "paper", ID: 2, Title: "Jaringan Komputer", Keywords: []string{"routing"}, Identifiers: []string{"10.1234/jk.2020"}},
+	))
+
+	count, err := index.Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	// A title match outranks an abstract match
+	hits, err := index.Search(mustParse(t, "basis data"), 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 2) {
+		assert.Equal(t, "book:1", DocumentID(hits[0].Type, hits[0].ID))
+		assert.Equal(t, "paper:1", DocumentID(hits[1].Type, hits[1].ID))
+		assert.Greater(t, hits[0].Score, hits[1].Score)
+	}
+
+	// Later pages continue where the first one ended
+	hits, err = index.Search(mustParse(t, "basis data"), 1, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, "paper:1", DocumentID(hits[0].Type, hits[0].ID))
+	}
+	hits, err = index.Search(mustParse(t, "basis data"), 2, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+
+	hits, err = index.Search(mustParse(t, "santoso"), 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+
+	hits, err = index.Search(mustParse(t, "10.1234/jk.2020"), 0, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, hits, 1) {
+		assert.Equal(t, uint(2), hits[0].ID)
+	}
+
+	hits, err = index.Search(mustParse(t, "  "), 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+
+	// Replacing and deleting documents
+	assert.NoError(t, index.Index(Document{Type: "book", ID: 1, Title: "Algoritma"}))
+	assert.NoError(t, index.Delete("paper", 1))
+	assert.NoError(t, index.Delete("paper", 99))
+	hits, err = index.Search(mustParse(t, "basis"), 0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, hits)
+	assert.NoError(t, index.Close())
+
+	// The index survives a restart
+	index, created, err = OpenBleve(path)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	defer index.Close()
+	hits, err = index.Search(mustParse(t, "algoritma"), 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
+
+func TestBleveIndexAdvancedQueries(t *testing.T) {
+	index, _, err := OpenBleve(filepath.Join(t.TempDir(), "search.bleve"))
+	assert.NoError(t, err)
+	defer index.Close()
+
+	assert.NoError(t, index.Index(
+		Document{Type: "book", ID: 1, Title: "Sistem Informasi Manajemen", Authors: []string{"Budi Santoso"}, Identifiers: []string{"978-602-1234-56-7"}, Year: 2018, Language: "Indonesia"},
+		Document{Type: "paper", ID: 1, Title: "Sistem Informasi Akademik", Authors: []string{"Dewi Santoso"}, Keywords: []string{"covid", "e-learning"}, Year: 2021},
+		Document{Type: "paper", ID: 2, Title: "Informasi Sistem Pakar", Authors: []string{"Andi Wijaya"}, Keywords: []string{"pakar"}, Year: 2022},
+	))
+
+	for input, want := range map[string][]string{
+		`title:"sistem informasi" AND author:santoso`: {"book:1", "paper:1"},
+		`title:"sistem informasi" -keywords:covid`:    {"book:1"},
+		`year:2019..2023 -keywords:covid`:             {"paper:2"},
+		`year:..2018`:                                 {"book:1"},
+		`isbn:978*`:                                   {"book:1"},
+		`ISBN:978-602-1234-56-7`:                      {"book:1"},
+		`author:wijaya OR keywords:covid`:             {"paper:1", "paper:2"},
+		`sistem NOT (type:book OR author:wijaya)`:     {"paper:1"},
+		`title:(manajemen OR pakar)`:                  {"book:1", "paper:2"},
+		`language:indonesia`:                          {"book:1"},
+		`tit*`:                                        {},
+		`e-learning`:                                  {"paper:1"},
+	} {
+		hits, err := index.Search(mustParse(t, input), 0, 10)
+		assert.NoError(t, err, input)
+		got := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			got = append(got, DocumentID(hit.Type, hit.ID))
+		}
+		assert.ElementsMatch(t, want, got, input)
+	}
+}
+
+func TestBleveIndexLanguageAnalysis(t *testing.T) {
+	index, _, err := OpenBleve(filepath.Join(t.TempDir(), "search.bleve"))
+	assert.NoError(t, err)
+	defer index.Close()
+
+	assert.NoError(t, index.Index(
+		Document{Type: "book", ID: 1, Title: "Strategi Pembelajaran Daring", TextLanguage: "id"},
+		Document{Type: "book", ID: 2, Title: "Teaching Computer Networks", TextLanguage: "en"},
+		Document{Type: "paper", ID: 1, Title: "Analisis Sistem dan Jaringan", Abstract: "Peneliti mengukur kinerja jaringan", TextLanguage: "id"},
+		Document{Type: "paper", ID: 2, Title: "Pembelajaran Mesin"},
+	))
+
+	for input, want := range map[string][]string{
+		// Indonesian words match their derived forms
+		`belajar`:                  {"book:1"},
+		`title:"strategi belajar"`: {"book:1"},
+		`penelitian`:               {"paper:1"},
+		`title:jaringan`:           {"paper:1"},
+		`pembelajaran`:             {"book:1", "paper:2"},
+		// English words are stemmed too
+		`network teach`: {"book:2"},
+		// Stopwords do not have to match
+		`sistem yang jaringan`:     {"paper:1"},
+		`"sistem dan jaringan"`:    {"paper:1"},
+		`networks of the computer`: {"book:2"},
+	} {
+		hits, err := index.Search(mustParse(t, input), 0, 10)
+		assert.NoError(t, err, input)
+		got := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			got = append(got, DocumentID(hit.Type, hit.ID))
+		}
+		assert.ElementsMatch(t, want, got, input)
+	}
+}
+
+func TestOpenBleveRebuildsOutdatedIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "search.bleve")
+	index, _, err := OpenBleve(path)
+	assert.NoError(t, err)
+	assert.NoError(t, index.Index(Document{Type: "book", ID: 1, Title: "Algoritma"}))
+	assert.NoError(t, index.index.SetInternal(schemaVersionKey, []byte("1")))
+	assert.NoError(t, index.Close())
+
+	index, created, err := OpenBleve(path)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	defer index.Close()
+	count, err := index.Count()
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func mustParse(t *testing.T, input string) Query {
+	q, err := Parse(input)
+	assert.NoError(t, err, input)
+	return q
+}
@@ -0,0 +1,86 @@
+// Package search maintains the relevance-ranked index of books and papers used by the
+// unified search. The index is kept on local disk next to the API.
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document is the searchable text of a book or paper
+type Document struct {
+	Type        string   `json:"type"`
+	ID          uint     `json:"-"`
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors"`
+	Abstract    string   `json:"abstract"`
+	Keywords    []string `json:"keywords"`
+	Identifiers []string `json:"identifiers"`
+	Year        int      `json:"year,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	// TextLanguage picks the analyzer of the text fields: "id", "en" or "" for neither
+	TextLanguage string `json:"-"`
+}
+
+// BleveType maps the document to the Bleve mapping of its text language
+func (d Document) BleveType() string {
+	return d.TextLanguage
+}
+
+// Hit is a document matching a query with its relevance score
+type Hit struct {
+	Type  string
+	ID    uint
+	Score float64
+}
+
+// Index is a relevance-ranked full-text index of books and papers
+type Index interface {
+	// Index adds or replaces documents
+	Index(docs ...Document) error
+	// Delete removes a document; removing a missing document is not an error
+	Delete(itemType string, id uint) error
+	// Search returns up to limit documents matching a parsed query, best first, after
+	// skipping the first offset
+	Search(q Query, offset, limit int) ([]Hit, error)
+	// Count returns the number of indexed documents
+	Count() (uint64, error)
+	Close() error
+}
+
+// Field boosts: a match in the title outweighs one in the authors, keywords or abstract
+const (
+	TitleBoost      = 5.0
+	AuthorsBoost    = 3.0
+	KeywordsBoost   = 2.0
+	AbstractBoost   = 1.0
+	IdentifierBoost = 1.0
+)
+
+var defaultIndex Index
+
+// SetDefault sets the index kept up to date by the handlers
+func SetDefault(index Index) {
+	defaultIndex = index
+}
+
+// Default returns the index set with SetDefault, or nil when search runs without one
+func Default() Index {
+	return defaultIndex
+}
+
+// DocumentID returns the index key of a book or paper, e.g. "book:12"
+func DocumentID(itemType string, id uint) string {
+	return itemType + ":" + strconv.FormatUint(uint64(id), 10)
+}
+
+// ParseDocumentID splits an index key into its item type and ID
+func ParseDocumentID(key string) (string, uint, error) {
+	itemType, rawID, ok := strings.Cut(key, ":")
+	id, err := strconv.ParseUint(rawID, 10, 32)
+	if !ok || err != nil || (itemType != "book" && itemType != "paper") {
+		return "", 0, fmt.Errorf("invalid document ID %q", key)
+	}
+	return itemType, uint(id), nil
+}
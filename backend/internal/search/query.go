@@ -0,0 +1,506 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Query fields. A term without a field matches the title, authors, keywords, abstract
+// and identifiers.
+const (
+	FieldTitle    = "title"
+	FieldAuthor   = "author"
+	FieldAbstract = "abstract"
+	FieldKeywords = "keywords"
+	FieldISBN     = "isbn"
+	FieldISSN     = "issn"
+	FieldDOI      = "doi"
+	FieldYear     = "year"
+	FieldLanguage = "language"
+	FieldType     = "type"
+)
+
+// fieldNames maps the qualifiers accepted in queries to their field
+var fieldNames = map[string]string{
+	"title":    FieldTitle,
+	"author":   FieldAuthor,
+	"authors":  FieldAuthor,
+	"abstract": FieldAbstract,
+	"summary":  FieldAbstract,
+	"keywords": FieldKeywords,
+	"keyword":  FieldKeywords,
+	"subject":  FieldKeywords,
+	"isbn":     FieldISBN,
+	"issn":     FieldISSN,
+	"doi":      FieldDOI,
+	"year":     FieldYear,
+	"language": FieldLanguage,
+	"lang":     FieldLanguage,
+	"type":     FieldType,
+}
+
+// Query is a node of a parsed search query
+type Query interface {
+	isQuery()
+}
+
+// Term matches a word, a phrase or a wildcard pattern (* for any characters, ? for one)
+// in a field, or in any text field when Field is empty
+type Term struct {
+	Field    string
+	Value    string
+	Phrase   bool
+	Wildcard bool
+}
+
+// Range matches years in an inclusive range; a nil bound is open
+type Range struct {
+	Field string
+	From  *int
+	To    *int
+}
+
+// And matches items matching every clause
+type And struct {
+	Clauses []Query
+}
+
+// Or matches items matching any clause
+type Or struct {
+	Clauses []Query
+}
+
+// Not matches items not matching its clause
+type Not struct {
+	Clause Query
+}
+
+func (Term) isQuery()  {}
+func (Range) isQuery() {}
+func (And) isQuery()   {}
+func (Or) isQuery()    {}
+func (Not) isQuery()   {}
+
+// SyntaxError describes why a query can't be parsed and where (1-based character position)
+type SyntaxError struct {
+	Position int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query syntax error at position %d: %s", e.Position, e.Message)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenPhrase
+	tokenField
+	tokenOpen
+	tokenClose
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // byte offset in the input
+}
+
+// Parse parses a search query. Words are matched together (AND) unless joined by OR;
+// NOT or a leading - excludes, parentheses group, and field:value qualifies a word,
+// "quoted phrase" or group. Years take ranges such as year:2019..2023, year:2019.. or
+// year:..2023. An empty query parses to nil.
+//
+// Examples: title:"sistem informasi" AND author:santoso, year:2019..2023 -keywords:covid,
+// isbn:978*
+func Parse(input string) (Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{input: input, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, nil
+	}
+
+	q, err := p.parseOr("")
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "unexpected %s", describe(tok))
+	}
+	return q, nil
+}
+
+// tokenize splits a query into words, phrases, field qualifiers, parentheses and operators
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", pos: i})
+			i++
+		case r == '"':
+			end := strings.IndexByte(input[i+1:], '"')
+			if end < 0 {
+				return nil, syntaxError(input, i, "unclosed quote")
+			}
+			phrase := strings.TrimSpace(input[i+1 : i+1+end])
+			if phrase == "" {
+				return nil, syntaxError(input, i, "empty phrase")
+			}
+			tokens = append(tokens, token{kind: tokenPhrase, text: phrase, pos: i})
+			i += end + 2
+		case r == '-' && wordStart(input, i):
+			if i+1 == len(input) || !startsTerm(input[i+1:]) {
+				return nil, syntaxError(input, i, "nothing to exclude after -")
+			}
+			tokens = append(tokens, token{kind: tokenNot, text: "-", pos: i})
+			i++
+		default:
+			end := i
+			for end < len(input) {
+				r, size := utf8.DecodeRuneInString(input[end:])
+				if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' {
+					break
+				}
+				end += size
+			}
+			word := input[i:end]
+			wordTokens, err := wordTokens(input, word, i, end)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, wordTokens...)
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// wordTokens turns a word into an operator, a word, or a field qualifier and its value
+func wordTokens(input, word string, start, end int) ([]token, error) {
+	switch word {
+	case "AND":
+		return []token{{kind: tokenAnd, text: word, pos: start}}, nil
+	case "OR":
+		return []token{{kind: tokenOr, text: word, pos: start}}, nil
+	case "NOT":
+		return []token{{kind: tokenNot, text: word, pos: start}}, nil
+	}
+
+	name, value, qualified := strings.Cut(word, ":")
+	// URLs such as https://doi.org/... are searched as they are
+	if !qualified || name == "" || !isLetters(name) || strings.HasPrefix(value, "//") {
+		return []token{{kind: tokenWord, text: word, pos: start}}, nil
+	}
+	field, known := fieldNames[strings.ToLower(name)]
+	followedByGroup := end < len(input) && (input[end] == '"' || input[end] == '(')
+	if value == "" && !followedByGroup {
+		if known {
+			return nil, syntaxError(input, start, "missing value after %s:", name)
+		}
+		// Punctuation, as in a pasted title "Sistem Informasi: Konsep dan Aplikasi"
+		return []token{{kind: tokenWord, text: name, pos: start}}, nil
+	}
+	if !known {
+		return nil, syntaxError(input, start, "unknown field %q (use %s)", name, strings.Join(FieldNames(), ", "))
+	}
+
+	tokens := []token{{kind: tokenField, text: field, pos: start}}
+	if value != "" {
+		tokens = append(tokens, token{kind: tokenWord, text: value, pos: start + len(name) + 1})
+	}
+	return tokens, nil
+}
+
+// FieldNames returns the fields that can qualify query terms
+func FieldNames() []string {
+	return []string{FieldAbstract, FieldAuthor, FieldDOI, FieldISBN, FieldISSN, FieldKeywords, FieldLanguage, FieldTitle, FieldType, FieldYear}
+}
+
+type parser struct {
+	input  string
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+// parseOr parses clauses joined by OR. field is the qualifier of an enclosing group.
+func (p *parser) parseOr(field string) (Query, error) {
+	first, err := p.parseAnd(field)
+	if err != nil {
+		return nil, err
+	}
+	clauses := []Query{first}
+	for p.peek().kind == tokenOr {
+		operator := p.take()
+		if !startsClause(p.peek()) {
+			return nil, p.errorAt(operator, "missing term after OR")
+		}
+		clause, err := p.parseAnd(field)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) == 1 {
+		return first, nil
+	}
+	return Or{Clauses: clauses}, nil
+}
+
+// parseAnd parses clauses joined by AND or simply following each other
+func (p *parser) parseAnd(field string) (Query, error) {
+	first, err := p.parseUnary(field)
+	if err != nil {
+		return nil, err
+	}
+	clauses := []Query{first}
+	for {
+		if p.peek().kind == tokenAnd {
+			operator := p.take()
+			if !startsClause(p.peek()) {
+				return nil, p.errorAt(operator, "missing term after AND")
+			}
+		} else if !startsClause(p.peek()) {
+			break
+		}
+		clause, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	if len(clauses) == 1 {
+		return first, nil
+	}
+	return And{Clauses: clauses}, nil
+}
+
+// parseUnary parses an excluded (NOT or -) or plain clause
+func (p *parser) parseUnary(field string) (Query, error) {
+	if p.peek().kind == tokenNot {
+		operator := p.take()
+		if !startsClause(p.peek()) {
+			return nil, p.errorAt(operator, "missing term after %s", operator.text)
+		}
+		clause, err := p.parseUnary(field)
+		if err != nil {
+			return nil, err
+		}
+		return Not{Clause: clause}, nil
+	}
+	return p.parsePrimary(field)
+}
+
+// parsePrimary parses a word, phrase, group or qualified value
+func (p *parser) parsePrimary(field string) (Query, error) {
+	tok := p.take()
+	switch tok.kind {
+	case tokenOpen:
+		if p.peek().kind == tokenClose {
+			return nil, p.errorAt(tok, "empty parentheses")
+		}
+		group, err := p.parseOr(field)
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenClose {
+			return nil, p.errorAt(tok, "missing closing parenthesis")
+		}
+		p.take()
+		return group, nil
+	case tokenField:
+		next := p.peek()
+		if next.kind != tokenWord && next.kind != tokenPhrase && next.kind != tokenOpen {
+			return nil, p.errorAt(tok, "missing value after %s:", tok.text)
+		}
+		return p.parsePrimary(tok.text)
+	case tokenWord:
+		return p.word(tok, field)
+	case tokenPhrase:
+		if field == FieldYear || field == FieldType {
+			return nil, p.errorAt(tok, "%s takes a single value, not a phrase", field)
+		}
+		return Term{Field: field, Value: tok.text, Phrase: true}, nil
+	default:
+		return nil, p.errorAt(tok, "unexpected %s", describe(tok))
+	}
+}
+
+// word builds the term or year range of a word
+func (p *parser) word(tok token, field string) (Query, error) {
+	switch field {
+	case FieldYear:
+		return p.yearRange(tok)
+	case FieldType:
+		value := strings.ToLower(tok.text)
+		if value != "book" && value != "paper" {
+			return nil, p.errorAt(tok, "type must be book or paper")
+		}
+		return Term{Field: field, Value: value}, nil
+	}
+
+	if strings.Contains(tok.text, "..") {
+		return nil, p.errorAt(tok, "ranges are only supported for year")
+	}
+	term := Term{Field: field, Value: tok.text}
+	if strings.ContainsAny(tok.text, "*?") {
+		if strings.Trim(tok.text, "*?") == "" {
+			return nil, p.errorAt(tok, "a wildcard needs at least one other character")
+		}
+		term.Wildcard = true
+	}
+	return term, nil
+}
+
+// yearRange parses "2019..2023", "2019..", "..2023" or a single year
+func (p *parser) yearRange(tok token) (Query, error) {
+	from, to, isRange := strings.Cut(tok.text, "..")
+	if !isRange {
+		to = from
+	}
+	bound := func(value string) (*int, error) {
+		if value == "" {
+			return nil, nil
+		}
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 0 {
+			return nil, p.errorAt(tok, "year must be a number or a range such as 2019..2023, not %q", tok.text)
+		}
+		return &year, nil
+	}
+
+	fromYear, err := bound(from)
+	if err != nil {
+		return nil, err
+	}
+	toYear, err := bound(to)
+	if err != nil {
+		return nil, err
+	}
+	if fromYear == nil && toYear == nil {
+		return nil, p.errorAt(tok, "year range needs at least one bound")
+	}
+	if fromYear != nil && toYear != nil && *fromYear > *toYear {
+		return nil, p.errorAt(tok, "year range %s ends before it starts", tok.text)
+	}
+	return Range{Field: FieldYear, From: fromYear, To: toYear}, nil
+}
+
+func (p *parser) errorAt(tok token, format string, args ...interface{}) error {
+	return syntaxError(p.input, tok.pos, format, args...)
+}
+
+// Terms returns the lowercase words a query looks for, leaving out excluded clauses,
+// wildcard characters and non-text fields
+func Terms(q Query) []string {
+	var terms []string
+	var walk func(Query)
+	walk = func(q Query) {
+		switch q := q.(type) {
+		case Term:
+			if q.Field == FieldType || q.Field == FieldLanguage {
+				return
+			}
+			for _, word := range strings.Fields(strings.ToLower(q.Value)) {
+				if word = strings.Trim(word, "*?"); word != "" && !strings.ContainsAny(word, "*?") {
+					terms = append(terms, word)
+				}
+			}
+		case And:
+			for _, clause := range q.Clauses {
+				walk(clause)
+			}
+		case Or:
+			for _, clause := range q.Clauses {
+				walk(clause)
+			}
+		}
+	}
+	walk(q)
+	return terms
+}
+
+func syntaxError(input string, offset int, format string, args ...interface{}) error {
+	return &SyntaxError{
+		Position: utf8.RuneCountInString(input[:offset]) + 1,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// describe names a token in error messages
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenField:
+		return tok.text + ":"
+	case tokenPhrase:
+		return `"` + tok.text + `"`
+	default:
+		return tok.text
+	}
+}
+
+// startsClause reports whether a token can begin a clause
+func startsClause(tok token) bool {
+	switch tok.kind {
+	case tokenWord, tokenPhrase, tokenField, tokenOpen, tokenNot:
+		return true
+	}
+	return false
+}
+
+// wordStart reports whether the byte at i begins a word
+func wordStart(input string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(input[:i])
+	return unicode.IsSpace(r) || r == '('
+}
+
+// startsTerm reports whether text begins with something that can be excluded
+func startsTerm(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return !unicode.IsSpace(r) && r != ')' && r != '-'
+}
+
+// isLetters reports whether s consists of letters only
+func isLetters(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
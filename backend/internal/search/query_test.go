@@ -0,0 +1,90 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func year(y int) *int {
+	return &y
+}
+
+func TestParse(t *testing.T) {
+	for input, want := range map[string]Query{
+		"":                 nil,
+		"  ":               nil,
+		"santoso":          Term{Value: "santoso"},
+		"sistem informasi": And{Clauses: []Query{Term{Value: "sistem"}, Term{Value: "informasi"}}},
+		`title:"sistem informasi" AND author:santoso`: And{Clauses: []Query{
+			Term{Field: FieldTitle, Value: "sistem informasi", Phrase: true},
+			Term{Field: FieldAuthor, Value: "santoso"},
+		}},
+		"year:2019..2023 -keywords:covid": And{Clauses: []Query{
+			Range{Field: FieldYear, From: year(2019), To: year(2023)},
+			Not{Clause: Term{Field: FieldKeywords, Value: "covid"}},
+		}},
+		"year:2020":   Range{Field: FieldYear, From: year(2020), To: year(2020)},
+		"year:2019..": Range{Field: FieldYear, From: year(2019)},
+		"year:..2010": Range{Field: FieldYear, To: year(2010)},
+		"isbn:978*":   Term{Field: FieldISBN, Value: "978*", Wildcard: true},
+		"a OR b c": Or{Clauses: []Query{
+			Term{Value: "a"},
+			And{Clauses: []Query{Term{Value: "b"}, Term{Value: "c"}}},
+		}},
+		"(a OR b) NOT c": And{Clauses: []Query{
+			Or{Clauses: []Query{Term{Value: "a"}, Term{Value: "b"}}},
+			Not{Clause: Term{Value: "c"}},
+		}},
+		"Title:(a OR b)":   Or{Clauses: []Query{Term{Field: FieldTitle, Value: "a"}, Term{Field: FieldTitle, Value: "b"}}},
+		"type:Book":        Term{Field: FieldType, Value: "book"},
+		"subject:jaringan": Term{Field: FieldKeywords, Value: "jaringan"},
+		// Hyphens inside words, lowercase operators and colons used as punctuation are text
+		"covid-19 and":              And{Clauses: []Query{Term{Value: "covid-19"}, Term{Value: "and"}}},
+		"Informasi: Konsep":         And{Clauses: []Query{Term{Value: "Informasi"}, Term{Value: "Konsep"}}},
+		"doi:10.1000/xyz:1":         Term{Field: FieldDOI, Value: "10.1000/xyz:1"},
+		"https://doi.org/10.1000/1": Term{Value: "https://doi.org/10.1000/1"},
+	} {
+		got, err := Parse(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		`title:"sistem`:      "query syntax error at position 7: unclosed quote",
+		`""`:                 "query syntax error at position 1: empty phrase",
+		"(a OR b":            "query syntax error at position 1: missing closing parenthesis",
+		"a OR b)":            "query syntax error at position 7: unexpected )",
+		"()":                 "query syntax error at position 1: empty parentheses",
+		"a AND":              "query syntax error at position 3: missing term after AND",
+		"OR a":               "query syntax error at position 1: unexpected OR",
+		"a OR":               "query syntax error at position 3: missing term after OR",
+		"NOT":                "query syntax error at position 1: missing term after NOT",
+		"a - b":              "query syntax error at position 3: nothing to exclude after -",
+		"title:":             "query syntax error at position 1: missing value after title:",
+		"publisher:gramedia": `query syntax error at position 1: unknown field "publisher" (use abstract, author, doi, isbn, issn, keywords, language, title, type, year)`,
+		"year:2023..2019":    "query syntax error at position 6: year range 2023..2019 ends before it starts",
+		"year:recent":        `query syntax error at position 6: year must be a number or a range such as 2019..2023, not "recent"`,
+		"year:..":            "query syntax error at position 6: year range needs at least one bound",
+		`year:"2020"`:        "query syntax error at position 6: year takes a single value, not a phrase",
+		"title:2019..2020":   "query syntax error at position 7: ranges are only supported for year",
+		"type:journal":       "query syntax error at position 6: type must be book or paper",
+		"isbn:*":             "query syntax error at position 6: a wildcard needs at least one other character",
+		"ilmu title:":        "query syntax error at position 6: missing value after title:",
+	} {
+		_, err := Parse(input)
+		if assert.Error(t, err, input) {
+			assert.Equal(t, want, err.Error(), input)
+			assert.IsType(t, &SyntaxError{}, err)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	q, err := Parse(`title:"Sistem Informasi" author:sant* -covid type:paper year:2020 (Jaringan OR pakar)`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sistem", "informasi", "sant", "jaringan", "pakar"}, Terms(q))
+	assert.Empty(t, Terms(nil))
+}
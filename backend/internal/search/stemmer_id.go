@@ -0,0 +1,437 @@
+package search
+
+import (
+	_ "embed"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/registry"
+)
+
+// IndonesianStemmerName is the Bleve token filter that stems Indonesian words
+const IndonesianStemmerName = "stem_id"
+
+// Prefixes removed from a word, which decide the suffixes that may follow
+const (
+	removedDi = 1 << iota
+	removedMeng
+	removedPeng
+	removedTer
+	removedKe
+	removedBer
+	removedPe
+)
+
+//go:embed stemmer_id_roots.txt
+var indonesianRootList string
+
+// indonesianRoots holds the root words a stem is confirmed against
+var indonesianRoots = loadRoots(indonesianRootList)
+
+// loadRoots reads a root-word list with one word per line and # comments
+func loadRoots(list string) map[string]bool {
+	roots := make(map[string]bool)
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			roots[line] = true
+		}
+	}
+	return roots
+}
+
+// StemIndonesian reduces an Indonesian word to its root by stripping particles (-kah,
+// -lah, -tah, -pun), possessives (-ku, -mu, -nya), derivational suffixes (-kan, -an, -i)
+// and up to three prefixes (meN-, peN-, di-, ter-, ke-, se-, ber-, per-), confirming each
+// candidate against a root-word dictionary as the Nazief-Adriani algorithm does. Every
+// spelling a nasal prefix may hide is tried, so pemerintah stems to "perintah" and
+// penerapan to "terap". Words whose root is not in the dictionary fall back to the
+// confix rules alone, which keep words of two syllables or fewer.
+func StemIndonesian(word string) string {
+	if indonesianRoots[word] {
+		return word
+	}
+	if stem, ok := stemWithRoots(word); ok {
+		return stem
+	}
+	return stemByRules(word)
+}
+
+// stemWithRoots strips affixes from a word until it finds a root in the dictionary
+func stemWithRoots(word string) (string, bool) {
+	// Words like mempengaruhi or berlayarlah take off their prefixes first
+	if prefixFirst(word) {
+		if stem, ok := removePrefixes(word, ""); ok {
+			return stem, true
+		}
+	}
+
+	afterParticle := trimAnySuffix(word, "lah", "kah", "tah", "pun")
+	if indonesianRoots[afterParticle] {
+		return afterParticle, true
+	}
+	inflected := trimAnySuffix(afterParticle, "ku", "mu", "nya")
+	if indonesianRoots[inflected] {
+		return inflected, true
+	}
+
+	derived, suffix := inflected, ""
+	for _, candidate := range []string{"kan", "an", "i"} {
+		if strings.HasSuffix(inflected, candidate) && len(inflected)-len(candidate) >= 2 {
+			derived, suffix = inflected[:len(inflected)-len(candidate)], candidate
+			break
+		}
+	}
+	if indonesianRoots[derived] {
+		return derived, true
+	}
+	if stem, ok := removePrefixes(derived, suffix); ok {
+		return stem, true
+	}
+
+	// The suffix may have been part of the root: -kan of kebijakan is the k of bijak
+	// followed by -an, and -an or -i may end the root itself, as in dimakan
+	if suffix == "kan" {
+		if indonesianRoots[derived+"k"] {
+			return derived + "k", true
+		}
+		if stem, ok := removePrefixes(derived+"k", "an"); ok {
+			return stem, true
+		}
+	}
+	tried := derived
+	for _, candidate := range []string{inflected, afterParticle, word} {
+		if candidate == tried {
+			continue
+		}
+		tried = candidate
+		if stem, ok := removePrefixes(candidate, ""); ok {
+			return stem, true
+		}
+	}
+	return "", false
+}
+
+// prefixFirst reports whether the prefixes of a word come off before its suffixes
+func prefixFirst(word string) bool {
+	if strings.HasPrefix(word, "be") {
+		return strings.HasSuffix(word, "lah") || strings.HasSuffix(word, "an")
+	}
+	if !strings.HasSuffix(word, "i") {
+		return false
+	}
+	for _, prefix := range []string{"me", "di", "pe", "ter"} {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidConfix reports whether a prefix never occurs together with the suffix
+func invalidConfix(word, suffix string) bool {
+	if suffix == "" || len(word) < 2 {
+		return false
+	}
+	switch word[:2] + "-" + suffix {
+	case "be-i", "di-an", "ke-i", "ke-kan", "me-an", "se-i", "se-kan", "te-an":
+		return true
+	}
+	return false
+}
+
+// removePrefixes strips up to three prefixes from a word whose suffix was already
+// removed and returns the first reading found in the dictionary
+func removePrefixes(word, suffix string) (string, bool) {
+	if invalidConfix(word, suffix) {
+		return "", false
+	}
+	candidates := []string{word}
+	for level := 0; level < 3 && len(candidates) > 0; level++ {
+		var next []string
+		for _, candidate := range candidates {
+			for _, reading := range prefixReadings(candidate) {
+				if indonesianRoots[reading] {
+					return reading, true
+				}
+				next = append(next, reading)
+			}
+		}
+		candidates = next
+	}
+	return "", false
+}
+
+// prefixReadings lists the words left after removing the first prefix of a word. A
+// nasal prefix may replace the first letter of the root (menulis from tulis, memakai
+// from pakai), so each letter it could have replaced is tried as well.
+func prefixReadings(word string) []string {
+	var readings []string
+	add := func(prefix, replacement string) {
+		if strings.HasPrefix(word, prefix) && len(word)-len(prefix) >= 2 {
+			readings = append(readings, replacement+word[len(prefix):])
+		}
+	}
+	has := func(prefix string) bool {
+		return strings.HasPrefix(word, prefix) && len(word) > len(prefix)+1
+	}
+	nasalR := func(i int) bool {
+		return i+1 < len(word) && word[i] == 'r' && vowelAt(word, i+1)
+	}
+
+	switch {
+	case has("di"), has("ke"), has("se"):
+		add(word[:2], "")
+	case strings.HasPrefix(word, "belajar"):
+		add("bel", "")
+	case has("ber"):
+		add("ber", "")
+		if vowelAt(word, 3) {
+			add("be", "")
+		}
+	case has("be") && !vowelAt(word, 2) && strings.HasPrefix(word[3:], "er"):
+		add("be", "")
+	case has("ter"):
+		add("ter", "")
+		if vowelAt(word, 3) {
+			add("te", "")
+		}
+	case has("te") && !vowelAt(word, 2) && strings.HasPrefix(word[3:], "er"):
+		add("te", "")
+	case has("menge"):
+		add("menge", "")
+		add("meng", "")
+		add("meng", "k")
+	case has("meng") && vowelAt(word, 4):
+		add("meng", "")
+		add("meng", "k")
+	case has("meng"):
+		add("meng", "")
+	case has("meny"):
+		add("meny", "s")
+		add("me", "")
+	case has("mempe"):
+		add("mem", "")
+	case has("mem") && (vowelAt(word, 3) || nasalR(3)):
+		add("me", "")
+		add("mem", "p")
+	case has("mem"):
+		add("mem", "")
+	case has("men") && vowelAt(word, 3):
+		add("me", "")
+		add("men", "t")
+	case has("men"):
+		add("men", "")
+	case has("me"):
+		add("me", "")
+	case strings.HasPrefix(word, "pelajar"):
+		add("pel", "")
+	case has("penge"):
+		add("penge", "")
+		add("peng", "")
+		add("peng", "k")
+	case has("peng") && vowelAt(word, 4):
+		add("peng", "")
+		add("peng", "k")
+	case has("peng"):
+		add("peng", "")
+	case has("peny"):
+		add("peny", "s")
+		add("pe", "")
+	case has("pem") && (vowelAt(word, 3) || nasalR(3)):
+		add("pe", "")
+		add("pem", "p")
+	case has("pem"):
+		add("pem", "")
+	case has("pen") && vowelAt(word, 3):
+		add("pe", "")
+		add("pen", "t")
+	case has("pen"):
+		add("pen", "")
+	case has("per"):
+		add("per", "")
+		if vowelAt(word, 3) {
+			add("pe", "")
+		}
+	case has("pe"):
+		add("pe", "")
+	}
+	return readings
+}
+
+// trimAnySuffix removes the first of the suffixes the word ends with
+func trimAnySuffix(word string, suffixes ...string) string {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 2 {
+			return word[:len(word)-len(suffix)]
+		}
+	}
+	return word
+}
+
+// stemByRules stems a word whose root is not in the dictionary with the confix rules of
+// Nazief and Adriani alone. Derived forms still share a stem, but it is only a root when
+// the rules happen to guess it.
+func stemByRules(word string) string {
+	s := &idStem{word: word, syllables: countVowels(word)}
+	s.removeParticle()
+	s.removePossessive()
+
+	before := s.word
+	s.removeFirstOrderPrefix()
+	if s.word != before {
+		before = s.word
+		s.removeSuffix()
+		if s.word != before {
+			s.removeSecondOrderPrefix()
+		}
+	} else {
+		s.removeSecondOrderPrefix()
+		s.removeSuffix()
+	}
+	return s.word
+}
+
+type idStem struct {
+	word      string
+	syllables int
+	flags     int
+}
+
+// long reports whether the word is still long enough to strip an affix from
+func (s *idStem) long() bool {
+	return s.syllables > 2
+}
+
+func (s *idStem) trimPrefix(prefix, replacement string, flag int) bool {
+	if !strings.HasPrefix(s.word, prefix) {
+		return false
+	}
+	s.word = replacement + s.word[len(prefix):]
+	s.flags |= flag
+	s.syllables--
+	return true
+}
+
+func (s *idStem) trimSuffix(suffix string) bool {
+	if !strings.HasSuffix(s.word, suffix) {
+		return false
+	}
+	s.word = s.word[:len(s.word)-len(suffix)]
+	s.syllables--
+	return true
+}
+
+func (s *idStem) removeParticle() {
+	if !s.long() {
+		return
+	}
+	switch {
+	case s.trimSuffix("kah"), s.trimSuffix("lah"), s.trimSuffix("pun"):
+	}
+}
+
+func (s *idStem) removePossessive() {
+	if !s.long() {
+		return
+	}
+	switch {
+	case s.trimSuffix("ku"), s.trimSuffix("mu"), s.trimSuffix("nya"):
+	}
+}
+
+func (s *idStem) removeFirstOrderPrefix() {
+	if !s.long() {
+		return
+	}
+	switch {
+	case s.trimPrefix("meng", "", removedMeng):
+	case s.vowelAt(4) && s.trimPrefix("meny", "s", removedMeng):
+	case s.vowelAt(3) && s.trimPrefix("men", "t", removedMeng):
+	case s.trimPrefix("men", "", removedMeng):
+	case s.trimPrefix("mem", "", removedMeng):
+	case s.trimPrefix("me", "", removedMeng):
+	case s.trimPrefix("peng", "", removedPeng):
+	case s.vowelAt(4) && s.trimPrefix("peny", "s", removedPeng):
+	case s.trimPrefix("peny", "", removedPeng):
+	case s.vowelAt(3) && s.trimPrefix("pen", "t", removedPeng):
+	case s.trimPrefix("pen", "", removedPeng):
+	case s.trimPrefix("pem", "", removedPeng):
+	case s.trimPrefix("di", "", removedDi):
+	case s.trimPrefix("ter", "", removedTer):
+	case s.trimPrefix("ke", "", removedKe):
+	}
+}
+
+func (s *idStem) removeSecondOrderPrefix() {
+	if !s.long() {
+		return
+	}
+	switch {
+	case s.trimPrefix("ber", "", removedBer):
+	case strings.HasPrefix(s.word, "belajar") && s.trimPrefix("bel", "", removedBer):
+	case len(s.word) > 4 && !s.vowelAt(2) && s.word[3:5] == "er" && s.trimPrefix("be", "", removedBer):
+	case s.trimPrefix("per", "", 0):
+	case strings.HasPrefix(s.word, "pelajar") && s.trimPrefix("pel", "", 0):
+	case s.trimPrefix("pe", "", removedPe):
+	}
+}
+
+func (s *idStem) removeSuffix() {
+	if !s.long() {
+		return
+	}
+	switch {
+	case s.flags&(removedKe|removedPeng|removedPe) == 0 && s.trimSuffix("kan"):
+	case s.flags&(removedDi|removedMeng|removedTer) == 0 && s.trimSuffix("an"):
+	case s.flags&(removedBer|removedKe|removedPeng) == 0 && !strings.HasSuffix(s.word, "si") && s.trimSuffix("i"):
+	}
+}
+
+// vowelAt reports whether the byte at i is a vowel
+func (s *idStem) vowelAt(i int) bool {
+	return vowelAt(s.word, i)
+}
+
+// vowelAt reports whether the byte of a word at i is a vowel
+func vowelAt(word string, i int) bool {
+	return i < len(word) && isVowel(rune(word[i]))
+}
+
+func isVowel(r rune) bool {
+	switch r {
+	case 'a', 'e', 'i', 'o', 'u':
+		return true
+	}
+	return false
+}
+
+// countVowels approximates the number of syllables of a word
+func countVowels(word string) int {
+	count := 0
+	for _, r := range word {
+		if isVowel(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// indonesianStemFilter stems the tokens of a stream in place
+type indonesianStemFilter struct{}
+
+func (indonesianStemFilter) Filter(input analysis.TokenStream) analysis.TokenStream {
+	for _, token := range input {
+		token.Term = []byte(StemIndonesian(string(token.Term)))
+	}
+	return input
+}
+
+func init() {
+	err := registry.RegisterTokenFilter(IndonesianStemmerName, func(map[string]interface{}, *registry.Cache) (analysis.TokenFilter, error) {
+		return indonesianStemFilter{}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,62 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStemIndonesian(t *testing.T) {
+	for word, want := range map[string]string{
+		"pembelajaran": "ajar",
+		"belajar":      "ajar",
+		"pelajaran":    "ajar",
+		"mengajar":     "ajar",
+		"membaca":      "baca",
+		"menulis":      "tulis",
+		"penelitian":   "teliti",
+		"peneliti":     "teliti",
+		"menyapu":      "sapu",
+		"penulisan":    "tulis",
+		"dimakan":      "makan",
+		"bukunya":      "buku",
+		"bermain":      "main",
+		"perekonomian": "ekonomi",
+		"kebersihan":   "bersih",
+		"bukulah":      "buku",
+		// Short words and words without affixes are kept
+		"buku":   "buku",
+		"data":   "data",
+		"sistem": "sistem",
+		"di":     "di",
+		"2020":   "2020",
+	} {
+		assert.Equal(t, want, StemIndonesian(word), word)
+	}
+}
+
+func TestStemIndonesianWithRoots(t *testing.T) {
+	// Derived forms stem to the root in the dictionary, whichever letter the prefix hid
+	for root, words := range map[string][]string{
+		"ekonomi":  {"ekonomi", "perekonomian"},
+		"pengaruh": {"pengaruh", "berpengaruh", "mempengaruhi"},
+		"perintah": {"perintah", "pemerintah", "pemerintahan"},
+		"terap":    {"terap", "penerapan", "terapan"},
+		"pegawai":  {"pegawai", "kepegawaian"},
+		"menteri":  {"menteri", "kementerian"},
+		"kerja":    {"kerja", "pekerjaan", "bekerja"},
+		"bijak":    {"bijak", "kebijakan"},
+		"tahu":     {"tahu", "pengetahuan", "mengetahui"},
+		"milik":    {"milik", "memiliki"},
+		"makan":    {"makan", "memakan", "dimakan"},
+		"pakai":    {"memakai", "pemakaian"},
+		"tulis":    {"menulis", "tulisannya"},
+	} {
+		for _, word := range words {
+			assert.Equal(t, root, StemIndonesian(word), word)
+		}
+	}
+
+	// Words whose root is not in the dictionary are stemmed by the rules alone
+	assert.Equal(t, "gombal", StemIndonesian("menggombal"))
+}
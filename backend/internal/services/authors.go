@@ -0,0 +1,234 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// AuthorMatchThreshold is the minimum AuthorNameSimilarity for two names to be
+// suggested as the same person
+const AuthorMatchThreshold = 0.6
+
+// AuthorNameSimilarity scores how likely two author names refer to the same person,
+// from 0 (unrelated) to 1 (same tokens). Initials match full given names, so
+// "B. Santoso" and "Budi Santoso" score highly.
+func AuthorNameSimilarity(a, b string) float64 {
+	tokensA, tokensB := utils.NameTokens(a), utils.NameTokens(b)
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0
+	}
+	if len(tokensA) > len(tokensB) {
+		tokensA, tokensB = tokensB, tokensA
+	}
+
+	used := make([]bool, len(tokensB))
+	var score float64
+	for _, ta := range tokensA {
+		best, bestIdx := 0.0, -1
+		for j, tb := range tokensB {
+			if used[j] {
+				continue
+			}
+			if s := tokenSimilarity(ta, tb); s > best {
+				best, bestIdx = s, j
+			}
+		}
+		if bestIdx >= 0 {
+			used[bestIdx] = true
+			score += best
+		}
+	}
+
+	return score / float64(len(tokensB))
+}
+
+// tokenSimilarity compares two name tokens, allowing initials and small typos
+func tokenSimilarity(a, b string) float64 {
+	switch {
+	case a == b:
+		return 1
+	case len(a) == 1 || len(b) == 1:
+		if a[0] == b[0] {
+			return 0.8
+		}
+		return 0
+	case len(a) >= 4 && len(b) >= 4 && levenshtein(a, b) <= 1:
+		return 0.9
+	default:
+		return 0
+	}
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, min(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// UnlinkMismatchedAuthors clears author links that were set to the uploader for
+// every co-author of an item. An entry keeps its link when the author name matches
+// the uploader's name or the link came from an approved authorship claim. It repairs
+// data saved before co-authors were matched by name, so it is run once through
+// RunDataMigration; both tables are repaired in one transaction.
+func UnlinkMismatchedAuthors(db *gorm.DB) error {
+	return db.Transaction(unlinkMismatchedAuthors)
+}
+
+func unlinkMismatchedAuthors(db *gorm.DB) error {
+	for _, itemType := range []string{"book", "paper"} {
+		var entries []struct {
+			ID         uint
+			AuthorName string
+			UserName   string
+		}
+		err := db.Table(itemType+"_authors ia").
+			Select("ia.id, ia.author_name, u.name AS user_name").
+			Joins("JOIN "+itemType+"s i ON i.id = ia."+itemType+"_id").
+			Joins("JOIN users u ON u.id = ia.user_id").
+			Where("ia.user_id = i.created_by").
+			Where("NOT EXISTS (SELECT 1 FROM authorship_claims ac WHERE ac.item_type = ? AND ac.item_id = ia."+itemType+"_id AND ac.author_name = ia.author_name AND ac.status = 'approved')", itemType).
+			Scan(&entries).Error
+		if err != nil {
+			return fmt.Errorf("failed to load %s authors: %w", itemType, err)
+		}
+
+		var mismatched []uint
+		for _, entry := range entries {
+			if AuthorNameSimilarity(entry.AuthorName, entry.UserName) < AuthorMatchThreshold {
+				mismatched = append(mismatched, entry.ID)
+			}
+		}
+		if len(mismatched) == 0 {
+			continue
+		}
+
+		if err := db.Table(itemType+"_authors").Where("id IN ?", mismatched).Update("user_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to unlink %s authors: %w", itemType, err)
+		}
+		log.Printf("[UnlinkMismatchedAuthors] Unlinked %d %s author entries from their uploader", len(mismatched), itemType)
+	}
+	return nil
+}
+
+// ResolveAuthorID returns the authority record a published author name belongs to.
+// A name that matches no known variant gets a new record with itself as the preferred name.
+func ResolveAuthorID(db *gorm.DB, name string) (*uint, error) {
+	name = strings.TrimSpace(name)
+	normalized := utils.NameKey(name)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	db = db.Session(&gorm.Session{NewDB: true})
+	find := func() (*uint, error) {
+		var variants []models.AuthorVariant
+		if err := db.Where("normalized = ?", normalized).Limit(1).Find(&variants).Error; err != nil {
+			return nil, err
+		}
+		if len(variants) == 0 {
+			return nil, nil
+		}
+		return &variants[0].AuthorID, nil
+	}
+
+	if authorID, err := find(); err != nil || authorID != nil {
+		return authorID, err
+	}
+
+	author := models.Author{
+		PreferredName: name,
+		Variants:      []models.AuthorVariant{{Name: name, Normalized: normalized}},
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&author).Error
+	})
+	if err != nil {
+		// Another request may have created the same variant meanwhile
+		if authorID, findErr := find(); findErr == nil && authorID != nil {
+			return authorID, nil
+		}
+		return nil, err
+	}
+	return &author.ID, nil
+}
+
+// CreateBookAuthor saves an author entry of a book, linking it to the authority record
+// of its name unless it was assigned one
+func CreateBookAuthor(tx *gorm.DB, entry *models.BookAuthor) error {
+	if entry.AuthorID == nil {
+		authorID, err := ResolveAuthorID(tx, entry.AuthorName)
+		if err != nil {
+			return err
+		}
+		entry.AuthorID = authorID
+	}
+	return tx.Create(entry).Error
+}
+
+// CreatePaperAuthor saves an author entry of a paper, linking it to the authority
+// record of its name unless it was assigned one
+func CreatePaperAuthor(tx *gorm.DB, entry *models.PaperAuthor) error {
+	if entry.AuthorID == nil {
+		authorID, err := ResolveAuthorID(tx, entry.AuthorName)
+		if err != nil {
+			return err
+		}
+		entry.AuthorID = authorID
+	}
+	return tx.Create(entry).Error
+}
+
+// BackfillAuthorRecords links author entries created before authority records existed
+// to a record resolved from their name. Linked entries are skipped, so it is safe to
+// run on every startup.
+func BackfillAuthorRecords(db *gorm.DB) error {
+	for _, itemType := range []string{"book", "paper"} {
+		var names []string
+		if err := db.Table(itemType+"_authors").Where("author_id IS NULL").
+			Distinct("author_name").Pluck("author_name", &names).Error; err != nil {
+			return fmt.Errorf("failed to load unlinked %s authors: %w", itemType, err)
+		}
+
+		linked := 0
+		for _, name := range names {
+			authorID, err := ResolveAuthorID(db, name)
+			if err != nil {
+				return fmt.Errorf("failed to resolve author %q: %w", name, err)
+			}
+			if authorID == nil {
+				continue
+			}
+			if err := db.Table(itemType+"_authors").Where("author_id IS NULL AND author_name = ?", name).
+				Update("author_id", *authorID).Error; err != nil {
+				return fmt.Errorf("failed to link %s author %q: %w", itemType, name, err)
+			}
+			linked++
+		}
+		if linked > 0 {
+			log.Printf("[BackfillAuthorRecords] Linked %d %s author names to authority records", linked, itemType)
+		}
+	}
+	return nil
+}
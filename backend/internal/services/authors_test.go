@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorNameSimilarity(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		match bool
+	}{
+		{name: "Identical", a: "Budi Santoso", b: "Budi Santoso", match: true},
+		{name: "Inverted", a: "Santoso, Budi", b: "Budi Santoso", match: true},
+		{name: "Initial", a: "B. Santoso", b: "Budi Santoso", match: true},
+		{name: "Typo", a: "Budi Santosa", b: "Budi Santoso", match: true},
+		{name: "With degrees", a: "Budi Santoso, S.Kom., M.Kom.", b: "budi santoso", match: true},
+		{name: "Different given name", a: "Andi Santoso", b: "Budi Santoso", match: false},
+		{name: "Unrelated", a: "Siti Aminah", b: "Budi Santoso", match: false},
+		{name: "Empty", a: "", b: "Budi Santoso", match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := AuthorNameSimilarity(tt.a, tt.b)
+			assert.Equal(t, tt.match, score >= AuthorMatchThreshold, "score %.2f", score)
+		})
+	}
+}
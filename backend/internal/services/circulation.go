@@ -0,0 +1,407 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Circulation errors returned to librarians and members
+var (
+	ErrCopyUnavailable      = errors.New("copy is not available for loan")
+	ErrLoanLimitReached     = errors.New("member has reached their loan limit")
+	ErrMemberHasOverdue     = errors.New("member has overdue loans")
+	ErrMemberHasUnpaidFines = errors.New("member has unpaid fines")
+	ErrLoanReturned         = errors.New("loan has already been returned")
+	ErrLoanOverdue          = errors.New("overdue loans cannot be renewed")
+	ErrRenewalLimitReached  = errors.New("loan has reached its renewal limit")
+	ErrLoanChanged          = errors.New("loan was changed by another request, please try again")
+)
+
+// HolidaySet holds the library's closed days keyed by YYYY-MM-DD
+type HolidaySet map[string]bool
+
+// calendarDay returns the calendar date of t as midnight UTC, so day arithmetic is
+// unaffected by time zones and daylight saving
+func calendarDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// NextOpenDay returns date itself or, when the library is closed that day, the first
+// open day after it
+func NextOpenDay(date time.Time, holidays HolidaySet) time.Time {
+	day := calendarDay(date)
+	for holidays[day.Format(utils.DateLayout)] {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// ChargeableOverdueDays counts the open days after due up to and including until.
+// Holidays are not charged.
+func ChargeableOverdueDays(due, until time.Time, holidays HolidaySet) int {
+	days := 0
+	last := calendarDay(until)
+	for day := calendarDay(due).AddDate(0, 0, 1); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if !holidays[day.Format(utils.DateLayout)] {
+			days++
+		}
+	}
+	return days
+}
+
+// LoadHolidays loads the library holidays from a date onwards
+func LoadHolidays(db *gorm.DB, from time.Time) (HolidaySet, error) {
+	var dates []time.Time
+	if err := db.Model(&models.Holiday{}).Where("date >= ?", calendarDay(from).Format(utils.DateLayout)).
+		Pluck("date", &dates).Error; err != nil {
+		return nil, err
+	}
+	holidays := make(HolidaySet, len(dates))
+	for _, date := range dates {
+		holidays[calendarDay(date).Format(utils.DateLayout)] = true
+	}
+	return holidays, nil
+}
+
+// LoanRuleFor returns the loan rule of a user type
+func LoanRuleFor(db *gorm.DB, userType string) (models.LoanRule, error) {
+	var rule models.LoanRule
+	if err := db.Where("user_type = ?", userType).First(&rule).Error; err != nil {
+		return rule, fmt.Errorf("no loan rule for user type %q: %w", userType, err)
+	}
+	return rule, nil
+}
+
+// dueDateFrom returns the due date of a loan period starting on start
+func dueDateFrom(db *gorm.DB, start time.Time, loanDays int) (time.Time, error) {
+	holidays, err := LoadHolidays(db, start)
+	if err != nil {
+		return time.Time{}, err
+	}
+	due := NextOpenDay(calendarDay(start).AddDate(0, 0, loanDays), holidays)
+	// DATE columns are read and written in the server's local time zone
+	return time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.Local), nil
+}
+
+// today returns the current calendar date formatted for SQL comparisons
+func today() string {
+	return calendarDay(time.Now()).Format(utils.DateLayout)
+}
+
+// AccruedFine returns the fine an open loan has accrued so far, or the fixed fine of a
+// returned loan
+func AccruedFine(db *gorm.DB, loan models.Loan, rule models.LoanRule) (days int, fine float64, err error) {
+	if loan.ReturnedAt != nil {
+		return 0, loan.FineAmount, nil
+	}
+	holidays, err := LoadHolidays(db, loan.DueDate)
+	if err != nil {
+		return 0, 0, err
+	}
+	days = ChargeableOverdueDays(loan.DueDate, time.Now(), holidays)
+	return days, float64(days) * rule.FinePerDay, nil
+}
+
+// Checkout lends a copy to a member. The copy row is locked so two librarians can't
+// lend the same copy at once.
+func Checkout(db *gorm.DB, copyID uint, member models.User, staffID uint) (*models.Loan, error) {
+	rule, err := LoanRuleFor(db, member.UserType)
+	if err != nil {
+		return nil, err
+	}
+
+	var loan models.Loan
+	var released []uint
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var bookCopy models.Copy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bookCopy, copyID).Error; err != nil {
+			return err
+		}
+		if bookCopy.Status != "available" {
+			return ErrCopyUnavailable
+		}
+		if hold := TrappedHold(tx, bookCopy.ID); hold != nil && hold.UserID != member.ID {
+			return ErrCopyOnHold
+		}
+
+		var active, overdue, unpaid int64
+		if err := tx.Model(&models.Loan{}).Where("user_id = ? AND returned_at IS NULL", member.ID).Count(&active).Error; err != nil {
+			return err
+		}
+		if int(active) >= rule.MaxLoans {
+			return ErrLoanLimitReached
+		}
+		if err := tx.Model(&models.Loan{}).Where("user_id = ? AND returned_at IS NULL AND due_date < ?", member.ID, today()).Count(&overdue).Error; err != nil {
+			return err
+		}
+		if overdue > 0 {
+			return ErrMemberHasOverdue
+		}
+		if err := tx.Model(&models.Loan{}).Where("user_id = ? AND fine_status = 'unpaid'", member.ID).Count(&unpaid).Error; err != nil {
+			return err
+		}
+		if unpaid > 0 {
+			return ErrMemberHasUnpaidFines
+		}
+
+		now := time.Now()
+		due, err := dueDateFrom(tx, now, rule.LoanDays)
+		if err != nil {
+			return err
+		}
+		loan = models.Loan{
+			CopyID:       bookCopy.ID,
+			UserID:       member.ID,
+			LoanedAt:     now,
+			DueDate:      due,
+			FineStatus:   "none",
+			CheckedOutBy: &staffID,
+		}
+		if err := tx.Create(&loan).Error; err != nil {
+			return err
+		}
+		if released, err = fulfilHolds(tx, member.ID, bookCopy.BookID, bookCopy.ID); err != nil {
+			return err
+		}
+		return tx.Model(&bookCopy).Update("status", "on_loan").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Other copies set aside for the member are no longer needed
+	for _, copyID := range released {
+		if _, err := TrapCopy(db, copyID); err != nil {
+			log.Printf("[Checkout] Failed to pass copy %d to the next hold: %v", copyID, err)
+		}
+	}
+	return &loan, nil
+}
+
+// Checkin closes a loan, fixing its fine from the open days it was overdue. The copy
+// becomes available again, or damaged when the librarian says so. An available copy
+// is trapped for the next hold on its book, which is returned.
+func Checkin(db *gorm.DB, loan *models.Loan, staffID uint, damaged bool) (*models.Hold, error) {
+	copyStatus := "available"
+	if damaged {
+		copyStatus = "damaged"
+	}
+
+	var trapped *models.Hold
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The loan row is locked so a copy scanned twice isn't checked in twice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(loan, loan.ID).Error; err != nil {
+			return err
+		}
+		if loan.ReturnedAt != nil {
+			return ErrLoanReturned
+		}
+
+		var member models.User
+		if err := tx.Select("id", "user_type").First(&member, loan.UserID).Error; err != nil {
+			return err
+		}
+		rule, err := LoanRuleFor(tx, member.UserType)
+		if err != nil {
+			return err
+		}
+		_, fine, err := AccruedFine(tx, *loan, rule)
+		if err != nil {
+			return err
+		}
+		fineStatus := "none"
+		if fine > 0 {
+			fineStatus = "unpaid"
+		}
+
+		if err := tx.Model(loan).Updates(map[string]interface{}{
+			"returned_at":   time.Now(),
+			"fine_amount":   fine,
+			"fine_status":   fineStatus,
+			"checked_in_by": staffID,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Copy{}).Where("id = ?", loan.CopyID).Update("status", copyStatus).Error; err != nil {
+			return err
+		}
+		if damaged {
+			return nil
+		}
+		trapped, err = TrapCopy(tx, loan.CopyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trapped, nil
+}
+
+// Renew extends an open loan by a full loan period from today
+func Renew(db *gorm.DB, loan *models.Loan) error {
+	if loan.ReturnedAt != nil {
+		return ErrLoanReturned
+	}
+	if calendarDay(loan.DueDate).Format(utils.DateLayout) < today() {
+		return ErrLoanOverdue
+	}
+
+	var member models.User
+	if err := db.Select("id", "user_type").First(&member, loan.UserID).Error; err != nil {
+		return err
+	}
+	rule, err := LoanRuleFor(db, member.UserType)
+	if err != nil {
+		return err
+	}
+	if loan.RenewalCount >= rule.MaxRenewals {
+		return ErrRenewalLimitReached
+	}
+
+	var waiting int64
+	if err := db.Model(&models.Hold{}).Joins("JOIN copies ON copies.book_id = holds.book_id").
+		Where("copies.id = ? AND holds.status = 'waiting'", loan.CopyID).Count(&waiting).Error; err != nil {
+		return err
+	}
+	if waiting > 0 {
+		return ErrBookHasHolds
+	}
+
+	due, err := dueDateFrom(db, time.Now(), rule.LoanDays)
+	if err != nil {
+		return err
+	}
+	// Only the loan as it was read is renewed, so two renewals at once can't both
+	// pass the limit and a loan returned meanwhile stays returned
+	result := db.Model(loan).Where("returned_at IS NULL AND renewal_count = ?", loan.RenewalCount).
+		Updates(map[string]interface{}{
+			"due_date":      due,
+			"renewal_count": loan.RenewalCount + 1,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLoanChanged
+	}
+	return nil
+}
+
+// HasOpenCirculation reports whether a member still has books on loan or unpaid fines
+func HasOpenCirculation(db *gorm.DB, userID uint) bool {
+	var count int64
+	db.Model(&models.Loan{}).Where("user_id = ? AND (returned_at IS NULL OR fine_status = 'unpaid')", userID).Count(&count)
+	return count > 0
+}
+
+// DeleteUserLoans removes a member's closed loan history
+func DeleteUserLoans(db *gorm.DB, userID uint) error {
+	return db.Where("user_id = ? AND returned_at IS NOT NULL", userID).Delete(&models.Loan{}).Error
+}
+
+// FormatRupiah formats an amount as Indonesian rupiah, e.g. "Rp 12.500"
+func FormatRupiah(amount float64) string {
+	digits := strconv.FormatInt(int64(amount+0.5), 10)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(digit)
+	}
+	return "Rp " + b.String()
+}
+
+// SendOverdueReminders emails every member with overdue loans a list of them, at most
+// once a day. It does nothing when email is not configured.
+func SendOverdueReminders(db *gorm.DB) error {
+	emailConfig, err := configs.LoadEmailConfig()
+	if err != nil {
+		log.Printf("[SendOverdueReminders] Email is not configured, skipping: %v", err)
+		return nil
+	}
+
+	startOfToday := utils.StartOfDay(time.Now())
+	var loans []models.Loan
+	if err := db.Preload("Copy.Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title")
+	}).Preload("User").
+		Where("returned_at IS NULL AND due_date < ?", today()).
+		Where("last_reminded_at IS NULL OR last_reminded_at < ?", startOfToday).
+		Order("user_id, due_date").Find(&loans).Error; err != nil {
+		return fmt.Errorf("failed to load overdue loans: %w", err)
+	}
+
+	byMember := make(map[uint][]models.Loan)
+	var memberOrder []uint
+	for _, loan := range loans {
+		if _, seen := byMember[loan.UserID]; !seen {
+			memberOrder = append(memberOrder, loan.UserID)
+		}
+		byMember[loan.UserID] = append(byMember[loan.UserID], loan)
+	}
+
+	rules := make(map[string]models.LoanRule)
+	sent := 0
+	for _, userID := range memberOrder {
+		memberLoans := byMember[userID]
+		member := memberLoans[0].User
+		if member == nil {
+			continue
+		}
+		rule, ok := rules[member.UserType]
+		if !ok {
+			if rule, err = LoanRuleFor(db, member.UserType); err != nil {
+				return err
+			}
+			rules[member.UserType] = rule
+		}
+
+		notices := make([]utils.OverdueLoanNotice, 0, len(memberLoans))
+		ids := make([]uint, 0, len(memberLoans))
+		for _, loan := range memberLoans {
+			days, fine, err := AccruedFine(db, loan, rule)
+			if err != nil {
+				return err
+			}
+			notice := utils.OverdueLoanNotice{
+				DueDate:     calendarDay(loan.DueDate).Format(utils.DateLayout),
+				DaysOverdue: days,
+				Fine:        FormatRupiah(fine),
+			}
+			if loan.Copy != nil {
+				notice.Barcode = loan.Copy.Barcode
+				if loan.Copy.Book != nil {
+					notice.Title = loan.Copy.Book.Title
+				}
+			}
+			notices = append(notices, notice)
+			ids = append(ids, loan.ID)
+		}
+
+		if err := utils.SendOverdueReminderEmail(member.Email, member.Name, notices, emailConfig); err != nil {
+			log.Printf("[SendOverdueReminders] Failed to email user %d: %v", userID, err)
+			continue
+		}
+		if err := db.Model(&models.Loan{}).Where("id IN ?", ids).Update("last_reminded_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to record reminders: %w", err)
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		log.Printf("[SendOverdueReminders] Sent overdue reminders to %d members", sent)
+	}
+	return nil
+}
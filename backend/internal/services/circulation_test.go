@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDate(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestChargeableOverdueDays(t *testing.T) {
+	holidays := HolidaySet{"2026-08-17": true, "2026-08-18": true}
+
+	assert.Equal(t, 0, ChargeableOverdueDays(testDate("2026-08-10"), testDate("2026-08-10"), holidays))
+	assert.Equal(t, 0, ChargeableOverdueDays(testDate("2026-08-10"), testDate("2026-08-05"), holidays))
+	assert.Equal(t, 3, ChargeableOverdueDays(testDate("2026-08-10"), testDate("2026-08-13"), nil))
+	// The 17th and 18th are holidays, so only the 16th and 19th are charged
+	assert.Equal(t, 2, ChargeableOverdueDays(testDate("2026-08-15"), testDate("2026-08-19"), holidays))
+	// Time of day does not matter
+	assert.Equal(t, 1, ChargeableOverdueDays(testDate("2026-08-10"), testDate("2026-08-11").Add(23*time.Hour), nil))
+}
+
+func TestNextOpenDay(t *testing.T) {
+	holidays := HolidaySet{"2026-08-17": true, "2026-08-18": true}
+
+	assert.Equal(t, testDate("2026-08-16"), NextOpenDay(testDate("2026-08-16"), holidays))
+	assert.Equal(t, testDate("2026-08-19"), NextOpenDay(testDate("2026-08-17"), holidays))
+}
+
+func TestFormatRupiah(t *testing.T) {
+	assert.Equal(t, "Rp 0", FormatRupiah(0))
+	assert.Equal(t, "Rp 500", FormatRupiah(500))
+	assert.Equal(t, "Rp 12.500", FormatRupiah(12500))
+	assert.Equal(t, "Rp 1.250.000", FormatRupiah(1250000))
+}
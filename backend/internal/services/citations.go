@@ -0,0 +1,746 @@
+package services
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// CitationItem holds the bibliographic fields of a book or paper used to format citations
+type CitationItem struct {
+	Type       string
+	ID         uint
+	Title      string
+	Authors    []string
+	Year       *int
+	Publisher  string
+	ISBN       string
+	Journal    string
+	Volume     string
+	Issue      string
+	Pages      string
+	ISSN       string
+	DOI        string
+	University string
+	Language   string
+}
+
+// citationFormat describes how a citation export is rendered and served
+type citationFormat struct {
+	ContentType string
+	Extension   string
+	render      func(items []CitationItem) string
+}
+
+// citationFormats are the supported export formats keyed by their query parameter value
+var citationFormats = map[string]citationFormat{
+	"bibtex":   {ContentType: "application/x-bibtex; charset=utf-8", Extension: "bib", render: renderBibTeX},
+	"ris":      {ContentType: "application/x-research-info-systems; charset=utf-8", Extension: "ris", render: renderRIS},
+	"endnote":  {ContentType: "application/xml; charset=utf-8", Extension: "xml", render: renderEndNoteXML},
+	"csl-json": {ContentType: "application/vnd.citationstyles.csl+json; charset=utf-8", Extension: "json", render: renderCSLJSON},
+	"apa":      {ContentType: "text/plain; charset=utf-8", Extension: "txt", render: renderAPA},
+	"ieee":     {ContentType: "text/plain; charset=utf-8", Extension: "txt", render: renderIEEE},
+	"mla":      {ContentType: "text/plain; charset=utf-8", Extension: "txt", render: renderMLA},
+	"chicago":  {ContentType: "text/plain; charset=utf-8", Extension: "txt", render: renderChicago},
+}
+
+// CitationFormats returns the names of the supported citation export formats
+func CitationFormats() []string {
+	names := make([]string, 0, len(citationFormats))
+	for name := range citationFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatCitations renders items in the given format and returns the document with its
+// content type and file extension
+func FormatCitations(items []CitationItem, format string) (body, contentType, extension string, err error) {
+	f, ok := citationFormats[strings.ToLower(format)]
+	if !ok {
+		return "", "", "", fmt.Errorf("unsupported citation format %q (supported: %s)", format, strings.Join(CitationFormats(), ", "))
+	}
+	return f.render(items), f.ContentType, f.Extension, nil
+}
+
+// BookCitationItem converts a book (with Authors preloaded) into a CitationItem
+func BookCitationItem(book models.Book) CitationItem {
+	names := make([]string, 0, len(book.Authors))
+	for _, author := range book.Authors {
+		names = append(names, author.AuthorName)
+	}
+	if len(names) == 0 && strings.TrimSpace(book.Author) != "" {
+		names = append(names, book.Author)
+	}
+
+	return CitationItem{
+		Type:      "book",
+		ID:        book.ID,
+		Title:     book.Title,
+		Authors:   names,
+		Year:      book.PublishedYear,
+		Publisher: utils.StringValue(book.Publisher),
+		ISBN:      utils.StringValue(book.ISBN),
+		Language:  utils.StringValue(book.Language),
+	}
+}
+
+// PaperCitationItem converts a paper (with Authors preloaded) into a CitationItem
+func PaperCitationItem(paper models.Paper) CitationItem {
+	names := make([]string, 0, len(paper.Authors))
+	for _, author := range paper.Authors {
+		names = append(names, author.AuthorName)
+	}
+	if len(names) == 0 && strings.TrimSpace(paper.Author) != "" {
+		names = append(names, paper.Author)
+	}
+
+	item := CitationItem{
+		Type:       "paper",
+		ID:         paper.ID,
+		Title:      paper.Title,
+		Authors:    names,
+		Year:       paper.Year,
+		Journal:    utils.StringValue(paper.Journal),
+		Pages:      utils.StringValue(paper.Pages),
+		ISSN:       utils.StringValue(paper.ISSN),
+		DOI:        utils.StringValue(paper.DOI),
+		University: utils.StringValue(paper.University),
+		Language:   utils.StringValue(paper.Language),
+	}
+	if paper.Volume != nil {
+		item.Volume = strconv.Itoa(*paper.Volume)
+	}
+	if paper.Issue != nil {
+		item.Issue = strconv.Itoa(*paper.Issue)
+	}
+	return item
+}
+
+// isArticle reports whether a paper was published in a journal rather than being a thesis
+func (item CitationItem) isArticle() bool {
+	return item.Type == "paper" && item.Journal != ""
+}
+
+// renderBibTeX renders items as BibTeX entries keyed by first author surname and year
+func renderBibTeX(items []CitationItem) string {
+	var b strings.Builder
+	used := make(map[string]int)
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		key := "item"
+		if len(item.Authors) > 0 {
+			if parts := utils.NameTokens(item.Authors[0]); len(parts) > 0 {
+				key = parts[len(parts)-1]
+			}
+		}
+		if item.Year != nil {
+			key += strconv.Itoa(*item.Year)
+		}
+		// Disambiguate repeated keys as santoso2020, santoso2020a, santoso2020b...
+		if n := used[key]; n > 0 {
+			used[key]++
+			key += string(rune('a' + n - 1))
+		} else {
+			used[key] = 1
+		}
+
+		entryType := "book"
+		switch {
+		case item.isArticle():
+			entryType = "article"
+		case item.Type == "paper":
+			entryType = "misc"
+		}
+
+		fmt.Fprintf(&b, "@%s{%s,\n", entryType, key)
+		field := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "  %s = {%s},\n", name, bibtexEscape(value))
+			}
+		}
+		field("title", item.Title)
+		field("author", strings.Join(item.Authors, " and "))
+		if item.Year != nil {
+			field("year", strconv.Itoa(*item.Year))
+		}
+		field("publisher", item.Publisher)
+		field("isbn", item.ISBN)
+		field("journal", item.Journal)
+		field("volume", item.Volume)
+		field("number", item.Issue)
+		field("pages", strings.ReplaceAll(item.Pages, "-", "--"))
+		field("issn", item.ISSN)
+		field("doi", item.DOI)
+		if item.Type == "paper" && !item.isArticle() {
+			field("howpublished", item.University)
+		}
+		field("language", item.Language)
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// bibtexEscape escapes the characters BibTeX treats specially
+func bibtexEscape(s string) string {
+	return strings.NewReplacer(`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`).Replace(s)
+}
+
+// renderRIS renders items as RIS records
+func renderRIS(items []CitationItem) string {
+	var b strings.Builder
+	for _, item := range items {
+		tag := func(name, value string) {
+			if value != "" {
+				fmt.Fprintf(&b, "%s  - %s\r\n", name, value)
+			}
+		}
+
+		switch {
+		case item.Type == "book":
+			tag("TY", "BOOK")
+		case item.isArticle():
+			tag("TY", "JOUR")
+		default:
+			tag("TY", "THES")
+		}
+		for _, author := range item.Authors {
+			tag("AU", author)
+		}
+		tag("TI", item.Title)
+		if item.Year != nil {
+			tag("PY", strconv.Itoa(*item.Year))
+		}
+		tag("PB", item.Publisher)
+		if item.Type == "paper" && !item.isArticle() {
+			tag("PB", item.University)
+		}
+		tag("JO", item.Journal)
+		tag("VL", item.Volume)
+		tag("IS", item.Issue)
+		if start, end, found := strings.Cut(item.Pages, "-"); found {
+			tag("SP", strings.TrimSpace(start))
+			tag("EP", strings.TrimSpace(end))
+		} else {
+			tag("SP", item.Pages)
+		}
+		tag("SN", item.ISBN)
+		tag("SN", item.ISSN)
+		tag("DO", item.DOI)
+		tag("LA", item.Language)
+		b.WriteString("ER  - \r\n")
+	}
+	return b.String()
+}
+
+// renderAPA renders items as APA 7 reference list entries, one per line
+func renderAPA(items []CitationItem) string {
+	var b strings.Builder
+	for _, item := range items {
+		var ref strings.Builder
+		if authors := apaAuthors(item.Authors); authors != "" {
+			ref.WriteString(authors)
+			ref.WriteString(" ")
+		}
+		if item.Year != nil {
+			fmt.Fprintf(&ref, "(%d). ", *item.Year)
+		} else {
+			ref.WriteString("(n.d.). ")
+		}
+		ref.WriteString(strings.TrimSuffix(item.Title, "."))
+		ref.WriteString(".")
+
+		switch {
+		case item.isArticle():
+			fmt.Fprintf(&ref, " %s", item.Journal)
+			if item.Volume != "" {
+				fmt.Fprintf(&ref, ", %s", item.Volume)
+				if item.Issue != "" {
+					fmt.Fprintf(&ref, "(%s)", item.Issue)
+				}
+			}
+			if item.Pages != "" {
+				fmt.Fprintf(&ref, ", %s", item.Pages)
+			}
+			ref.WriteString(".")
+		case item.Type == "book" && item.Publisher != "":
+			fmt.Fprintf(&ref, " %s.", item.Publisher)
+		case item.Type == "paper" && item.University != "":
+			fmt.Fprintf(&ref, " %s.", item.University)
+		}
+		if item.DOI != "" {
+			fmt.Fprintf(&ref, " https://doi.org/%s", strings.TrimPrefix(item.DOI, "https://doi.org/"))
+		}
+
+		b.WriteString(ref.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// apaAuthors formats author names as "Santoso, B., Wijaya, A. R., & Putri, D."
+func apaAuthors(authors []string) string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		parts := utils.NameParts(author)
+		if len(parts) == 0 {
+			continue
+		}
+		name := parts[len(parts)-1]
+		if len(parts) > 1 {
+			initials := make([]string, 0, len(parts)-1)
+			for _, given := range parts[:len(parts)-1] {
+				initials = append(initials, strings.ToUpper(string([]rune(given)[0]))+".")
+			}
+			name += ", " + strings.Join(initials, " ")
+		}
+		names = append(names, name)
+	}
+
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + ", & " + names[1]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + ", & " + names[len(names)-1]
+	}
+}
+
+// personName splits an author name into given names and family name. A single-word
+// name, common in Indonesia, is all family name. Initials get back their period.
+func personName(author string) (given, family string) {
+	parts := utils.NameParts(author)
+	if len(parts) == 0 {
+		return "", strings.TrimSpace(author)
+	}
+	givenNames := parts[:len(parts)-1]
+	for i, name := range givenNames {
+		if len([]rune(name)) == 1 {
+			givenNames[i] = name + "."
+		}
+	}
+	return strings.Join(givenNames, " "), parts[len(parts)-1]
+}
+
+// invertedName formats a name as "Santoso, Budi"
+func invertedName(author string) string {
+	given, family := personName(author)
+	if given == "" {
+		return family
+	}
+	return family + ", " + given
+}
+
+// joinNames joins names as "A, B, and C", or "A and B" for two
+func joinNames(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " and " + names[1]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + ", and " + names[len(names)-1]
+	}
+}
+
+// doiURL returns the resolver address of a DOI
+func doiURL(doi string) string {
+	return "https://doi.org/" + strings.TrimPrefix(doi, "https://doi.org/")
+}
+
+// yearText returns the year of an item, or "n.d." when it is unknown
+func yearText(item CitationItem) string {
+	if item.Year == nil {
+		return "n.d."
+	}
+	return strconv.Itoa(*item.Year)
+}
+
+// ieeeMaxAuthors is the number of authors IEEE lists before shortening to "et al."
+const ieeeMaxAuthors = 6
+
+// renderIEEE renders items as a numbered IEEE reference list
+func renderIEEE(items []CitationItem) string {
+	var b strings.Builder
+	for i, item := range items {
+		names := make([]string, 0, len(item.Authors))
+		for _, author := range item.Authors {
+			given, family := personName(author)
+			var initials []string
+			for _, part := range strings.Fields(given) {
+				initials = append(initials, strings.ToUpper(string([]rune(part)[0]))+".")
+			}
+			names = append(names, strings.TrimSpace(strings.Join(initials, " ")+" "+family))
+		}
+		authors := joinNames(names)
+		if len(names) > ieeeMaxAuthors {
+			authors = names[0] + " et al."
+		}
+
+		fmt.Fprintf(&b, "[%d] ", i+1)
+		if authors != "" {
+			b.WriteString(authors + ", ")
+		}
+		title := strings.TrimSuffix(item.Title, ".")
+		switch {
+		case item.isArticle():
+			fmt.Fprintf(&b, "\"%s,\" %s", title, item.Journal)
+			if item.Volume != "" {
+				fmt.Fprintf(&b, ", vol. %s", item.Volume)
+			}
+			if item.Issue != "" {
+				fmt.Fprintf(&b, ", no. %s", item.Issue)
+			}
+			if item.Pages != "" {
+				fmt.Fprintf(&b, ", pp. %s", item.Pages)
+			}
+			fmt.Fprintf(&b, ", %s", yearText(item))
+			if item.DOI != "" {
+				fmt.Fprintf(&b, ", doi: %s", strings.TrimPrefix(item.DOI, "https://doi.org/"))
+			}
+			b.WriteString(".")
+		case item.Type == "paper":
+			fmt.Fprintf(&b, "\"%s,\" Thesis", title)
+			if item.University != "" {
+				fmt.Fprintf(&b, ", %s", item.University)
+			}
+			fmt.Fprintf(&b, ", %s.", yearText(item))
+		default:
+			b.WriteString(title + ".")
+			if item.Publisher != "" {
+				fmt.Fprintf(&b, " %s,", item.Publisher)
+			}
+			fmt.Fprintf(&b, " %s.", yearText(item))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderMLA renders items as MLA 9 works cited entries, one per line
+func renderMLA(items []CitationItem) string {
+	var b strings.Builder
+	for _, item := range items {
+		switch len(item.Authors) {
+		case 0:
+		case 1:
+			b.WriteString(strings.TrimSuffix(invertedName(item.Authors[0]), ".") + ". ")
+		case 2:
+			given, family := personName(item.Authors[1])
+			fmt.Fprintf(&b, "%s, and %s. ", invertedName(item.Authors[0]), strings.TrimSpace(given+" "+family))
+		default:
+			fmt.Fprintf(&b, "%s, et al. ", invertedName(item.Authors[0]))
+		}
+
+		title := strings.TrimSuffix(item.Title, ".")
+		switch {
+		case item.isArticle():
+			fmt.Fprintf(&b, "\"%s.\" %s", title, item.Journal)
+			if item.Volume != "" {
+				fmt.Fprintf(&b, ", vol. %s", item.Volume)
+			}
+			if item.Issue != "" {
+				fmt.Fprintf(&b, ", no. %s", item.Issue)
+			}
+			if item.Year != nil {
+				fmt.Fprintf(&b, ", %d", *item.Year)
+			}
+			if item.Pages != "" {
+				fmt.Fprintf(&b, ", pp. %s", item.Pages)
+			}
+			b.WriteString(".")
+			if item.DOI != "" {
+				b.WriteString(" " + doiURL(item.DOI) + ".")
+			}
+		case item.Type == "paper":
+			fmt.Fprintf(&b, "\"%s.\"", title)
+			if item.Year != nil {
+				fmt.Fprintf(&b, " %d.", *item.Year)
+			}
+			if item.University != "" {
+				fmt.Fprintf(&b, " %s,", item.University)
+			}
+			b.WriteString(" Thesis.")
+		default:
+			b.WriteString(title + ".")
+			var publication []string
+			if item.Publisher != "" {
+				publication = append(publication, item.Publisher)
+			}
+			if item.Year != nil {
+				publication = append(publication, strconv.Itoa(*item.Year))
+			}
+			if len(publication) > 0 {
+				b.WriteString(" " + strings.Join(publication, ", ") + ".")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderChicago renders items as Chicago (notes and bibliography) bibliography entries,
+// one per line
+func renderChicago(items []CitationItem) string {
+	var b strings.Builder
+	for _, item := range items {
+		names := make([]string, 0, len(item.Authors))
+		for i, author := range item.Authors {
+			if i == 0 {
+				names = append(names, invertedName(author))
+			} else {
+				given, family := personName(author)
+				names = append(names, strings.TrimSpace(given+" "+family))
+			}
+		}
+		if authors := joinNames(names); authors != "" {
+			if len(names) == 2 {
+				// The first name is inverted, so a comma also separates two authors
+				authors = names[0] + ", and " + names[1]
+			}
+			b.WriteString(strings.TrimSuffix(authors, ".") + ". ")
+		}
+
+		title := strings.TrimSuffix(item.Title, ".")
+		switch {
+		case item.isArticle():
+			fmt.Fprintf(&b, "\"%s.\" %s", title, item.Journal)
+			if item.Volume != "" {
+				fmt.Fprintf(&b, " %s", item.Volume)
+			}
+			if item.Issue != "" {
+				fmt.Fprintf(&b, ", no. %s", item.Issue)
+			}
+			fmt.Fprintf(&b, " (%s)", yearText(item))
+			if item.Pages != "" {
+				fmt.Fprintf(&b, ": %s", item.Pages)
+			}
+			b.WriteString(".")
+			if item.DOI != "" {
+				b.WriteString(" " + doiURL(item.DOI) + ".")
+			}
+		case item.Type == "paper":
+			fmt.Fprintf(&b, "\"%s.\" Thesis", title)
+			if item.University != "" {
+				fmt.Fprintf(&b, ", %s", item.University)
+			}
+			fmt.Fprintf(&b, ", %s.", yearText(item))
+		default:
+			b.WriteString(title + ".")
+			if item.Publisher != "" {
+				fmt.Fprintf(&b, " %s,", item.Publisher)
+			}
+			fmt.Fprintf(&b, " %s.", yearText(item))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// cslName is a name in CSL-JSON
+type cslName struct {
+	Family string `json:"family,omitempty"`
+	Given  string `json:"given,omitempty"`
+}
+
+// cslItem is an item in CSL-JSON, the input format of citeproc processors
+type cslItem struct {
+	ID             string             `json:"id"`
+	Type           string             `json:"type"`
+	Title          string             `json:"title"`
+	Author         []cslName          `json:"author,omitempty"`
+	Issued         map[string][][]int `json:"issued,omitempty"`
+	Publisher      string             `json:"publisher,omitempty"`
+	ContainerTitle string             `json:"container-title,omitempty"`
+	Volume         string             `json:"volume,omitempty"`
+	Issue          string             `json:"issue,omitempty"`
+	Page           string             `json:"page,omitempty"`
+	ISBN           string             `json:"ISBN,omitempty"`
+	ISSN           string             `json:"ISSN,omitempty"`
+	DOI            string             `json:"DOI,omitempty"`
+	Genre          string             `json:"genre,omitempty"`
+	Language       string             `json:"language,omitempty"`
+}
+
+// renderCSLJSON renders items as a CSL-JSON array
+func renderCSLJSON(items []CitationItem) string {
+	entries := make([]cslItem, 0, len(items))
+	for _, item := range items {
+		entry := cslItem{
+			ID:       fmt.Sprintf("%s-%d", item.Type, item.ID),
+			Type:     "book",
+			Title:    item.Title,
+			ISBN:     item.ISBN,
+			DOI:      item.DOI,
+			Language: item.Language,
+		}
+		for _, author := range item.Authors {
+			given, family := personName(author)
+			entry.Author = append(entry.Author, cslName{Family: family, Given: given})
+		}
+		if item.Year != nil {
+			entry.Issued = map[string][][]int{"date-parts": {{*item.Year}}}
+		}
+		switch {
+		case item.isArticle():
+			entry.Type = "article-journal"
+			entry.ContainerTitle = item.Journal
+			entry.Volume = item.Volume
+			entry.Issue = item.Issue
+			entry.Page = item.Pages
+			entry.ISSN = item.ISSN
+		case item.Type == "paper":
+			entry.Type = "thesis"
+			entry.Genre = "Thesis"
+			entry.Publisher = item.University
+		default:
+			entry.Publisher = item.Publisher
+		}
+		entries = append(entries, entry)
+	}
+
+	body, _ := json.MarshalIndent(entries, "", "  ")
+	return string(body) + "\n"
+}
+
+// endNoteRecord is a record of an EndNote XML library
+type endNoteRecord struct {
+	RefType        endNoteRefType `xml:"ref-type"`
+	Authors        []string       `xml:"contributors>authors>author"`
+	Title          string         `xml:"titles>title"`
+	SecondaryTitle string         `xml:"titles>secondary-title,omitempty"`
+	Periodical     string         `xml:"periodical>full-title,omitempty"`
+	Pages          string         `xml:"pages,omitempty"`
+	Volume         string         `xml:"volume,omitempty"`
+	Number         string         `xml:"number,omitempty"`
+	Year           string         `xml:"dates>year,omitempty"`
+	Publisher      string         `xml:"publisher,omitempty"`
+	ISBN           string         `xml:"isbn,omitempty"`
+	DOI            string         `xml:"electronic-resource-num,omitempty"`
+	WorkType       string         `xml:"work-type,omitempty"`
+	Language       string         `xml:"language,omitempty"`
+}
+
+type endNoteRefType struct {
+	Name   string `xml:"name,attr"`
+	Number int    `xml:",chardata"`
+}
+
+// renderEndNoteXML renders items as an EndNote XML library
+func renderEndNoteXML(items []CitationItem) string {
+	records := make([]endNoteRecord, 0, len(items))
+	for _, item := range items {
+		record := endNoteRecord{
+			RefType:  endNoteRefType{Name: "Book", Number: 6},
+			Title:    item.Title,
+			DOI:      item.DOI,
+			Language: item.Language,
+		}
+		for _, author := range item.Authors {
+			record.Authors = append(record.Authors, invertedName(author))
+		}
+		if item.Year != nil {
+			record.Year = strconv.Itoa(*item.Year)
+		}
+		switch {
+		case item.isArticle():
+			record.RefType = endNoteRefType{Name: "Journal Article", Number: 17}
+			record.SecondaryTitle = item.Journal
+			record.Periodical = item.Journal
+			record.Volume = item.Volume
+			record.Number = item.Issue
+			record.Pages = item.Pages
+			record.ISBN = item.ISSN
+		case item.Type == "paper":
+			record.RefType = endNoteRefType{Name: "Thesis", Number: 32}
+			record.Publisher = item.University
+			record.WorkType = "Thesis"
+		default:
+			record.Publisher = item.Publisher
+			record.ISBN = item.ISBN
+		}
+		records = append(records, record)
+	}
+
+	body, _ := xml.MarshalIndent(struct {
+		XMLName xml.Name        `xml:"xml"`
+		Records []endNoteRecord `xml:"records>record"`
+	}{Records: records}, "", "  ")
+	return xml.Header + string(body) + "\n"
+}
+
+// ItemRef identifies a book or paper
+type ItemRef struct {
+	Type string
+	ID   uint
+}
+
+// LoadCitationItems loads the referenced books and papers in the given order.
+// References to items that no longer exist are skipped.
+func LoadCitationItems(db *gorm.DB, refs []ItemRef) ([]CitationItem, error) {
+	var bookIDs, paperIDs []uint
+	for _, ref := range refs {
+		switch ref.Type {
+		case "book":
+			bookIDs = append(bookIDs, ref.ID)
+		case "paper":
+			paperIDs = append(paperIDs, ref.ID)
+		}
+	}
+
+	orderedAuthors := func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }
+
+	books := make(map[uint]models.Book)
+	if len(bookIDs) > 0 {
+		var found []models.Book
+		if err := db.Preload("Authors", orderedAuthors).Where("id IN ?", bookIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, book := range found {
+			books[book.ID] = book
+		}
+	}
+
+	papers := make(map[uint]models.Paper)
+	if len(paperIDs) > 0 {
+		var found []models.Paper
+		if err := db.Preload("Authors", orderedAuthors).Where("id IN ?", paperIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, paper := range found {
+			papers[paper.ID] = paper
+		}
+	}
+
+	items := make([]CitationItem, 0, len(refs))
+	for _, ref := range refs {
+		switch ref.Type {
+		case "book":
+			if book, ok := books[ref.ID]; ok {
+				items = append(items, BookCitationItem(book))
+			}
+		case "paper":
+			if paper, ok := papers[ref.ID]; ok {
+				items = append(items, PaperCitationItem(paper))
+			}
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func citationFixtures() []CitationItem {
+	year := 2020
+	return []CitationItem{
+		{Type: "book", ID: 1, Title: "Basis Data", Authors: []string{"Budi Santoso", "Ani R. Wijaya"}, Year: &year, Publisher: "Andi"},
+		{Type: "paper", ID: 2, Title: "Deteksi Objek", Authors: []string{"Santoso, Budi"}, Year: &year, Journal: "Jurnal Informatika", Volume: "5", Issue: "2", Pages: "10-20", DOI: "10.1234/ji.5.2"},
+	}
+}
+
+func TestFormatCitationsBibTeX(t *testing.T) {
+	body, contentType, ext, err := FormatCitations(citationFixtures(), "bibtex")
+	assert.NoError(t, err)
+	assert.Equal(t, "bib", ext)
+	assert.Contains(t, contentType, "bibtex")
+	assert.Contains(t, body, "@book{santoso2020,")
+	assert.Contains(t, body, "@article{santoso2020a,")
+	assert.Contains(t, body, "author = {Budi Santoso and Ani R. Wijaya}")
+	assert.Contains(t, body, "pages = {10--20}")
+}
+
+func TestFormatCitationsRIS(t *testing.T) {
+	body, _, _, err := FormatCitations(citationFixtures(), "RIS")
+	assert.NoError(t, err)
+	assert.Contains(t, body, "TY  - BOOK\r\n")
+	assert.Contains(t, body, "TY  - JOUR\r\n")
+	assert.Contains(t, body, "SP  - 10\r\nEP  - 20\r\n")
+	assert.Equal(t, 2, strings.Count(body, "ER  - "))
+}
+
+func TestFormatCitationsAPA(t *testing.T) {
+	body, _, _, err := FormatCitations(citationFixtures(), "apa")
+	assert.NoError(t, err)
+	assert.Contains(t, body, "Santoso, B., & Wijaya, A. R. (2020). Basis Data. Andi.\n")
+	assert.Contains(t, body, "Santoso, B. (2020). Deteksi Objek. Jurnal Informatika, 5(2), 10-20. https://doi.org/10.1234/ji.5.2\n")
+}
+
+func TestFormatCitationsUnsupported(t *testing.T) {
+	_, _, _, err := FormatCitations(citationFixtures(), "docx")
+	assert.Error(t, err)
+}
+
+func TestFormatCitationsStyles(t *testing.T) {
+	thesisYear := 2021
+	items := append(citationFixtures(), CitationItem{
+		Type: "paper", ID: 3, Title: "Sistem Pakar", Authors: []string{"Sukarno"}, Year: &thesisYear, University: "Universitas Dumai",
+	})
+
+	body, _, _, err := FormatCitations(items, "ieee")
+	assert.NoError(t, err)
+	assert.Contains(t, body, "[1] B. Santoso and A. R. Wijaya, Basis Data. Andi, 2020.\n")
+	assert.Contains(t, body, "[2] B. Santoso, \"Deteksi Objek,\" Jurnal Informatika, vol. 5, no. 2, pp. 10-20, 2020, doi: 10.1234/ji.5.2.\n")
+	assert.Contains(t, body, "[3] Sukarno, \"Sistem Pakar,\" Thesis, Universitas Dumai, 2021.\n")
+
+	body, _, _, err = FormatCitations(items, "mla")
+	assert.NoError(t, err)
+	assert.Contains(t, body, "Santoso, Budi, and Ani R. Wijaya. Basis Data. Andi, 2020.\n")
+	assert.Contains(t, body, "Santoso, Budi. \"Deteksi Objek.\" Jurnal Informatika, vol. 5, no. 2, 2020, pp. 10-20. https://doi.org/10.1234/ji.5.2.\n")
+
+	body, _, _, err = FormatCitations(items, "chicago")
+	assert.NoError(t, err)
+	assert.Contains(t, body, "Santoso, Budi, and Ani R. Wijaya. Basis Data. Andi, 2020.\n")
+	assert.Contains(t, body, "Santoso, Budi. \"Deteksi Objek.\" Jurnal Informatika 5, no. 2 (2020): 10-20. https://doi.org/10.1234/ji.5.2.\n")
+	assert.Contains(t, body, "Sukarno. \"Sistem Pakar.\" Thesis, Universitas Dumai, 2021.\n")
+}
+
+func TestFormatCitationsCSLJSON(t *testing.T) {
+	body, contentType, ext, err := FormatCitations(citationFixtures(), "csl-json")
+	assert.NoError(t, err)
+	assert.Equal(t, "json", ext)
+	assert.Contains(t, contentType, "csl+json")
+
+	var entries []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(body), &entries))
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "book-1", entries[0]["id"])
+	assert.Equal(t, "book", entries[0]["type"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"family": "Santoso", "given": "Budi"}, map[string]interface{}{"family": "Wijaya", "given": "Ani R."}}, entries[0]["author"])
+	assert.Equal(t, "article-journal", entries[1]["type"])
+	assert.Equal(t, "Jurnal Informatika", entries[1]["container-title"])
+	assert.Equal(t, map[string]interface{}{"date-parts": []interface{}{[]interface{}{float64(2020)}}}, entries[1]["issued"])
+}
+
+func TestFormatCitationsEndNote(t *testing.T) {
+	body, _, ext, err := FormatCitations(citationFixtures(), "endnote")
+	assert.NoError(t, err)
+	assert.Equal(t, "xml", ext)
+	assert.True(t, strings.HasPrefix(body, "<?xml"))
+	assert.Contains(t, body, `<ref-type name="Book">6</ref-type>`)
+	assert.Contains(t, body, `<ref-type name="Journal Article">17</ref-type>`)
+	assert.Contains(t, body, "<author>Santoso, Budi</author>")
+	assert.Contains(t, body, "<secondary-title>Jurnal Informatika</secondary-title>")
+	assert.Contains(t, body, "<electronic-resource-num>10.1234/ji.5.2</electronic-resource-num>")
+	assert.Equal(t, 2, strings.Count(body, "<record>"))
+}
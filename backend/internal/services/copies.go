@@ -0,0 +1,68 @@
+package services
+
+import (
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CopyStatuses are the states a physical copy can be in
+var CopyStatuses = []string{"available", "on_loan", "lost", "damaged"}
+
+// BookAvailability counts a book's physical copies by status. Copies on the hold
+// shelf are counted as OnHold rather than Available.
+type BookAvailability struct {
+	Total        int `json:"total"`
+	Available    int `json:"available"`
+	OnHold       int `json:"on_hold"`
+	OnLoan       int `json:"on_loan"`
+	Lost         int `json:"lost"`
+	Damaged      int `json:"damaged"`
+	HoldsWaiting int `json:"holds_waiting"`
+}
+
+// GetBookAvailability counts the physical copies of a book by status
+func GetBookAvailability(db *gorm.DB, bookID uint) (BookAvailability, error) {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := db.Model(&models.Copy{}).Select("status, COUNT(*) AS count").
+		Where("book_id = ?", bookID).Group("status").Scan(&rows).Error; err != nil {
+		return BookAvailability{}, err
+	}
+
+	var availability BookAvailability
+	for _, row := range rows {
+		availability.Total += row.Count
+		switch row.Status {
+		case "available":
+			availability.Available = row.Count
+		case "on_loan":
+			availability.OnLoan = row.Count
+		case "lost":
+			availability.Lost = row.Count
+		case "damaged":
+			availability.Damaged = row.Count
+		}
+	}
+
+	var onHold, waiting int64
+	if err := db.Model(&models.Hold{}).Where("book_id = ? AND status = 'ready'", bookID).Count(&onHold).Error; err != nil {
+		return BookAvailability{}, err
+	}
+	if err := db.Model(&models.Hold{}).Where("book_id = ? AND status = 'waiting'", bookID).Count(&waiting).Error; err != nil {
+		return BookAvailability{}, err
+	}
+	availability.OnHold = int(onHold)
+	availability.Available -= availability.OnHold
+	availability.HoldsWaiting = int(waiting)
+	return availability, nil
+}
+
+// HasCopies reports whether the library holds physical copies of a book
+func HasCopies(db *gorm.DB, bookID uint) bool {
+	var count int64
+	db.Model(&models.Copy{}).Where("book_id = ?", bookID).Count(&count)
+	return count > 0
+}
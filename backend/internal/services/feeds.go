@@ -0,0 +1,391 @@
+package services
+
+import (
+	"encoding/xml"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// FeedDefaultSize is how many items a feed lists unless asked otherwise
+	FeedDefaultSize = 20
+	// FeedMaxSize is the most items a feed lists
+	FeedMaxSize = 100
+
+	atomNamespace = "http://www.w3.org/2005/Atom"
+	feedGenerator = "E-Repository"
+)
+
+// Feed is a list of newly added books and papers, rendered as Atom or RSS 2.0
+type Feed struct {
+	Title       string
+	Description string
+	SelfURL     string // the address of the feed itself
+	PageURL     string // the web page listing the same items
+	Entries     []FeedEntry
+}
+
+// FeedEntry is a book or paper in a feed
+type FeedEntry struct {
+	Type       string
+	ID         uint
+	Title      string
+	Authors    []string
+	Summary    string
+	Categories []string
+	PageURL    string
+	Published  time.Time
+	Updated    time.Time
+	Enclosure  *FeedEnclosure
+}
+
+// FeedEnclosure is the downloadable file of an entry
+type FeedEnclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// NewestItems returns the books and papers matching a query and the filters, most
+// recently added first. Queries use the syntax of search.Parse.
+func NewestItems(db *gorm.DB, query string, filters SearchFilters, limit int) ([]ItemRef, error) {
+	match, err := newSearchMatch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	var args []interface{}
+	for _, source := range searchSources {
+		if source.selected(filters) {
+			parts = append(parts, "?")
+			args = append(args, source.matching(db, match, filters).
+				Select("'"+source.itemType+"' AS item_type, "+source.column("id")+" AS id, "+source.column("created_at")+" AS created_at"))
+		}
+	}
+	refs := []ItemRef{}
+	if len(parts) == 0 {
+		return refs, nil
+	}
+	args = append(args, limit)
+
+	var rows []struct {
+		ItemType string
+		ID       uint
+	}
+	if err := db.Raw("SELECT item_type, id FROM ("+strings.Join(parts, " UNION ALL ")+") AS items "+
+		"ORDER BY created_at DESC, id DESC LIMIT ?", args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		refs = append(refs, ItemRef{Type: row.ItemType, ID: row.ID})
+	}
+	return refs, nil
+}
+
+// LoadFeedEntries loads the referenced books and papers as feed entries in the given
+// order, skipping the ones that no longer exist. Uploaded files can be downloaded
+// without an account, so every item with a file gets an enclosure.
+func LoadFeedEntries(db *gorm.DB, refs []ItemRef, links ItemLinks) ([]FeedEntry, error) {
+	var bookIDs, paperIDs []uint
+	for _, ref := range refs {
+		if ref.Type == "book" {
+			bookIDs = append(bookIDs, ref.ID)
+		} else {
+			paperIDs = append(paperIDs, ref.ID)
+		}
+	}
+	orderedAuthors := func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }
+
+	entries := make(map[ItemRef]FeedEntry, len(refs))
+	if len(bookIDs) > 0 {
+		var books []models.Book
+		if err := db.Preload("Authors", orderedAuthors).Preload("Categories").Where("id IN ?", bookIDs).Find(&books).Error; err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			entry := FeedEntry{
+				Type:      "book",
+				ID:        book.ID,
+				Title:     book.Title,
+				Authors:   BookCitationItem(book).Authors,
+				Summary:   utils.StringValue(book.Summary),
+				PageURL:   links.FrontendURL + "/books/" + strconv.FormatUint(uint64(book.ID), 10),
+				Published: book.CreatedAt,
+				Updated:   book.UpdatedAt,
+				Enclosure: links.enclosure("book", book.ID, book.FileURL),
+			}
+			for _, category := range book.Categories {
+				entry.Categories = append(entry.Categories, category.Name)
+			}
+			entries[ItemRef{Type: "book", ID: book.ID}] = entry
+		}
+	}
+	if len(paperIDs) > 0 {
+		var papers []models.Paper
+		if err := db.Preload("Authors", orderedAuthors).Where("id IN ?", paperIDs).Find(&papers).Error; err != nil {
+			return nil, err
+		}
+		// Papers have no categories relationship, only the join table
+		var categories []struct {
+			PaperID uint
+			Name    string
+		}
+		if err := db.Table("paper_categories").Select("paper_categories.paper_id, categories.name").
+			Joins("JOIN categories ON categories.id = paper_categories.category_id").
+			Where("paper_categories.paper_id IN ?", paperIDs).Order("categories.name").Scan(&categories).Error; err != nil {
+			return nil, err
+		}
+		for _, paper := range papers {
+			entry := FeedEntry{
+				Type:      "paper",
+				ID:        paper.ID,
+				Title:     paper.Title,
+				Authors:   PaperCitationItem(paper).Authors,
+				Summary:   utils.StringValue(paper.Abstract),
+				PageURL:   links.FrontendURL + "/papers/" + strconv.FormatUint(uint64(paper.ID), 10),
+				Published: paper.CreatedAt,
+				Updated:   paper.UpdatedAt,
+				Enclosure: links.enclosure("paper", paper.ID, paper.FileURL),
+			}
+			for _, category := range categories {
+				if category.PaperID == paper.ID {
+					entry.Categories = append(entry.Categories, category.Name)
+				}
+			}
+			entries[ItemRef{Type: "paper", ID: paper.ID}] = entry
+		}
+	}
+
+	ordered := make([]FeedEntry, 0, len(entries))
+	for _, ref := range refs {
+		if entry, ok := entries[ref]; ok {
+			ordered = append(ordered, entry)
+		}
+	}
+	return ordered, nil
+}
+
+// enclosure describes the uploaded file of an item, linking to its download endpoint
+// so that downloads from feed readers are counted. The length is read from the upload
+// on disk, and is 0 when it is stored elsewhere.
+func (l ItemLinks) enclosure(itemType string, id uint, fileURL *string) *FeedEnclosure {
+	path := utils.StringValue(fileURL)
+	if path == "" {
+		return nil
+	}
+
+	enclosure := &FeedEnclosure{
+		URL:  l.ServerURL + "/api/v1/" + itemType + "s/" + strconv.FormatUint(uint64(id), 10) + "/download",
+		Type: mime.TypeByExtension(strings.ToLower(filepath.Ext(path))),
+	}
+	if enclosure.Type == "" {
+		enclosure.Type = "application/octet-stream"
+	}
+	if l.ServerURL != "" {
+		path = strings.TrimPrefix(path, l.ServerURL)
+	}
+	if !strings.HasPrefix(path, "http") {
+		if info, err := os.Stat(strings.TrimPrefix(path, "/")); err == nil {
+			enclosure.Length = info.Size()
+		}
+	}
+	return enclosure
+}
+
+// LastModified returns when an entry of the feed was last changed, or the zero time
+// for an empty feed
+func (f Feed) LastModified() time.Time {
+	var latest time.Time
+	for _, entry := range f.Entries {
+		if entry.Updated.After(latest) {
+			latest = entry.Updated
+		}
+	}
+	return latest
+}
+
+// updated returns the feed's update time. An empty feed reports the Unix epoch so that
+// it renders the same until an item is added.
+func (f Feed) updated() time.Time {
+	if latest := f.LastModified(); !latest.IsZero() {
+		return latest.UTC()
+	}
+	return time.Unix(0, 0).UTC()
+}
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	Subtitle  string      `xml:"subtitle,omitempty"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Links      []atomLink     `xml:"link"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// Atom renders the feed as an Atom 1.0 document (RFC 4287)
+func (f Feed) Atom() ([]byte, error) {
+	feed := atomFeed{
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       f.SelfURL,
+		Updated:  f.updated().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: f.PageURL},
+		},
+		Generator: feedGenerator,
+	}
+	for _, entry := range f.Entries {
+		item := atomEntry{
+			Title:     entry.Title,
+			ID:        entry.PageURL,
+			Published: entry.Published.UTC().Format(time.RFC3339),
+			Updated:   entry.Updated.UTC().Format(time.RFC3339),
+			Summary:   entry.Summary,
+			Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: entry.PageURL}},
+		}
+		// Atom requires an author; the feed has none of its own to fall back on
+		for _, author := range entry.Authors {
+			item.Authors = append(item.Authors, atomPerson{Name: author})
+		}
+		if len(item.Authors) == 0 {
+			item.Authors = []atomPerson{{Name: "Anonymous"}}
+		}
+		for _, category := range entry.Categories {
+			item.Categories = append(item.Categories, atomCategory{Term: category})
+		}
+		if entry.Enclosure != nil {
+			item.Links = append(item.Links, atomLink{Rel: "enclosure", Type: entry.Enclosure.Type, Href: entry.Enclosure.URL, Length: entry.Enclosure.Length})
+		}
+		feed.Entries = append(feed.Entries, item)
+	}
+	return marshalFeed(feed)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Generator     string    `xml:"generator"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Updated     string        `xml:"atom:updated"`
+	Creators    []string      `xml:"dc:creator"`
+	Categories  []string      `xml:"category"`
+	Description string        `xml:"description,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// RSS renders the feed as an RSS 2.0 document. Authors are given as dc:creator, since
+// the RSS author element holds an email address, and the update time of each item as
+// atom:updated.
+func (f Feed) RSS() ([]byte, error) {
+	description := f.Description
+	if description == "" {
+		description = f.Title
+	}
+	document := rssDocument{
+		Version: "2.0",
+		Atom:    atomNamespace,
+		DC:      dcNamespace,
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.PageURL,
+			Description:   description,
+			Self:          atomLink{Rel: "self", Type: "application/rss+xml", Href: f.SelfURL},
+			LastBuildDate: f.updated().Format(time.RFC1123Z),
+			Generator:     feedGenerator,
+		},
+	}
+	for _, entry := range f.Entries {
+		item := rssItem{
+			Title:       entry.Title,
+			Link:        entry.PageURL,
+			GUID:        rssGUID{IsPermaLink: true, Value: entry.PageURL},
+			PubDate:     entry.Published.UTC().Format(time.RFC1123Z),
+			Updated:     entry.Updated.UTC().Format(time.RFC3339),
+			Creators:    entry.Authors,
+			Categories:  entry.Categories,
+			Description: entry.Summary,
+		}
+		if entry.Enclosure != nil {
+			item.Enclosure = &rssEnclosure{URL: entry.Enclosure.URL, Length: entry.Enclosure.Length, Type: entry.Enclosure.Type}
+		}
+		document.Channel.Items = append(document.Channel.Items, item)
+	}
+	return marshalFeed(document)
+}
+
+// marshalFeed encodes a feed document with the XML declaration
+func marshalFeed(document interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
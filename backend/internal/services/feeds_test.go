@@ -0,0 +1,99 @@
+package services
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testFeed() Feed {
+	added := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	return Feed{
+		Title:   "E-Repository: New papers",
+		SelfURL: "https://api.example.ac.id/api/v1/feeds/atom?type=paper",
+		PageURL: "https://repo.example.ac.id/search?type=paper",
+		Entries: []FeedEntry{
+			{
+				Type: "paper", ID: 2, Title: "Deteksi Objek & Citra", Authors: []string{"Budi Santoso", "Ani Wijaya"},
+				Summary: "Abstrak", Categories: []string{"Skripsi"}, PageURL: "https://repo.example.ac.id/papers/2",
+				Published: added, Updated: added.Add(48 * time.Hour),
+				Enclosure: &FeedEnclosure{URL: "https://api.example.ac.id/api/v1/papers/2/download", Type: "application/pdf", Length: 1024},
+			},
+			{Type: "book", ID: 1, Title: "Pemrograman Go", PageURL: "https://repo.example.ac.id/books/1", Published: added, Updated: added},
+		},
+	}
+}
+
+func TestFeedAtom(t *testing.T) {
+	body, err := testFeed().Atom()
+	assert.NoError(t, err)
+
+	var feed struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID      string   `xml:"id"`
+			Title   string   `xml:"title"`
+			Updated string   `xml:"updated"`
+			Authors []string `xml:"author>name"`
+			Links   []struct {
+				Rel    string `xml:"rel,attr"`
+				Href   string `xml:"href,attr"`
+				Length int64  `xml:"length,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	assert.NoError(t, xml.Unmarshal(body, &feed))
+	assert.Equal(t, "2026-03-03T08:00:00Z", feed.Updated)
+	assert.Len(t, feed.Entries, 2)
+	assert.Equal(t, "Deteksi Objek & Citra", feed.Entries[0].Title)
+	assert.Equal(t, "2026-03-03T08:00:00Z", feed.Entries[0].Updated)
+	assert.Equal(t, []string{"Budi Santoso", "Ani Wijaya"}, feed.Entries[0].Authors)
+	assert.Equal(t, "enclosure", feed.Entries[0].Links[1].Rel)
+	assert.Equal(t, int64(1024), feed.Entries[0].Links[1].Length)
+	assert.Equal(t, []string{"Anonymous"}, feed.Entries[1].Authors)
+	assert.Len(t, feed.Entries[1].Links, 1)
+}
+
+func TestFeedRSS(t *testing.T) {
+	body, err := testFeed().RSS()
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `<atom:link rel="self" type="application/rss+xml"`)
+
+	var rss struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				GUID      string   `xml:"guid"`
+				PubDate   string   `xml:"pubDate"`
+				Creators  []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				Enclosure *struct {
+					URL  string `xml:"url,attr"`
+					Type string `xml:"type,attr"`
+				} `xml:"enclosure"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	assert.NoError(t, xml.Unmarshal(body, &rss))
+	assert.Equal(t, "2.0", rss.Version)
+	assert.Equal(t, "Tue, 03 Mar 2026 08:00:00 +0000", rss.Channel.LastBuildDate)
+	assert.Equal(t, "Sun, 01 Mar 2026 08:00:00 +0000", rss.Channel.Items[0].PubDate)
+	assert.Equal(t, []string{"Budi Santoso", "Ani Wijaya"}, rss.Channel.Items[0].Creators)
+	assert.Equal(t, "application/pdf", rss.Channel.Items[0].Enclosure.Type)
+	assert.Nil(t, rss.Channel.Items[1].Enclosure)
+}
+
+func TestFeedEmpty(t *testing.T) {
+	feed := Feed{Title: "E-Repository: New books"}
+	assert.True(t, feed.LastModified().IsZero())
+
+	first, err := feed.Atom()
+	assert.NoError(t, err)
+	second, err := feed.Atom()
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Contains(t, string(first), "<updated>1970-01-01T00:00:00Z</updated>")
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// fullTextBatchSize is how many files of each item type one extraction run reads
+const fullTextBatchSize = 20
+
+// snippetRadius is how many characters a snippet shows on each side of the first match
+const snippetRadius = 90
+
+// fullTextMatch scores a page against a full-text query
+const fullTextMatch = "MATCH(content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// TextMatch is a page of an item's file that matches a full-text query
+type TextMatch struct {
+	Page    int    `json:"page"`
+	Snippet string `json:"snippet"`
+}
+
+// ExtractFullText reads the text of uploaded PDFs that have not been extracted yet, or
+// whose file has been replaced since, and stores it page by page for full-text search.
+// Text of files that were removed or replaced is dropped.
+func ExtractFullText(db *gorm.DB) error {
+	extractor := NewMetadataExtractor()
+	extracted := 0
+	for _, itemType := range []string{"book", "paper"} {
+		table := itemType + "s"
+
+		var stale []models.ItemText
+		if err := db.Select("item_type", "item_id").
+			Where("item_type = ? AND NOT EXISTS (SELECT 1 FROM "+table+" WHERE "+table+".id = item_texts.item_id AND "+table+".file_url = item_texts.file_url)", itemType).
+			Find(&stale).Error; err != nil {
+			return fmt.Errorf("failed to find stale %s text: %w", itemType, err)
+		}
+		for _, text := range stale {
+			if err := ClearItemText(db, text.ItemType, text.ItemID); err != nil {
+				return fmt.Errorf("failed to clear stale %s text: %w", itemType, err)
+			}
+		}
+
+		var items []struct {
+			ID      uint
+			FileURL string
+		}
+		if err := db.Table(table).Select("id, file_url").
+			Where("LOWER(file_url) LIKE '%.pdf' AND file_url NOT LIKE 'http%'").
+			Where("NOT EXISTS (SELECT 1 FROM item_texts WHERE item_texts.item_type = ? AND item_texts.item_id = "+table+".id AND item_texts.file_url = "+table+".file_url)", itemType).
+			Order("id").Limit(fullTextBatchSize).Scan(&items).Error; err != nil {
+			return fmt.Errorf("failed to find %ss to extract: %w", itemType, err)
+		}
+
+		for _, item := range items {
+			if err := extractItemText(db, extractor, itemType, item.ID, item.FileURL); err != nil {
+				return err
+			}
+			extracted++
+		}
+	}
+
+	if extracted > 0 {
+		log.Printf("[ExtractFullText] Extracted the text of %d files", extracted)
+	}
+	return nil
+}
+
+// extractItemText replaces the stored pages of an item with the text of its file. Files
+// that can't be read are recorded as failed so they are not retried until replaced.
+func extractItemText(db *gorm.DB, extractor *MetadataExtractor, itemType string, itemID uint, fileURL string) error {
+	state := models.ItemText{
+		ItemType:    itemType,
+		ItemID:      itemID,
+		FileURL:     fileURL,
+		Status:      "extracted",
+		ExtractedAt: time.Now(),
+	}
+
+	rawPages, err := extractor.ExtractPDFPages(filepath.FromSlash(strings.TrimPrefix(fileURL, "/")))
+	if err != nil {
+		log.Printf("[ExtractFullText] Failed to read %s %d (%s): %v", itemType, itemID, fileURL, err)
+		message := err.Error()
+		state.Status = "failed"
+		state.Error = &message
+	}
+
+	pages := make([]models.ItemPage, 0, len(rawPages))
+	for i, text := range rawPages {
+		text = normalizePageText(text)
+		if text == "" {
+			continue
+		}
+		pages = append(pages, models.ItemPage{ItemType: itemType, ItemID: itemID, PageNumber: i + 1, Content: text})
+	}
+	state.PageCount = len(rawPages)
+	if state.Status == "extracted" && len(pages) == 0 {
+		// Scanned documents without a text layer
+		state.Status = "empty"
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := ClearItemText(tx, itemType, itemID); err != nil {
+			return err
+		}
+		if len(pages) > 0 {
+			if err := tx.CreateInBatches(&pages, 50).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&state).Error
+	})
+}
+
+// normalizePageText collapses whitespace and drops bytes MySQL can't store as text
+func normalizePageText(text string) string {
+	text = strings.ToValidUTF8(strings.ReplaceAll(text, "\x00", ""), "")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// ClearItemText removes the extracted text of an item
+func ClearItemText(db *gorm.DB, itemType string, itemID uint) error {
+	if err := db.Where("item_type = ? AND item_id = ?", itemType, itemID).Delete(&models.ItemPage{}).Error; err != nil {
+		return err
+	}
+	return db.Where("item_type = ? AND item_id = ?", itemType, itemID).Delete(&models.ItemText{}).Error
+}
+
+// FullTextScores returns a subquery of the items of a type whose file text matches a
+// query, as item_id with the score of their best matching page
+func FullTextScores(db *gorm.DB, itemType, query string) *gorm.DB {
+	return db.Model(&models.ItemPage{}).
+		Select("item_id, MAX("+fullTextMatch+") AS score", query).
+		Where("item_type = ? AND "+fullTextMatch, itemType, query).
+		Group("item_id")
+}
+
+// FullTextMatches returns up to perItem best matching pages of each item, in page order,
+// with highlighted snippets
+func FullTextMatches(db *gorm.DB, itemType string, itemIDs []uint, query string, perItem int) (map[uint][]TextMatch, error) {
+	matches := make(map[uint][]TextMatch)
+	if len(itemIDs) == 0 {
+		return matches, nil
+	}
+
+	var rows []struct {
+		ItemID     uint
+		PageNumber int
+		Content    string
+	}
+	if err := db.Raw(`SELECT item_id, page_number, content FROM (
+		SELECT item_id, page_number, content,
+			ROW_NUMBER() OVER (PARTITION BY item_id ORDER BY `+fullTextMatch+` DESC, page_number) AS page_rank
+		FROM item_pages
+		WHERE item_type = ? AND item_id IN ? AND `+fullTextMatch+`
+	) ranked WHERE page_rank <= ? ORDER BY item_id, page_number`,
+		query, itemType, itemIDs, query, perItem).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	terms := searchTerms(query)
+	for _, row := range rows {
+		matches[row.ItemID] = append(matches[row.ItemID], TextMatch{
+			Page:    row.PageNumber,
+			Snippet: BuildSnippet(row.Content, terms, snippetRadius),
+		})
+	}
+	return matches, nil
+}
+
+// searchTerms splits a query into the distinct lowercase words to highlight
+func searchTerms(query string) []string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < 2 || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// BuildSnippet returns an HTML-escaped excerpt of text around the first occurrence of
+// any term, with every occurrence wrapped in <mark>. Without a match the excerpt is
+// taken from the start of the text.
+func BuildSnippet(text string, terms []string, radius int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return ""
+	}
+
+	var pattern *regexp.Regexp
+	if len(terms) > 0 {
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = regexp.QuoteMeta(term)
+		}
+		pattern = regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	}
+
+	runes := []rune(text)
+	start := 0
+	if pattern != nil {
+		if loc := pattern.FindStringIndex(text); loc != nil {
+			start = max(0, utf8.RuneCountInString(text[:loc[0]])-radius)
+		}
+	}
+	end := len(runes)
+	if start+2*radius < end {
+		end = start + 2*radius
+	}
+
+	// Don't cut words in half
+	if start > 0 && runes[start-1] != ' ' {
+		for i := start; i < end && i < start+20; i++ {
+			if runes[i] == ' ' {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if end < len(runes) && runes[end] != ' ' {
+		for i := end - 1; i > start && i > end-20; i-- {
+			if runes[i] == ' ' {
+				end = i
+				break
+			}
+		}
+	}
+	excerpt := string(runes[start:end])
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	last := 0
+	if pattern != nil {
+		for _, loc := range pattern.FindAllStringIndex(excerpt, -1) {
+			b.WriteString(html.EscapeString(excerpt[last:loc[0]]))
+			b.WriteString("<mark>" + html.EscapeString(excerpt[loc[0]:loc[1]]) + "</mark>")
+			last = loc[1]
+		}
+	}
+	b.WriteString(html.EscapeString(excerpt[last:]))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
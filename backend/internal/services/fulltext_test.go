@@ -0,0 +1,33 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchTerms(t *testing.T) {
+	assert.Equal(t, []string{"sistem", "informasi"}, searchTerms("Sistem  informasi, SISTEM!"))
+	assert.Equal(t, []string{"covid", "19"}, searchTerms("covid-19 a"))
+	assert.Empty(t, searchTerms("  "))
+}
+
+func TestBuildSnippet(t *testing.T) {
+	assert.Equal(t, "Analisis <mark>sistem</mark> informasi", BuildSnippet("Analisis  sistem\ninformasi", []string{"sistem"}, 90))
+	assert.Equal(t, "a &lt;b&gt; <mark>C</mark>", BuildSnippet("a <b> C", []string{"c"}, 90))
+	assert.Equal(t, "", BuildSnippet("   ", []string{"c"}, 90))
+
+	// A match deep in the page is shown with ellipses, cut at word boundaries
+	text := strings.Repeat("lorem ipsum ", 50) + "metode waterfall " + strings.Repeat("dolor sit ", 50)
+	snippet := BuildSnippet(text, []string{"waterfall"}, 30)
+	assert.True(t, strings.HasPrefix(snippet, "…"))
+	assert.True(t, strings.HasSuffix(snippet, "…"))
+	assert.Contains(t, snippet, "metode <mark>waterfall</mark>")
+	for _, word := range strings.Fields(strings.Trim(snippet, "…")) {
+		assert.Contains(t, []string{"lorem", "ipsum", "metode", "<mark>waterfall</mark>", "dolor", "sit"}, word)
+	}
+
+	// Without a match the start of the page is shown
+	assert.Equal(t, "lorem ipsum…", BuildSnippet(text, []string{"absent"}, 7))
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HoldPickupDays is how many days a trapped copy waits on the hold shelf
+const HoldPickupDays = 3
+
+// Hold errors returned to librarians and members
+var (
+	ErrNoCopies        = errors.New("the library holds no loanable copies of this book")
+	ErrCopyOnShelf     = errors.New("a copy is available on the shelf")
+	ErrHoldExists      = errors.New("member already has a hold on this book")
+	ErrAlreadyBorrowed = errors.New("member already has this book on loan")
+	ErrHoldClosed      = errors.New("hold is no longer active")
+	ErrCopyOnHold      = errors.New("copy is on the hold shelf for another member")
+	ErrBookHasHolds    = errors.New("other members are waiting for this book")
+)
+
+// HoldStatuses are the states a hold can be in
+var HoldStatuses = []string{"waiting", "ready", "fulfilled", "cancelled", "expired"}
+
+// activeHoldStatuses are the statuses of holds still in the queue or on the shelf
+var activeHoldStatuses = []string{"waiting", "ready"}
+
+// ValidHoldStatus reports whether status is one of HoldStatuses
+func ValidHoldStatus(status string) bool {
+	return containsString(HoldStatuses, status)
+}
+
+// loanableCopies filters the copies a hold can be filled from
+const loanableCopies = "status IN ('available', 'on_loan')"
+
+// PlaceHold queues a member for a book whose loanable copies are all out
+func PlaceHold(db *gorm.DB, userID, bookID uint) (*models.Hold, error) {
+	var loanable int64
+	if err := db.Model(&models.Copy{}).Where("book_id = ? AND "+loanableCopies, bookID).Count(&loanable).Error; err != nil {
+		return nil, err
+	}
+	if loanable == 0 {
+		return nil, ErrNoCopies
+	}
+
+	var onShelf int64
+	if err := db.Model(&models.Copy{}).Where("book_id = ? AND status = 'available'", bookID).
+		Where("id NOT IN (?)", db.Model(&models.Hold{}).Select("copy_id").Where("status = 'ready' AND copy_id IS NOT NULL")).
+		Count(&onShelf).Error; err != nil {
+		return nil, err
+	}
+	if onShelf > 0 {
+		return nil, ErrCopyOnShelf
+	}
+
+	hold := models.Hold{BookID: bookID, UserID: userID, Status: "waiting"}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The member's row is locked so two requests at once can't both pass the
+		// duplicate check; their holds on the book are locked with it
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.Hold{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND book_id = ? AND status IN ?", userID, bookID, activeHoldStatuses).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrHoldExists
+		}
+		if err := tx.Model(&models.Loan{}).Joins("JOIN copies ON copies.id = loans.copy_id").
+			Where("loans.user_id = ? AND copies.book_id = ? AND loans.returned_at IS NULL", userID, bookID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyBorrowed
+		}
+		return tx.Create(&hold).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// HoldPosition returns a waiting hold's place in its book's queue, starting at 1.
+// Ready and closed holds have position 0.
+func HoldPosition(db *gorm.DB, hold models.Hold) (int, error) {
+	if hold.Status != "waiting" {
+		return 0, nil
+	}
+	var ahead int64
+	if err := db.Model(&models.Hold{}).
+		Where("book_id = ? AND status = 'waiting' AND (created_at < ? OR (created_at = ? AND id < ?))", hold.BookID, hold.CreatedAt, hold.CreatedAt, hold.ID).
+		Count(&ahead).Error; err != nil {
+		return 0, err
+	}
+	return int(ahead) + 1, nil
+}
+
+// TrappedHold returns the ready hold a copy is set aside for, or nil
+func TrappedHold(db *gorm.DB, copyID uint) *models.Hold {
+	var hold models.Hold
+	if err := db.Where("copy_id = ? AND status = 'ready'", copyID).First(&hold).Error; err != nil {
+		return nil
+	}
+	return &hold
+}
+
+// TrapCopy sets an available copy aside for the first waiting hold on its book. It
+// returns the hold that became ready, or nil when nobody is waiting.
+func TrapCopy(db *gorm.DB, copyID uint) (*models.Hold, error) {
+	var trapped *models.Hold
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var bookCopy models.Copy
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&bookCopy, copyID).Error; err != nil {
+			return err
+		}
+		if bookCopy.Status != "available" || TrappedHold(tx, copyID) != nil {
+			return nil
+		}
+
+		var hold models.Hold
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("book_id = ? AND status = 'waiting'", bookCopy.BookID).
+			Order("created_at ASC, id ASC").First(&hold).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		holidays, err := LoadHolidays(tx, time.Now())
+		if err != nil {
+			return err
+		}
+		deadline := NextOpenDay(calendarDay(time.Now()).AddDate(0, 0, HoldPickupDays), holidays)
+		deadline = time.Date(deadline.Year(), deadline.Month(), deadline.Day(), 0, 0, 0, 0, time.Local)
+		now := time.Now()
+		if err := tx.Model(&hold).Updates(map[string]interface{}{
+			"status":          "ready",
+			"copy_id":         bookCopy.ID,
+			"ready_at":        now,
+			"pickup_deadline": deadline,
+		}).Error; err != nil {
+			return err
+		}
+		trapped = &hold
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return trapped, nil
+}
+
+// CancelHold closes an active hold. A copy trapped for it goes to the next member.
+func CancelHold(db *gorm.DB, hold *models.Hold) error {
+	return closeHold(db, hold, "cancelled")
+}
+
+// closeHold closes an active hold with the given status and passes a trapped copy on.
+// The hold is re-read under a lock, so a copy is passed on once even when the hold is
+// closed twice at the same time.
+func closeHold(db *gorm.DB, hold *models.Hold, status string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(hold, hold.ID).Error; err != nil {
+			return err
+		}
+		if hold.Status != "waiting" && hold.Status != "ready" {
+			return ErrHoldClosed
+		}
+		wasReady := hold.Status == "ready"
+		copyID := hold.CopyID
+
+		if err := tx.Model(hold).Updates(map[string]interface{}{"status": status, "closed_at": time.Now()}).Error; err != nil {
+			return err
+		}
+		if wasReady && copyID != nil {
+			if _, err := TrapCopy(tx, *copyID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// fulfilHolds closes a member's active holds on a book once they borrow a copy of it.
+// Copies trapped for those holds, other than the one borrowed, go to the next member.
+func fulfilHolds(tx *gorm.DB, userID, bookID, borrowedCopyID uint) ([]uint, error) {
+	var holds []models.Hold
+	if err := tx.Where("user_id = ? AND book_id = ? AND status IN ?", userID, bookID, activeHoldStatuses).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+
+	var released []uint
+	for _, hold := range holds {
+		if err := tx.Model(&hold).Updates(map[string]interface{}{"status": "fulfilled", "closed_at": time.Now()}).Error; err != nil {
+			return nil, err
+		}
+		if hold.CopyID != nil && *hold.CopyID != borrowedCopyID {
+			released = append(released, *hold.CopyID)
+		}
+	}
+	return released, nil
+}
+
+// ExpireHolds closes ready holds that were not picked up by their deadline and traps
+// their copies for the next member in the queue
+func ExpireHolds(db *gorm.DB) (int, error) {
+	var holds []models.Hold
+	if err := db.Where("status = 'ready' AND pickup_deadline < ?", today()).Find(&holds).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for i := range holds {
+		if err := closeHold(db, &holds[i], "expired"); err != nil {
+			if errors.Is(err, ErrHoldClosed) {
+				continue
+			}
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// ProcessHolds expires uncollected holds and emails members whose holds became ready.
+// Emails are skipped when email is not configured.
+func ProcessHolds(db *gorm.DB) error {
+	expired, err := ExpireHolds(db)
+	if err != nil {
+		return fmt.Errorf("failed to expire holds: %w", err)
+	}
+	if expired > 0 {
+		log.Printf("[ProcessHolds] Expired %d uncollected holds", expired)
+	}
+
+	emailConfig, err := configs.LoadEmailConfig()
+	if err != nil {
+		log.Printf("[ProcessHolds] Email is not configured, skipping notifications: %v", err)
+		return nil
+	}
+
+	var holds []models.Hold
+	if err := db.Preload("Book", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title")
+	}).Preload("Copy").Preload("User").
+		Where("status = 'ready' AND notified_at IS NULL").Find(&holds).Error; err != nil {
+		return fmt.Errorf("failed to load ready holds: %w", err)
+	}
+
+	for _, hold := range holds {
+		if hold.User == nil || hold.Book == nil || hold.PickupDeadline == nil {
+			continue
+		}
+		location := ""
+		if hold.Copy != nil {
+			location = utils.StringValue(hold.Copy.Location)
+		}
+		if err := utils.SendHoldReadyEmail(hold.User.Email, hold.User.Name, hold.Book.Title, location,
+			hold.PickupDeadline.Format(utils.DateLayout), emailConfig); err != nil {
+			log.Printf("[ProcessHolds] Failed to email user %d about hold %d: %v", hold.UserID, hold.ID, err)
+			continue
+		}
+		if err := db.Model(&hold).Update("notified_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to record hold notification: %w", err)
+		}
+	}
+	return nil
+}
+
+// CancelUserHolds cancels a member's active holds, passing trapped copies on, and
+// removes their closed hold history
+func CancelUserHolds(db *gorm.DB, userID uint) error {
+	var holds []models.Hold
+	if err := db.Where("user_id = ? AND status IN ?", userID, activeHoldStatuses).Find(&holds).Error; err != nil {
+		return err
+	}
+	for i := range holds {
+		if err := CancelHold(db, &holds[i]); err != nil && !errors.Is(err, ErrHoldClosed) {
+			return err
+		}
+	}
+	return db.Where("user_id = ?", userID).Delete(&models.Hold{}).Error
+}
+
+// CancelOrphanedHolds cancels the waiting holds of a book that has no loanable copies left
+func CancelOrphanedHolds(db *gorm.DB, bookID uint) error {
+	var loanable int64
+	if err := db.Model(&models.Copy{}).Where("book_id = ? AND "+loanableCopies, bookID).Count(&loanable).Error; err != nil {
+		return err
+	}
+	if loanable > 0 {
+		return nil
+	}
+	return db.Model(&models.Hold{}).Where("book_id = ? AND status = 'waiting'", bookID).
+		Updates(map[string]interface{}{"status": "cancelled", "closed_at": time.Now()}).Error
+}
+
+// HasActiveHolds reports whether members are still waiting for a book or have a copy
+// of it on the hold shelf
+func HasActiveHolds(db *gorm.DB, bookID uint) (bool, error) {
+	var count int64
+	if err := db.Model(&models.Hold{}).Where("book_id = ? AND status IN ?", bookID, activeHoldStatuses).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// ClearBookHolds removes the closed holds of a book. Active holds are never removed
+// silently: ErrBookHasHolds is returned while any remain.
+func ClearBookHolds(db *gorm.DB, bookID uint) error {
+	active, err := HasActiveHolds(db, bookID)
+	if err != nil {
+		return err
+	}
+	if active {
+		return ErrBookHasHolds
+	}
+	return db.Where("book_id = ? AND status NOT IN ?", bookID, activeHoldStatuses).Delete(&models.Hold{}).Error
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// InsertImportedBook saves a book from an import with its author entries and subjects
+// inside tx. The caller indexes and counts it once the transaction is committed.
+func InsertImportedBook(tx *gorm.DB, book *models.Book, authors []string) error {
+	if err := tx.Create(book).Error; err != nil {
+		return fmt.Errorf("failed to create book: %w", err)
+	}
+	for _, authorName := range authors {
+		if err := CreateBookAuthor(tx, &models.BookAuthor{BookID: book.ID, AuthorName: authorName}); err != nil {
+			return fmt.Errorf("failed to create book authors: %w", err)
+		}
+	}
+	if err := SyncItemKeywords(tx, "book", book.ID, book.Subject, book.Language); err != nil {
+		return fmt.Errorf("failed to save book subjects: %w", err)
+	}
+	return nil
+}
+
+// InsertImportedPaper saves a paper from an import with its author entries and
+// keywords inside tx. userIDFor, when not nil, links an author entry to the account
+// of that author. The caller indexes and counts it once the transaction is committed.
+func InsertImportedPaper(tx *gorm.DB, paper *models.Paper, authors []string, userIDFor func(authorName string) *uint) error {
+	if err := tx.Create(paper).Error; err != nil {
+		return fmt.Errorf("failed to create paper: %w", err)
+	}
+	for _, authorName := range authors {
+		paperAuthor := models.PaperAuthor{PaperID: paper.ID, AuthorName: authorName}
+		if userIDFor != nil {
+			paperAuthor.UserID = userIDFor(authorName)
+		}
+		if err := CreatePaperAuthor(tx, &paperAuthor); err != nil {
+			return fmt.Errorf("failed to create paper authors: %w", err)
+		}
+	}
+	if err := SyncItemKeywords(tx, "paper", paper.ID, paper.Keywords, paper.Language); err != nil {
+		return fmt.Errorf("failed to save paper keywords: %w", err)
+	}
+	return nil
+}
+
+// CreateImportedBook saves a book from an import in its own transaction, indexes it
+// and counts it, as creating a book through the form does. A non-empty sourceID is
+// remembered as the record the book was imported from.
+func CreateImportedBook(db *gorm.DB, book *models.Book, authors []string, source, sourceID string) error {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := InsertImportedBook(tx, book, authors); err != nil {
+			return err
+		}
+		if sourceID == "" {
+			return nil
+		}
+		return RecordImport(tx, source, sourceID, "book", book.ID)
+	}); err != nil {
+		return err
+	}
+
+	IndexItem(db, "book", book.ID)
+	db.Model(&models.Counter{}).Where("name = ?", "total_books").UpdateColumn("count", gorm.Expr("count + 1"))
+	return nil
+}
+
+// ImportedItemID returns the book or paper a record was imported as, or 0 when it has
+// not been imported or the item was deleted since
+func ImportedItemID(db *gorm.DB, source, sourceID string) (string, uint, error) {
+	var imported models.ImportedRecord
+	err := db.Where("source = ? AND source_id = ?", source, sourceID).First(&imported).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	var count int64
+	if err := db.Table(imported.ItemType+"s").Where("id = ?", imported.ItemID).Count(&count).Error; err != nil {
+		return "", 0, err
+	}
+	if count == 0 {
+		return "", 0, nil
+	}
+	return imported.ItemType, imported.ItemID, nil
+}
+
+// RecordImport remembers inside tx that the record sourceID of source was imported as
+// the item. A record imported before and deleted since is pointed at its new item.
+func RecordImport(tx *gorm.DB, source, sourceID, itemType string, itemID uint) error {
+	if err := tx.Where("source = ? AND source_id = ?", source, sourceID).Delete(&models.ImportedRecord{}).Error; err != nil {
+		return err
+	}
+	imported := models.ImportedRecord{Source: source, SourceID: sourceID, ItemType: itemType, ItemID: itemID}
+	if err := tx.Create(&imported).Error; err != nil {
+		return fmt.Errorf("failed to record import: %w", err)
+	}
+	return nil
+}
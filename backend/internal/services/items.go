@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// DeleteItemRecords removes what belongs to a book or paper being deleted: it records
+// the deletion for harvesters, removes the item's keyword links, bookmarks and reading
+// list entries, reviews, extracted full text, related-item recommendations and closed
+// holds, and queues its removal from the search index. Run it in the transaction that
+// deletes the item, so that a failed deletion leaves everything in place; the caller
+// drops the item from the index with RemoveFromIndex once the transaction is committed.
+func DeleteItemRecords(tx *gorm.DB, itemType string, itemID uint) error {
+	// The deletion record reads the categories, so it goes first
+	if err := RecordDeletion(tx, itemType, itemID); err != nil {
+		return fmt.Errorf("failed to record deletion: %w", err)
+	}
+	if err := ClearItemKeywords(tx, itemType, itemID); err != nil {
+		return fmt.Errorf("failed to delete keyword links: %w", err)
+	}
+	if err := RemoveSavedItem(tx, itemType, itemID); err != nil {
+		return fmt.Errorf("failed to remove from reading lists: %w", err)
+	}
+	if err := ClearItemReviews(tx, itemType, itemID); err != nil {
+		return fmt.Errorf("failed to delete reviews: %w", err)
+	}
+	if err := ClearItemText(tx, itemType, itemID); err != nil {
+		return fmt.Errorf("failed to delete full text: %w", err)
+	}
+	if err := ClearRelatedItems(tx, itemType, itemID); err != nil {
+		return fmt.Errorf("failed to delete related items: %w", err)
+	}
+	if itemType == "book" {
+		// Active holds block the deletion with ErrBookHasHolds
+		if err := ClearBookHolds(tx, itemID); err != nil {
+			return err
+		}
+	}
+	if err := queueIndexRemoval(tx, itemType, itemID); err != nil {
+		return fmt.Errorf("failed to queue index removal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RunPeriodically runs job in the background right away and then every interval.
+// Errors are logged and the job is retried at the next tick.
+func RunPeriodically(name string, interval time.Duration, job func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := job(); err != nil {
+				log.Printf("[%s] %v", name, err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunDataMigration runs a one-off data migration unless it has already run, and
+// records it once it succeeds so that later startups skip it
+func RunDataMigration(db *gorm.DB, name string, migrate func(db *gorm.DB) error) error {
+	var count int64
+	if err := db.Model(&models.DataMigration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := migrate(db); err != nil {
+		return err
+	}
+	log.Printf("[DataMigration] Ran %s", name)
+	return db.Create(&models.DataMigration{Name: name, RanAt: time.Now()}).Error
+}
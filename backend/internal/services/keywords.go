@@ -0,0 +1,210 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// keywordJoinTables maps an item type to its keyword junction table and item column
+var keywordJoinTables = map[string][2]string{
+	"book":  {"book_keywords", "book_id"},
+	"paper": {"paper_keywords", "paper_id"},
+}
+
+// NormalizeKeyword folds a keyword to its comparison form so that
+// "Machine-Learning", "machine learning" and " MACHINE  learning" are equal
+func NormalizeKeyword(term string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(term) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteRune(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// SplitKeywords splits a free-text keyword blob on commas, semicolons and newlines
+func SplitKeywords(raw string) []string {
+	parts := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r'
+	})
+
+	seen := make(map[string]bool)
+	var terms []string
+	for _, part := range parts {
+		term := strings.Join(strings.Fields(part), " ")
+		normalized := NormalizeKeyword(term)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// LanguageCode maps an item's free-text Language value to "id", "en" or ""
+func LanguageCode(language *string) string {
+	if language == nil {
+		return ""
+	}
+	lang := strings.ToLower(strings.TrimSpace(*language))
+	switch {
+	case lang == "id" || strings.Contains(lang, "indo"):
+		return "id"
+	case lang == "en" || strings.Contains(lang, "engl") || strings.Contains(lang, "inggris"):
+		return "en"
+	default:
+		return ""
+	}
+}
+
+// FindOrCreateKeyword returns the vocabulary entry for term, creating it if needed
+func FindOrCreateKeyword(db *gorm.DB, term string, language string) (*models.Keyword, error) {
+	normalized := NormalizeKeyword(term)
+	if normalized == "" {
+		return nil, fmt.Errorf("empty keyword")
+	}
+
+	keyword := models.Keyword{Term: term}
+	if language != "" {
+		keyword.Language = &language
+	}
+	if err := db.Where(models.Keyword{Normalized: normalized}).Attrs(keyword).FirstOrCreate(&keyword).Error; err != nil {
+		return nil, err
+	}
+	return &keyword, nil
+}
+
+// SyncItemKeywords replaces the keyword links of a book or paper with the terms in raw
+func SyncItemKeywords(db *gorm.DB, itemType string, itemID uint, raw *string, language *string) error {
+	join, ok := keywordJoinTables[itemType]
+	if !ok {
+		return fmt.Errorf("unsupported item type: %s", itemType)
+	}
+
+	if err := ClearItemKeywords(db, itemType, itemID); err != nil {
+		return err
+	}
+	if raw == nil {
+		return nil
+	}
+
+	lang := LanguageCode(language)
+	for _, term := range SplitKeywords(*raw) {
+		keyword, err := FindOrCreateKeyword(db, term, lang)
+		if err != nil {
+			return err
+		}
+		if err := db.Exec(fmt.Sprintf("INSERT IGNORE INTO %s (%s, keyword_id) VALUES (?, ?)", join[0], join[1]), itemID, keyword.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearItemKeywords removes all keyword links of a book or paper
+func ClearItemKeywords(db *gorm.DB, itemType string, itemID uint) error {
+	join, ok := keywordJoinTables[itemType]
+	if !ok {
+		return fmt.Errorf("unsupported item type: %s", itemType)
+	}
+	return db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", join[0], join[1]), itemID).Error
+}
+
+// ExpandKeywordIDs returns the given keyword IDs plus every synonym or translation grouped with them
+func ExpandKeywordIDs(db *gorm.DB, keywordIDs []uint) ([]uint, error) {
+	if len(keywordIDs) == 0 {
+		return nil, nil
+	}
+
+	var expanded []uint
+	err := db.Model(&models.Keyword{}).
+		Where("id IN ? OR group_id IN (?)", keywordIDs,
+			db.Model(&models.Keyword{}).Select("group_id").Where("id IN ? AND group_id IS NOT NULL", keywordIDs)).
+		Pluck("id", &expanded).Error
+	return expanded, err
+}
+
+// KeywordItemIDs returns the IDs of items of itemType tagged with term or any of its synonyms
+func KeywordItemIDs(db *gorm.DB, itemType string, term string) ([]uint, error) {
+	join, ok := keywordJoinTables[itemType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported item type: %s", itemType)
+	}
+
+	var keywordIDs []uint
+	if err := db.Model(&models.Keyword{}).Where("normalized = ?", NormalizeKeyword(term)).Pluck("id", &keywordIDs).Error; err != nil {
+		return nil, err
+	}
+	expanded, err := ExpandKeywordIDs(db, keywordIDs)
+	if err != nil || len(expanded) == 0 {
+		return nil, err
+	}
+
+	var itemIDs []uint
+	err = db.Table(join[0]).Distinct(join[1]).Where("keyword_id IN ?", expanded).Pluck(join[1], &itemIDs).Error
+	return itemIDs, err
+}
+
+// BackfillKeywords splits existing Paper.Keywords and Book.Subject strings into the
+// keyword vocabulary, each item in its own transaction. Items that already have
+// keyword links are skipped, so an interrupted run can be resumed; it is run once
+// through RunDataMigration, since items created later are linked when saved.
+func BackfillKeywords(db *gorm.DB) error {
+	var papers []models.Paper
+	err := db.Select("id", "keywords", "language").
+		Where("keywords IS NOT NULL AND keywords <> ''").
+		Where("id NOT IN (?)", db.Table("paper_keywords").Select("paper_id")).
+		FindInBatches(&papers, 200, func(tx *gorm.DB, batch int) error {
+			for _, paper := range papers {
+				err := db.Transaction(func(itemTx *gorm.DB) error {
+					return SyncItemKeywords(itemTx, "paper", paper.ID, paper.Keywords, paper.Language)
+				})
+				if err != nil {
+					return fmt.Errorf("paper %d: %w", paper.ID, err)
+				}
+			}
+			log.Printf("[BackfillKeywords] Processed batch %d (%d papers)", batch, len(papers))
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to backfill paper keywords: %w", err)
+	}
+
+	var books []models.Book
+	err = db.Select("id", "subject", "language").
+		Where("subject IS NOT NULL AND subject <> ''").
+		Where("id NOT IN (?)", db.Table("book_keywords").Select("book_id")).
+		FindInBatches(&books, 200, func(tx *gorm.DB, batch int) error {
+			for _, book := range books {
+				err := db.Transaction(func(itemTx *gorm.DB) error {
+					return SyncItemKeywords(itemTx, "book", book.ID, book.Subject, book.Language)
+				})
+				if err != nil {
+					return fmt.Errorf("book %d: %w", book.ID, err)
+				}
+			}
+			log.Printf("[BackfillKeywords] Processed batch %d (%d books)", batch, len(books))
+			return nil
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to backfill book subjects: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeKeyword(t *testing.T) {
+	tests := []struct {
+		name     string
+		term     string
+		expected string
+	}{
+		{name: "Lowercase", term: "Machine Learning", expected: "machine learning"},
+		{name: "Hyphenated", term: "Machine-Learning", expected: "machine learning"},
+		{name: "Extra whitespace", term: "  machine   learning ", expected: "machine learning"},
+		{name: "Punctuation only", term: " - ; ", expected: ""},
+		{name: "Indonesian", term: "Pembelajaran Mesin", expected: "pembelajaran mesin"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NormalizeKeyword(tt.term))
+		})
+	}
+}
+
+func TestSplitKeywords(t *testing.T) {
+	terms := SplitKeywords("Machine Learning; machine-learning, Data  Mining\nSistem Informasi,,")
+	assert.Equal(t, []string{"Machine Learning", "Data Mining", "Sistem Informasi"}, terms)
+	assert.Empty(t, SplitKeywords(" , ; "))
+}
+
+func TestLanguageCode(t *testing.T) {
+	lang := func(s string) *string { return &s }
+
+	assert.Equal(t, "id", LanguageCode(lang("Indonesia")))
+	assert.Equal(t, "id", LanguageCode(lang("Bahasa Indonesia")))
+	assert.Equal(t, "en", LanguageCode(lang("English")))
+	assert.Equal(t, "en", LanguageCode(lang("Inggris")))
+	assert.Equal(t, "", LanguageCode(lang("Arabic")))
+	assert.Equal(t, "", LanguageCode(nil))
+}
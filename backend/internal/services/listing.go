@@ -0,0 +1,343 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not issued for the same listing and sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortKind is the type of a sort column's values, used to carry them in a cursor
+type SortKind int
+
+const (
+	SortNumber SortKind = iota
+	SortText
+	SortTime
+)
+
+// SortColumn is an SQL expression a listing is ordered by. It must never be NULL, so
+// that rows can be compared with the values in a cursor.
+type SortColumn struct {
+	Expr string
+	Kind SortKind
+}
+
+// Sorts declares the fields a listing can be sorted by. Rows are always ordered by the
+// ID of Table last, so the order is total and keyset pagination never skips a row.
+type Sorts struct {
+	Table  string
+	Fields map[string][]SortColumn
+}
+
+// popularity counts the rows of a log table (downloads or citations) for each item
+func popularity(table, itemType, itemTable string) SortColumn {
+	return SortColumn{
+		Expr: fmt.Sprintf("(SELECT COUNT(*) FROM %s WHERE %s.item_type = '%s' AND %s.item_id = %s.id)",
+			table, table, itemType, table, itemTable),
+		Kind: SortNumber,
+	}
+}
+
+// BookSorts are the sort fields of book listings. "relevance" only applies to full-text
+// searches, which join the fulltext_matches scores.
+var BookSorts = Sorts{
+	Table: "books",
+	Fields: map[string][]SortColumn{
+		"created_at":     {{"books.created_at", SortTime}},
+		"updated_at":     {{"books.updated_at", SortTime}},
+		"title":          {{"books.title", SortText}},
+		"author":         {{"COALESCE(books.author, '')", SortText}},
+		"published_year": {{"COALESCE(books.published_year, 0)", SortNumber}},
+		// "rating" sorts by average, breaking ties by the number of ratings
+		"rating":         {{"books.rating_average", SortNumber}, {"books.rating_count", SortNumber}},
+		"rating_average": {{"books.rating_average", SortNumber}},
+		"rating_count":   {{"books.rating_count", SortNumber}},
+		"downloads":      {popularity("downloads", "book", "books")},
+		"citations":      {popularity("citations", "book", "books")},
+		"relevance":      {{"COALESCE(fulltext_matches.score, 0)", SortNumber}},
+	},
+}
+
+// PaperSorts are the sort fields of paper listings
+var PaperSorts = Sorts{
+	Table: "papers",
+	Fields: map[string][]SortColumn{
+		"created_at":     {{"papers.created_at", SortTime}},
+		"updated_at":     {{"papers.updated_at", SortTime}},
+		"title":          {{"papers.title", SortText}},
+		"author":         {{"COALESCE(papers.author, '')", SortText}},
+		"year":           {{"COALESCE(papers.year, 0)", SortNumber}},
+		"rating":         {{"papers.rating_average", SortNumber}, {"papers.rating_count", SortNumber}},
+		"rating_average": {{"papers.rating_average", SortNumber}},
+		"rating_count":   {{"papers.rating_count", SortNumber}},
+		"downloads":      {popularity("downloads", "paper", "papers")},
+		"citations":      {popularity("citations", "paper", "papers")},
+		"relevance":      {{"COALESCE(fulltext_matches.score, 0)", SortNumber}},
+	},
+}
+
+// UserSorts are the sort fields of the admin user listing
+var UserSorts = Sorts{
+	Table: "users",
+	Fields: map[string][]SortColumn{
+		"created_at":    {{"users.created_at", SortTime}},
+		"name":          {{"users.name", SortText}},
+		"email":         {{"users.email", SortText}},
+		"nim_nidn":      {{"COALESCE(users.nim_nidn, '')", SortText}},
+		"login_counter": {{"users.login_counter", SortNumber}},
+	},
+}
+
+// LoanSorts are the sort fields of loan listings
+var LoanSorts = Sorts{
+	Table: "loans",
+	Fields: map[string][]SortColumn{
+		"loaned_at":   {{"loans.loaned_at", SortTime}},
+		"due_date":    {{"loans.due_date", SortTime}},
+		"returned_at": {{"COALESCE(loans.returned_at, loans.loaned_at)", SortTime}},
+	},
+}
+
+// CopySorts are the sort fields of the copy listing
+var CopySorts = Sorts{
+	Table: "copies",
+	Fields: map[string][]SortColumn{
+		"barcode":    {{"copies.barcode", SortText}},
+		"location":   {{"COALESCE(copies.location, '')", SortText}, {"COALESCE(copies.call_number, '')", SortText}},
+		"created_at": {{"copies.created_at", SortTime}},
+	},
+}
+
+// HoldSorts are the sort fields of the hold listing. "queue" lists the holds of each
+// book in the order they are served.
+var HoldSorts = Sorts{
+	Table: "holds",
+	Fields: map[string][]SortColumn{
+		"queue":      {{"holds.book_id", SortNumber}, {"holds.created_at", SortTime}},
+		"created_at": {{"holds.created_at", SortTime}},
+	},
+}
+
+// ReviewSorts are the sort fields of review listings. "rating" breaks ties by date.
+var ReviewSorts = Sorts{
+	Table: "reviews",
+	Fields: map[string][]SortColumn{
+		"created_at": {{"reviews.created_at", SortTime}},
+		"updated_at": {{"reviews.updated_at", SortTime}},
+		"rating":     {{"COALESCE(reviews.rating, 0)", SortNumber}, {"reviews.created_at", SortTime}},
+	},
+}
+
+// NotificationSorts are the sort fields of the notification listing
+var NotificationSorts = Sorts{
+	Table: "notifications",
+	Fields: map[string][]SortColumn{
+		"created_at": {{"notifications.created_at", SortTime}},
+	},
+}
+
+// StockTakeSorts are the sort fields of the stock-take listing
+var StockTakeSorts = Sorts{
+	Table: "stock_takes",
+	Fields: map[string][]SortColumn{
+		"created_at": {{"stock_takes.created_at", SortTime}},
+	},
+}
+
+// StockTakeItemSorts are the sort fields of stock-take items. "shelf" lists them in
+// shelf order with unknown barcodes last, and needs copies joined on copy_id.
+var StockTakeItemSorts = Sorts{
+	Table: "stock_take_items",
+	Fields: map[string][]SortColumn{
+		"shelf": {
+			{"CASE WHEN copies.id IS NULL THEN 1 ELSE 0 END", SortNumber},
+			{"COALESCE(copies.location, '')", SortText},
+			{"COALESCE(copies.call_number, '')", SortText},
+			{"stock_take_items.barcode", SortText},
+		},
+	},
+}
+
+// FieldNames returns the sort fields in alphabetical order
+func (s Sorts) FieldNames() []string {
+	names := make([]string, 0, len(s.Fields))
+	for name := range s.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse reads a sort parameter such as "title:asc" or "downloads:desc". The direction
+// defaults to ascending, and an empty parameter selects fallback.
+func (s Sorts) Parse(param, fallback string) (Sort, error) {
+	if strings.TrimSpace(param) == "" {
+		param = fallback
+	}
+	name, direction, _ := strings.Cut(strings.TrimSpace(param), ":")
+	columns, ok := s.Fields[strings.ToLower(name)]
+	if !ok {
+		return Sort{}, fmt.Errorf("invalid sort field %q, must be one of: %s", name, strings.Join(s.FieldNames(), ", "))
+	}
+
+	result := Sort{Field: strings.ToLower(name), table: s.Table, columns: columns}
+	switch strings.ToLower(direction) {
+	case "", "asc":
+	case "desc":
+		result.Desc = true
+	default:
+		return Sort{}, fmt.Errorf("invalid sort direction %q, must be asc or desc", direction)
+	}
+	return result, nil
+}
+
+// Sort is a validated sort field and direction
+type Sort struct {
+	Field   string
+	Desc    bool
+	table   string
+	columns []SortColumn
+}
+
+// String returns the sort as a sort parameter
+func (s Sort) String() string {
+	if s.Desc {
+		return s.Field + ":desc"
+	}
+	return s.Field + ":asc"
+}
+
+// expressions returns the SQL expressions the rows are ordered by, ending with the ID
+func (s Sort) expressions() []string {
+	exprs := make([]string, 0, len(s.columns)+1)
+	for _, column := range s.columns {
+		exprs = append(exprs, column.Expr)
+	}
+	return append(exprs, s.table+".id")
+}
+
+// Order orders a query by the sort
+func (s Sort) Order(query *gorm.DB) *gorm.DB {
+	direction := " ASC"
+	if s.Desc {
+		direction = " DESC"
+	}
+	for _, expr := range s.expressions() {
+		query = query.Order(expr + direction)
+	}
+	return query
+}
+
+// cursor is the position of the last row of a page
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     uint     `json:"id"`
+}
+
+// After restricts a query to the rows that follow the position encoded in a cursor
+func (s Sort) After(query *gorm.DB, encoded string) (*gorm.DB, error) {
+	condition, args, err := s.after(encoded)
+	if err != nil {
+		return nil, err
+	}
+	return query.Where(condition, args...), nil
+}
+
+// after returns the condition selecting the rows after a cursor, comparing the sort
+// columns and the ID as one row value
+func (s Sort) after(encoded string) (string, []interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, ErrInvalidCursor
+	}
+	var position cursor
+	if err := json.Unmarshal(raw, &position); err != nil || position.Sort != s.String() || len(position.Values) != len(s.columns) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	args := make([]interface{}, 0, len(s.columns)+1)
+	for i, column := range s.columns {
+		value, err := parseSortValue(column.Kind, position.Values[i])
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		args = append(args, value)
+	}
+	args = append(args, position.ID)
+
+	operator := ">"
+	if s.Desc {
+		operator = "<"
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(s.expressions(), ", "), operator, placeholders), args, nil
+}
+
+// Cursor returns the cursor of the page ending with the row lastID. query must be the
+// filtered listing query, so that expressions over its joins can be read.
+func (s Sort) Cursor(query *gorm.DB, lastID uint) (string, error) {
+	exprs := make([]string, len(s.columns))
+	dests := make([]interface{}, len(s.columns))
+	for i, column := range s.columns {
+		exprs[i] = column.Expr
+		switch column.Kind {
+		case SortNumber:
+			dests[i] = new(float64)
+		case SortText:
+			dests[i] = new(string)
+		case SortTime:
+			dests[i] = new(time.Time)
+		}
+	}
+
+	row := query.Session(&gorm.Session{}).Select(strings.Join(exprs, ", ")).
+		Where(s.table+".id = ?", lastID).Limit(1).Row()
+	if err := row.Scan(dests...); err != nil {
+		return "", err
+	}
+
+	values := make([]string, len(dests))
+	for i, dest := range dests {
+		switch value := dest.(type) {
+		case *float64:
+			values[i] = strconv.FormatFloat(*value, 'f', -1, 64)
+		case *string:
+			values[i] = *value
+		case *time.Time:
+			values[i] = value.Format(time.RFC3339Nano)
+		}
+	}
+	return s.encodeCursor(values, lastID)
+}
+
+// encodeCursor encodes the position of a row as an opaque cursor
+func (s Sort) encodeCursor(values []string, id uint) (string, error) {
+	raw, err := json.Marshal(cursor{Sort: s.String(), Values: values, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// parseSortValue reads a value of a cursor back into the type of its column
+func parseSortValue(kind SortKind, value string) (interface{}, error) {
+	switch kind {
+	case SortNumber:
+		return strconv.ParseFloat(value, 64)
+	case SortTime:
+		return time.Parse(time.RFC3339Nano, value)
+	default:
+		return value, nil
+	}
+}
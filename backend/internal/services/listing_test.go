@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortsParse(t *testing.T) {
+	order, err := BookSorts.Parse("", "created_at:desc")
+	assert.NoError(t, err)
+	assert.Equal(t, "created_at:desc", order.String())
+	assert.Equal(t, []string{"books.created_at", "books.id"}, order.expressions())
+
+	order, err = BookSorts.Parse("Rating:DESC", "created_at:desc")
+	assert.NoError(t, err)
+	assert.True(t, order.Desc)
+	assert.Equal(t, []string{"books.rating_average", "books.rating_count", "books.id"}, order.expressions())
+
+	order, err = PaperSorts.Parse("title", "created_at:desc")
+	assert.NoError(t, err)
+	assert.Equal(t, "title:asc", order.String())
+
+	for _, param := range []string{"title; DROP TABLE books", "password_hash:asc", "title:sideways", "year:asc"} {
+		_, err := BookSorts.Parse(param, "created_at:desc")
+		assert.Error(t, err, param)
+	}
+}
+
+func TestSortCursor(t *testing.T) {
+	order, err := BookSorts.Parse("rating:desc", "created_at:desc")
+	assert.NoError(t, err)
+	encoded, err := order.encodeCursor([]string{"4.5", "12"}, 7)
+	assert.NoError(t, err)
+
+	condition, args, err := order.after(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "(books.rating_average, books.rating_count, books.id) < (?, ?, ?)", condition)
+	assert.Equal(t, []interface{}{4.5, 12.0, uint(7)}, args)
+
+	// A cursor only continues the sort it was issued for
+	other, err := BookSorts.Parse("rating:asc", "created_at:desc")
+	assert.NoError(t, err)
+	_, _, err = other.after(encoded)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	for _, bad := range []string{"", "not a cursor", "e30"} {
+		_, _, err := order.after(bad)
+		assert.ErrorIs(t, err, ErrInvalidCursor, bad)
+	}
+
+	created := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	order, err = UserSorts.Parse("created_at", "created_at:desc")
+	assert.NoError(t, err)
+	encoded, err = order.encodeCursor([]string{created.Format(time.RFC3339Nano)}, 3)
+	assert.NoError(t, err)
+	condition, args, err = order.after(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "(users.created_at, users.id) > (?, ?)", condition)
+	assert.Equal(t, []interface{}{created, uint(3)}, args)
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/marc"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// MARC field limits, matching the sizes of the books columns
+const (
+	marcMaxPublisher = 255
+	marcMaxSubject   = 255
+	marcMaxPages     = 50
+	marcMaxISBN      = 50
+	// imported_records.source_id
+	marcMaxControlNumber = 191
+)
+
+// MARCSource is the Source of the imported records of MARC files
+const MARCSource = "marc"
+
+// yearPattern finds the year in a publication date such as "c2019." or "[2020?]"
+var yearPattern = regexp.MustCompile(`\d{4}`)
+
+// marcLanguages maps MARC language codes (008/35-37, 041) to the Language values of books
+var marcLanguages = map[string]string{
+	"ind": "Indonesian",
+	"eng": "English",
+}
+
+// BookFromMARC maps a MARC bibliographic record to a book and its author names:
+// 020 ISBN, 100/110/111 and 700/710 authors, 245 title, 264 or 260 publisher and
+// year, 300 extent, 520 summary, 650 subjects and 008 or 041 language
+func BookFromMARC(record *marc.Record) (models.Book, []string, error) {
+	var book models.Book
+
+	for _, field := range record.Fields("245") {
+		title := trimISBD(field.Subfield('a'))
+		if subtitle := trimISBD(field.Subfield('b')); subtitle != "" {
+			title += ": " + subtitle
+		}
+		book.Title = title
+		break
+	}
+	if book.Title == "" {
+		return book, nil, errors.New("the record has no title (245 $a)")
+	}
+
+	var authors []string
+	addAuthor := func(field marc.DataField) {
+		name := marcPersonName(field)
+		if name != "" && !containsString(authors, name) {
+			authors = append(authors, name)
+		}
+	}
+	for _, tag := range []string{"100", "110", "111", "700", "710"} {
+		for _, field := range record.Fields(tag) {
+			addAuthor(field)
+		}
+	}
+	if len(authors) == 0 {
+		return book, nil, errors.New("the record has no author (100, 110, 111 or 700)")
+	}
+	book.Author = authors[0]
+
+	for _, field := range record.Fields("020") {
+		if isbn := strings.Fields(field.Subfield('a')); len(isbn) > 0 {
+			book.ISBN = optionalString(isbn[0], marcMaxISBN)
+			break
+		}
+	}
+
+	// 264 with second indicator 1 is the publication statement under RDA; older
+	// records put it in 260
+	imprints := record.Fields("260")
+	for _, field := range record.Fields("264") {
+		if field.Ind2 == '1' {
+			imprints = append([]marc.DataField{field}, imprints...)
+		}
+	}
+	for _, field := range imprints {
+		if book.Publisher == nil {
+			book.Publisher = optionalString(trimISBD(field.Subfield('b')), marcMaxPublisher)
+		}
+		if book.PublishedYear == nil {
+			if year, err := strconv.Atoi(yearPattern.FindString(field.Subfield('c'))); err == nil {
+				book.PublishedYear = &year
+			}
+		}
+	}
+
+	for _, field := range record.Fields("300") {
+		book.Pages = optionalString(trimISBD(field.Subfield('a')), marcMaxPages)
+		break
+	}
+
+	var summaries []string
+	for _, field := range record.Fields("520") {
+		if summary := strings.TrimSpace(field.Subfield('a')); summary != "" {
+			summaries = append(summaries, summary)
+		}
+	}
+	book.Summary = optionalString(strings.Join(summaries, "\n\n"), 0)
+
+	// Subjects are kept whole while they fit the column
+	var subjects []string
+	length := 0
+	for _, field := range record.Fields("650") {
+		var parts []string
+		for _, value := range field.SubfieldValues('a', 'x', 'y', 'z') {
+			if value = trimISBD(value); value != "" {
+				parts = append(parts, value)
+			}
+		}
+		subject := strings.Join(parts, " -- ")
+		if subject == "" || containsString(subjects, subject) {
+			continue
+		}
+		if length+len(subject)+2 > marcMaxSubject {
+			break
+		}
+		subjects = append(subjects, subject)
+		length += len(subject) + 2
+	}
+	book.Subject = optionalString(strings.Join(subjects, "; "), 0)
+
+	code := ""
+	if fixed := record.ControlField("008"); len(fixed) >= 38 {
+		code = fixed[35:38]
+	}
+	if _, ok := marcLanguages[code]; !ok {
+		for _, field := range record.Fields("041") {
+			code = field.Subfield('a')
+			break
+		}
+	}
+	if language, ok := marcLanguages[strings.ToLower(code)]; ok {
+		book.Language = &language
+	}
+
+	return book, authors, nil
+}
+
+// BookToMARC maps a book, with its authors loaded, to a MARC bibliographic record.
+// serverURL makes the 856 link to the book's file absolute.
+func BookToMARC(book models.Book, serverURL string) *marc.Record {
+	record := &marc.Record{Leader: marc.DefaultLeader}
+	record.AddControlField("001", strconv.FormatUint(uint64(book.ID), 10))
+	record.AddControlField("005", book.UpdatedAt.Format("20060102150405.0"))
+
+	// 008 fixed-length data elements: entered date, publication date and language
+	dates := "nuuuu"
+	if book.PublishedYear != nil {
+		dates = fmt.Sprintf("s%04d", *book.PublishedYear)
+	}
+	language := "und"
+	switch LanguageCode(book.Language) {
+	case "id":
+		language = "ind"
+	case "en":
+		language = "eng"
+	}
+	record.AddControlField("008", book.CreatedAt.Format("060102")+dates+"    xx "+strings.Repeat(" ", 17)+language+" d")
+
+	record.AddDataField("020", ' ', ' ', marc.Subfield{Code: 'a', Value: utils.StringValue(book.ISBN)})
+
+	authors := make([]string, 0, len(book.Authors))
+	for _, author := range book.Authors {
+		authors = append(authors, author.AuthorName)
+	}
+	if len(authors) == 0 && book.Author != "" {
+		authors = []string{book.Author}
+	}
+	titleIndicator := byte('0')
+	for i, author := range authors {
+		tag := "700"
+		if i == 0 {
+			tag = "100"
+			titleIndicator = '1'
+		}
+		given, family := personName(author)
+		if given == "" {
+			record.AddDataField(tag, '0', ' ', marc.Subfield{Code: 'a', Value: family})
+		} else {
+			record.AddDataField(tag, '1', ' ', marc.Subfield{Code: 'a', Value: family + ", " + given})
+		}
+	}
+
+	title, subtitle, _ := strings.Cut(book.Title, ": ")
+	record.AddDataField("245", titleIndicator, '0',
+		marc.Subfield{Code: 'a', Value: title},
+		marc.Subfield{Code: 'b', Value: subtitle})
+
+	year := ""
+	if book.PublishedYear != nil {
+		year = strconv.Itoa(*book.PublishedYear)
+	}
+	record.AddDataField("264", ' ', '1',
+		marc.Subfield{Code: 'b', Value: utils.StringValue(book.Publisher)},
+		marc.Subfield{Code: 'c', Value: year})
+
+	record.AddDataField("300", ' ', ' ', marc.Subfield{Code: 'a', Value: utils.StringValue(book.Pages)})
+	record.AddDataField("520", ' ', ' ', marc.Subfield{Code: 'a', Value: utils.StringValue(book.Summary)})
+
+	for _, subject := range SplitKeywords(utils.StringValue(book.Subject)) {
+		parts := strings.Split(subject, " -- ")
+		subfields := []marc.Subfield{{Code: 'a', Value: parts[0]}}
+		for _, part := range parts[1:] {
+			subfields = append(subfields, marc.Subfield{Code: 'x', Value: part})
+		}
+		record.AddDataField("650", ' ', '4', subfields...)
+	}
+
+	if fileURL := utils.StringValue(book.FileURL); fileURL != "" {
+		if !strings.HasPrefix(fileURL, "http") {
+			fileURL = serverURL + fileURL
+		}
+		record.AddDataField("856", '4', '0', marc.Subfield{Code: 'u', Value: fileURL})
+	}
+
+	return record
+}
+
+// MARCImportResult is the outcome of importing one record of a MARC file
+type MARCImportResult struct {
+	Record  int    `json:"record"`
+	Status  string `json:"status"` // created, skipped or failed
+	BookID  uint   `json:"book_id,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ImportMARCRecords creates a book for every record. Records imported before (by their
+// 035 system control number, or 003 and 001) and records whose ISBN is already in the
+// catalog are skipped, so a file can be imported again after fixing failed records.
+func ImportMARCRecords(db *gorm.DB, records []*marc.Record, createdBy *uint) []MARCImportResult {
+	results := make([]MARCImportResult, 0, len(records))
+	for i, record := range records {
+		result := MARCImportResult{Record: i + 1}
+		book, authors, err := BookFromMARC(record)
+		result.Title = book.Title
+		sourceID := marcControlNumber(record)
+		var importedID uint
+		if err == nil && sourceID != "" {
+			_, importedID, err = ImportedItemID(db, MARCSource, sourceID)
+		}
+		switch {
+		case err != nil:
+			result.Status, result.Message = "failed", err.Error()
+		case importedID != 0:
+			result.Status, result.BookID = "skipped", importedID
+			result.Message = "record " + sourceID + " was already imported"
+		case book.ISBN != nil && isbnExists(db, *book.ISBN):
+			result.Status, result.Message = "skipped", "a book with ISBN "+*book.ISBN+" already exists"
+		default:
+			book.CreatedBy = createdBy
+			if err := CreateImportedBook(db, &book, authors, MARCSource, sourceID); err != nil {
+				result.Status, result.Message = "failed", err.Error()
+			} else {
+				result.Status, result.BookID = "created", book.ID
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// marcControlNumber returns the number that identifies a record in the catalog it came
+// from: the first 035 $a, such as "(OCoLC)12345", or else 001 qualified by the 003
+// agency as 035 would write it. A bare 001 is only unique within its own catalog, so
+// records without 003, like numbers too long to store, are matched by ISBN alone.
+func marcControlNumber(record *marc.Record) string {
+	number := ""
+	for _, field := range record.Fields("035") {
+		if number = strings.TrimSpace(field.Subfield('a')); number != "" {
+			break
+		}
+	}
+	if number == "" {
+		id := strings.TrimSpace(record.ControlField("001"))
+		agency := strings.TrimSpace(record.ControlField("003"))
+		if id != "" && agency != "" {
+			number = "(" + agency + ")" + id
+		}
+	}
+	if len([]rune(number)) > marcMaxControlNumber {
+		return ""
+	}
+	return number
+}
+
+// isbnExists reports whether a book with the ISBN is already in the catalog
+func isbnExists(db *gorm.DB, isbn string) bool {
+	var count int64
+	db.Model(&models.Book{}).Where("isbn = ?", isbn).Count(&count)
+	return count > 0
+}
+
+// marcPersonName returns the name in a 1XX/7XX field in reading order. Personal
+// names entered surname first (first indicator 1) are turned around.
+func marcPersonName(field marc.DataField) string {
+	name := trimISBD(field.Subfield('a'))
+	if field.Tag[1:] == "00" && field.Ind1 == '1' {
+		if family, given, ok := strings.Cut(name, ","); ok && strings.TrimSpace(given) != "" {
+			name = strings.TrimSpace(given) + " " + strings.TrimSpace(family)
+		}
+	}
+	return name
+}
+
+// trimISBD removes the ISBD punctuation that ends MARC subfields, such as the " /"
+// before a statement of responsibility or the closing period. A period that ends
+// an initial is kept.
+func trimISBD(value string) string {
+	value = strings.TrimRight(strings.TrimSpace(value), " /:;,=")
+	if strings.HasSuffix(value, ".") && !strings.HasSuffix(value, "..") {
+		words := strings.Fields(value)
+		if last := words[len(words)-1]; len([]rune(last)) != 2 {
+			value = strings.TrimSuffix(value, ".")
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+// optionalString returns a pointer to a non-empty value cut to limit runes (0 for no limit)
+func optionalString(value string, limit int) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if runes := []rune(value); limit > 0 && len(runes) > limit {
+		value = strings.TrimSpace(string(runes[:limit]))
+	}
+	return &value
+}
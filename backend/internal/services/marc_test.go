@@ -0,0 +1,100 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"e-repository-api/internal/marc"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookFromMARC(t *testing.T) {
+	record := &marc.Record{Leader: marc.DefaultLeader}
+	record.AddControlField("008", "200315s2020    io            000 0 ind d")
+	record.AddDataField("020", ' ', ' ', marc.Subfield{Code: 'a', Value: "9786020000000 (pbk.)"})
+	record.AddDataField("100", '1', ' ', marc.Subfield{Code: 'a', Value: "Santoso, Budi,"}, marc.Subfield{Code: 'e', Value: "author."})
+	record.AddDataField("245", '1', '0', marc.Subfield{Code: 'a', Value: "Basis data :"}, marc.Subfield{Code: 'b', Value: "teori dan praktik /"}, marc.Subfield{Code: 'c', Value: "Budi Santoso."})
+	record.AddDataField("260", ' ', ' ', marc.Subfield{Code: 'b', Value: "Penerbit Lama,"}, marc.Subfield{Code: 'c', Value: "2019."})
+	record.AddDataField("264", ' ', '1', marc.Subfield{Code: 'a', Value: "Yogyakarta :"}, marc.Subfield{Code: 'b', Value: "Andi,"}, marc.Subfield{Code: 'c', Value: "[2020]"})
+	record.AddDataField("300", ' ', ' ', marc.Subfield{Code: 'a', Value: "xii, 350 halaman ;"}, marc.Subfield{Code: 'c', Value: "23 cm"})
+	record.AddDataField("650", ' ', '4', marc.Subfield{Code: 'a', Value: "Basis data"}, marc.Subfield{Code: 'x', Value: "Perancangan."})
+	record.AddDataField("650", ' ', '4', marc.Subfield{Code: 'a', Value: "SQL."})
+	record.AddDataField("700", '1', ' ', marc.Subfield{Code: 'a', Value: "Wijaya, Ani R."})
+
+	book, authors, err := BookFromMARC(record)
+	assert.NoError(t, err)
+	assert.Equal(t, "Basis data: teori dan praktik", book.Title)
+	assert.Equal(t, []string{"Budi Santoso", "Ani R. Wijaya"}, authors)
+	assert.Equal(t, "Budi Santoso", book.Author)
+	assert.Equal(t, "9786020000000", utils.StringValue(book.ISBN))
+	assert.Equal(t, "Andi", utils.StringValue(book.Publisher))
+	assert.Equal(t, 2020, utils.IntValue(book.PublishedYear))
+	assert.Equal(t, "xii, 350 halaman", utils.StringValue(book.Pages))
+	assert.Equal(t, "Basis data -- Perancangan; SQL", utils.StringValue(book.Subject))
+	assert.Equal(t, "Indonesian", utils.StringValue(book.Language))
+}
+
+func TestBookFromMARCRequiresTitleAndAuthor(t *testing.T) {
+	record := &marc.Record{}
+	record.AddDataField("100", '1', ' ', marc.Subfield{Code: 'a', Value: "Santoso, Budi"})
+	_, _, err := BookFromMARC(record)
+	assert.ErrorContains(t, err, "245")
+
+	record = &marc.Record{}
+	record.AddDataField("245", '0', '0', marc.Subfield{Code: 'a', Value: "Anonim"})
+	_, _, err = BookFromMARC(record)
+	assert.ErrorContains(t, err, "author")
+}
+
+func TestBookToMARCRoundTrip(t *testing.T) {
+	year := 2021
+	publisher, pages, subject, language, isbn, fileURL := "Andi", "200 hlm", "Basis data; SQL", "Indonesian", "9786020000000", "/uploads/books/basis.pdf"
+	book := models.Book{
+		ID: 12, Title: "Basis data: teori dan praktik", Author: "Budi Santoso",
+		Publisher: &publisher, PublishedYear: &year, Pages: &pages, Subject: &subject,
+		Language: &language, ISBN: &isbn, FileURL: &fileURL,
+		Authors:   []models.BookAuthor{{AuthorName: "Budi Santoso"}, {AuthorName: "Ani Wijaya"}},
+		CreatedAt: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 3, 6, 10, 30, 0, 0, time.UTC),
+	}
+
+	record := BookToMARC(book, "https://repo.example.ac.id")
+	fixed := record.ControlField("008")
+	assert.Len(t, fixed, 40)
+	assert.Equal(t, "240305s2021", fixed[:11])
+	assert.Equal(t, "ind", fixed[35:38])
+	assert.Equal(t, "20240306103000.0", record.ControlField("005"))
+	assert.Equal(t, "Santoso, Budi", record.Fields("100")[0].Subfield('a'))
+	assert.Equal(t, "https://repo.example.ac.id/uploads/books/basis.pdf", record.Fields("856")[0].Subfield('u'))
+
+	imported, authors, err := BookFromMARC(record)
+	assert.NoError(t, err)
+	assert.Equal(t, book.Title, imported.Title)
+	assert.Equal(t, []string{"Budi Santoso", "Ani Wijaya"}, authors)
+	assert.Equal(t, publisher, utils.StringValue(imported.Publisher))
+	assert.Equal(t, year, utils.IntValue(imported.PublishedYear))
+	assert.Equal(t, subject, utils.StringValue(imported.Subject))
+	assert.Equal(t, isbn, utils.StringValue(imported.ISBN))
+}
+
+func TestMARCControlNumber(t *testing.T) {
+	record := &marc.Record{Leader: marc.DefaultLeader}
+	record.AddControlField("001", "12")
+	assert.Empty(t, marcControlNumber(record), "001 without 003")
+
+	record.AddControlField("003", "UPT-PUST")
+	assert.Equal(t, "(UPT-PUST)12", marcControlNumber(record))
+
+	record.AddDataField("035", ' ', ' ', marc.Subfield{Code: 'z', Value: "(OCoLC)1"})
+	record.AddDataField("035", ' ', ' ', marc.Subfield{Code: 'a', Value: "(OCoLC)987654 "})
+	assert.Equal(t, "(OCoLC)987654", marcControlNumber(record))
+}
+
+func TestTrimISBD(t *testing.T) {
+	assert.Equal(t, "Basis data", trimISBD("Basis data :"))
+	assert.Equal(t, "Budi Santoso", trimISBD("Budi Santoso."))
+	assert.Equal(t, "Wijaya, Ani R.", trimISBD("Wijaya, Ani R.,"))
+}
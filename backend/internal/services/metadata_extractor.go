@@ -142,6 +142,48 @@ func (me *MetadataExtractor) extractFromPDF(filePath string) (*ExtractedMetadata
 	return me.parseTextMetadata(text, "pdf")
 }
 
+// ExtractPDFPages returns the plain text of every page of a PDF file, in page order.
+// Pages whose text can't be read are returned empty so page numbers stay aligned.
+func (me *MetadataExtractor) ExtractPDFPages(filePath string) (pages []string, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("failed to read PDF file: %v", r)
+		}
+	}()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF file: %v", err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %v", err)
+	}
+
+	reader, err := pdf.NewReader(file, fileInfo.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PDF reader: %v", err)
+	}
+
+	numPages := reader.NumPage()
+	pages = make([]string, numPages)
+	for i := 1; i <= numPages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			continue
+		}
+		pages[i-1] = text
+	}
+	return pages, nil
+}
+
 // extractFromWord extracts metadata from Word documents (simplified - would need a proper Word parser)
 func (me *MetadataExtractor) extractFromWord(filePath string) (*ExtractedMetadata, error) {
 	// For now, we'll return a basic structure
@@ -0,0 +1,930 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// oaiPageSize is how many headers or records a list response holds before it is
+// continued with a resumption token
+const oaiPageSize = 100
+
+const (
+	oaiSchemaLocation  = "http://www.openarchives.org/OAI/2.0/ http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+	oaiDCNamespace     = "http://www.openarchives.org/OAI/2.0/oai_dc/"
+	oaiDCSchema        = "http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+	dcNamespace        = "http://purl.org/dc/elements/1.1/"
+	xsiNamespace       = "http://www.w3.org/2001/XMLSchema-instance"
+	oaiDayFormat       = "2006-01-02"
+	oaiDatestampFormat = "2006-01-02T15:04:05Z"
+)
+
+// oaiPhases are the kinds of records a list walks through in order: live books, live
+// papers, then deleted items of either type
+var oaiPhases = []string{"book", "paper", "deleted"}
+
+// OAIRepository describes the repository to harvesters
+type OAIRepository struct {
+	Name       string
+	BaseURL    string // the address of the OAI-PMH endpoint
+	ServerURL  string // the address of the API, for links to items and their files
+	Identifier string // the namespace of item identifiers, usually the domain
+	AdminEmail string
+}
+
+// OAIResponse is an OAI-PMH response document. Protocol errors are reported in it
+// rather than as HTTP errors.
+type OAIResponse struct {
+	XMLName             xml.Name                `xml:"http://www.openarchives.org/OAI/2.0/ OAI-PMH"`
+	XSI                 string                  `xml:"xmlns:xsi,attr"`
+	SchemaLocation      string                  `xml:"xsi:schemaLocation,attr"`
+	ResponseDate        string                  `xml:"responseDate"`
+	Request             oaiRequest              `xml:"request"`
+	Errors              []oaiError              `xml:"error"`
+	Identify            *oaiIdentify            `xml:"Identify"`
+	ListMetadataFormats *oaiListMetadataFormats `xml:"ListMetadataFormats"`
+	ListSets            *oaiListSets            `xml:"ListSets"`
+	GetRecord           *oaiGetRecord           `xml:"GetRecord"`
+	ListIdentifiers     *oaiListIdentifiers     `xml:"ListIdentifiers"`
+	ListRecords         *oaiListRecords         `xml:"ListRecords"`
+}
+
+type oaiRequest struct {
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+	URL             string `xml:",chardata"`
+}
+
+// oaiError is a protocol error such as badArgument or idDoesNotExist
+type oaiError struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+func (e *oaiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+type oaiIdentify struct {
+	RepositoryName    string               `xml:"repositoryName"`
+	BaseURL           string               `xml:"baseURL"`
+	ProtocolVersion   string               `xml:"protocolVersion"`
+	AdminEmail        []string             `xml:"adminEmail"`
+	EarliestDatestamp string               `xml:"earliestDatestamp"`
+	DeletedRecord     string               `xml:"deletedRecord"`
+	Granularity       string               `xml:"granularity"`
+	Description       *oaiIdentifierScheme `xml:"description>oai-identifier"`
+}
+
+type oaiIdentifierScheme struct {
+	XMLName              xml.Name `xml:"http://www.openarchives.org/OAI/2.0/oai-identifier oai-identifier"`
+	SchemaLocation       string   `xml:"xsi:schemaLocation,attr"`
+	Scheme               string   `xml:"scheme"`
+	RepositoryIdentifier string   `xml:"repositoryIdentifier"`
+	Delimiter            string   `xml:"delimiter"`
+	SampleIdentifier     string   `xml:"sampleIdentifier"`
+}
+
+type oaiListMetadataFormats struct {
+	Formats []oaiMetadataFormat `xml:"metadataFormat"`
+}
+
+type oaiMetadataFormat struct {
+	MetadataPrefix    string `xml:"metadataPrefix"`
+	Schema            string `xml:"schema"`
+	MetadataNamespace string `xml:"metadataNamespace"`
+}
+
+type oaiListSets struct {
+	Sets []oaiSet `xml:"set"`
+}
+
+type oaiSet struct {
+	Spec string `xml:"setSpec"`
+	Name string `xml:"setName"`
+}
+
+type oaiGetRecord struct {
+	Record oaiRecord `xml:"record"`
+}
+
+type oaiListIdentifiers struct {
+	Headers []oaiHeader         `xml:"header"`
+	Token   *oaiResumptionToken `xml:"resumptionToken"`
+}
+
+type oaiListRecords struct {
+	Records []oaiRecord         `xml:"record"`
+	Token   *oaiResumptionToken `xml:"resumptionToken"`
+}
+
+type oaiResumptionToken struct {
+	CompleteListSize int64  `xml:"completeListSize,attr"`
+	Cursor           int    `xml:"cursor,attr"`
+	Value            string `xml:",chardata"`
+}
+
+type oaiRecord struct {
+	Header   oaiHeader    `xml:"header"`
+	Metadata *oaiMetadata `xml:"metadata"`
+}
+
+type oaiHeader struct {
+	Status     string   `xml:"status,attr,omitempty"`
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	SetSpecs   []string `xml:"setSpec"`
+}
+
+type oaiMetadata struct {
+	DC *oaiDC `xml:"oai_dc:dc"`
+}
+
+// oaiDC is a simple Dublin Core record
+type oaiDC struct {
+	OAIDC          string   `xml:"xmlns:oai_dc,attr"`
+	DC             string   `xml:"xmlns:dc,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Title          []string `xml:"dc:title"`
+	Creator        []string `xml:"dc:creator"`
+	Subject        []string `xml:"dc:subject"`
+	Description    []string `xml:"dc:description"`
+	Publisher      []string `xml:"dc:publisher"`
+	Contributor    []string `xml:"dc:contributor"`
+	Date           []string `xml:"dc:date"`
+	Type           []string `xml:"dc:type"`
+	Identifier     []string `xml:"dc:identifier"`
+	Source         []string `xml:"dc:source"`
+	Language       []string `xml:"dc:language"`
+}
+
+// oaiVerb lists the arguments a verb takes besides the verb itself
+type oaiVerb struct {
+	required []string
+	optional []string
+	// resumable verbs may instead be given only a resumptionToken
+	resumable bool
+}
+
+var oaiVerbs = map[string]oaiVerb{
+	"Identify":            {},
+	"ListMetadataFormats": {optional: []string{"identifier"}},
+	"ListSets":            {resumable: true},
+	"GetRecord":           {required: []string{"identifier", "metadataPrefix"}},
+	"ListIdentifiers":     {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}, resumable: true},
+	"ListRecords":         {required: []string{"metadataPrefix"}, optional: []string{"from", "until", "set"}, resumable: true},
+}
+
+// HandleOAI answers an OAI-PMH request. Protocol errors are part of the returned
+// response; the error is only set when the database fails.
+func HandleOAI(db *gorm.DB, repo OAIRepository, args url.Values) (*OAIResponse, error) {
+	response := &OAIResponse{
+		XSI:            xsiNamespace,
+		SchemaLocation: oaiSchemaLocation,
+		ResponseDate:   oaiDatestamp(time.Now()),
+		Request:        oaiRequest{URL: repo.BaseURL},
+	}
+
+	verb, argumentErr := checkOAIArguments(args)
+	if argumentErr != nil {
+		// The request is not echoed when its verb or arguments are invalid
+		response.Errors = []oaiError{*argumentErr}
+		return response, nil
+	}
+	response.Request = oaiRequest{
+		Verb:            verb,
+		Identifier:      args.Get("identifier"),
+		MetadataPrefix:  args.Get("metadataPrefix"),
+		From:            args.Get("from"),
+		Until:           args.Get("until"),
+		Set:             args.Get("set"),
+		ResumptionToken: args.Get("resumptionToken"),
+		URL:             repo.BaseURL,
+	}
+
+	provider := oaiProvider{db: db, repo: repo}
+	var err error
+	switch verb {
+	case "Identify":
+		response.Identify, err = provider.identify()
+	case "ListMetadataFormats":
+		response.ListMetadataFormats, err = provider.listMetadataFormats(args.Get("identifier"))
+	case "ListSets":
+		response.ListSets, err = provider.listSets(args.Get("resumptionToken"))
+	case "GetRecord":
+		response.GetRecord, err = provider.getRecord(args.Get("identifier"), args.Get("metadataPrefix"))
+	case "ListIdentifiers":
+		var records []oaiRecord
+		var token *oaiResumptionToken
+		if records, token, err = provider.list(verb, args, false); err == nil {
+			response.ListIdentifiers = &oaiListIdentifiers{Token: token}
+			for _, record := range records {
+				response.ListIdentifiers.Headers = append(response.ListIdentifiers.Headers, record.Header)
+			}
+		}
+	case "ListRecords":
+		var records []oaiRecord
+		var token *oaiResumptionToken
+		if records, token, err = provider.list(verb, args, true); err == nil {
+			response.ListRecords = &oaiListRecords{Records: records, Token: token}
+		}
+	}
+
+	var protocolErr *oaiError
+	if errors.As(err, &protocolErr) {
+		response.Errors = []oaiError{*protocolErr}
+		return response, nil
+	}
+	return response, err
+}
+
+// checkOAIArguments validates the verb of a request and the arguments it was given
+func checkOAIArguments(args url.Values) (string, *oaiError) {
+	verbs := args["verb"]
+	if len(verbs) != 1 {
+		return "", &oaiError{Code: "badVerb", Message: "Exactly one verb argument is required"}
+	}
+	verb, ok := oaiVerbs[verbs[0]]
+	if !ok {
+		return "", &oaiError{Code: "badVerb", Message: fmt.Sprintf("Illegal verb %q", verbs[0])}
+	}
+
+	for name, values := range args {
+		if len(values) > 1 {
+			return "", &oaiError{Code: "badArgument", Message: fmt.Sprintf("Argument %q is repeated", name)}
+		}
+	}
+	if _, ok := args["resumptionToken"]; ok && verb.resumable {
+		if len(args) != 2 {
+			return "", &oaiError{Code: "badArgument", Message: "resumptionToken is an exclusive argument"}
+		}
+		return verbs[0], nil
+	}
+	for name := range args {
+		if name != "verb" && !containsString(verb.required, name) && !containsString(verb.optional, name) {
+			return "", &oaiError{Code: "badArgument", Message: fmt.Sprintf("Illegal argument %q for %s", name, verbs[0])}
+		}
+	}
+	for _, name := range verb.required {
+		if args.Get(name) == "" {
+			return "", &oaiError{Code: "badArgument", Message: fmt.Sprintf("Missing required argument %q", name)}
+		}
+	}
+	return verbs[0], nil
+}
+
+// oaiProvider answers the verbs of the protocol
+type oaiProvider struct {
+	db   *gorm.DB
+	repo OAIRepository
+}
+
+func (p oaiProvider) identify() (*oaiIdentify, error) {
+	earliest := time.Now()
+	for _, source := range searchSources {
+		var first struct{ UpdatedAt time.Time }
+		err := p.db.Table(source.table).Select("updated_at").Order("updated_at").Limit(1).Scan(&first).Error
+		if err != nil {
+			return nil, err
+		}
+		if !first.UpdatedAt.IsZero() && first.UpdatedAt.Before(earliest) {
+			earliest = first.UpdatedAt
+		}
+	}
+	var deleted models.DeletedItem
+	if err := p.db.Order("deleted_at").Limit(1).Find(&deleted).Error; err != nil {
+		return nil, err
+	}
+	if deleted.ID != 0 && deleted.DeletedAt.Before(earliest) {
+		earliest = deleted.DeletedAt
+	}
+
+	return &oaiIdentify{
+		RepositoryName:    p.repo.Name,
+		BaseURL:           p.repo.BaseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmail:        []string{p.repo.AdminEmail},
+		EarliestDatestamp: oaiDatestamp(earliest),
+		DeletedRecord:     "persistent",
+		Granularity:       "YYYY-MM-DDThh:mm:ssZ",
+		Description: &oaiIdentifierScheme{
+			SchemaLocation:       "http://www.openarchives.org/OAI/2.0/oai-identifier http://www.openarchives.org/OAI/2.0/oai-identifier.xsd",
+			Scheme:               "oai",
+			RepositoryIdentifier: p.repo.Identifier,
+			Delimiter:            ":",
+			SampleIdentifier:     p.identifier("paper", 1),
+		},
+	}, nil
+}
+
+func (p oaiProvider) listMetadataFormats(identifier string) (*oaiListMetadataFormats, error) {
+	if identifier != "" {
+		if _, err := p.record(identifier, false); err != nil {
+			return nil, err
+		}
+	}
+	return &oaiListMetadataFormats{Formats: []oaiMetadataFormat{
+		{MetadataPrefix: "oai_dc", Schema: oaiDCSchema, MetadataNamespace: oaiDCNamespace},
+	}}, nil
+}
+
+// listSets returns the set hierarchy: items by type and by category
+func (p oaiProvider) listSets(resumptionToken string) (*oaiListSets, error) {
+	// The whole list is returned at once, so no token was ever issued
+	if resumptionToken != "" {
+		return nil, &oaiError{Code: "badResumptionToken", Message: "The resumption token is invalid"}
+	}
+
+	sets := []oaiSet{
+		{Spec: "type", Name: "Item types"},
+		{Spec: "type:book", Name: "Books"},
+		{Spec: "type:paper", Name: "Papers"},
+		{Spec: "category", Name: "Categories"},
+	}
+	var categories []models.Category
+	if err := p.db.Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, category := range categories {
+		sets = append(sets, oaiSet{Spec: fmt.Sprintf("category:%d", category.ID), Name: category.Name})
+	}
+	return &oaiListSets{Sets: sets}, nil
+}
+
+func (p oaiProvider) getRecord(identifier, metadataPrefix string) (*oaiGetRecord, error) {
+	if err := checkMetadataPrefix(metadataPrefix); err != nil {
+		return nil, err
+	}
+	record, err := p.record(identifier, true)
+	if err != nil {
+		return nil, err
+	}
+	return &oaiGetRecord{Record: *record}, nil
+}
+
+// oaiToken is the state of a list carried between requests by a resumption token
+type oaiToken struct {
+	Verb           string `json:"v"`
+	MetadataPrefix string `json:"p"`
+	From           string `json:"f,omitempty"`
+	Until          string `json:"u,omitempty"`
+	Set            string `json:"s,omitempty"`
+	// Phase and LastID are the position of the last record sent, Cursor how many were sent
+	Phase  int  `json:"k"`
+	LastID uint `json:"id"`
+	Cursor int  `json:"c"`
+}
+
+func (t oaiToken) encode() string {
+	raw, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeOAIToken(encoded, verb string) (oaiToken, error) {
+	var token oaiToken
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = json.Unmarshal(raw, &token)
+	}
+	if err != nil || token.Verb != verb || token.Phase < 0 || token.Phase >= len(oaiPhases) {
+		return token, &oaiError{Code: "badResumptionToken", Message: "The resumption token is invalid"}
+	}
+	return token, nil
+}
+
+// oaiEntry is a record of a list with its position
+type oaiEntry struct {
+	record oaiRecord
+	phase  int
+	id     uint
+}
+
+// list selects the records of ListIdentifiers and ListRecords, a page at a time
+func (p oaiProvider) list(verb string, args url.Values, withMetadata bool) ([]oaiRecord, *oaiResumptionToken, error) {
+	resumed := args.Get("resumptionToken") != ""
+	state := oaiToken{
+		Verb:           verb,
+		MetadataPrefix: args.Get("metadataPrefix"),
+		From:           args.Get("from"),
+		Until:          args.Get("until"),
+		Set:            args.Get("set"),
+	}
+	if resumed {
+		var err error
+		if state, err = decodeOAIToken(args.Get("resumptionToken"), verb); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := checkMetadataPrefix(state.MetadataPrefix); err != nil {
+		return nil, nil, err
+	}
+	selection, err := parseOAISelection(state.From, state.Until, state.Set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var total int64
+	for _, phase := range oaiPhases {
+		query := p.selected(phase, selection)
+		if query == nil {
+			continue
+		}
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, nil, err
+		}
+		total += count
+	}
+
+	// One record more than a page tells whether the list goes on
+	var entries []oaiEntry
+	for phase := state.Phase; phase < len(oaiPhases) && len(entries) <= oaiPageSize; phase++ {
+		var after uint
+		if phase == state.Phase {
+			after = state.LastID
+		}
+		page, err := p.page(phase, selection, after, oaiPageSize+1-len(entries), withMetadata)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, page...)
+	}
+	if len(entries) == 0 && !resumed {
+		return nil, nil, &oaiError{Code: "noRecordsMatch", Message: "No records match the request"}
+	}
+
+	var token *oaiResumptionToken
+	if len(entries) > oaiPageSize {
+		entries = entries[:oaiPageSize]
+		last := entries[len(entries)-1]
+		next := state
+		next.Phase, next.LastID, next.Cursor = last.phase, last.id, state.Cursor+oaiPageSize
+		token = &oaiResumptionToken{CompleteListSize: total, Cursor: state.Cursor, Value: next.encode()}
+	} else if resumed {
+		// An empty token marks the last page of a list that was split
+		token = &oaiResumptionToken{CompleteListSize: total, Cursor: state.Cursor}
+	}
+
+	records := make([]oaiRecord, len(entries))
+	for i, entry := range entries {
+		records[i] = entry.record
+	}
+	return records, token, nil
+}
+
+// oaiSelection restricts a list by datestamp and set
+type oaiSelection struct {
+	from   *time.Time
+	before *time.Time // exclusive, one unit of granularity past until
+	set    oaiSetFilter
+}
+
+// oaiSetFilter is a parsed set spec
+type oaiSetFilter struct {
+	itemType    string
+	categoryID  uint
+	anyCategory bool
+	// unknown sets have no members
+	unknown bool
+}
+
+func parseOAISelection(from, until, set string) (oaiSelection, error) {
+	var selection oaiSelection
+	var fromDay, untilDay bool
+	var err error
+	if from != "" {
+		var t time.Time
+		if t, fromDay, err = parseOAIDate(from); err != nil {
+			return selection, err
+		}
+		selection.from = &t
+	}
+	if until != "" {
+		var t time.Time
+		if t, untilDay, err = parseOAIDate(until); err != nil {
+			return selection, err
+		}
+		if untilDay {
+			t = t.AddDate(0, 0, 1)
+		} else {
+			t = t.Add(time.Second)
+		}
+		selection.before = &t
+	}
+	if from != "" && until != "" {
+		if fromDay != untilDay {
+			return selection, &oaiError{Code: "badArgument", Message: "from and until must have the same granularity"}
+		}
+		if !selection.from.Before(*selection.before) {
+			return selection, &oaiError{Code: "badArgument", Message: "from must not be later than until"}
+		}
+	}
+
+	selection.set = parseOAISet(set)
+	return selection, nil
+}
+
+// parseOAIDate reads a date or a UTC datestamp, reporting whether it was a date
+func parseOAIDate(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(oaiDayFormat, value); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.Parse(oaiDatestampFormat, value); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, &oaiError{Code: "badArgument", Message: fmt.Sprintf("Illegal date %q, use YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ", value)}
+}
+
+func parseOAISet(spec string) oaiSetFilter {
+	name, value, qualified := strings.Cut(spec, ":")
+	switch {
+	case spec == "" || spec == "type":
+		return oaiSetFilter{}
+	case spec == "category":
+		return oaiSetFilter{anyCategory: true}
+	case name == "type" && (value == "book" || value == "paper"):
+		return oaiSetFilter{itemType: value}
+	case name == "category" && qualified:
+		if id, err := strconv.ParseUint(value, 10, 64); err == nil && id > 0 {
+			return oaiSetFilter{categoryID: uint(id)}
+		}
+	}
+	return oaiSetFilter{unknown: true}
+}
+
+// selected returns the query for the records of a phase within the selection, or nil
+// when the set leaves none
+func (p oaiProvider) selected(phase string, selection oaiSelection) *gorm.DB {
+	set := selection.set
+	if set.unknown || (set.itemType != "" && phase != "deleted" && phase != set.itemType) {
+		return nil
+	}
+
+	if phase == "deleted" {
+		query := p.db.Model(&models.DeletedItem{})
+		if selection.from != nil {
+			query = query.Where("deleted_at >= ?", *selection.from)
+		}
+		if selection.before != nil {
+			query = query.Where("deleted_at < ?", *selection.before)
+		}
+		switch {
+		case set.itemType != "":
+			query = query.Where("item_type = ?", set.itemType)
+		case set.anyCategory:
+			query = query.Where("sets LIKE ?", "% category:%")
+		case set.categoryID != 0:
+			query = query.Where("sets LIKE ?", fmt.Sprintf("%% category:%d %%", set.categoryID))
+		}
+		return query
+	}
+
+	source := oaiSource(phase)
+	query := p.db.Table(source.table)
+	if selection.from != nil {
+		query = query.Where(source.column("updated_at")+" >= ?", *selection.from)
+	}
+	if selection.before != nil {
+		query = query.Where(source.column("updated_at")+" < ?", *selection.before)
+	}
+	switch {
+	case set.anyCategory:
+		query = query.Where(source.column("id")+" IN (?)", p.db.Table(source.categories).Select(source.foreignKey))
+	case set.categoryID != 0:
+		query = query.Where(source.column("id")+" IN (?)",
+			p.db.Table(source.categories).Select(source.foreignKey).Where("category_id = ?", set.categoryID))
+	}
+	return query
+}
+
+// page loads up to limit records of a phase that follow the record with ID after
+func (p oaiProvider) page(phase int, selection oaiSelection, after uint, limit int, withMetadata bool) ([]oaiEntry, error) {
+	query := p.selected(oaiPhases[phase], selection)
+	if query == nil {
+		return nil, nil
+	}
+	query = query.Where("id > ?", after).Order("id").Limit(limit)
+
+	var entries []oaiEntry
+	if oaiPhases[phase] == "deleted" {
+		var deleted []models.DeletedItem
+		if err := query.Find(&deleted).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range deleted {
+			entries = append(entries, oaiEntry{record: p.deletedRecord(item), phase: phase, id: item.ID})
+		}
+		return entries, nil
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	records, err := p.liveRecords(oaiPhases[phase], ids, withMetadata)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if record, ok := records[id]; ok {
+			entries = append(entries, oaiEntry{record: record, phase: phase, id: id})
+		}
+	}
+	return entries, nil
+}
+
+// record looks up the record of an OAI identifier, which may be a deleted item
+func (p oaiProvider) record(identifier string, withMetadata bool) (*oaiRecord, error) {
+	itemType, id, ok := p.parseIdentifier(identifier)
+	if !ok {
+		return nil, &oaiError{Code: "idDoesNotExist", Message: fmt.Sprintf("Unknown identifier %q", identifier)}
+	}
+
+	records, err := p.liveRecords(itemType, []uint{id}, withMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if record, ok := records[id]; ok {
+		return &record, nil
+	}
+
+	var deleted models.DeletedItem
+	if err := p.db.Where("item_type = ? AND item_id = ?", itemType, id).Limit(1).Find(&deleted).Error; err != nil {
+		return nil, err
+	}
+	if deleted.ID == 0 {
+		return nil, &oaiError{Code: "idDoesNotExist", Message: fmt.Sprintf("Unknown identifier %q", identifier)}
+	}
+	record := p.deletedRecord(deleted)
+	return &record, nil
+}
+
+// liveRecords loads the records of existing books or papers by ID
+func (p oaiProvider) liveRecords(itemType string, ids []uint, withMetadata bool) (map[uint]oaiRecord, error) {
+	records := make(map[uint]oaiRecord, len(ids))
+	if len(ids) == 0 {
+		return records, nil
+	}
+	source := oaiSource(itemType)
+
+	var links []struct {
+		ItemID     uint
+		CategoryID uint
+	}
+	if err := p.db.Table(source.categories).Select(source.foreignKey+" AS item_id, category_id").
+		Where(source.foreignKey+" IN ?", ids).Order("category_id").Scan(&links).Error; err != nil {
+		return nil, err
+	}
+	categories := make(map[uint][]uint)
+	for _, link := range links {
+		categories[link.ItemID] = append(categories[link.ItemID], link.CategoryID)
+	}
+
+	header := func(id uint, updatedAt time.Time) oaiHeader {
+		return oaiHeader{
+			Identifier: p.identifier(itemType, id),
+			Datestamp:  oaiDatestamp(updatedAt),
+			SetSpecs:   oaiItemSets(itemType, categories[id]),
+		}
+	}
+
+	if itemType == "book" {
+		var books []models.Book
+		query := p.db.Where("id IN ?", ids)
+		if withMetadata {
+			query = query.Preload("Authors").Preload("Subjects")
+		}
+		if err := query.Find(&books).Error; err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			record := oaiRecord{Header: header(book.ID, book.UpdatedAt)}
+			if withMetadata {
+				record.Metadata = &oaiMetadata{DC: p.bookDC(book)}
+			}
+			records[book.ID] = record
+		}
+		return records, nil
+	}
+
+	var papers []models.Paper
+	query := p.db.Where("id IN ?", ids)
+	if withMetadata {
+		query = query.Preload("Authors").Preload("KeywordTerms")
+	}
+	if err := query.Find(&papers).Error; err != nil {
+		return nil, err
+	}
+	for _, paper := range papers {
+		record := oaiRecord{Header: header(paper.ID, paper.UpdatedAt)}
+		if withMetadata {
+			record.Metadata = &oaiMetadata{DC: p.paperDC(paper)}
+		}
+		records[paper.ID] = record
+	}
+	return records, nil
+}
+
+func (p oaiProvider) deletedRecord(item models.DeletedItem) oaiRecord {
+	return oaiRecord{Header: oaiHeader{
+		Status:     "deleted",
+		Identifier: p.identifier(item.ItemType, item.ItemID),
+		Datestamp:  oaiDatestamp(item.DeletedAt),
+		SetSpecs:   strings.Fields(item.Sets),
+	}}
+}
+
+func (p oaiProvider) bookDC(book models.Book) *oaiDC {
+	dc := newOAIDC()
+	dc.Title = []string{book.Title}
+	for _, author := range book.Authors {
+		dc.Creator = append(dc.Creator, author.AuthorName)
+	}
+	dc.Creator = authorNames(book.Author, dc.Creator)
+	for _, subject := range book.Subjects {
+		dc.Subject = append(dc.Subject, subject.Term)
+	}
+	if len(dc.Subject) == 0 {
+		dc.Subject = SplitKeywords(utils.StringValue(book.Subject))
+	}
+	dc.Description = nonEmpty(utils.StringValue(book.Summary))
+	dc.Publisher = nonEmpty(utils.StringValue(book.Publisher))
+	if book.PublishedYear != nil {
+		dc.Date = []string{strconv.Itoa(*book.PublishedYear)}
+	}
+	dc.Type = []string{"Text", "Book"}
+	dc.Identifier = nonEmpty(p.repo.ServerURL+"/api/v1/books/"+strconv.FormatUint(uint64(book.ID), 10),
+		p.fileURL(book.FileURL))
+	if isbn := utils.StringValue(book.ISBN); isbn != "" {
+		dc.Identifier = append(dc.Identifier, "ISBN:"+isbn)
+	}
+	dc.Language = oaiLanguage(book.Language)
+	return dc
+}
+
+func (p oaiProvider) paperDC(paper models.Paper) *oaiDC {
+	dc := newOAIDC()
+	dc.Title = []string{paper.Title}
+	for _, author := range paper.Authors {
+		dc.Creator = append(dc.Creator, author.AuthorName)
+	}
+	dc.Creator = authorNames(paper.Author, dc.Creator)
+	for _, keyword := range paper.KeywordTerms {
+		dc.Subject = append(dc.Subject, keyword.Term)
+	}
+	if len(dc.Subject) == 0 {
+		dc.Subject = SplitKeywords(utils.StringValue(paper.Keywords))
+	}
+	dc.Description = nonEmpty(utils.StringValue(paper.Abstract))
+	dc.Contributor = nonEmpty(utils.StringValue(paper.Advisor))
+	if paper.Year != nil {
+		dc.Date = []string{strconv.Itoa(*paper.Year)}
+	}
+	dc.Identifier = nonEmpty(p.repo.ServerURL+"/api/v1/papers/"+strconv.FormatUint(uint64(paper.ID), 10),
+		p.fileURL(paper.FileURL))
+	if doi := utils.StringValue(paper.DOI); doi != "" {
+		dc.Identifier = append(dc.Identifier, "https://doi.org/"+doi)
+	}
+	dc.Language = oaiLanguage(paper.Language)
+
+	item := PaperCitationItem(paper)
+	if item.isArticle() {
+		dc.Type = []string{"Text", "Article"}
+		source := item.Journal
+		if item.Volume != "" {
+			source += ", Vol. " + item.Volume
+		}
+		if item.Issue != "" {
+			source += ", No. " + item.Issue
+		}
+		if item.Pages != "" {
+			source += ", pp. " + item.Pages
+		}
+		dc.Source = []string{source}
+		if item.ISSN != "" {
+			dc.Source = append(dc.Source, "ISSN:"+item.ISSN)
+		}
+	} else {
+		dc.Type = []string{"Text", "Thesis"}
+		dc.Publisher = nonEmpty(strings.Trim(utils.StringValue(paper.Department)+", "+item.University, ", "))
+	}
+	return dc
+}
+
+// fileURL returns the address of an uploaded file, or "" when there is none
+func (p oaiProvider) fileURL(path *string) string {
+	value := utils.StringValue(path)
+	if value == "" || strings.HasPrefix(value, "http") {
+		return value
+	}
+	return p.repo.ServerURL + value
+}
+
+// identifier returns the OAI identifier of an item, e.g. oai:repository.example.ac.id:paper/12
+func (p oaiProvider) identifier(itemType string, id uint) string {
+	return fmt.Sprintf("oai:%s:%s/%d", p.repo.Identifier, itemType, id)
+}
+
+func (p oaiProvider) parseIdentifier(identifier string) (string, uint, bool) {
+	local, ok := strings.CutPrefix(identifier, "oai:"+p.repo.Identifier+":")
+	if !ok {
+		return "", 0, false
+	}
+	itemType, rawID, ok := strings.Cut(local, "/")
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if !ok || err != nil || id == 0 || (itemType != "book" && itemType != "paper") {
+		return "", 0, false
+	}
+	return itemType, uint(id), true
+}
+
+// RecordDeletion remembers that a book or paper is being deleted so that harvesters
+// learn of it. It must run before the item's category links are removed.
+func RecordDeletion(db *gorm.DB, itemType string, itemID uint) error {
+	source := oaiSource(itemType)
+	var categoryIDs []uint
+	if err := db.Table(source.categories).Where(source.foreignKey+" = ?", itemID).
+		Order("category_id").Pluck("category_id", &categoryIDs).Error; err != nil {
+		return err
+	}
+
+	deleted := models.DeletedItem{
+		ItemType:  itemType,
+		ItemID:    itemID,
+		Sets:      " " + strings.Join(oaiItemSets(itemType, categoryIDs), " ") + " ",
+		DeletedAt: time.Now(),
+	}
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&deleted).Error
+}
+
+// oaiItemSets returns the set specs of an item of the given type and categories
+func oaiItemSets(itemType string, categoryIDs []uint) []string {
+	sets := []string{"type:" + itemType}
+	for _, id := range categoryIDs {
+		sets = append(sets, fmt.Sprintf("category:%d", id))
+	}
+	return sets
+}
+
+func oaiSource(itemType string) searchSource {
+	if itemType == "book" {
+		return searchSources[0]
+	}
+	return searchSources[1]
+}
+
+func checkMetadataPrefix(prefix string) error {
+	if prefix != "oai_dc" {
+		return &oaiError{Code: "cannotDisseminateFormat", Message: fmt.Sprintf("Metadata format %q is not supported, use oai_dc", prefix)}
+	}
+	return nil
+}
+
+func newOAIDC() *oaiDC {
+	return &oaiDC{
+		OAIDC:          oaiDCNamespace,
+		DC:             dcNamespace,
+		SchemaLocation: oaiDCNamespace + " " + oaiDCSchema,
+	}
+}
+
+// oaiLanguage returns the ISO 639-1 code of a language, or the language as entered
+func oaiLanguage(language *string) []string {
+	if code := LanguageCode(language); code != "" {
+		return []string{code}
+	}
+	return nonEmpty(strings.TrimSpace(utils.StringValue(language)))
+}
+
+func oaiDatestamp(t time.Time) string {
+	return t.UTC().Format(oaiDatestampFormat)
+}
+
+// nonEmpty returns the values that are not empty
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"e-repository-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckOAIArguments(t *testing.T) {
+	cases := []struct {
+		query string
+		code  string
+	}{
+		{"verb=Identify", ""},
+		{"verb=ListRecords&metadataPrefix=oai_dc&from=2024-01-01&set=type:paper", ""},
+		{"verb=ListRecords&resumptionToken=abc", ""},
+		{"", "badVerb"},
+		{"verb=Harvest", "badVerb"},
+		{"verb=Identify&verb=Identify", "badVerb"},
+		{"verb=Identify&metadataPrefix=oai_dc", "badArgument"},
+		{"verb=ListRecords", "badArgument"},
+		{"verb=ListRecords&metadataPrefix=oai_dc&metadataPrefix=oai_dc", "badArgument"},
+		{"verb=ListIdentifiers&resumptionToken=abc&metadataPrefix=oai_dc", "badArgument"},
+		{"verb=GetRecord&metadataPrefix=oai_dc", "badArgument"},
+	}
+	for _, tc := range cases {
+		args, _ := url.ParseQuery(tc.query)
+		_, err := checkOAIArguments(args)
+		if tc.code == "" {
+			assert.Nil(t, err, tc.query)
+		} else if assert.NotNil(t, err, tc.query) {
+			assert.Equal(t, tc.code, err.Code, tc.query)
+		}
+	}
+}
+
+func TestParseOAISelection(t *testing.T) {
+	selection, err := parseOAISelection("2024-01-01", "2024-01-31", "category:4")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *selection.from)
+	// until is inclusive, so a day runs to the start of the next
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), *selection.before)
+	assert.Equal(t, oaiSetFilter{categoryID: 4}, selection.set)
+
+	selection, err = parseOAISelection("", "2024-01-31T10:00:00Z", "")
+	assert.NoError(t, err)
+	assert.Nil(t, selection.from)
+	assert.Equal(t, time.Date(2024, 1, 31, 10, 0, 1, 0, time.UTC), *selection.before)
+
+	for _, dates := range [][2]string{
+		{"2024-01-01", "2024-01-31T00:00:00Z"}, // mixed granularity
+		{"2024-02-01", "2024-01-31"},
+		{"01/02/2024", ""},
+		{"2024-01-01T10:00:00", ""},
+	} {
+		_, err := parseOAISelection(dates[0], dates[1], "")
+		assert.Error(t, err, dates)
+	}
+}
+
+func TestParseOAISet(t *testing.T) {
+	assert.Equal(t, oaiSetFilter{}, parseOAISet(""))
+	assert.Equal(t, oaiSetFilter{}, parseOAISet("type"))
+	assert.Equal(t, oaiSetFilter{itemType: "book"}, parseOAISet("type:book"))
+	assert.Equal(t, oaiSetFilter{anyCategory: true}, parseOAISet("category"))
+	assert.Equal(t, oaiSetFilter{categoryID: 12}, parseOAISet("category:12"))
+	assert.True(t, parseOAISet("type:thesis").unknown)
+	assert.True(t, parseOAISet("category:x").unknown)
+	assert.True(t, parseOAISet("faculty").unknown)
+}
+
+func TestOAIToken(t *testing.T) {
+	token := oaiToken{Verb: "ListRecords", MetadataPrefix: "oai_dc", Set: "type:paper", Phase: 1, LastID: 42, Cursor: 100}
+	decoded, err := decodeOAIToken(token.encode(), "ListRecords")
+	assert.NoError(t, err)
+	assert.Equal(t, token, decoded)
+
+	// A token only continues the list it was issued for
+	_, err = decodeOAIToken(token.encode(), "ListIdentifiers")
+	assert.Error(t, err)
+	_, err = decodeOAIToken("not-a-token", "ListRecords")
+	assert.Error(t, err)
+}
+
+func TestOAIIdentifier(t *testing.T) {
+	provider := oaiProvider{repo: OAIRepository{Identifier: "repository.example.ac.id"}}
+	identifier := provider.identifier("paper", 12)
+	assert.Equal(t, "oai:repository.example.ac.id:paper/12", identifier)
+
+	itemType, id, ok := provider.parseIdentifier(identifier)
+	assert.True(t, ok)
+	assert.Equal(t, "paper", itemType)
+	assert.Equal(t, uint(12), id)
+
+	for _, invalid := range []string{"oai:other.ac.id:paper/12", "oai:repository.example.ac.id:thesis/1", "oai:repository.example.ac.id:book/x"} {
+		_, _, ok := provider.parseIdentifier(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestOAIResponseXML(t *testing.T) {
+	provider := oaiProvider{repo: OAIRepository{Identifier: "repository.example.ac.id", ServerURL: "https://repository.example.ac.id"}}
+	journal, volume := "Jurnal Informatika", 3
+	language := "Indonesia"
+	paper := models.Paper{ID: 7, Title: "Analisis Sistem", Author: "Budi Santoso", Journal: &journal, Volume: &volume, Language: &language}
+
+	response := &OAIResponse{
+		XSI:            xsiNamespace,
+		SchemaLocation: oaiSchemaLocation,
+		ResponseDate:   "2024-05-01T00:00:00Z",
+		Request:        oaiRequest{Verb: "GetRecord", URL: "https://repository.example.ac.id/oai"},
+		GetRecord: &oaiGetRecord{Record: oaiRecord{
+			Header:   oaiHeader{Identifier: provider.identifier("paper", 7), Datestamp: "2024-04-01T10:00:00Z", SetSpecs: oaiItemSets("paper", []uint{2})},
+			Metadata: &oaiMetadata{DC: provider.paperDC(paper)},
+		}},
+	}
+	body, err := xml.Marshal(response)
+	assert.NoError(t, err)
+	document := string(body)
+
+	assert.True(t, strings.HasPrefix(document, `<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/" xmlns:xsi=`), document)
+	assert.Contains(t, document, `<request verb="GetRecord">https://repository.example.ac.id/oai</request>`)
+	assert.Contains(t, document, `<setSpec>type:paper</setSpec><setSpec>category:2</setSpec>`)
+	assert.Contains(t, document, `<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"`)
+	assert.Contains(t, document, `<dc:title>Analisis Sistem</dc:title><dc:creator>Budi Santoso</dc:creator>`)
+	assert.Contains(t, document, `<dc:type>Article</dc:type>`)
+	assert.Contains(t, document, `<dc:identifier>https://repository.example.ac.id/api/v1/papers/7</dc:identifier>`)
+	assert.Contains(t, document, `<dc:source>Jurnal Informatika, Vol. 3</dc:source>`)
+	assert.Contains(t, document, `<dc:language>id</dc:language>`)
+	assert.NotContains(t, document, "<error")
+}
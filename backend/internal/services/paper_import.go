@@ -0,0 +1,778 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// PaperEntry is a paper read from a reference file, before it is created. Volume and
+// issue are kept as text so a value that is not a number can be reported.
+type PaperEntry struct {
+	Title      string   `json:"title"`
+	Authors    []string `json:"authors"`
+	Year       *int     `json:"year,omitempty"`
+	Journal    string   `json:"journal,omitempty"`
+	Volume     string   `json:"volume,omitempty"`
+	Issue      string   `json:"issue,omitempty"`
+	Pages      string   `json:"pages,omitempty"`
+	DOI        string   `json:"doi,omitempty"`
+	ISSN       string   `json:"issn,omitempty"`
+	Abstract   string   `json:"abstract,omitempty"`
+	Keywords   string   `json:"keywords,omitempty"`
+	Language   string   `json:"language,omitempty"`
+	University string   `json:"university,omitempty"`
+	Department string   `json:"department,omitempty"`
+	Advisor    string   `json:"advisor,omitempty"`
+}
+
+// ErrUnsupportedImportFormat is returned for files that are not BibTeX, RIS or CSV
+var ErrUnsupportedImportFormat = errors.New("unsupported file format, expected .bib, .ris or .csv")
+
+// ParsePaperEntries reads the entries of a BibTeX, RIS or CSV file. The format is
+// taken from the file name, or from the content when the extension is unknown.
+func ParsePaperEntries(fileName string, data []byte) (string, []PaperEntry, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), ".")
+	switch format {
+	case "bib", "bibtex":
+		format = "bibtex"
+	case "ris", "csv":
+	case "txt", "":
+		firstLine, _, _ := bytes.Cut(bytes.TrimSpace(data), []byte("\n"))
+		switch {
+		case bytes.HasPrefix(firstLine, []byte("@")):
+			format = "bibtex"
+		case risTagPattern.Match(bytes.TrimRight(firstLine, " \r")):
+			format = "ris"
+		default:
+			format = "csv"
+		}
+	default:
+		return "", nil, ErrUnsupportedImportFormat
+	}
+
+	var entries []PaperEntry
+	var err error
+	switch format {
+	case "bibtex":
+		entries, err = ParseBibTeX(string(data))
+	case "ris":
+		entries, err = ParseRIS(string(data))
+	default:
+		entries, err = ParsePaperCSV(data)
+	}
+	return format, entries, err
+}
+
+// ValidatePaperEntry returns the problems that keep an entry from being created
+// (errors) and the ones it can be created with (warnings)
+func ValidatePaperEntry(entry PaperEntry) (problems []string, warnings []string) {
+	if strings.TrimSpace(entry.Title) == "" {
+		problems = append(problems, "title is required")
+	} else if len([]rune(entry.Title)) > 500 {
+		problems = append(problems, "title is longer than 500 characters")
+	}
+	authors := 0
+	for _, author := range entry.Authors {
+		if strings.TrimSpace(author) == "" {
+			continue
+		}
+		authors++
+		if len([]rune(author)) > 255 {
+			problems = append(problems, fmt.Sprintf("author %q is longer than 255 characters", author))
+		}
+	}
+	if authors == 0 {
+		problems = append(problems, "at least one author is required")
+	}
+	if entry.Year != nil && (*entry.Year < 1000 || *entry.Year > time.Now().Year()+1) {
+		problems = append(problems, fmt.Sprintf("year %d is not a valid publication year", *entry.Year))
+	}
+	if entry.DOI != "" && !doiPattern.MatchString(NormalizeDOI(entry.DOI)) {
+		problems = append(problems, fmt.Sprintf("DOI %q is not valid", entry.DOI))
+	}
+	if len([]rune(entry.Pages)) > 50 {
+		problems = append(problems, "pages is longer than 50 characters")
+	}
+	for _, field := range []struct{ name, value string }{{"journal", entry.Journal}, {"university", entry.University}, {"department", entry.Department}, {"advisor", entry.Advisor}, {"language", entry.Language}} {
+		if len([]rune(field.value)) > 255 {
+			problems = append(problems, field.name+" is longer than 255 characters")
+		}
+	}
+
+	if _, err := strconv.Atoi(entry.Volume); entry.Volume != "" && err != nil {
+		warnings = append(warnings, fmt.Sprintf("volume %q is not a number and will be left out", entry.Volume))
+	}
+	if _, err := strconv.Atoi(entry.Issue); entry.Issue != "" && err != nil {
+		warnings = append(warnings, fmt.Sprintf("issue %q is not a number and will be left out", entry.Issue))
+	}
+	return problems, warnings
+}
+
+// doiPattern matches a DOI without its resolver prefix
+var doiPattern = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+
+// NormalizeDOI strips the resolver address or "doi:" prefix from a DOI
+func NormalizeDOI(doi string) string {
+	doi = strings.TrimSpace(doi)
+	lower := strings.ToLower(doi)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return strings.TrimSpace(doi[len(prefix):])
+		}
+	}
+	return doi
+}
+
+// Paper returns the paper and author names an entry is created as
+func (e PaperEntry) Paper() (models.Paper, []string) {
+	var authors []string
+	for _, author := range e.Authors {
+		if author = strings.Join(strings.Fields(author), " "); author != "" {
+			authors = append(authors, author)
+		}
+	}
+
+	paper := models.Paper{
+		Title:      strings.Join(strings.Fields(e.Title), " "),
+		Year:       e.Year,
+		Journal:    optionalString(e.Journal, 0),
+		Pages:      optionalString(e.Pages, 0),
+		DOI:        optionalString(NormalizeDOI(e.DOI), 0),
+		ISSN:       optionalString(e.ISSN, 191),
+		Abstract:   optionalString(e.Abstract, 0),
+		Keywords:   optionalString(e.Keywords, 0),
+		Language:   optionalString(e.Language, 0),
+		University: optionalString(e.University, 0),
+		Department: optionalString(e.Department, 0),
+		Advisor:    optionalString(e.Advisor, 0),
+	}
+	if len(authors) > 0 {
+		paper.Author = authors[0]
+	}
+	if volume, err := strconv.Atoi(e.Volume); err == nil {
+		paper.Volume = &volume
+	}
+	if issue, err := strconv.Atoi(e.Issue); err == nil {
+		paper.Issue = &issue
+	}
+	return paper, authors
+}
+
+// PaperDuplicateWarnings returns, for each entry, warnings about papers already in the
+// repository with the same DOI or title and about earlier entries of the same file
+func PaperDuplicateWarnings(db *gorm.DB, entries []PaperEntry) ([][]string, error) {
+	var dois, titles []string
+	for _, entry := range entries {
+		if doi := NormalizeDOI(entry.DOI); doi != "" {
+			dois = append(dois, strings.ToLower(doi))
+		}
+		if title := normalizedTitle(entry.Title); title != "" {
+			titles = append(titles, title)
+		}
+	}
+
+	type existingPaper struct {
+		ID    uint
+		Title string
+		DOI   *string
+	}
+	var existing []existingPaper
+	if len(dois) > 0 || len(titles) > 0 {
+		query := db.Model(&models.Paper{}).Select("id, title, doi")
+		switch {
+		case len(dois) > 0 && len(titles) > 0:
+			query = query.Where("LOWER(doi) IN ? OR LOWER(title) IN ?", dois, titles)
+		case len(dois) > 0:
+			query = query.Where("LOWER(doi) IN ?", dois)
+		default:
+			query = query.Where("LOWER(title) IN ?", titles)
+		}
+		if err := query.Scan(&existing).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	warnings := make([][]string, len(entries))
+	firstDOI := make(map[string]int)
+	firstTitle := make(map[string]int)
+	for i, entry := range entries {
+		doi := strings.ToLower(NormalizeDOI(entry.DOI))
+		title := normalizedTitle(entry.Title)
+		for _, paper := range existing {
+			switch {
+			case doi != "" && paper.DOI != nil && strings.EqualFold(*paper.DOI, doi):
+				warnings[i] = append(warnings[i], fmt.Sprintf("paper %d %q has the same DOI", paper.ID, paper.Title))
+			case title != "" && normalizedTitle(paper.Title) == title:
+				warnings[i] = append(warnings[i], fmt.Sprintf("paper %d %q has the same title", paper.ID, paper.Title))
+			}
+		}
+
+		if first, ok := firstDOI[doi]; ok && doi != "" {
+			warnings[i] = append(warnings[i], fmt.Sprintf("entry %d has the same DOI", first+1))
+		} else if first, ok := firstTitle[title]; ok && title != "" {
+			warnings[i] = append(warnings[i], fmt.Sprintf("entry %d has the same title", first+1))
+		}
+		if _, ok := firstDOI[doi]; !ok {
+			firstDOI[doi] = i
+		}
+		if _, ok := firstTitle[title]; !ok {
+			firstTitle[title] = i
+		}
+	}
+	return warnings, nil
+}
+
+// normalizedTitle lowercases a title and collapses its whitespace for comparison
+func normalizedTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), " "))
+}
+
+// ParseBibTeX reads the entries of a BibTeX file. @string macros are expanded and
+// @comment and @preamble blocks are skipped.
+func ParseBibTeX(data string) ([]PaperEntry, error) {
+	p := &bibtexParser{data: data, macros: map[string]string{}}
+	var entries []PaperEntry
+	for {
+		start := strings.IndexByte(p.data[p.pos:], '@')
+		if start < 0 {
+			return entries, nil
+		}
+		p.pos += start + 1
+
+		entryType := strings.ToLower(p.identifier())
+		p.skipSpace()
+		if p.pos >= len(p.data) || (p.data[p.pos] != '{' && p.data[p.pos] != '(') {
+			continue // an @ outside an entry, e.g. in a comment
+		}
+		closing := byte('}')
+		if p.data[p.pos] == '(' {
+			closing = ')'
+		}
+		p.pos++
+
+		switch entryType {
+		case "comment", "preamble":
+			p.skipBlock(closing)
+			continue
+		case "string":
+			fields, err := p.fields(closing)
+			if err != nil {
+				return entries, err
+			}
+			for name, value := range fields {
+				p.macros[name] = value
+			}
+			continue
+		}
+
+		// The citation key, up to the first comma
+		end := strings.IndexAny(p.data[p.pos:], ",}")
+		if end < 0 {
+			return entries, fmt.Errorf("entry %d: unterminated entry", len(entries)+1)
+		}
+		p.pos += end
+		if p.data[p.pos] == ',' {
+			p.pos++
+		}
+		fields, err := p.fields(closing)
+		if err != nil {
+			return entries, fmt.Errorf("entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, bibtexEntry(entryType, fields))
+	}
+}
+
+// bibtexEntry maps the fields of a BibTeX entry to a paper entry
+func bibtexEntry(entryType string, fields map[string]string) PaperEntry {
+	text := func(name string) string { return bibtexText(fields[name]) }
+
+	entry := PaperEntry{
+		Title:    text("title"),
+		Volume:   text("volume"),
+		Issue:    text("number"),
+		Pages:    strings.ReplaceAll(text("pages"), "--", "-"),
+		DOI:      text("doi"),
+		ISSN:     text("issn"),
+		Abstract: text("abstract"),
+		Keywords: text("keywords"),
+		Language: text("language"),
+		Journal:  firstNonEmpty(text("journal"), text("journaltitle"), text("booktitle")),
+	}
+	entry.Authors = bibtexNames(fields["author"])
+	if year, err := strconv.Atoi(yearPattern.FindString(firstNonEmpty(text("year"), text("date")))); err == nil {
+		entry.Year = &year
+	}
+	switch entryType {
+	case "mastersthesis", "phdthesis", "thesis":
+		entry.University = firstNonEmpty(text("school"), text("institution"))
+	case "techreport":
+		entry.University = text("institution")
+	}
+	return entry
+}
+
+// firstNonEmpty returns the first value that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// bibtexParser reads BibTeX entries from data
+type bibtexParser struct {
+	data   string
+	pos    int
+	macros map[string]string
+}
+
+// identifier reads an entry type, field name or macro name
+func (p *bibtexParser) identifier() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if unicode.IsSpace(rune(c)) || strings.IndexByte(`{}(),="#%`, c) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return p.data[start:p.pos]
+}
+
+// skipSpace skips whitespace
+func (p *bibtexParser) skipSpace() {
+	for p.pos < len(p.data) && unicode.IsSpace(rune(p.data[p.pos])) {
+		p.pos++
+	}
+}
+
+// skipBlock skips to the end of the block closed by closing
+func (p *bibtexParser) skipBlock(closing byte) {
+	depth := 0
+	for ; p.pos < len(p.data); p.pos++ {
+		switch c := p.data[p.pos]; {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == closing && depth == 0:
+			p.pos++
+			return
+		}
+	}
+}
+
+// fields reads name = value pairs up to the end of the entry. Names are lowercased.
+func (p *bibtexParser) fields(closing byte) (map[string]string, error) {
+	fields := make(map[string]string)
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return fields, errors.New("unterminated entry")
+		}
+		if p.data[p.pos] == closing {
+			p.pos++
+			return fields, nil
+		}
+		if p.data[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+
+		name := strings.ToLower(p.identifier())
+		p.skipSpace()
+		if name == "" || p.pos >= len(p.data) || p.data[p.pos] != '=' {
+			return fields, fmt.Errorf("expected a field near %q", p.excerpt())
+		}
+		p.pos++
+		value, err := p.value()
+		if err != nil {
+			return fields, fmt.Errorf("field %s: %w", name, err)
+		}
+		fields[name] = value
+	}
+}
+
+// value reads a field value: braced or quoted text, numbers and macros joined by #
+func (p *bibtexParser) value() (string, error) {
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return "", errors.New("unterminated value")
+		}
+		switch p.data[p.pos] {
+		case '{':
+			text, err := p.delimited('{', '}')
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(text)
+		case '"':
+			text, err := p.delimited('"', '"')
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(text)
+		default:
+			word := p.identifier()
+			if word == "" {
+				return "", fmt.Errorf("unexpected %q", p.excerpt())
+			}
+			if macro, ok := p.macros[strings.ToLower(word)]; ok {
+				word = macro
+			} else if month, ok := bibtexMonths[strings.ToLower(word)]; ok {
+				word = month
+			}
+			b.WriteString(word)
+		}
+
+		p.skipSpace()
+		if p.pos < len(p.data) && p.data[p.pos] == '#' {
+			p.pos++
+			continue
+		}
+		return b.String(), nil
+	}
+}
+
+// delimited reads text between open and close, keeping nested braces
+func (p *bibtexParser) delimited(open, close byte) (string, error) {
+	p.pos++ // the opening delimiter
+	start, depth := p.pos, 0
+	for ; p.pos < len(p.data); p.pos++ {
+		c := p.data[p.pos]
+		switch {
+		case c == '\\':
+			p.pos++ // an escaped character never closes the text
+		case c == '{' && open == '"':
+			depth++
+		case c == '}' && open == '"' && depth > 0:
+			depth--
+		case c == close && depth == 0:
+			text := p.data[start:p.pos]
+			p.pos++
+			return text, nil
+		case c == open:
+			depth++
+		case c == close:
+			depth--
+		}
+	}
+	return "", errors.New("unterminated value")
+}
+
+// excerpt returns the text at the parse position for error messages
+func (p *bibtexParser) excerpt() string {
+	return p.data[p.pos:min(p.pos+20, len(p.data))]
+}
+
+// bibtexMonths are the predefined month macros
+var bibtexMonths = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April", "may": "May", "jun": "June",
+	"jul": "July", "aug": "August", "sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// bibtexAccents maps LaTeX accent commands and the letters they apply to
+var bibtexAccents = map[string]map[string]string{
+	`'`: {"a": "á", "e": "é", "i": "í", "o": "ó", "u": "ú", "y": "ý", "A": "Á", "E": "É", "I": "Í", "O": "Ó", "U": "Ú"},
+	"`": {"a": "à", "e": "è", "i": "ì", "o": "ò", "u": "ù", "A": "À", "E": "È", "O": "Ò"},
+	`^`: {"a": "â", "e": "ê", "i": "î", "o": "ô", "u": "û", "A": "Â", "E": "Ê", "O": "Ô"},
+	`"`: {"a": "ä", "e": "ë", "i": "ï", "o": "ö", "u": "ü", "A": "Ä", "O": "Ö", "U": "Ü"},
+	`~`: {"a": "ã", "n": "ñ", "o": "õ", "N": "Ñ"},
+	`c`: {"c": "ç", "C": "Ç"},
+}
+
+// bibtexAccent matches accent commands such as \'e, \'{e} and {\'e}
+var bibtexAccent = regexp.MustCompile(`\\([` + "`" + `'^"~]|c )\{?\\?([A-Za-z])\}?`)
+
+// bibtexCommand matches other LaTeX commands such as \emph, whose argument is kept
+var bibtexCommand = regexp.MustCompile(`\\[A-Za-z]+\s*`)
+
+// bibtexText turns a BibTeX value into plain text: accents are resolved, escaped
+// characters unescaped, and commands and grouping braces dropped
+func bibtexText(value string) string {
+	value = bibtexAccent.ReplaceAllStringFunc(value, func(match string) string {
+		parts := bibtexAccent.FindStringSubmatch(match)
+		if letter, ok := bibtexAccents[strings.TrimSpace(parts[1])][parts[2]]; ok {
+			return letter
+		}
+		return parts[2]
+	})
+	value = strings.NewReplacer(`\&`, "&", `\%`, "%", `\_`, "_", `\$`, "$", `\#`, "#", `\{`, "", `\}`, "", "~", " ").Replace(value)
+	value = bibtexCommand.ReplaceAllString(value, "")
+	value = strings.NewReplacer("{", "", "}", "").Replace(value)
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// bibtexNames splits a BibTeX name list on "and" outside braces and returns the names
+// in reading order. "others" is dropped.
+func bibtexNames(value string) []string {
+	var names []string
+	depth, start := 0, 0
+	add := func(name string) {
+		name = bibtexText(name)
+		if name == "" || strings.EqualFold(name, "others") {
+			return
+		}
+		if family, given, ok := strings.Cut(name, ","); ok {
+			// "von Last, Jr, First" keeps the last part as the given names
+			if i := strings.LastIndex(given, ","); i >= 0 {
+				given = given[i+1:]
+			}
+			name = strings.TrimSpace(strings.TrimSpace(given) + " " + strings.TrimSpace(family))
+		}
+		names = append(names, name)
+	}
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		default:
+			if depth == 0 && i+5 <= len(value) && strings.EqualFold(value[i:i+5], " and ") {
+				add(value[start:i])
+				start = i + 5
+				i += 4
+			}
+		}
+	}
+	add(value[start:])
+	return names
+}
+
+// risTagPattern matches an RIS line: a two-character tag, two spaces and a dash
+var risTagPattern = regexp.MustCompile(`^([A-Z][A-Z0-9])  -( (.*))?$`)
+
+// ParseRIS reads the entries of an RIS file
+func ParseRIS(data string) ([]PaperEntry, error) {
+	var entries []PaperEntry
+	var tags map[string][]string
+	lastTag := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \r")
+		match := risTagPattern.FindStringSubmatch(text)
+		if match == nil {
+			// A line without a tag continues the previous value, e.g. a long abstract
+			if tags != nil && lastTag != "" && strings.TrimSpace(text) != "" {
+				values := tags[lastTag]
+				values[len(values)-1] += " " + strings.TrimSpace(text)
+			}
+			continue
+		}
+
+		tag, value := match[1], strings.TrimSpace(match[3])
+		switch {
+		case tag == "TY":
+			tags = map[string][]string{"TY": {value}}
+		case tags == nil:
+			return entries, fmt.Errorf("line %d: %s before the TY line of an entry", line, tag)
+		case tag == "ER":
+			entries = append(entries, risEntry(tags))
+			tags = nil
+		default:
+			tags[tag] = append(tags[tag], value)
+		}
+		lastTag = tag
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, err
+	}
+	if tags != nil {
+		return entries, fmt.Errorf("entry %d has no ER line", len(entries)+1)
+	}
+	return entries, nil
+}
+
+// risEntry maps the tags of an RIS entry to a paper entry
+func risEntry(tags map[string][]string) PaperEntry {
+	first := func(names ...string) string {
+		for _, name := range names {
+			for _, value := range tags[name] {
+				if value != "" {
+					return value
+				}
+			}
+		}
+		return ""
+	}
+
+	entry := PaperEntry{
+		Title:    first("TI", "T1", "CT"),
+		Journal:  first("JF", "JO", "T2", "JA", "J2"),
+		Volume:   first("VL"),
+		Issue:    first("IS"),
+		DOI:      first("DO"),
+		ISSN:     first("SN"),
+		Abstract: first("AB", "N2"),
+		Language: first("LA"),
+	}
+	for _, name := range []string{"AU", "A1"} {
+		for _, author := range tags[name] {
+			if author = strings.TrimSpace(author); author != "" {
+				if family, given, ok := strings.Cut(author, ","); ok {
+					author = strings.TrimSpace(strings.TrimSpace(given) + " " + strings.TrimSpace(family))
+				}
+				entry.Authors = append(entry.Authors, author)
+			}
+		}
+	}
+	if year, err := strconv.Atoi(yearPattern.FindString(first("PY", "Y1", "DA"))); err == nil {
+		entry.Year = &year
+	}
+	if start, end := first("SP"), first("EP"); start != "" && end != "" && start != end {
+		entry.Pages = start + "-" + end
+	} else {
+		entry.Pages = start
+	}
+	if entry.DOI == "" {
+		if url := first("UR"); strings.Contains(url, "doi.org/") {
+			entry.DOI = NormalizeDOI(url)
+		}
+	}
+	entry.Keywords = strings.Join(tags["KW"], "; ")
+	if ty := first("TY"); ty == "THES" {
+		entry.University = first("PB")
+	}
+	return entry
+}
+
+// paperCSVColumns maps the accepted CSV headers, in English and Indonesian, to fields
+var paperCSVColumns = map[string]string{
+	"title": "title", "judul": "title",
+	"authors": "authors", "author": "authors", "penulis": "authors",
+	"year": "year", "tahun": "year",
+	"journal": "journal", "jurnal": "journal",
+	"volume": "volume",
+	"issue":  "issue", "number": "issue", "nomor": "issue",
+	"pages": "pages", "halaman": "pages",
+	"doi":      "doi",
+	"issn":     "issn",
+	"abstract": "abstract", "abstrak": "abstract",
+	"keywords": "keywords", "kata kunci": "keywords",
+	"language": "language", "bahasa": "language",
+	"university": "university", "universitas": "university",
+	"department": "department", "jurusan": "department", "program studi": "department",
+	"advisor": "advisor", "pembimbing": "advisor",
+}
+
+// ParsePaperCSV reads papers from a CSV file with a header row. Authors are separated
+// by semicolons; unknown columns are ignored.
+func ParsePaperCSV(data []byte) ([]PaperEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if line, _, _ := bytes.Cut(data, []byte("\n")); bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		reader.Comma = ';' // spreadsheets in Indonesian locales export with semicolons
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header row: %w", err)
+	}
+	columns := make([]string, len(header))
+	hasTitle := false
+	for i, name := range header {
+		columns[i] = paperCSVColumns[strings.ToLower(strings.TrimSpace(name))]
+		hasTitle = hasTitle || columns[i] == "title"
+	}
+	if !hasTitle {
+		return nil, errors.New("the header row has no title column")
+	}
+
+	var entries []PaperEntry
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+
+		var entry PaperEntry
+		empty := true
+		for i, value := range record {
+			if i >= len(columns) || strings.TrimSpace(value) == "" {
+				continue
+			}
+			empty = false
+			value = strings.TrimSpace(value)
+			switch columns[i] {
+			case "title":
+				entry.Title = value
+			case "authors":
+				entry.Authors = SplitAuthorList(value)
+			case "year":
+				if year, err := strconv.Atoi(yearPattern.FindString(value)); err == nil {
+					entry.Year = &year
+				}
+			case "journal":
+				entry.Journal = value
+			case "volume":
+				entry.Volume = value
+			case "issue":
+				entry.Issue = value
+			case "pages":
+				entry.Pages = value
+			case "doi":
+				entry.DOI = value
+			case "issn":
+				entry.ISSN = value
+			case "abstract":
+				entry.Abstract = value
+			case "keywords":
+				entry.Keywords = value
+			case "language":
+				entry.Language = value
+			case "university":
+				entry.University = value
+			case "department":
+				entry.Department = value
+			case "advisor":
+				entry.Advisor = value
+			}
+		}
+		if !empty {
+			entries = append(entries, entry)
+		}
+	}
+}
+
+// SplitAuthorList splits authors separated by semicolons, or by " and " when there
+// are none
+func SplitAuthorList(value string) []string {
+	separator := ";"
+	if !strings.Contains(value, ";") {
+		separator = " and "
+	}
+	var authors []string
+	for _, author := range strings.Split(value, separator) {
+		if author = strings.Join(strings.Fields(author), " "); author != "" {
+			authors = append(authors, author)
+		}
+	}
+	return authors
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBibTeX(t *testing.T) {
+	data := `% exported from a reference manager
+@string{jti = "Jurnal Teknologi Informasi"}
+
+@article{santoso2020,
+  title = {Deteksi {Objek} pada Citra \& Video},
+  author = {Santoso, Budi and Ani R. Wijaya and {Tim Riset Informatika} and others},
+  journal = jti # " Indonesia",
+  year = 2020,
+  volume = {5},
+  number = {2},
+  pages = {10--20},
+  doi = {https://doi.org/10.1234/jti.5.2},
+  keywords = {deteksi objek, citra},
+}
+
+@comment{ignored @article{x, title={y}} }
+
+@mastersthesis{wijaya2019,
+  title = "Analisis Caf{\'e} Digital",
+  author = "M{\"u}ller, Jan",
+  school = {Universitas Dumai},
+  year = {2019}
+}`
+	entries, err := ParseBibTeX(data)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	article := entries[0]
+	assert.Equal(t, "Deteksi Objek pada Citra & Video", article.Title)
+	assert.Equal(t, []string{"Budi Santoso", "Ani R. Wijaya", "Tim Riset Informatika"}, article.Authors)
+	assert.Equal(t, "Jurnal Teknologi Informasi Indonesia", article.Journal)
+	assert.Equal(t, 2020, *article.Year)
+	assert.Equal(t, "5", article.Volume)
+	assert.Equal(t, "2", article.Issue)
+	assert.Equal(t, "10-20", article.Pages)
+	assert.Equal(t, "deteksi objek, citra", article.Keywords)
+
+	thesis := entries[1]
+	assert.Equal(t, "Analisis Café Digital", thesis.Title)
+	assert.Equal(t, []string{"Jan Müller"}, thesis.Authors)
+	assert.Equal(t, "Universitas Dumai", thesis.University)
+
+	_, err = ParseBibTeX("@article{broken, title = {no end}")
+	assert.Error(t, err)
+}
+
+func TestParseRIS(t *testing.T) {
+	data := "TY  - JOUR\r\nAU  - Santoso, Budi\r\nAU  - Wijaya, Ani\r\nTI  - Deteksi Objek\r\nJO  - Jurnal Informatika\r\n" +
+		"PY  - 2021/03/01\r\nVL  - 5\r\nIS  - 2\r\nSP  - 10\r\nEP  - 20\r\nKW  - citra\r\nKW  - deteksi\r\n" +
+		"AB  - Baris pertama\r\nlanjutan abstrak\r\nDO  - 10.1234/ji.5.2\r\nER  - \r\n" +
+		"TY  - THES\r\nA1  - Rahmawati\r\nT1  - Sistem Informasi\r\nPB  - Universitas Dumai\r\nY1  - 2018\r\nER  -\r\n"
+	entries, err := ParseRIS(data)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	article := entries[0]
+	assert.Equal(t, "Deteksi Objek", article.Title)
+	assert.Equal(t, []string{"Budi Santoso", "Ani Wijaya"}, article.Authors)
+	assert.Equal(t, 2021, *article.Year)
+	assert.Equal(t, "10-20", article.Pages)
+	assert.Equal(t, "citra; deteksi", article.Keywords)
+	assert.Equal(t, "Baris pertama lanjutan abstrak", article.Abstract)
+	assert.Equal(t, "Universitas Dumai", entries[1].University)
+
+	_, err = ParseRIS("TY  - JOUR\nTI  - Tanpa akhir\n")
+	assert.Error(t, err)
+}
+
+func TestParsePaperCSV(t *testing.T) {
+	data := []byte("Judul;Penulis;Tahun;Volume;Catatan\n\"Deteksi Objek\";Budi Santoso; Ani Wijaya;2020;V\n;;;;\n")
+	entries, err := ParsePaperCSV(data)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "Deteksi Objek", entries[0].Title)
+
+	data = []byte("title,authors,year,volume\nDeteksi Objek,Budi Santoso; Ani Wijaya,2020,V\n")
+	entries, err = ParsePaperCSV(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Budi Santoso", "Ani Wijaya"}, entries[0].Authors)
+	assert.Equal(t, 2020, *entries[0].Year)
+
+	_, err = ParsePaperCSV([]byte("name,year\nx,2020\n"))
+	assert.Error(t, err)
+}
+
+func TestParsePaperEntriesDetectsFormat(t *testing.T) {
+	format, entries, err := ParsePaperEntries("refs.txt", []byte("TY  - JOUR\nTI  - A\nAU  - B\nER  - \n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ris", format)
+	assert.Len(t, entries, 1)
+
+	format, _, err = ParsePaperEntries("refs.bib", []byte("@article{a, title={A}, author={B}}"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bibtex", format)
+
+	_, _, err = ParsePaperEntries("refs.docx", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedImportFormat)
+}
+
+func TestValidatePaperEntry(t *testing.T) {
+	year := 3020
+	problems, warnings := ValidatePaperEntry(PaperEntry{Year: &year, DOI: "not-a-doi", Volume: "V"})
+	assert.Contains(t, problems, "title is required")
+	assert.Contains(t, problems, "at least one author is required")
+	assert.Len(t, problems, 4)
+	assert.Len(t, warnings, 1)
+
+	problems, warnings = ValidatePaperEntry(PaperEntry{Title: "A", Authors: []string{"B"}, DOI: "doi:10.1234/abc", Volume: "5"})
+	assert.Empty(t, problems)
+	assert.Empty(t, warnings)
+
+	paper, authors := PaperEntry{Title: " A  title ", Authors: []string{"B", " "}, DOI: "https://doi.org/10.1234/abc", Volume: "5", Issue: "II"}.Paper()
+	assert.Equal(t, "A title", paper.Title)
+	assert.Equal(t, []string{"B"}, authors)
+	assert.Equal(t, "10.1234/abc", *paper.DOI)
+	assert.Equal(t, 5, *paper.Volume)
+	assert.Nil(t, paper.Issue)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"fmt"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// bookmarkTables maps an item type to its bookmark junction table and item column
+var bookmarkTables = map[string][2]string{
+	"book":  {"user_books", "book_id"},
+	"paper": {"user_papers", "paper_id"},
+}
+
+// RemoveSavedItem removes a book or paper from every bookmark and reading list
+func RemoveSavedItem(db *gorm.DB, itemType string, itemID uint) error {
+	join, ok := bookmarkTables[itemType]
+	if !ok {
+		return fmt.Errorf("unsupported item type: %s", itemType)
+	}
+	if err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", join[0], join[1]), itemID).Error; err != nil {
+		return err
+	}
+	return db.Where("item_type = ? AND item_id = ?", itemType, itemID).Delete(&models.ReadingListEntry{}).Error
+}
+
+// DeleteUserSavedItems removes a user's bookmarks, reading lists, saved searches and
+// notifications
+func DeleteUserSavedItems(db *gorm.DB, userID uint) error {
+	if err := db.Where("user_id = ?", userID).Delete(&models.SavedSearch{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("user_id = ?", userID).Delete(&models.Notification{}).Error; err != nil {
+		return err
+	}
+	for _, join := range bookmarkTables {
+		if err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE user_id = ?", join[0]), userID).Error; err != nil {
+			return err
+		}
+	}
+	if err := db.Where("list_id IN (?)", db.Model(&models.ReadingList{}).Select("id").Where("user_id = ?", userID)).
+		Delete(&models.ReadingListEntry{}).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id = ?", userID).Delete(&models.ReadingList{}).Error
+}
+
+// IsBookmarked reports whether a user bookmarked a book or paper
+func IsBookmarked(db *gorm.DB, userID uint, itemType string, itemID uint) bool {
+	join, ok := bookmarkTables[itemType]
+	if !ok {
+		return false
+	}
+	var count int64
+	db.Table(join[0]).Where("user_id = ? AND "+join[1]+" = ?", userID, itemID).Count(&count)
+	return count > 0
+}
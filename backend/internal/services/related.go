@@ -0,0 +1,413 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Related item signal weights. Each signal contributes between 0 and its weight.
+const (
+	relatedAuthorWeight     = 3.0
+	relatedKeywordWeight    = 2.5
+	relatedCategoryWeight   = 1.0
+	relatedTextWeight       = 2.0
+	relatedCoDownloadWeight = 2.0
+
+	// relatedMinScore drops pairs that only share a weak signal
+	relatedMinScore = 0.4
+	// relatedCommonTermRatio skips terms used by more than this share of items when
+	// looking for similar texts; they are too common to relate items
+	relatedCommonTermRatio = 0.05
+
+	// relatedCoDownloadWindow is how far back downloads count as co-downloads
+	relatedCoDownloadWindow = 365 * 24 * time.Hour
+	// relatedCoDownloadMaxItems skips users who downloaded more distinct items than this
+	// in the window. Bulk downloaders say little about which items belong together, and
+	// each of them would add the square of their downloads to the pairs.
+	relatedCoDownloadMaxItems = 200
+)
+
+// RelatedItemsPerItem is the number of related items cached for each book and paper
+const RelatedItemsPerItem = 12
+
+// relatedStopwords are frequent English and Indonesian words ignored when comparing texts
+var relatedStopwords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "this": true, "that": true,
+	"are": true, "was": true, "were": true, "into": true, "using": true, "based": true, "its": true,
+	"dan": true, "yang": true, "untuk": true, "dengan": true, "dari": true, "pada": true, "dalam": true,
+	"atau": true, "ini": true, "itu": true, "sebagai": true, "oleh": true, "terhadap": true, "adalah": true,
+	"akan": true, "juga": true, "tidak": true, "dapat": true, "studi": true, "kasus": true,
+}
+
+// relatedDoc holds the signals of one book or paper
+type relatedDoc struct {
+	ref        ItemRef
+	authors    map[uint]bool
+	categories map[uint]bool
+	keywords   map[uint]bool
+	terms      map[string]float64
+}
+
+// relatedPair accumulates the shared signals of a candidate pair
+type relatedPair struct {
+	authors, categories, keywords int
+	text                          float64
+	coDownloads                   int
+}
+
+// ComputeRelatedItems scores every pair of items that share at least one signal
+// (authors, categories, keywords, distinctive title/abstract terms or co-downloads)
+// and replaces the related_items cache with the best matches of each item.
+func ComputeRelatedItems(db *gorm.DB) error {
+	started := time.Now()
+
+	docs, index, err := loadRelatedDocs(db)
+	if err != nil {
+		return err
+	}
+	if err := loadRelatedSignals(db, docs, index); err != nil {
+		return err
+	}
+	weighTerms(docs)
+
+	coDownloads, err := loadCoDownloads(db)
+	if err != nil {
+		return err
+	}
+
+	// Inverted indexes from each signal to the documents that have it
+	authorPostings := make(map[uint][]int)
+	categoryPostings := make(map[uint][]int)
+	keywordPostings := make(map[uint][]int)
+	termPostings := make(map[string][]int)
+	for i, doc := range docs {
+		for id := range doc.authors {
+			authorPostings[id] = append(authorPostings[id], i)
+		}
+		for id := range doc.categories {
+			categoryPostings[id] = append(categoryPostings[id], i)
+		}
+		for id := range doc.keywords {
+			keywordPostings[id] = append(keywordPostings[id], i)
+		}
+		for term := range doc.terms {
+			termPostings[term] = append(termPostings[term], i)
+		}
+	}
+	maxTermDocs := int(math.Max(3, relatedCommonTermRatio*float64(len(docs))))
+
+	now := time.Now()
+	var rows []models.RelatedItem
+	for i, doc := range docs {
+		pairs := make(map[int]*relatedPair)
+		pair := func(j int) *relatedPair {
+			p, ok := pairs[j]
+			if !ok {
+				p = &relatedPair{}
+				pairs[j] = p
+			}
+			return p
+		}
+
+		for id := range doc.authors {
+			for _, j := range authorPostings[id] {
+				pair(j).authors++
+			}
+		}
+		for id := range doc.categories {
+			for _, j := range categoryPostings[id] {
+				pair(j).categories++
+			}
+		}
+		for id := range doc.keywords {
+			for _, j := range keywordPostings[id] {
+				pair(j).keywords++
+			}
+		}
+		for term, weight := range doc.terms {
+			if postings := termPostings[term]; len(postings) <= maxTermDocs {
+				for _, j := range postings {
+					pair(j).text += weight * docs[j].terms[term]
+				}
+			}
+		}
+		for other, users := range coDownloads[doc.ref] {
+			if j, ok := index[other]; ok {
+				pair(j).coDownloads = users
+			}
+		}
+		delete(pairs, i)
+
+		var scored []models.RelatedItem
+		for j, p := range pairs {
+			score, reasons := scoreRelatedPair(doc, docs[j], p)
+			if score < relatedMinScore {
+				continue
+			}
+			scored = append(scored, models.RelatedItem{
+				ItemType:    doc.ref.Type,
+				ItemID:      doc.ref.ID,
+				RelatedType: docs[j].ref.Type,
+				RelatedID:   docs[j].ref.ID,
+				Score:       math.Round(score*1000) / 1000,
+				Reasons:     strings.Join(reasons, ","),
+				ComputedAt:  now,
+			})
+		}
+		sort.Slice(scored, func(a, b int) bool {
+			if scored[a].Score != scored[b].Score {
+				return scored[a].Score > scored[b].Score
+			}
+			return scored[a].RelatedID < scored[b].RelatedID
+		})
+		if len(scored) > RelatedItemsPerItem {
+			scored = scored[:RelatedItemsPerItem]
+		}
+		rows = append(rows, scored...)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.RelatedItem{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store related items: %w", err)
+	}
+
+	log.Printf("[ComputeRelatedItems] Stored %d recommendations for %d items in %s", len(rows), len(docs), time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// ClearRelatedItems removes a deleted item's recommendations and the recommendations
+// of it on other items
+func ClearRelatedItems(db *gorm.DB, itemType string, itemID uint) error {
+	return db.Where("(item_type = ? AND item_id = ?) OR (related_type = ? AND related_id = ?)",
+		itemType, itemID, itemType, itemID).Delete(&models.RelatedItem{}).Error
+}
+
+// scoreRelatedPair combines the shared signals of two documents into one score and
+// lists the signals that contributed
+func scoreRelatedPair(a, b *relatedDoc, p *relatedPair) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if p.authors > 0 {
+		score += relatedAuthorWeight * math.Min(float64(p.authors), 2) / 2
+		reasons = append(reasons, "author")
+	}
+	if p.keywords > 0 {
+		score += relatedKeywordWeight * jaccard(p.keywords, len(a.keywords), len(b.keywords))
+		reasons = append(reasons, "keyword")
+	}
+	if p.categories > 0 {
+		score += relatedCategoryWeight * jaccard(p.categories, len(a.categories), len(b.categories))
+		reasons = append(reasons, "category")
+	}
+	if p.text >= 0.1 {
+		score += relatedTextWeight * math.Min(p.text, 1)
+		reasons = append(reasons, "text")
+	}
+	if p.coDownloads > 0 {
+		// Saturates at five shared downloaders
+		score += relatedCoDownloadWeight * math.Min(math.Log1p(float64(p.coDownloads))/math.Log1p(5), 1)
+		reasons = append(reasons, "co-download")
+	}
+	return score, reasons
+}
+
+// jaccard returns the Jaccard index of two sets of the given sizes sharing shared members
+func jaccard(shared, sizeA, sizeB int) float64 {
+	union := sizeA + sizeB - shared
+	if union <= 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// loadRelatedDocs loads every book and paper with the raw term counts of its title and
+// summary or abstract. Titles count twice.
+func loadRelatedDocs(db *gorm.DB) ([]*relatedDoc, map[ItemRef]int, error) {
+	var docs []*relatedDoc
+	index := make(map[ItemRef]int)
+	add := func(ref ItemRef, title string, text *string) {
+		doc := &relatedDoc{
+			ref:        ref,
+			authors:    make(map[uint]bool),
+			categories: make(map[uint]bool),
+			keywords:   make(map[uint]bool),
+			terms:      make(map[string]float64),
+		}
+		for _, term := range relatedTerms(title) {
+			doc.terms[term] += 2
+		}
+		if text != nil {
+			for _, term := range relatedTerms(*text) {
+				doc.terms[term]++
+			}
+		}
+		index[ref] = len(docs)
+		docs = append(docs, doc)
+	}
+
+	var books []models.Book
+	if err := db.Select("id", "title", "summary").Find(&books).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load books: %w", err)
+	}
+	for _, book := range books {
+		add(ItemRef{Type: "book", ID: book.ID}, book.Title, book.Summary)
+	}
+
+	var papers []models.Paper
+	if err := db.Select("id", "title", "abstract").Find(&papers).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load papers: %w", err)
+	}
+	for _, paper := range papers {
+		add(ItemRef{Type: "paper", ID: paper.ID}, paper.Title, paper.Abstract)
+	}
+
+	return docs, index, nil
+}
+
+// loadRelatedSignals fills in the authority records, categories and keywords of each
+// document. Keywords in a synonym group count as the same keyword.
+func loadRelatedSignals(db *gorm.DB, docs []*relatedDoc, index map[ItemRef]int) error {
+	for _, itemType := range []string{"book", "paper"} {
+		var links []struct {
+			ItemID uint
+			Value  uint
+		}
+
+		if err := db.Table(itemType + "_authors").Select(itemType + "_id AS item_id, author_id AS value").
+			Where("author_id IS NOT NULL").Scan(&links).Error; err != nil {
+			return fmt.Errorf("failed to load %s authors: %w", itemType, err)
+		}
+		for _, link := range links {
+			if i, ok := index[ItemRef{Type: itemType, ID: link.ItemID}]; ok {
+				docs[i].authors[link.Value] = true
+			}
+		}
+
+		links = nil
+		if err := db.Table(itemType + "_categories").Select(itemType + "_id AS item_id, category_id AS value").
+			Scan(&links).Error; err != nil {
+			return fmt.Errorf("failed to load %s categories: %w", itemType, err)
+		}
+		for _, link := range links {
+			if i, ok := index[ItemRef{Type: itemType, ID: link.ItemID}]; ok {
+				docs[i].categories[link.Value] = true
+			}
+		}
+
+		// Grouped keywords are represented by the smallest keyword ID of their group
+		join := keywordJoinTables[itemType]
+		links = nil
+		if err := db.Table(join[0] + " ik").
+			Select("ik." + join[1] + " AS item_id, COALESCE((SELECT MIN(g.id) FROM keywords g WHERE g.group_id = k.group_id), k.id) AS value").
+			Joins("JOIN keywords k ON k.id = ik.keyword_id").
+			Scan(&links).Error; err != nil {
+			return fmt.Errorf("failed to load %s keywords: %w", itemType, err)
+		}
+		for _, link := range links {
+			if i, ok := index[ItemRef{Type: itemType, ID: link.ItemID}]; ok {
+				docs[i].keywords[link.Value] = true
+			}
+		}
+	}
+	return nil
+}
+
+// weighTerms turns raw term counts into L2-normalized TF-IDF weights, so that the dot
+// product of two documents is their cosine similarity
+func weighTerms(docs []*relatedDoc) {
+	df := make(map[string]int)
+	for _, doc := range docs {
+		for term := range doc.terms {
+			df[term]++
+		}
+	}
+
+	n := float64(len(docs))
+	for _, doc := range docs {
+		var norm float64
+		for term, tf := range doc.terms {
+			weight := (1 + math.Log(tf)) * math.Log(1+n/float64(df[term]))
+			doc.terms[term] = weight
+			norm += weight * weight
+		}
+		norm = math.Sqrt(norm)
+		for term := range doc.terms {
+			doc.terms[term] /= norm
+		}
+	}
+}
+
+// loadCoDownloads counts, for each pair of items, the registered users who downloaded both
+// within relatedCoDownloadWindow. Repeated downloads of an item count once, and users
+// above relatedCoDownloadMaxItems are left out, which bounds the self-join.
+func loadCoDownloads(db *gorm.DB) (map[ItemRef]map[ItemRef]int, error) {
+	var pairs []struct {
+		TypeA string
+		IDA   uint `gorm:"column:id_a"`
+		TypeB string
+		IDB   uint `gorm:"column:id_b"`
+		Users int
+	}
+	since := time.Now().Add(-relatedCoDownloadWindow)
+	err := db.Raw(`WITH recent AS (
+			SELECT DISTINCT user_id, item_type, item_id
+			FROM downloads
+			WHERE user_id IS NOT NULL AND downloaded_at >= ? AND user_id IN (
+				SELECT user_id FROM downloads
+				WHERE user_id IS NOT NULL AND downloaded_at >= ?
+				GROUP BY user_id
+				HAVING COUNT(DISTINCT item_type, item_id) <= ?
+			)
+		)
+		SELECT a.item_type AS type_a, a.item_id AS id_a, b.item_type AS type_b, b.item_id AS id_b,
+			COUNT(*) AS users
+		FROM recent a
+		JOIN recent b ON b.user_id = a.user_id AND (b.item_type <> a.item_type OR b.item_id <> a.item_id)
+		GROUP BY a.item_type, a.item_id, b.item_type, b.item_id`,
+		since, since, relatedCoDownloadMaxItems).Scan(&pairs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load co-downloads: %w", err)
+	}
+
+	coDownloads := make(map[ItemRef]map[ItemRef]int)
+	for _, pair := range pairs {
+		a := ItemRef{Type: pair.TypeA, ID: pair.IDA}
+		if coDownloads[a] == nil {
+			coDownloads[a] = make(map[ItemRef]int)
+		}
+		coDownloads[a][ItemRef{Type: pair.TypeB, ID: pair.IDB}] = pair.Users
+	}
+	return coDownloads, nil
+}
+
+// relatedTerms splits text into lowercase words of at least three letters, without stopwords
+func relatedTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) >= 3 && !relatedStopwords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelatedTerms(t *testing.T) {
+	assert.Equal(t, []string{"sistem", "informasi", "perpustakaan", "berbasis", "web"},
+		relatedTerms("Sistem Informasi Perpustakaan Berbasis Web"))
+	assert.Equal(t, []string{"deep", "learning", "image", "classification"},
+		relatedTerms("Deep learning for image classification"))
+	assert.Empty(t, relatedTerms("of a an"))
+}
+
+func TestWeighTermsCosine(t *testing.T) {
+	docs := []*relatedDoc{
+		{terms: map[string]float64{"library": 2, "system": 1}},
+		{terms: map[string]float64{"library": 2, "system": 1}},
+		{terms: map[string]float64{"image": 1, "classification": 1}},
+	}
+	weighTerms(docs)
+
+	dot := func(a, b *relatedDoc) float64 {
+		var sum float64
+		for term, weight := range a.terms {
+			sum += weight * b.terms[term]
+		}
+		return sum
+	}
+	assert.InDelta(t, 1.0, dot(docs[0], docs[1]), 1e-9)
+	assert.Zero(t, dot(docs[0], docs[2]))
+}
+
+func TestScoreRelatedPair(t *testing.T) {
+	a := &relatedDoc{keywords: map[uint]bool{1: true, 2: true}, categories: map[uint]bool{1: true}}
+	b := &relatedDoc{keywords: map[uint]bool{1: true}, categories: map[uint]bool{2: true}}
+
+	score, reasons := scoreRelatedPair(a, b, &relatedPair{authors: 1, keywords: 1})
+	assert.InDelta(t, relatedAuthorWeight/2+relatedKeywordWeight/2, score, 1e-9)
+	assert.Equal(t, []string{"author", "keyword"}, reasons)
+
+	score, reasons = scoreRelatedPair(a, b, &relatedPair{text: 0.05})
+	assert.Zero(t, score)
+	assert.Empty(t, reasons)
+
+	score, _ = scoreRelatedPair(a, b, &relatedPair{coDownloads: 50})
+	assert.InDelta(t, relatedCoDownloadWeight, score, 1e-9)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// reviewItemTables maps an item type to the table holding its rating aggregates
+var reviewItemTables = map[string]string{
+	"book":  "books",
+	"paper": "papers",
+}
+
+// RefreshItemRating recomputes the rating average and count stored on a book or
+// paper from its visible top-level reviews
+func RefreshItemRating(db *gorm.DB, itemType string, itemID uint) error {
+	table, ok := reviewItemTables[itemType]
+	if !ok {
+		return fmt.Errorf("unsupported item type: %s", itemType)
+	}
+
+	var aggregate struct {
+		Average float64
+		Count   int
+	}
+	if err := db.Model(&models.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("item_type = ? AND item_id = ? AND parent_id IS NULL AND rating IS NOT NULL AND status <> 'hidden'", itemType, itemID).
+		Scan(&aggregate).Error; err != nil {
+		return err
+	}
+
+	return db.Table(table).Where("id = ?", itemID).UpdateColumns(map[string]interface{}{
+		"rating_average": aggregate.Average,
+		"rating_count":   aggregate.Count,
+	}).Error
+}
+
+// ReviewThreadIDs returns the given review IDs together with the IDs of every reply
+// below them
+func ReviewThreadIDs(db *gorm.DB, rootIDs []uint) ([]uint, error) {
+	ids := append([]uint(nil), rootIDs...)
+	frontier := rootIDs
+	for len(frontier) > 0 {
+		var children []uint
+		if err := db.Model(&models.Review{}).Where("parent_id IN ?", frontier).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		ids = append(ids, children...)
+		frontier = children
+	}
+	return ids, nil
+}
+
+// DeleteReviews deletes reviews with their replies and abuse reports
+func DeleteReviews(db *gorm.DB, reviewIDs []uint) error {
+	if len(reviewIDs) == 0 {
+		return nil
+	}
+	ids, err := ReviewThreadIDs(db, reviewIDs)
+	if err != nil {
+		return err
+	}
+	if err := db.Where("review_id IN ?", ids).Delete(&models.ReviewReport{}).Error; err != nil {
+		return err
+	}
+	return db.Where("id IN ?", ids).Delete(&models.Review{}).Error
+}
+
+// ClearItemReviews removes all reviews of a book or paper
+func ClearItemReviews(db *gorm.DB, itemType string, itemID uint) error {
+	var ids []uint
+	if err := db.Model(&models.Review{}).Where("item_type = ? AND item_id = ?", itemType, itemID).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	return DeleteReviews(db, ids)
+}
+
+// DeleteUserReviews removes a user's reviews (with the replies below them) and the
+// abuse reports they filed, then refreshes the ratings of the affected items
+func DeleteUserReviews(db *gorm.DB, userID uint) error {
+	var reviews []models.Review
+	if err := db.Select("id, item_type, item_id").Where("user_id = ?", userID).Find(&reviews).Error; err != nil {
+		return err
+	}
+	if err := db.Where("user_id = ?", userID).Delete(&models.ReviewReport{}).Error; err != nil {
+		return err
+	}
+
+	ids := make([]uint, 0, len(reviews))
+	items := make(map[ItemRef]bool)
+	for _, review := range reviews {
+		ids = append(ids, review.ID)
+		items[ItemRef{Type: review.ItemType, ID: review.ItemID}] = true
+	}
+	if err := DeleteReviews(db, ids); err != nil {
+		return err
+	}
+
+	for item := range items {
+		if err := RefreshItemRating(db, item.Type, item.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsItemAuthor reports whether a user uploaded a book or paper or is linked to one
+// of its author entries
+func IsItemAuthor(db *gorm.DB, userID uint, itemType string, itemID uint) bool {
+	table, ok := reviewItemTables[itemType]
+	if !ok {
+		return false
+	}
+	var count int64
+	db.Table(table).Where("id = ? AND created_by = ?", itemID, userID).Count(&count)
+	if count > 0 {
+		return true
+	}
+	db.Table(itemType+"_authors").Where(itemType+"_id = ? AND user_id = ?", itemID, userID).Count(&count)
+	return count > 0
+}
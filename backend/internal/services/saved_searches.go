@@ -0,0 +1,273 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"e-repository-api/configs"
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// savedSearchPeriods is how often saved searches of each frequency are run
+var savedSearchPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// savedSearchSlack lets a search run slightly early, so that the time of day of the
+// digest does not drift by the job interval each period
+const savedSearchSlack = 30 * time.Minute
+
+// savedSearchDigestItems is how many new items a digest lists per saved search
+const savedSearchDigestItems = 10
+
+// SearchFiltersFrom validates the filters of a saved search and converts them for Search
+func SearchFiltersFrom(saved models.SavedSearchFilters) (SearchFilters, error) {
+	filters := SearchFilters{
+		Categories:  saved.Categories,
+		Languages:   saved.Languages,
+		Faculties:   saved.Faculties,
+		Departments: saved.Departments,
+		AuthorIDs:   saved.AuthorIDs,
+	}
+	for _, itemType := range saved.Types {
+		if itemType != "book" && itemType != "paper" {
+			return filters, fmt.Errorf("type must be book or paper")
+		}
+		filters.Types = append(filters.Types, itemType)
+	}
+	for _, value := range saved.YearRanges {
+		yearRange, err := ParseYearRange(value)
+		if err != nil {
+			return filters, err
+		}
+		filters.YearRanges = append(filters.YearRanges, yearRange)
+	}
+	return filters, nil
+}
+
+// SavedSearchLink returns the search page of a saved search, relative to the frontend
+func SavedSearchLink(saved models.SavedSearch) string {
+	values := url.Values{}
+	if saved.Query != "" {
+		values.Set("q", saved.Query)
+	}
+	for _, itemType := range saved.Filters.Types {
+		values.Add("type", itemType)
+	}
+	for name, selected := range map[string][]string{
+		"year_range": saved.Filters.YearRanges,
+		"category":   saved.Filters.Categories,
+		"language":   saved.Filters.Languages,
+		"faculty":    saved.Filters.Faculties,
+		"department": saved.Filters.Departments,
+	} {
+		for _, value := range selected {
+			values.Add(name, value)
+		}
+	}
+	for _, authorID := range saved.Filters.AuthorIDs {
+		values.Add("author", strconv.FormatUint(uint64(authorID), 10))
+	}
+	return "/search?" + values.Encode()
+}
+
+// savedSearchDue reports whether a saved search is due to run at now
+func savedSearchDue(saved models.SavedSearch, now time.Time) bool {
+	period, ok := savedSearchPeriods[saved.Frequency]
+	if !ok {
+		period = savedSearchPeriods["daily"]
+	}
+	return !now.Before(saved.LastRunAt.Add(period - savedSearchSlack))
+}
+
+// savedSearchRun is the outcome of running one saved search
+type savedSearchRun struct {
+	search models.SavedSearch
+	digest utils.SavedSearchDigest
+}
+
+// SendSavedSearchAlerts runs the active saved searches that are due and sends their
+// owners the items created since the previous run: one email digest per user for
+// searches delivered by email, and one notification per search for in-app delivery.
+// baseURL is the address of the API, used for unsubscribe links.
+func SendSavedSearchAlerts(db *gorm.DB, baseURL string) error {
+	now := time.Now()
+	var searches []models.SavedSearch
+	if err := db.Preload("User").Where("active = ?", true).Order("user_id, id").Find(&searches).Error; err != nil {
+		return fmt.Errorf("failed to load saved searches: %w", err)
+	}
+
+	var emailConfig *configs.EmailConfig
+	emailChecked := false
+	byUser := make(map[uint][]savedSearchRun)
+	var userOrder []uint
+	for _, saved := range searches {
+		if saved.User == nil || !savedSearchDue(saved, now) {
+			continue
+		}
+		if saved.Channel == "email" && !emailChecked {
+			emailChecked = true
+			var err error
+			if emailConfig, err = configs.LoadEmailConfig(); err != nil {
+				log.Printf("[SendSavedSearchAlerts] Email is not configured, skipping email digests: %v", err)
+			}
+		}
+		if saved.Channel == "email" && emailConfig == nil {
+			continue
+		}
+
+		digest, err := runSavedSearch(db, saved, baseURL)
+		if err != nil {
+			log.Printf("[SendSavedSearchAlerts] Failed to run saved search %d: %v", saved.ID, err)
+			continue
+		}
+		if _, seen := byUser[saved.UserID]; !seen {
+			userOrder = append(userOrder, saved.UserID)
+		}
+		byUser[saved.UserID] = append(byUser[saved.UserID], savedSearchRun{search: saved, digest: digest})
+	}
+
+	sent := 0
+	for _, userID := range userOrder {
+		var done []uint
+		var emailed []utils.SavedSearchDigest
+		var emailedIDs []uint
+		for _, run := range byUser[userID] {
+			if run.search.Channel == "email" {
+				emailedIDs = append(emailedIDs, run.search.ID)
+				if run.digest.Total > 0 {
+					emailed = append(emailed, run.digest)
+				}
+				continue
+			}
+			if run.digest.Total > 0 {
+				if err := notifySavedSearch(db, run); err != nil {
+					return err
+				}
+				sent++
+			}
+			done = append(done, run.search.ID)
+		}
+
+		if len(emailed) > 0 {
+			user := byUser[userID][0].search.User
+			if err := utils.SendSavedSearchDigestEmail(user.Email, user.Name, emailed, emailConfig); err != nil {
+				// Keep the searches due so the items are sent with the next attempt
+				log.Printf("[SendSavedSearchAlerts] Failed to email user %d: %v", userID, err)
+				emailedIDs = nil
+			} else {
+				sent++
+			}
+		}
+		done = append(done, emailedIDs...)
+
+		if len(done) > 0 {
+			if err := db.Model(&models.SavedSearch{}).Where("id IN ?", done).UpdateColumn("last_run_at", now).Error; err != nil {
+				return fmt.Errorf("failed to record saved search runs: %w", err)
+			}
+		}
+	}
+
+	if sent > 0 {
+		log.Printf("[SendSavedSearchAlerts] Sent %d saved search digests", sent)
+	}
+	return nil
+}
+
+// runSavedSearch finds the items a saved search matches that were created since its last run
+func runSavedSearch(db *gorm.DB, saved models.SavedSearch, baseURL string) (utils.SavedSearchDigest, error) {
+	digest := utils.SavedSearchDigest{
+		Name:            saved.Name,
+		UnsubscribeLink: baseURL + "/api/v1/saved-searches/unsubscribe/" + saved.UnsubscribeToken,
+	}
+	filters, err := SearchFiltersFrom(saved.Filters)
+	if err != nil {
+		return digest, err
+	}
+	since := saved.LastRunAt
+	filters.CreatedAfter = &since
+
+	result, err := Search(db, saved.Query, filters, 0, savedSearchDigestItems)
+	if err != nil {
+		return digest, err
+	}
+	digest.Total = result.Total
+
+	refs := make([]ItemRef, len(result.Hits))
+	for i, hit := range result.Hits {
+		refs[i] = hit.ItemRef
+	}
+	titles, err := itemTitles(db, refs)
+	if err != nil {
+		return digest, err
+	}
+	for _, ref := range refs {
+		if title, ok := titles[ref]; ok {
+			digest.Items = append(digest.Items, utils.SavedSearchDigestItem{Type: ref.Type, Title: title})
+		}
+	}
+	return digest, nil
+}
+
+// notifySavedSearch leaves an in-app notification with the new items of a saved search
+func notifySavedSearch(db *gorm.DB, run savedSearchRun) error {
+	message := ""
+	for i, item := range run.digest.Items {
+		if i > 0 {
+			message += "\n"
+		}
+		message += item.Title
+	}
+	if extra := run.digest.Total - int64(len(run.digest.Items)); extra > 0 {
+		message += fmt.Sprintf("\n...and %d more", extra)
+	}
+
+	noun := "items"
+	if run.digest.Total == 1 {
+		noun = "item"
+	}
+	link := SavedSearchLink(run.search)
+	notification := models.Notification{
+		UserID:  run.search.UserID,
+		Title:   fmt.Sprintf("%d new %s for \"%s\"", run.digest.Total, noun, run.search.Name),
+		Message: message,
+		Link:    &link,
+	}
+	if err := db.Create(&notification).Error; err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+	return nil
+}
+
+// itemTitles returns the titles of the referenced books and papers
+func itemTitles(db *gorm.DB, refs []ItemRef) (map[ItemRef]string, error) {
+	ids := make(map[string][]uint)
+	for _, ref := range refs {
+		ids[ref.Type] = append(ids[ref.Type], ref.ID)
+	}
+
+	titles := make(map[ItemRef]string, len(refs))
+	for _, source := range searchSources {
+		if len(ids[source.itemType]) == 0 {
+			continue
+		}
+		var rows []struct {
+			ID    uint
+			Title string
+		}
+		if err := db.Table(source.table).Select("id, title").Where("id IN ?", ids[source.itemType]).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			titles[ItemRef{Type: source.itemType, ID: row.ID}] = row.Title
+		}
+	}
+	return titles, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"e-repository-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchFiltersFrom(t *testing.T) {
+	filters, err := SearchFiltersFrom(models.SavedSearchFilters{
+		Types:      []string{"paper"},
+		YearRanges: []string{"2019-2021"},
+		Languages:  []string{"id"},
+		AuthorIDs:  []uint{7},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"paper"}, filters.Types)
+	assert.Equal(t, []YearRange{{From: 2019, To: 2021}}, filters.YearRanges)
+	assert.Equal(t, []string{"id"}, filters.Languages)
+	assert.Equal(t, []uint{7}, filters.AuthorIDs)
+
+	_, err = SearchFiltersFrom(models.SavedSearchFilters{Types: []string{"thesis"}})
+	assert.Error(t, err)
+	_, err = SearchFiltersFrom(models.SavedSearchFilters{YearRanges: []string{"recent"}})
+	assert.Error(t, err)
+}
+
+func TestSavedSearchLink(t *testing.T) {
+	link := SavedSearchLink(models.SavedSearch{
+		Query:   "sistem informasi",
+		Filters: models.SavedSearchFilters{Types: []string{"book"}, Categories: []string{"Teknologi"}, AuthorIDs: []uint{3}},
+	})
+	assert.True(t, strings.HasPrefix(link, "/search?"))
+
+	values, err := url.ParseQuery(strings.TrimPrefix(link, "/search?"))
+	assert.NoError(t, err)
+	assert.Equal(t, "sistem informasi", values.Get("q"))
+	assert.Equal(t, []string{"book"}, values["type"])
+	assert.Equal(t, []string{"Teknologi"}, values["category"])
+	assert.Equal(t, []string{"3"}, values["author"])
+}
+
+func TestSavedSearchDue(t *testing.T) {
+	now := time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)
+
+	daily := models.SavedSearch{Frequency: "daily", LastRunAt: now.Add(-24 * time.Hour)}
+	assert.True(t, savedSearchDue(daily, now))
+	// A run slightly early still counts, so the digest time does not drift
+	daily.LastRunAt = now.Add(-23*time.Hour - 45*time.Minute)
+	assert.True(t, savedSearchDue(daily, now))
+	daily.LastRunAt = now.Add(-12 * time.Hour)
+	assert.False(t, savedSearchDue(daily, now))
+
+	weekly := models.SavedSearch{Frequency: "weekly", LastRunAt: now.Add(-3 * 24 * time.Hour)}
+	assert.False(t, savedSearchDue(weekly, now))
+	weekly.LastRunAt = now.Add(-7 * 24 * time.Hour)
+	assert.True(t, savedSearchDue(weekly, now))
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/utils"
+)
+
+// MetaTag is a <meta name="..." content="..."> tag
+type MetaTag struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// ScholarlyMeta is the discovery metadata of an item landing page: Highwire Press
+// tags for Google Scholar, Dublin Core tags and a Schema.org JSON-LD object
+type ScholarlyMeta struct {
+	Highwire   []MetaTag      `json:"highwire"`
+	DublinCore []MetaTag      `json:"dublin_core"`
+	JSONLD     map[string]any `json:"json_ld"`
+
+	// What the landing page shows to readers
+	Title       string   `json:"-"`
+	Authors     []string `json:"-"`
+	Description string   `json:"-"`
+	PageURL     string   `json:"-"`
+	FileURL     string   `json:"-"`
+}
+
+// ItemLinks holds the addresses item metadata links to
+type ItemLinks struct {
+	ServerURL   string // the API, which serves uploaded files
+	FrontendURL string // the web app, which has the item pages
+}
+
+// scholarlyItem is what the metadata of a book or paper is built from
+type scholarlyItem struct {
+	CitationItem
+	schemaType  string // Book, ScholarlyArticle or Thesis
+	description string
+	keywords    []string
+	department  string
+	advisor     string
+	pageURL     string
+	fileURL     string
+}
+
+// BookScholarlyMeta returns the discovery metadata of a book with its authors and
+// subjects loaded
+func BookScholarlyMeta(book models.Book, links ItemLinks) ScholarlyMeta {
+	item := scholarlyItem{
+		CitationItem: BookCitationItem(book),
+		schemaType:   "Book",
+		description:  utils.StringValue(book.Summary),
+		pageURL:      links.FrontendURL + "/books/" + strconv.FormatUint(uint64(book.ID), 10),
+		fileURL:      links.fileURL(book.FileURL),
+	}
+	for _, subject := range book.Subjects {
+		item.keywords = append(item.keywords, subject.Term)
+	}
+	if len(item.keywords) == 0 {
+		item.keywords = SplitKeywords(utils.StringValue(book.Subject))
+	}
+	return item.meta()
+}
+
+// PaperScholarlyMeta returns the discovery metadata of a paper with its authors and
+// keywords loaded. Papers in a journal are articles, the others theses.
+func PaperScholarlyMeta(paper models.Paper, links ItemLinks) ScholarlyMeta {
+	item := scholarlyItem{
+		CitationItem: PaperCitationItem(paper),
+		schemaType:   "Thesis",
+		description:  utils.StringValue(paper.Abstract),
+		department:   utils.StringValue(paper.Department),
+		advisor:      utils.StringValue(paper.Advisor),
+		pageURL:      links.FrontendURL + "/papers/" + strconv.FormatUint(uint64(paper.ID), 10),
+		fileURL:      links.fileURL(paper.FileURL),
+	}
+	if item.isArticle() {
+		item.schemaType = "ScholarlyArticle"
+	}
+	for _, keyword := range paper.KeywordTerms {
+		item.keywords = append(item.keywords, keyword.Term)
+	}
+	if len(item.keywords) == 0 {
+		item.keywords = SplitKeywords(utils.StringValue(paper.Keywords))
+	}
+	return item.meta()
+}
+
+// fileURL returns the absolute address of an uploaded file, or "" when there is none
+func (l ItemLinks) fileURL(path *string) string {
+	value := utils.StringValue(path)
+	if value == "" || strings.HasPrefix(value, "http") {
+		return value
+	}
+	return l.ServerURL + value
+}
+
+// meta builds the three kinds of metadata of an item
+func (item scholarlyItem) meta() ScholarlyMeta {
+	return ScholarlyMeta{
+		Highwire:    item.highwire(),
+		DublinCore:  item.dublinCore(),
+		JSONLD:      item.jsonLD(),
+		Title:       item.Title,
+		Authors:     item.Authors,
+		Description: item.description,
+		PageURL:     item.pageURL,
+		FileURL:     item.fileURL,
+	}
+}
+
+// pageRange splits pages such as "10-20" into the first and last page
+func (item scholarlyItem) pageRange() (string, string) {
+	first, last, _ := strings.Cut(strings.ReplaceAll(item.Pages, "--", "-"), "-")
+	return strings.TrimSpace(first), strings.TrimSpace(last)
+}
+
+// institution returns the degree-granting institution of a thesis, e.g.
+// "Teknik Informatika, Universitas Dumai"
+func (item scholarlyItem) institution() string {
+	return strings.Trim(item.department+", "+item.University, ", ")
+}
+
+// highwire returns the citation_* tags Google Scholar reads
+// (https://scholar.google.com/intl/en/scholar/inclusion.html#indexing)
+func (item scholarlyItem) highwire() []MetaTag {
+	var tags []MetaTag
+	add := func(name, content string) {
+		if content = strings.TrimSpace(content); content != "" {
+			tags = append(tags, MetaTag{Name: name, Content: content})
+		}
+	}
+
+	add("citation_title", item.Title)
+	for _, author := range item.Authors {
+		add("citation_author", author)
+	}
+	if item.Year != nil {
+		add("citation_publication_date", strconv.Itoa(*item.Year))
+	}
+	switch item.schemaType {
+	case "Book":
+		add("citation_publisher", item.Publisher)
+		add("citation_isbn", item.ISBN)
+	case "ScholarlyArticle":
+		add("citation_journal_title", item.Journal)
+		add("citation_volume", item.Volume)
+		add("citation_issue", item.Issue)
+		first, last := item.pageRange()
+		add("citation_firstpage", first)
+		add("citation_lastpage", last)
+		add("citation_issn", item.ISSN)
+	case "Thesis":
+		add("citation_dissertation_institution", item.institution())
+	}
+	add("citation_doi", item.DOI)
+	add("citation_language", LanguageCode(&item.Language))
+	add("citation_keywords", strings.Join(item.keywords, "; "))
+	add("citation_abstract_html_url", item.pageURL)
+	if strings.HasSuffix(strings.ToLower(item.fileURL), ".pdf") {
+		add("citation_pdf_url", item.fileURL)
+	}
+	return tags
+}
+
+// dublinCore returns the DC.* tags of an item
+func (item scholarlyItem) dublinCore() []MetaTag {
+	var tags []MetaTag
+	add := func(name, content string) {
+		if content = strings.TrimSpace(content); content != "" {
+			tags = append(tags, MetaTag{Name: name, Content: content})
+		}
+	}
+
+	add("DC.title", item.Title)
+	for _, author := range item.Authors {
+		add("DC.creator", author)
+	}
+	add("DC.contributor", item.advisor)
+	for _, keyword := range item.keywords {
+		add("DC.subject", keyword)
+	}
+	add("DC.description", item.description)
+	if item.schemaType == "Thesis" {
+		add("DC.publisher", item.institution())
+	} else {
+		add("DC.publisher", item.Publisher)
+	}
+	if item.Year != nil {
+		add("DC.date", strconv.Itoa(*item.Year))
+	}
+	add("DC.type", map[string]string{"Book": "Book", "ScholarlyArticle": "Article", "Thesis": "Thesis"}[item.schemaType])
+	add("DC.identifier", item.pageURL)
+	if item.DOI != "" {
+		add("DC.identifier", doiURL(item.DOI))
+	}
+	if item.ISBN != "" {
+		add("DC.identifier", "ISBN:"+item.ISBN)
+	}
+	if item.schemaType == "ScholarlyArticle" {
+		add("DC.source", item.Journal)
+	}
+	add("DC.language", LanguageCode(&item.Language))
+	return tags
+}
+
+// jsonLD returns the Schema.org description of an item
+func (item scholarlyItem) jsonLD() map[string]any {
+	data := map[string]any{
+		"@context": "https://schema.org",
+		"@type":    item.schemaType,
+		"name":     item.Title,
+		"url":      item.pageURL,
+	}
+	if item.schemaType == "ScholarlyArticle" {
+		data["headline"] = item.Title
+	}
+
+	var authors []map[string]any
+	for _, author := range item.Authors {
+		authors = append(authors, map[string]any{"@type": "Person", "name": author})
+	}
+	if len(authors) > 0 {
+		data["author"] = authors
+	}
+	if item.Year != nil {
+		data["datePublished"] = strconv.Itoa(*item.Year)
+	}
+	if item.description != "" {
+		data["abstract"] = item.description
+	}
+	if len(item.keywords) > 0 {
+		data["keywords"] = strings.Join(item.keywords, ", ")
+	}
+	if language := LanguageCode(&item.Language); language != "" {
+		data["inLanguage"] = language
+	}
+	if item.DOI != "" {
+		data["identifier"] = map[string]any{"@type": "PropertyValue", "propertyID": "DOI", "value": item.DOI}
+		data["sameAs"] = doiURL(item.DOI)
+	}
+	if item.fileURL != "" {
+		media := map[string]any{"@type": "MediaObject", "contentUrl": item.fileURL}
+		if strings.HasSuffix(strings.ToLower(item.fileURL), ".pdf") {
+			media["encodingFormat"] = "application/pdf"
+		}
+		data["encoding"] = media
+	}
+
+	switch item.schemaType {
+	case "Book":
+		if item.Publisher != "" {
+			data["publisher"] = map[string]any{"@type": "Organization", "name": item.Publisher}
+		}
+		if item.ISBN != "" {
+			data["isbn"] = item.ISBN
+		}
+	case "ScholarlyArticle":
+		first, last := item.pageRange()
+		if first != "" {
+			data["pageStart"] = first
+		}
+		if last != "" {
+			data["pageEnd"] = last
+		}
+		if item.Journal != "" {
+			// The article is part of an issue, of a volume, of the journal
+			partOf := map[string]any{"@type": "Periodical", "name": item.Journal}
+			if item.ISSN != "" {
+				partOf["issn"] = item.ISSN
+			}
+			if item.Volume != "" {
+				partOf = map[string]any{"@type": "PublicationVolume", "volumeNumber": item.Volume, "isPartOf": partOf}
+			}
+			if item.Issue != "" {
+				partOf = map[string]any{"@type": "PublicationIssue", "issueNumber": item.Issue, "isPartOf": partOf}
+			}
+			data["isPartOf"] = partOf
+		}
+	case "Thesis":
+		if institution := item.institution(); institution != "" {
+			data["sourceOrganization"] = map[string]any{"@type": "Organization", "name": institution}
+		}
+		if item.advisor != "" {
+			data["contributor"] = map[string]any{"@type": "Person", "name": item.advisor}
+		}
+	}
+	return data
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"testing"
+
+	"e-repository-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// metaContents returns the contents of the tags with a name
+func metaContents(tags []MetaTag, name string) []string {
+	var contents []string
+	for _, tag := range tags {
+		if tag.Name == name {
+			contents = append(contents, tag.Content)
+		}
+	}
+	return contents
+}
+
+func TestPaperScholarlyMetaArticle(t *testing.T) {
+	year, volume, issue := 2021, 5, 2
+	journal, pages, doi, file := "Jurnal Informatika", "10-20", "10.1234/ji.5.2", "/uploads/papers/a.PDF"
+	paper := models.Paper{
+		ID: 7, Title: "Deteksi Objek", Year: &year, Volume: &volume, Issue: &issue,
+		Journal: &journal, Pages: &pages, DOI: &doi, FileURL: &file,
+		Authors: []models.PaperAuthor{{AuthorName: "Budi Santoso"}, {AuthorName: "Ani Wijaya"}},
+	}
+	meta := PaperScholarlyMeta(paper, ItemLinks{ServerURL: "https://api.example.ac.id", FrontendURL: "https://repo.example.ac.id"})
+
+	assert.Equal(t, []string{"Budi Santoso", "Ani Wijaya"}, metaContents(meta.Highwire, "citation_author"))
+	assert.Equal(t, []string{"Jurnal Informatika"}, metaContents(meta.Highwire, "citation_journal_title"))
+	assert.Equal(t, []string{"10"}, metaContents(meta.Highwire, "citation_firstpage"))
+	assert.Equal(t, []string{"20"}, metaContents(meta.Highwire, "citation_lastpage"))
+	assert.Equal(t, []string{"https://api.example.ac.id/uploads/papers/a.PDF"}, metaContents(meta.Highwire, "citation_pdf_url"))
+	assert.Equal(t, []string{"https://repo.example.ac.id/papers/7"}, metaContents(meta.Highwire, "citation_abstract_html_url"))
+	assert.Equal(t, []string{"https://repo.example.ac.id/papers/7", "https://doi.org/10.1234/ji.5.2"}, metaContents(meta.DublinCore, "DC.identifier"))
+
+	assert.Equal(t, "ScholarlyArticle", meta.JSONLD["@type"])
+	partOf := meta.JSONLD["isPartOf"].(map[string]any)
+	assert.Equal(t, "PublicationIssue", partOf["@type"])
+	assert.Equal(t, "PublicationVolume", partOf["isPartOf"].(map[string]any)["@type"])
+}
+
+func TestPaperScholarlyMetaThesis(t *testing.T) {
+	department, university, language := "Teknik Informatika", "Universitas Dumai", "Indonesia"
+	paper := models.Paper{ID: 3, Title: "Sistem Informasi", Department: &department, University: &university, Language: &language}
+	meta := PaperScholarlyMeta(paper, ItemLinks{})
+
+	assert.Equal(t, []string{"Teknik Informatika, Universitas Dumai"}, metaContents(meta.Highwire, "citation_dissertation_institution"))
+	assert.Equal(t, []string{"id"}, metaContents(meta.Highwire, "citation_language"))
+	assert.Empty(t, metaContents(meta.Highwire, "citation_journal_title"))
+	assert.Equal(t, []string{"Thesis"}, metaContents(meta.DublinCore, "DC.type"))
+	assert.Equal(t, "Thesis", meta.JSONLD["@type"])
+}
+
+func TestBookScholarlyMeta(t *testing.T) {
+	year, publisher, isbn, file := 2019, "Andi", "9786020000000", "/uploads/books/b.epub"
+	book := models.Book{
+		ID: 4, Title: "Pemrograman Go", PublishedYear: &year, Publisher: &publisher, ISBN: &isbn, FileURL: &file,
+		Subjects: []models.Keyword{{Term: "pemrograman"}, {Term: "golang"}},
+	}
+	meta := BookScholarlyMeta(book, ItemLinks{ServerURL: "https://api.example.ac.id", FrontendURL: "https://repo.example.ac.id"})
+
+	assert.Equal(t, []string{"9786020000000"}, metaContents(meta.Highwire, "citation_isbn"))
+	assert.Equal(t, []string{"pemrograman; golang"}, metaContents(meta.Highwire, "citation_keywords"))
+	assert.Empty(t, metaContents(meta.Highwire, "citation_pdf_url"))
+	assert.Equal(t, []string{"pemrograman", "golang"}, metaContents(meta.DublinCore, "DC.subject"))
+	assert.Equal(t, "Book", meta.JSONLD["@type"])
+	assert.Equal(t, "9786020000000", meta.JSONLD["isbn"])
+	assert.Equal(t, "https://api.example.ac.id/uploads/books/b.epub", meta.JSONLD["encoding"].(map[string]any)["contentUrl"])
+}
@@ -0,0 +1,685 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/search"
+
+	"gorm.io/gorm"
+)
+
+// searchFacetSize is how many values the category and author facets return
+const searchFacetSize = 20
+
+// searchIndexMaxMatches is how many of the best index matches a search considers, as
+// the matching IDs are sent to the database for filtering, counting and faceting.
+// Totals and facets of broader searches count only these and are approximate.
+const searchIndexMaxMatches = 1000
+
+// SearchFilters are the facet selections of a unified search. Values within a facet
+// are alternatives; all facets with a selection must match.
+type SearchFilters struct {
+	Types       []string
+	YearRanges  []YearRange
+	Categories  []string
+	Languages   []string
+	Faculties   []string
+	Departments []string
+	AuthorIDs   []uint
+	// CreatedAfter keeps the items added after a time, for new-item alerts
+	CreatedAfter *time.Time
+}
+
+// SearchHit is a book or paper matching a search, with its relevance score
+type SearchHit struct {
+	ItemRef
+	Score float64
+}
+
+// FacetCount is the number of matching items with a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets are the facet counts of the items matching a search
+type SearchFacets struct {
+	Type       []FacetCount `json:"type"`
+	Year       []FacetCount `json:"year"`
+	Category   []FacetCount `json:"category"`
+	Language   []FacetCount `json:"language"`
+	Faculty    []FacetCount `json:"faculty"`
+	Department []FacetCount `json:"department"`
+	Author     []FacetCount `json:"author"`
+}
+
+// SearchResult is a page of search hits with the total and facets of all matches
+type SearchResult struct {
+	Total  int64
+	Hits   []SearchHit
+	Facets SearchFacets
+	// Approximate is set when the query matched more items than a search considers,
+	// so Total and Facets count only the best of them
+	Approximate bool
+}
+
+// YearRange is an inclusive range of publication years; a zero bound is open
+type YearRange struct {
+	From int
+	To   int
+}
+
+// YearFacetRanges are the ranges the year facet counts, newest first
+var YearFacetRanges = []YearRange{
+	{From: 2020},
+	{From: 2015, To: 2019},
+	{From: 2010, To: 2014},
+	{From: 2000, To: 2009},
+	{To: 1999},
+}
+
+// String formats a range as its facet value, e.g. "2015-2019", "2020-" or "-1999"
+func (r YearRange) String() string {
+	var b strings.Builder
+	if r.From != 0 {
+		b.WriteString(strconv.Itoa(r.From))
+	}
+	b.WriteByte('-')
+	if r.To != 0 {
+		b.WriteString(strconv.Itoa(r.To))
+	}
+	return b.String()
+}
+
+// Contains reports whether a year falls in the range
+func (r YearRange) Contains(year int) bool {
+	return (r.From == 0 || year >= r.From) && (r.To == 0 || year <= r.To)
+}
+
+// ParseYearRange parses "2015-2019", "2020-", "-1999" or a single year
+func ParseYearRange(s string) (YearRange, error) {
+	s = strings.TrimSpace(s)
+	from, to, isRange := strings.Cut(s, "-")
+	if !isRange {
+		to = from
+	}
+	var r YearRange
+	var err error
+	if from != "" {
+		if r.From, err = strconv.Atoi(from); err != nil || r.From <= 0 {
+			return r, fmt.Errorf("invalid year range %q", s)
+		}
+	}
+	if to != "" {
+		if r.To, err = strconv.Atoi(to); err != nil || r.To <= 0 {
+			return r, fmt.Errorf("invalid year range %q", s)
+		}
+	}
+	if (r.From == 0 && r.To == 0) || (r.From != 0 && r.To != 0 && r.From > r.To) {
+		return r, fmt.Errorf("invalid year range %q", s)
+	}
+	return r, nil
+}
+
+// searchField is a column matched by search terms and its weight in the relevance score
+type searchField struct {
+	column string
+	weight int
+}
+
+// searchSource describes how one item type is searched and faceted
+type searchSource struct {
+	itemType   string
+	table      string
+	yearColumn string
+	fields     []searchField
+	columns    map[string]string // columns of the query fields the items have
+	categories string            // join table to categories
+	authors    string            // authorship table
+	foreignKey string            // item column of the join tables
+	department bool              // items carry a faculty department
+}
+
+var searchSources = []searchSource{
+	{
+		itemType:   "book",
+		table:      "books",
+		yearColumn: "published_year",
+		fields: []searchField{
+			{"title", 5}, {"author", 3}, {"subject", 2}, {"summary", 1}, {"isbn", 1},
+		},
+		columns: map[string]string{
+			search.FieldTitle:    "title",
+			search.FieldAuthor:   "author",
+			search.FieldAbstract: "summary",
+			search.FieldKeywords: "subject",
+			search.FieldISBN:     "isbn",
+			search.FieldLanguage: "language",
+		},
+		categories: "book_categories",
+		authors:    "book_authors",
+		foreignKey: "book_id",
+	},
+	{
+		itemType:   "paper",
+		table:      "papers",
+		yearColumn: "year",
+		fields: []searchField{
+			{"title", 5}, {"author", 3}, {"keywords", 2}, {"abstract", 1}, {"doi", 1}, {"issn", 1},
+		},
+		columns: map[string]string{
+			search.FieldTitle:    "title",
+			search.FieldAuthor:   "author",
+			search.FieldAbstract: "abstract",
+			search.FieldKeywords: "keywords",
+			search.FieldISSN:     "issn",
+			search.FieldDOI:      "doi",
+			search.FieldLanguage: "language",
+		},
+		categories: "paper_categories",
+		authors:    "paper_authors",
+		foreignKey: "paper_id",
+		department: true,
+	},
+}
+
+// column qualifies a column with the source table
+func (s searchSource) column(name string) string {
+	return s.table + "." + name
+}
+
+// selected reports whether the filters leave any items of the source
+func (s searchSource) selected(filters SearchFilters) bool {
+	if len(filters.Types) > 0 && !containsString(filters.Types, s.itemType) {
+		return false
+	}
+	// Only papers belong to a faculty department
+	return s.department || (len(filters.Faculties) == 0 && len(filters.Departments) == 0)
+}
+
+// searchMatch is what a query matches: the items the search index found, or without an
+// index the items the query selects in the database
+type searchMatch struct {
+	query  search.Query
+	terms  []string
+	phrase string
+	// indexed holds the index scores of the matching items by type; nil without an index
+	indexed map[string]map[uint]float64
+	// capped is set when the index matched more than searchIndexMaxMatches items
+	capped bool
+}
+
+// newSearchMatch parses a query and runs it on the default search index, falling back
+// to the database when there is no index or it fails
+func newSearchMatch(input string) (searchMatch, error) {
+	q, err := search.Parse(input)
+	if err != nil {
+		return searchMatch{}, err
+	}
+	match := searchMatch{query: q, terms: search.Terms(q)}
+	match.phrase = strings.Join(match.terms, " ")
+	index := search.Default()
+	if index == nil || q == nil {
+		return match, nil
+	}
+
+	// One extra hit tells whether there are more matches than are considered
+	hits, err := index.Search(q, 0, searchIndexMaxMatches+1)
+	if err != nil {
+		log.Printf("[Search] Search index failed, matching in the database: %v", err)
+		return match, nil
+	}
+	if len(hits) > searchIndexMaxMatches {
+		hits = hits[:searchIndexMaxMatches]
+		match.capped = true
+	}
+	indexed := map[string]map[uint]float64{"book": {}, "paper": {}}
+	for _, hit := range hits {
+		if scores, ok := indexed[hit.Type]; ok {
+			scores[hit.ID] = hit.Score
+		}
+	}
+	match.indexed = indexed
+	return match, nil
+}
+
+// matching selects the items of the source that match the query and the filters
+func (s searchSource) matching(db *gorm.DB, match searchMatch, filters SearchFilters) *gorm.DB {
+	query := db.Table(s.table)
+	if match.indexed != nil {
+		ids := make([]uint, 0, len(match.indexed[s.itemType]))
+		for id := range match.indexed[s.itemType] {
+			ids = append(ids, id)
+		}
+		if len(ids) == 0 {
+			return query.Where("1 = 0")
+		}
+		query = query.Where(s.column("id")+" IN ?", ids)
+	} else if match.query != nil {
+		condition, args := s.condition(match.query)
+		query = query.Where(condition, args...)
+	}
+
+	if len(filters.YearRanges) > 0 {
+		conditions := make([]string, 0, len(filters.YearRanges))
+		var args []interface{}
+		for _, r := range filters.YearRanges {
+			switch {
+			case r.From == 0:
+				conditions = append(conditions, s.column(s.yearColumn)+" <= ?")
+				args = append(args, r.To)
+			case r.To == 0:
+				conditions = append(conditions, s.column(s.yearColumn)+" >= ?")
+				args = append(args, r.From)
+			default:
+				conditions = append(conditions, s.column(s.yearColumn)+" BETWEEN ? AND ?")
+				args = append(args, r.From, r.To)
+			}
+		}
+		query = query.Where(strings.Join(conditions, " OR "), args...)
+	}
+	if len(filters.Categories) > 0 {
+		query = query.Where(s.column("id")+" IN (?)", db.Table(s.categories).Select(s.categories+"."+s.foreignKey).
+			Joins("JOIN categories ON categories.id = "+s.categories+".category_id").
+			Where("categories.name IN ?", filters.Categories))
+	}
+	if len(filters.Languages) > 0 {
+		query = query.Where(s.column("language")+" IN ?", filters.Languages)
+	}
+	if len(filters.Departments) > 0 {
+		query = query.Where(s.column("department")+" IN ?", filters.Departments)
+	}
+	if len(filters.Faculties) > 0 {
+		query = query.Where(s.column("department")+" IN (?)", db.Table("departments").Select("name").Where("faculty IN ?", filters.Faculties))
+	}
+	if len(filters.AuthorIDs) > 0 {
+		query = query.Where(s.column("id")+" IN (?)", db.Table(s.authors).Select(s.foreignKey).Where("author_id IN ?", filters.AuthorIDs))
+	}
+	if filters.CreatedAfter != nil {
+		query = query.Where(s.column("created_at")+" > ?", *filters.CreatedAfter)
+	}
+	return query
+}
+
+// score returns the relevance expression of the source and its arguments: the weights
+// of the fields each term appears in, plus a bonus when the whole phrase is in the title
+func (s searchSource) score(match searchMatch) (string, []interface{}) {
+	terms := match.terms
+	if len(terms) == 0 {
+		return "0", nil
+	}
+	var parts []string
+	var args []interface{}
+	for _, term := range terms {
+		for _, field := range s.fields {
+			// An empty column makes LIKE NULL, which would make the whole sum NULL
+			parts = append(parts, fmt.Sprintf("COALESCE(LOWER(%s) LIKE ?, 0) * %d", s.column(field.column), field.weight))
+			args = append(args, "%"+escapeLike(term)+"%")
+		}
+	}
+	if len(terms) > 1 {
+		parts = append(parts, "COALESCE(LOWER("+s.column("title")+") LIKE ?, 0) * 5")
+		args = append(args, "%"+escapeLike(match.phrase)+"%")
+	}
+	return strings.Join(parts, " + "), args
+}
+
+// condition compiles a parsed query to a SQL condition on the items of the source
+func (s searchSource) condition(q search.Query) (string, []interface{}) {
+	switch q := q.(type) {
+	case search.Term:
+		return s.termCondition(q)
+	case search.Range:
+		column := s.column(s.yearColumn)
+		switch {
+		case q.From == nil:
+			return column + " <= ?", []interface{}{*q.To}
+		case q.To == nil:
+			return column + " >= ?", []interface{}{*q.From}
+		default:
+			return column + " BETWEEN ? AND ?", []interface{}{*q.From, *q.To}
+		}
+	case search.And:
+		return s.joinConditions(q.Clauses, " AND ")
+	case search.Or:
+		return s.joinConditions(q.Clauses, " OR ")
+	case search.Not:
+		condition, args := s.condition(q.Clause)
+		// Empty columns make LIKE NULL rather than false
+		return "NOT COALESCE((" + condition + "), FALSE)", args
+	}
+	return "1 = 0", nil
+}
+
+// joinConditions compiles clauses and joins them with an operator
+func (s searchSource) joinConditions(clauses []search.Query, operator string) (string, []interface{}) {
+	conditions := make([]string, len(clauses))
+	var args []interface{}
+	for i, clause := range clauses {
+		condition, clauseArgs := s.condition(clause)
+		conditions[i] = "(" + condition + ")"
+		args = append(args, clauseArgs...)
+	}
+	return strings.Join(conditions, operator), args
+}
+
+// termCondition matches a word, phrase or wildcard pattern in a field, or in any of the
+// searched columns. Identifiers match wildcard patterns from their start, as in isbn:978*;
+// other fields contain the word or pattern.
+func (s searchSource) termCondition(term search.Term) (string, []interface{}) {
+	value := strings.ToLower(term.Value)
+	switch term.Field {
+	case search.FieldType:
+		if value == s.itemType {
+			return "1 = 1", nil
+		}
+		return "1 = 0", nil
+	case search.FieldLanguage:
+		if column, ok := s.columns[search.FieldLanguage]; ok && !term.Wildcard {
+			return "LOWER(" + s.column(column) + ") = ?", []interface{}{value}
+		}
+	}
+
+	pattern := "%" + escapeLike(value) + "%"
+	if term.Wildcard {
+		pattern = wildcardPattern(value)
+		switch term.Field {
+		case search.FieldISBN, search.FieldISSN, search.FieldDOI:
+		default:
+			pattern = "%" + pattern + "%"
+		}
+	}
+
+	var columns []string
+	if term.Field == "" {
+		for _, field := range s.fields {
+			columns = append(columns, field.column)
+		}
+	} else if column, ok := s.columns[term.Field]; ok {
+		columns = []string{column}
+	} else {
+		// Books have no ISSN and papers no ISBN
+		return "1 = 0", nil
+	}
+
+	conditions := make([]string, 0, len(columns)+1)
+	args := make([]interface{}, 0, len(columns)+1)
+	for _, column := range columns {
+		conditions = append(conditions, "LOWER("+s.column(column)+") LIKE ?")
+		args = append(args, pattern)
+	}
+	if term.Field == search.FieldAuthor {
+		// Co-authors are only listed in the authorship table
+		conditions = append(conditions, s.column("id")+" IN (SELECT "+s.foreignKey+" FROM "+s.authors+" WHERE LOWER(author_name) LIKE ?)")
+		args = append(args, pattern)
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// escapeLike escapes the LIKE wildcards in text matched literally
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
+
+// wildcardPattern turns a query wildcard pattern into a LIKE pattern
+func wildcardPattern(value string) string {
+	return strings.NewReplacer("*", "%", "?", "_").Replace(escapeLike(value))
+}
+
+// Search finds books and papers matching a query and the filters, ranked by relevance
+// (newest first without a query), and counts the facets of all matches. Queries use the
+// syntax of search.Parse, whose *search.SyntaxError is returned for malformed queries;
+// they are ranked by the search index when one is open. Broad index searches consider
+// only their best matches and mark the result Approximate.
+func Search(db *gorm.DB, query string, filters SearchFilters, offset, limit int) (*SearchResult, error) {
+	match, err := newSearchMatch(query)
+	if err != nil {
+		return nil, err
+	}
+	// New-item alerts must see every new match, not only those among the best overall
+	if match.capped && filters.CreatedAfter != nil {
+		match.indexed, match.capped = nil, false
+	}
+
+	var sources []searchSource
+	for _, source := range searchSources {
+		if source.selected(filters) {
+			sources = append(sources, source)
+		}
+	}
+
+	result := &SearchResult{Hits: make([]SearchHit, 0), Approximate: match.capped}
+	facets := newFacetCounter()
+	for _, source := range sources {
+		var count int64
+		if err := source.matching(db, match, filters).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		result.Total += count
+		if count > 0 {
+			facets.add("type", source.itemType, "", int(count))
+			if err := countSourceFacets(db, source, match, filters, facets); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result.Facets = facets.facets()
+	if result.Total == 0 {
+		return result, nil
+	}
+	if match.indexed != nil {
+		hits, err := rankIndexed(db, sources, match, filters)
+		if err != nil {
+			return nil, err
+		}
+		if offset < len(hits) {
+			result.Hits = hits[offset:min(offset+limit, len(hits))]
+		}
+		return result, nil
+	}
+
+	parts := make([]string, len(sources))
+	args := make([]interface{}, 0, len(sources)+2)
+	for i, source := range sources {
+		score, scoreArgs := source.score(match)
+		parts[i] = "?"
+		args = append(args, source.matching(db, match, filters).
+			Select("'"+source.itemType+"' AS item_type, "+source.column("id")+" AS id, ("+score+") AS score, "+source.column("created_at")+" AS created_at", scoreArgs...))
+	}
+	args = append(args, limit, offset)
+
+	var rows []struct {
+		ItemType string
+		ID       uint
+		Score    float64
+	}
+	if err := db.Raw("SELECT item_type, id, score FROM ("+strings.Join(parts, " UNION ALL ")+") AS results "+
+		"ORDER BY score DESC, created_at DESC, id DESC LIMIT ? OFFSET ?", args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result.Hits = append(result.Hits, SearchHit{ItemRef: ItemRef{Type: row.ItemType, ID: row.ID}, Score: row.Score})
+	}
+	return result, nil
+}
+
+// rankIndexed orders the filtered index matches by their index score
+func rankIndexed(db *gorm.DB, sources []searchSource, match searchMatch, filters SearchFilters) ([]SearchHit, error) {
+	var hits []SearchHit
+	for _, source := range sources {
+		var ids []uint
+		if err := source.matching(db, match, filters).Pluck(source.column("id"), &ids).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			hits = append(hits, SearchHit{ItemRef: ItemRef{Type: source.itemType, ID: id}, Score: match.indexed[source.itemType][id]})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Type != hits[j].Type {
+			return hits[i].Type < hits[j].Type
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hits, nil
+}
+
+// countSourceFacets adds the facet counts of a source's matching items
+func countSourceFacets(db *gorm.DB, source searchSource, match searchMatch, filters SearchFilters, facets *facetCounter) error {
+	matchingIDs := func() *gorm.DB {
+		return source.matching(db, match, filters).Select(source.column("id"))
+	}
+	var rows []facetRow
+
+	yearColumn := source.column(source.yearColumn)
+	if err := source.matching(db, match, filters).Select(yearColumn + " AS value, COUNT(*) AS count").
+		Where(yearColumn + " IS NOT NULL").Group(yearColumn).Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		year, _ := strconv.Atoi(row.Value)
+		for _, r := range YearFacetRanges {
+			if r.Contains(year) {
+				facets.add("year", r.String(), "", row.Count)
+				break
+			}
+		}
+	}
+
+	rows = nil
+	if err := db.Table(source.categories).Select("categories.name AS value, COUNT(DISTINCT "+source.categories+"."+source.foreignKey+") AS count").
+		Joins("JOIN categories ON categories.id = "+source.categories+".category_id").
+		Where(source.categories+"."+source.foreignKey+" IN (?)", matchingIDs()).
+		Group("categories.name").Scan(&rows).Error; err != nil {
+		return err
+	}
+	facets.addRows("category", rows)
+
+	language := source.column("language")
+	rows = nil
+	if err := source.matching(db, match, filters).Select(language + " AS value, COUNT(*) AS count").
+		Where(language + " IS NOT NULL AND " + language + " <> ''").Group(language).Scan(&rows).Error; err != nil {
+		return err
+	}
+	facets.addRows("language", rows)
+
+	if source.department {
+		department := source.column("department")
+		rows = nil
+		if err := source.matching(db, match, filters).Select(department + " AS value, COUNT(*) AS count").
+			Where(department + " IS NOT NULL AND " + department + " <> ''").Group(department).Scan(&rows).Error; err != nil {
+			return err
+		}
+		facets.addRows("department", rows)
+
+		rows = nil
+		if err := source.matching(db, match, filters).Select("departments.faculty AS value, COUNT(DISTINCT " + source.column("id") + ") AS count").
+			Joins("JOIN departments ON departments.name = " + department).Group("departments.faculty").Scan(&rows).Error; err != nil {
+			return err
+		}
+		facets.addRows("faculty", rows)
+	}
+
+	rows = nil
+	if err := db.Table(source.authors).Select("authors.id AS value, authors.preferred_name AS label, COUNT(DISTINCT "+source.authors+"."+source.foreignKey+") AS count").
+		Joins("JOIN authors ON authors.id = "+source.authors+".author_id").
+		Where(source.authors+"."+source.foreignKey+" IN (?)", matchingIDs()).
+		Group("authors.id, authors.preferred_name").Scan(&rows).Error; err != nil {
+		return err
+	}
+	facets.addRows("author", rows)
+	return nil
+}
+
+// facetRow is a facet value counted by a grouped query
+type facetRow struct {
+	Value string
+	Label string
+	Count int
+}
+
+// facetCounter sums facet counts across item types
+type facetCounter struct {
+	counts map[string]map[string]*FacetCount
+}
+
+func newFacetCounter() *facetCounter {
+	return &facetCounter{counts: make(map[string]map[string]*FacetCount)}
+}
+
+// add adds count items to a facet value
+func (f *facetCounter) add(facet, value, label string, count int) {
+	values, ok := f.counts[facet]
+	if !ok {
+		values = make(map[string]*FacetCount)
+		f.counts[facet] = values
+	}
+	if existing, ok := values[value]; ok {
+		existing.Count += count
+		return
+	}
+	values[value] = &FacetCount{Value: value, Label: label, Count: count}
+}
+
+// addRows adds grouped query rows to a facet
+func (f *facetCounter) addRows(facet string, rows []facetRow) {
+	for _, row := range rows {
+		f.add(facet, row.Value, row.Label, row.Count)
+	}
+}
+
+// sorted returns a facet's values by descending count, at most limit of them when limit > 0
+func (f *facetCounter) sorted(facet string, limit int) []FacetCount {
+	values := make([]FacetCount, 0, len(f.counts[facet]))
+	for _, value := range f.counts[facet] {
+		values = append(values, *value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+	return values
+}
+
+// facets returns the counted facets. Year ranges keep their chronological order.
+func (f *facetCounter) facets() SearchFacets {
+	years := make([]FacetCount, 0, len(YearFacetRanges))
+	for _, r := range YearFacetRanges {
+		if value, ok := f.counts["year"][r.String()]; ok {
+			years = append(years, *value)
+		}
+	}
+	return SearchFacets{
+		Type:       f.sorted("type", 0),
+		Year:       years,
+		Category:   f.sorted("category", searchFacetSize),
+		Language:   f.sorted("language", 0),
+		Faculty:    f.sorted("faculty", 0),
+		Department: f.sorted("department", 0),
+		Author:     f.sorted("author", searchFacetSize),
+	}
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
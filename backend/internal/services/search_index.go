@@ -0,0 +1,235 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"e-repository-api/internal/models"
+	"e-repository-api/internal/search"
+	"e-repository-api/internal/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reindexBatchSize is how many items Reindex loads and indexes at a time
+const reindexBatchSize = 200
+
+// retryIndexBatchSize is how many queued index updates RetryIndexUpdates applies per run
+const retryIndexBatchSize = 500
+
+// IndexItem brings the search index entry of a book or paper up to date, removing it
+// when the item no longer exists. Failures are logged and queued rather than returned
+// so a broken index never fails an edit; RetryIndexUpdates applies them later.
+func IndexItem(db *gorm.DB, itemType string, itemID uint) {
+	index := search.Default()
+	if index == nil {
+		return
+	}
+	if err := updateIndexEntry(db, index, itemType, itemID); err != nil {
+		log.Printf("[SearchIndex] Failed to index %s %d: %v", itemType, itemID, err)
+		queueIndexUpdate(db, itemType, itemID, err)
+	}
+}
+
+// RemoveFromIndex drops a deleted book or paper from the search index, queueing the
+// removal for a retry when it fails. A removal DeleteItemRecords queued is cleared once
+// it succeeds.
+func RemoveFromIndex(db *gorm.DB, itemType string, itemID uint) {
+	index := search.Default()
+	if index == nil {
+		return
+	}
+	if err := index.Delete(itemType, itemID); err != nil {
+		log.Printf("[SearchIndex] Failed to remove %s %d: %v", itemType, itemID, err)
+		queueIndexUpdate(db, itemType, itemID, err)
+		return
+	}
+	if err := db.Where("item_type = ? AND item_id = ?", itemType, itemID).Delete(&models.PendingIndexUpdate{}).Error; err != nil {
+		log.Printf("[SearchIndex] Failed to clear queued removal of %s %d: %v", itemType, itemID, err)
+	}
+}
+
+// updateIndexEntry indexes an item as it is in the database, or removes it from the
+// index when it no longer exists
+func updateIndexEntry(db *gorm.DB, index search.Index, itemType string, itemID uint) error {
+	docs, err := itemDocuments(db, itemType, []uint{itemID})
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return index.Delete(itemType, itemID)
+	}
+	return index.Index(docs...)
+}
+
+// queueIndexUpdate records a failed index update for RetryIndexUpdates
+func queueIndexUpdate(db *gorm.DB, itemType string, itemID uint, cause error) {
+	pending := models.PendingIndexUpdate{ItemType: itemType, ItemID: itemID, Attempts: 1, LastError: cause.Error()}
+	if err := db.Clauses(clause.OnConflict{DoUpdates: clause.Assignments(map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": cause.Error(),
+		"updated_at": time.Now(),
+	})}).Create(&pending).Error; err != nil {
+		log.Printf("[SearchIndex] Failed to queue %s %d for a retry: %v", itemType, itemID, err)
+	}
+}
+
+// queueIndexRemoval queues the removal of an item that is being deleted, so that it
+// reaches the index even if the removal after the commit fails or never runs
+func queueIndexRemoval(db *gorm.DB, itemType string, itemID uint) error {
+	if search.Default() == nil {
+		return nil
+	}
+	pending := models.PendingIndexUpdate{ItemType: itemType, ItemID: itemID}
+	return db.Clauses(clause.OnConflict{DoUpdates: clause.Assignments(map[string]interface{}{
+		"updated_at": time.Now(),
+	})}).Create(&pending).Error
+}
+
+// RetryIndexUpdates applies the queued index updates, oldest first. Updates that fail
+// again stay queued for the next run.
+func RetryIndexUpdates(db *gorm.DB) error {
+	index := search.Default()
+	if index == nil {
+		return nil
+	}
+
+	var pending []models.PendingIndexUpdate
+	if err := db.Order("updated_at ASC").Limit(retryIndexBatchSize).Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to load pending index updates: %w", err)
+	}
+
+	applied := 0
+	for _, update := range pending {
+		if err := updateIndexEntry(db, index, update.ItemType, update.ItemID); err != nil {
+			if err := db.Model(&update).Updates(map[string]interface{}{
+				"attempts":   update.Attempts + 1,
+				"last_error": err.Error(),
+			}).Error; err != nil {
+				return fmt.Errorf("failed to record index retry: %w", err)
+			}
+			continue
+		}
+		// An update queued again while this one ran stays queued
+		if err := db.Where("id = ? AND updated_at = ?", update.ID, update.UpdatedAt).
+			Delete(&models.PendingIndexUpdate{}).Error; err != nil {
+			return fmt.Errorf("failed to clear index update: %w", err)
+		}
+		applied++
+	}
+
+	if applied > 0 {
+		log.Printf("[RetryIndexUpdates] Applied %d queued index updates", applied)
+	}
+	return nil
+}
+
+// ClearIndexUpdates drops the index updates queued before a full reindex started,
+// since the reindex has covered them
+func ClearIndexUpdates(db *gorm.DB, before time.Time) error {
+	return db.Where("updated_at < ?", before).Delete(&models.PendingIndexUpdate{}).Error
+}
+
+// Reindex adds every book and paper to an index and returns how many were indexed
+func Reindex(db *gorm.DB, index search.Index) (int, error) {
+	indexed := 0
+	for _, itemType := range []string{"book", "paper"} {
+		var lastID uint
+		for {
+			var ids []uint
+			if err := db.Table(itemType+"s").Where("id > ?", lastID).Order("id").
+				Limit(reindexBatchSize).Pluck("id", &ids).Error; err != nil {
+				return indexed, err
+			}
+			if len(ids) == 0 {
+				break
+			}
+			docs, err := itemDocuments(db, itemType, ids)
+			if err != nil {
+				return indexed, err
+			}
+			if err := index.Index(docs...); err != nil {
+				return indexed, err
+			}
+			indexed += len(docs)
+			lastID = ids[len(ids)-1]
+		}
+	}
+	return indexed, nil
+}
+
+// itemDocuments loads the searchable text of books or papers
+func itemDocuments(db *gorm.DB, itemType string, ids []uint) ([]search.Document, error) {
+	docs := make([]search.Document, 0, len(ids))
+	if itemType == "book" {
+		var books []models.Book
+		if err := db.Preload("Authors").Where("id IN ?", ids).Find(&books).Error; err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			names := make([]string, 0, len(book.Authors))
+			for _, author := range book.Authors {
+				names = append(names, author.AuthorName)
+			}
+			docs = append(docs, search.Document{
+				Type:         "book",
+				ID:           book.ID,
+				Title:        book.Title,
+				Authors:      authorNames(book.Author, names),
+				Abstract:     utils.StringValue(book.Summary),
+				Keywords:     SplitKeywords(utils.StringValue(book.Subject)),
+				Identifiers:  identifiers(book.ISBN),
+				Year:         utils.IntValue(book.PublishedYear),
+				Language:     utils.StringValue(book.Language),
+				TextLanguage: LanguageCode(book.Language),
+			})
+		}
+		return docs, nil
+	}
+
+	var papers []models.Paper
+	if err := db.Preload("Authors").Where("id IN ?", ids).Find(&papers).Error; err != nil {
+		return nil, err
+	}
+	for _, paper := range papers {
+		names := make([]string, 0, len(paper.Authors))
+		for _, author := range paper.Authors {
+			names = append(names, author.AuthorName)
+		}
+		docs = append(docs, search.Document{
+			Type:         "paper",
+			ID:           paper.ID,
+			Title:        paper.Title,
+			Authors:      authorNames(paper.Author, names),
+			Abstract:     utils.StringValue(paper.Abstract),
+			Keywords:     SplitKeywords(utils.StringValue(paper.Keywords)),
+			Identifiers:  identifiers(paper.ISSN, paper.DOI),
+			Year:         utils.IntValue(paper.Year),
+			Language:     utils.StringValue(paper.Language),
+			TextLanguage: LanguageCode(paper.Language),
+		})
+	}
+	return docs, nil
+}
+
+// authorNames returns the author entries of an item, or its main author for items
+// created before authors were listed separately
+func authorNames(mainAuthor string, entries []string) []string {
+	if len(entries) > 0 {
+		return entries
+	}
+	return []string{mainAuthor}
+}
+
+// identifiers collects the non-empty identifiers of an item
+func identifiers(values ...*string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != nil && *value != "" {
+			result = append(result, *value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"testing"
+
+	"e-repository-api/internal/search"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYearRange(t *testing.T) {
+	for input, want := range map[string]YearRange{
+		"2015-2019": {From: 2015, To: 2019},
+		"2020-":     {From: 2020},
+		"-1999":     {To: 1999},
+		" 2021 ":    {From: 2021, To: 2021},
+	} {
+		got, err := ParseYearRange(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "-", "2019-2015", "abc", "2015-x", "0-10"} {
+		_, err := ParseYearRange(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestYearRange(t *testing.T) {
+	for _, r := range YearFacetRanges {
+		parsed, err := ParseYearRange(r.String())
+		assert.NoError(t, err)
+		assert.Equal(t, r, parsed)
+	}
+
+	assert.True(t, YearRange{From: 2020}.Contains(2024))
+	assert.False(t, YearRange{From: 2020}.Contains(2019))
+	assert.True(t, YearRange{To: 1999}.Contains(1987))
+	assert.True(t, YearRange{From: 2015, To: 2019}.Contains(2019))
+	assert.False(t, YearRange{From: 2015, To: 2019}.Contains(2020))
+}
+
+func TestSearchSourceSelected(t *testing.T) {
+	books, papers := searchSources[0], searchSources[1]
+	assert.True(t, books.selected(SearchFilters{}))
+	assert.False(t, books.selected(SearchFilters{Types: []string{"paper"}}))
+	assert.False(t, books.selected(SearchFilters{Faculties: []string{"Teknik"}}))
+	assert.True(t, papers.selected(SearchFilters{Departments: []string{"Informatika"}}))
+}
+
+func TestFacetCounter(t *testing.T) {
+	facets := newFacetCounter()
+	facets.add("language", "English", "", 2)
+	facets.add("language", "Indonesia", "", 3)
+	facets.add("language", "English", "", 4)
+	facets.add("year", "-1999", "", 1)
+	facets.add("year", "2020-", "", 5)
+
+	result := facets.facets()
+	assert.Equal(t, []FacetCount{{Value: "English", Count: 6}, {Value: "Indonesia", Count: 3}}, result.Language)
+	// Year ranges stay newest first whatever their counts
+	assert.Equal(t, []FacetCount{{Value: "2020-", Count: 5}, {Value: "-1999", Count: 1}}, result.Year)
+	assert.Empty(t, result.Author)
+}
+
+func TestSearchCondition(t *testing.T) {
+	books, papers := searchSources[0], searchSources[1]
+	condition := func(source searchSource, input string) (string, []interface{}) {
+		q, err := search.Parse(input)
+		assert.NoError(t, err, input)
+		return source.condition(q)
+	}
+
+	sql, args := condition(books, `title:"Sistem Informasi" AND author:santoso`)
+	assert.Equal(t, "(LOWER(books.title) LIKE ?) AND (LOWER(books.author) LIKE ? OR books.id IN (SELECT book_id FROM book_authors WHERE LOWER(author_name) LIKE ?))", sql)
+	assert.Equal(t, []interface{}{"%sistem informasi%", "%santoso%", "%santoso%"}, args)
+
+	sql, args = condition(papers, "year:2019..2023 -keywords:covid")
+	assert.Equal(t, "(papers.year BETWEEN ? AND ?) AND (NOT COALESCE((LOWER(papers.keywords) LIKE ?), FALSE))", sql)
+	assert.Equal(t, []interface{}{2019, 2023, "%covid%"}, args)
+
+	sql, args = condition(books, "isbn:978*")
+	assert.Equal(t, "LOWER(books.isbn) LIKE ?", sql)
+	assert.Equal(t, []interface{}{"978%"}, args)
+
+	// Papers have no ISBN and books no ISSN
+	sql, _ = condition(papers, "isbn:978*")
+	assert.Equal(t, "1 = 0", sql)
+	sql, _ = condition(books, "type:paper OR type:book")
+	assert.Equal(t, "(1 = 0) OR (1 = 1)", sql)
+
+	sql, args = condition(books, "language:English")
+	assert.Equal(t, "LOWER(books.language) = ?", sql)
+	assert.Equal(t, []interface{}{"english"}, args)
+
+	sql, args = condition(papers, "year:..2000")
+	assert.Equal(t, "papers.year <= ?", sql)
+	assert.Equal(t, []interface{}{2000}, args)
+
+	// Unqualified words search every weighted column; LIKE wildcards in them are literal
+	sql, args = condition(books, "100%_sis?em")
+	assert.Equal(t, "LOWER(books.title) LIKE ? OR LOWER(books.author) LIKE ? OR LOWER(books.subject) LIKE ? OR LOWER(books.summary) LIKE ? OR LOWER(books.isbn) LIKE ?", sql)
+	assert.Equal(t, `%100\%\_sis_em%`, args[0])
+}
+
+// hitsIndex is a search index that matches count papers for every query
+type hitsIndex struct {
+	count int
+}
+
+func (x hitsIndex) Index(docs ...search.Document) error   { return nil }
+func (x hitsIndex) Delete(itemType string, id uint) error { return nil }
+func (x hitsIndex) Count() (uint64, error)                { return uint64(x.count), nil }
+func (x hitsIndex) Close() error                          { return nil }
+
+func (x hitsIndex) Search(q search.Query, offset, limit int) ([]search.Hit, error) {
+	var hits []search.Hit
+	for i := offset; i < x.count && i < offset+limit; i++ {
+		hits = append(hits, search.Hit{Type: "paper", ID: uint(i + 1), Score: float64(x.count - i)})
+	}
+	return hits, nil
+}
+
+func TestSearchMatchCapsIndexMatches(t *testing.T) {
+	defer search.SetDefault(nil)
+
+	search.SetDefault(hitsIndex{count: searchIndexMaxMatches})
+	match, err := newSearchMatch("sistem")
+	assert.NoError(t, err)
+	assert.False(t, match.capped)
+	assert.Len(t, match.indexed["paper"], searchIndexMaxMatches)
+
+	// Only the best matches of a broader query are kept
+	search.SetDefault(hitsIndex{count: searchIndexMaxMatches + 500})
+	match, err = newSearchMatch("a*")
+	assert.NoError(t, err)
+	assert.True(t, match.capped)
+	assert.Len(t, match.indexed["paper"], searchIndexMaxMatches)
+	assert.Contains(t, match.indexed["paper"], uint(1))
+	assert.NotContains(t, match.indexed["paper"], uint(searchIndexMaxMatches+1))
+}
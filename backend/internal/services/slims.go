@@ -0,0 +1,275 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SLiMSSource is the Source of the imported records of a SLiMS (Senayan) catalog
+const SLiMSSource = "slims"
+
+// SLiMSColumns maps the fields the importer reads to the columns of a SLiMS
+// bibliography export. An empty column leaves the field unmapped.
+type SLiMSColumns map[string]string
+
+// DefaultSLiMSColumns returns the columns of the bibliography export of SLiMS with
+// Indonesian headers. It has no biblio ID column; map "id" to one when the export
+// includes it.
+func DefaultSLiMSColumns() SLiMSColumns {
+	return SLiMSColumns{
+		"id":        "",
+		"title":     "Judul",
+		"gmd":       "Jenis File",
+		"edition":   "Jumlah Revisi atau Perubahan",
+		"isbn":      "ISBN",
+		"publisher": "Penerbit",
+		"year":      "Tahun",
+		"pages":     "Jumlah Halaman dan Dimensi",
+		"language":  "Bahasa",
+		"place":     "Alamat",
+		"notes":     "Tambahan",
+		"cover":     "Cover",
+		"file":      "",
+		"advisor":   "Penasehat",
+		"authors":   "Penulis Tambahan",
+		"subjects":  "Subjek",
+		"items":     "Nomor Induk Buku atau Barcode",
+	}
+}
+
+// Set applies a mapping such as "id=biblio_id,file=Lampiran" to the columns
+func (c SLiMSColumns) Set(spec string) error {
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if _, known := c[field]; !ok || !known {
+			return fmt.Errorf("invalid mapping %q, expected <field>=<column> with a field of %s", pair, strings.Join(c.fields(), ", "))
+		}
+		c[field] = strings.TrimSpace(column)
+	}
+	return nil
+}
+
+// Missing returns the mapped columns absent from a header row
+func (c SLiMSColumns) Missing(header []string) []string {
+	var missing []string
+	for _, field := range c.fields() {
+		if column := c[field]; column != "" && !containsString(header, column) {
+			missing = append(missing, column)
+		}
+	}
+	return missing
+}
+
+// fields returns the mapped field names in order
+func (c SLiMSColumns) fields() []string {
+	fields := make([]string, 0, len(c))
+	for field := range c {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// SLiMSRecord is a book or paper read from a row of a SLiMS export
+type SLiMSRecord struct {
+	Key        string // identifies the biblio record across runs
+	ItemType   string
+	Book       *models.Book
+	Paper      *models.Paper
+	Authors    []string
+	CoverFile  string
+	AttachFile string
+}
+
+// Title returns the title of the record's book or paper
+func (r SLiMSRecord) Title() string {
+	if r.Book != nil {
+		return r.Book.Title
+	}
+	if r.Paper != nil {
+		return r.Paper.Title
+	}
+	return ""
+}
+
+// slimsPaperGMD finds general material designations and notes of theses and articles
+var slimsPaperGMD = regexp.MustCompile(`(?i)skripsi|tesis|thesis|disertasi|dissertation|tugas akhir|jurnal|journal|artikel|article|karya ilmiah`)
+
+// slimsListItem matches the entries of the <a><b> lists SLiMS exports authors,
+// subjects and item codes in
+var slimsListItem = regexp.MustCompile(`<([^<>]*)>`)
+
+// SLiMSRecordFromRow maps a row, keyed by column header, to a book or paper.
+// itemType is "book", "paper" or "auto"; auto imports theses and articles (by their
+// GMD or notes) as papers and everything else as books.
+func SLiMSRecordFromRow(row map[string]string, columns SLiMSColumns, itemType string) (SLiMSRecord, error) {
+	value := func(field string) string {
+		if column := columns[field]; column != "" {
+			return strings.TrimSpace(row[column])
+		}
+		return ""
+	}
+
+	record := SLiMSRecord{
+		ItemType:   itemType,
+		Authors:    slimsNames(value("authors")),
+		CoverFile:  value("cover"),
+		AttachFile: value("file"),
+	}
+	if record.ItemType == "auto" {
+		record.ItemType = "book"
+		if slimsPaperGMD.MatchString(value("gmd") + " " + value("notes")) {
+			record.ItemType = "paper"
+		}
+	}
+
+	title := strings.Join(strings.Fields(value("title")), " ")
+	if title == "" {
+		return record, errors.New("the row has no title")
+	}
+	if len(record.Authors) == 0 {
+		return record, errors.New("the row has no author")
+	}
+
+	itemCodes := SLiMSList(value("items"))
+	switch {
+	case value("id") != "":
+		record.Key = "biblio:" + value("id")
+	case len(itemCodes) > 0:
+		// Item codes are unique in SLiMS, so the first one identifies the biblio record
+		record.Key = "item:" + itemCodes[0]
+	default:
+		sum := sha1.Sum([]byte(strings.ToLower(strings.Join([]string{title, value("isbn"), value("publisher"), value("year"), value("edition")}, "|"))))
+		record.Key = "hash:" + hex.EncodeToString(sum[:])
+	}
+
+	year := slimsYear(value("year"))
+	language := optionalString(value("language"), 100)
+	pages := optionalString(slimsExtent(value("pages")), marcMaxPages)
+	subjects := optionalString(strings.Join(SLiMSList(value("subjects")), "; "), 0)
+	notes := optionalString(value("notes"), 0)
+
+	if record.ItemType == "paper" {
+		record.Paper = &models.Paper{
+			Title:      title,
+			Author:     record.Authors[0],
+			Advisor:    optionalString(strings.Join(slimsNames(value("advisor")), "; "), 255),
+			University: optionalString(value("publisher"), 255),
+			Year:       year,
+			Language:   language,
+			Pages:      pages,
+			Abstract:   notes,
+			Keywords:   subjects,
+		}
+		return record, nil
+	}
+
+	var isbn *string
+	if fields := strings.Fields(value("isbn")); len(fields) > 0 {
+		isbn = optionalString(fields[0], marcMaxISBN)
+	}
+	if subjects != nil && len(*subjects) > marcMaxSubject {
+		subjects = optionalString(*subjects, marcMaxSubject)
+	}
+	record.Book = &models.Book{
+		Title:         title,
+		Author:        record.Authors[0],
+		Publisher:     optionalString(value("publisher"), marcMaxPublisher),
+		PublishedYear: year,
+		ISBN:          isbn,
+		Subject:       subjects,
+		Language:      language,
+		Pages:         pages,
+		Summary:       notes,
+	}
+	return record, nil
+}
+
+// SLiMSList splits a SLiMS list such as "<KIMIA><FISIKA>" into its entries. A value
+// without angle brackets is split on semicolons.
+func SLiMSList(value string) []string {
+	var entries []string
+	if strings.Contains(value, "<") {
+		for _, match := range slimsListItem.FindAllStringSubmatch(value, -1) {
+			entries = append(entries, match[1])
+		}
+	} else {
+		entries = strings.Split(value, ";")
+	}
+
+	var list []string
+	for _, entry := range entries {
+		entry = strings.Join(strings.Fields(entry), " ")
+		if entry != "" && !containsString(list, entry) {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// slimsNames returns the names in a SLiMS author list in reading order; SLiMS
+// stores personal names as "Family, Given"
+func slimsNames(value string) []string {
+	names := SLiMSList(value)
+	for i, name := range names {
+		if family, given, ok := strings.Cut(name, ","); ok && strings.TrimSpace(given) != "" && !strings.Contains(given, ",") {
+			names[i] = strings.TrimSpace(given) + " " + strings.TrimSpace(family)
+		}
+	}
+	return names
+}
+
+// slimsYear reads a publication year such as "2007", "c2007" or "[2007?]"
+func slimsYear(value string) *int {
+	year, err := strconv.Atoi(yearPattern.FindString(value))
+	if err != nil || year < 1000 {
+		return nil
+	}
+	return &year
+}
+
+// slimsExtent keeps the extent of a collation such as "224 hlm.: ill.; 25 cm."
+func slimsExtent(value string) string {
+	if i := strings.IndexAny(value, ":;"); i >= 0 {
+		value = value[:i]
+	}
+	return trimISBD(value)
+}
+
+// InsertSLiMSRecord saves a record as a book or paper inside tx and remembers the
+// item it was imported as
+func InsertSLiMSRecord(tx *gorm.DB, record *SLiMSRecord, createdBy *uint) (uint, error) {
+	var itemID uint
+	if record.Book != nil {
+		record.Book.CreatedBy = createdBy
+		if err := InsertImportedBook(tx, record.Book, record.Authors); err != nil {
+			return 0, err
+		}
+		itemID = record.Book.ID
+	} else {
+		record.Paper.CreatedBy = createdBy
+		if err := InsertImportedPaper(tx, record.Paper, record.Authors, nil); err != nil {
+			return 0, err
+		}
+		itemID = record.Paper.ID
+	}
+
+	if err := RecordImport(tx, SLiMSSource, record.Key, record.ItemType, itemID); err != nil {
+		return 0, err
+	}
+	return itemID, nil
+}
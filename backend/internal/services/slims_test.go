@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+
+	"e-repository-api/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func slimsRow() map[string]string {
+	return map[string]string{
+		"Judul":                         "Statistika  Untuk Kimia Analitik",
+		"Jenis File":                    "Text",
+		"ISBN":                          "979-8001-48-6",
+		"Penerbit":                      "ITB",
+		"Tahun":                         "[c1991]",
+		"Jumlah Halaman dan Dimensi":    "258 hlm.; 21 cm",
+		"Bahasa":                        "Indonesia",
+		"Cover":                         "cover_statistika.jpg",
+		"Penulis Tambahan":              "<Miller, J C><Miller, J N>",
+		"Subjek":                        "<KIMIA><STATISTIKA>",
+		"Nomor Induk Buku atau Barcode": "<NF0103127><NF0103128>",
+	}
+}
+
+func TestSLiMSRecordFromRowBook(t *testing.T) {
+	record, err := SLiMSRecordFromRow(slimsRow(), DefaultSLiMSColumns(), "auto")
+	assert.NoError(t, err)
+	assert.Equal(t, "book", record.ItemType)
+	assert.Equal(t, "item:NF0103127", record.Key)
+	assert.Equal(t, []string{"J C Miller", "J N Miller"}, record.Authors)
+	assert.Equal(t, "cover_statistika.jpg", record.CoverFile)
+
+	book := record.Book
+	assert.Equal(t, "Statistika Untuk Kimia Analitik", book.Title)
+	assert.Equal(t, "J C Miller", book.Author)
+	assert.Equal(t, 1991, utils.IntValue(book.PublishedYear))
+	assert.Equal(t, "258 hlm", utils.StringValue(book.Pages))
+	assert.Equal(t, "KIMIA; STATISTIKA", utils.StringValue(book.Subject))
+	assert.Equal(t, "979-8001-48-6", utils.StringValue(book.ISBN))
+}
+
+func TestSLiMSRecordFromRowPaperAndKeys(t *testing.T) {
+	row := slimsRow()
+	row["Jenis File"] = "Skripsi"
+	row["biblio_id"] = "42"
+	columns := DefaultSLiMSColumns()
+	assert.NoError(t, columns.Set("id=biblio_id"))
+
+	record, err := SLiMSRecordFromRow(row, columns, "auto")
+	assert.NoError(t, err)
+	assert.Equal(t, "paper", record.ItemType)
+	assert.Equal(t, "biblio:42", record.Key)
+	assert.Equal(t, "ITB", utils.StringValue(record.Paper.University))
+	assert.Equal(t, "KIMIA; STATISTIKA", utils.StringValue(record.Paper.Keywords))
+
+	// Without an ID or item codes the key is derived from the record itself
+	delete(row, "Nomor Induk Buku atau Barcode")
+	first, _ := SLiMSRecordFromRow(row, DefaultSLiMSColumns(), "book")
+	second, _ := SLiMSRecordFromRow(row, DefaultSLiMSColumns(), "book")
+	assert.Regexp(t, `^hash:[0-9a-f]{40}$`, first.Key)
+	assert.Equal(t, first.Key, second.Key)
+
+	row["Penulis Tambahan"] = ""
+	_, err = SLiMSRecordFromRow(row, DefaultSLiMSColumns(), "book")
+	assert.ErrorContains(t, err, "author")
+}
+
+func TestSLiMSColumns(t *testing.T) {
+	columns := DefaultSLiMSColumns()
+	assert.NoError(t, columns.Set("title=Title, file=Lampiran"))
+	assert.Equal(t, "Lampiran", columns["file"])
+	assert.Error(t, columns.Set("colour=Warna"))
+	assert.Error(t, columns.Set("title"))
+
+	missing := columns.Missing([]string{"Judul", "Lampiran"})
+	assert.Contains(t, missing, "Title")
+	assert.NotContains(t, missing, "Lampiran")
+}
+
+func TestSLiMSList(t *testing.T) {
+	assert.Equal(t, []string{"AGUS SACHARI", "YAN YAN SUNARYA"}, SLiMSList("<AGUS SACHARI><YAN YAN  SUNARYA><AGUS SACHARI>"))
+	assert.Equal(t, []string{"Kimia", "Fisika"}, SLiMSList("Kimia; Fisika;"))
+	assert.Nil(t, SLiMSList(""))
+}
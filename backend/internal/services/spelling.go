@@ -0,0 +1,183 @@
+package services
+
+import (
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// SpellingResultThreshold is the number of search results below which spelling
+// corrections are offered
+const SpellingResultThreshold = 3
+
+// spellingMinLength is the length of the shortest word that is corrected or learned
+const spellingMinLength = 3
+
+// SpellingDictionary holds the words of the catalog with the number of titles, author
+// names and keywords each appears in
+type SpellingDictionary struct {
+	words map[string]int
+}
+
+var spellingDictionary atomic.Pointer[SpellingDictionary]
+
+// NewSpellingDictionary counts the words of texts
+func NewSpellingDictionary(texts []string) *SpellingDictionary {
+	d := &SpellingDictionary{words: make(map[string]int)}
+	for _, text := range texts {
+		seen := make(map[string]bool)
+		for _, word := range spellingWords(text) {
+			if !seen[word] {
+				seen[word] = true
+				d.words[word]++
+			}
+		}
+	}
+	return d
+}
+
+// BuildSpellingDictionary rebuilds the dictionary used for corrections from the titles,
+// author names and keywords in the catalog
+func BuildSpellingDictionary(db *gorm.DB) error {
+	var texts []string
+	for _, source := range searchSources {
+		var titles []string
+		if err := db.Table(source.table).Pluck("title", &titles).Error; err != nil {
+			return err
+		}
+		texts = append(texts, titles...)
+
+		var authors []string
+		if err := db.Table(source.authors).Pluck("author_name", &authors).Error; err != nil {
+			return err
+		}
+		texts = append(texts, authors...)
+	}
+	var keywords []string
+	if err := db.Table("keywords").Pluck("term", &keywords).Error; err != nil {
+		return err
+	}
+	texts = append(texts, keywords...)
+
+	spellingDictionary.Store(NewSpellingDictionary(texts))
+	return nil
+}
+
+// SuggestSpelling returns the query with misspelled words replaced by the closest
+// catalog words, or "" when the dictionary has not been built yet or knows every word
+func SuggestSpelling(query string) string {
+	dictionary := spellingDictionary.Load()
+	if dictionary == nil {
+		return ""
+	}
+	return dictionary.CorrectQuery(query)
+}
+
+// CorrectQuery replaces the unknown words of a query by their corrections. Operators,
+// field qualifiers and numbers are kept as typed. It returns "" when nothing changed.
+func (d *SpellingDictionary) CorrectQuery(query string) string {
+	runes := []rune(query)
+	var b strings.Builder
+	changed := false
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		// Field names and wildcard patterns are not words to correct
+		literal := end < len(runes) && (runes[end] == ':' || runes[end] == '*' || runes[end] == '?')
+		if !literal && word != "AND" && word != "OR" && word != "NOT" {
+			if correction, ok := d.Correct(strings.ToLower(word)); ok {
+				word = correction
+				changed = true
+			}
+		}
+		b.WriteString(word)
+		i = end
+	}
+	if !changed {
+		return ""
+	}
+	return b.String()
+}
+
+// Correct returns the catalog word closest to a word the catalog does not contain:
+// the one fewest edits away (at most one for short words, two otherwise), preferring
+// the most frequent. ok is false when the word is known or has no close match.
+func (d *SpellingDictionary) Correct(word string) (correction string, ok bool) {
+	length := len([]rune(word))
+	if length < spellingMinLength || d.words[word] > 0 || !hasLetter(word) {
+		return "", false
+	}
+	maxDistance := 1
+	if length > 5 {
+		maxDistance = 2
+	}
+
+	bestDistance, bestCount := maxDistance+1, 0
+	for candidate, count := range d.words {
+		difference := len([]rune(candidate)) - length
+		if difference > maxDistance || -difference > maxDistance {
+			continue
+		}
+		distance := editDistance(word, candidate)
+		if distance < bestDistance || (distance == bestDistance && (count > bestCount || (count == bestCount && candidate < correction))) {
+			correction, bestDistance, bestCount = candidate, distance, count
+		}
+	}
+	return correction, correction != ""
+}
+
+// editDistance counts the insertions, deletions, substitutions and transpositions of
+// adjacent letters that turn a into b
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous2 := make([]int, len(t)+1)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(t)]
+}
+
+// spellingWords splits a text into its lowercase words long enough to learn
+func spellingWords(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !isWordRune(r) }) {
+		if len([]rune(word)) >= spellingMinLength && hasLetter(word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func hasLetter(word string) bool {
+	return strings.IndexFunc(word, unicode.IsLetter) >= 0
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("data", "data"))
+	assert.Equal(t, 1, editDistance("informatka", "informatika"))
+	assert.Equal(t, 1, editDistance("sitsem", "sistem"))
+	assert.Equal(t, 2, editDistance("jarngn", "jaringan"))
+	assert.Equal(t, 3, editDistance("", "abc"))
+}
+
+func TestSpellingDictionaryCorrect(t *testing.T) {
+	dictionary := NewSpellingDictionary([]string{
+		"Sistem Informasi Manajemen",
+		"Teknik Informatika",
+		"Informatika Kesehatan",
+		"Budi Santoso",
+		"Jaringan Komputer",
+	})
+
+	correction, ok := dictionary.Correct("informatka")
+	assert.True(t, ok)
+	assert.Equal(t, "informatika", correction)
+
+	// Known, short and far-off words are left alone
+	for _, word := range []string{"sistem", "ai", "xyzxyz"} {
+		_, ok := dictionary.Correct(word)
+		assert.False(t, ok, word)
+	}
+
+	// Ties go to the more frequent word
+	correction, ok = NewSpellingDictionary([]string{"buka", "buku", "buku baru"}).Correct("bukx")
+	assert.True(t, ok)
+	assert.Equal(t, "buku", correction)
+}
+
+func TestSpellingDictionaryCorrectQuery(t *testing.T) {
+	dictionary := NewSpellingDictionary([]string{"Sistem Informasi", "Teknik Informatika", "Budi Santoso"})
+
+	assert.Equal(t, "teknik informatika", dictionary.CorrectQuery("teknik informatka"))
+	assert.Equal(t, `title:"sistem informasi" AND author:santoso`, dictionary.CorrectQuery(`title:"sistem infromasi" AND author:santsoo`))
+	assert.Equal(t, "", dictionary.CorrectQuery("sistem informasi 2020"))
+	assert.Equal(t, "", dictionary.CorrectQuery("infor* OR tekn?k"))
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"e-repository-api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrStockTakeClosed is returned when scanning into or closing a closed session
+var ErrStockTakeClosed = errors.New("stock-take session is closed")
+
+// StockTakeSummary counts a session's items by result
+type StockTakeSummary struct {
+	Expected      int `json:"expected"`
+	Found         int `json:"found"`
+	Missing       int `json:"missing"`
+	WrongLocation int `json:"wrong_location"`
+	Unknown       int `json:"unknown"`
+}
+
+// ScanResult is the outcome of one scanned barcode
+type ScanResult struct {
+	Barcode string `json:"barcode"`
+	Result  string `json:"result"`
+	// Duplicate is set when the barcode was already scanned in this session
+	Duplicate bool `json:"duplicate"`
+}
+
+// shelvedCopies selects the copies of a location range expected on the shelves: copies
+// on loan, lost or set aside on the hold shelf are not
+func shelvedCopies(db *gorm.DB, from, to string) *gorm.DB {
+	return db.Model(&models.Copy{}).
+		Where("location BETWEEN ? AND ?", from, to).
+		Where("status IN ('available', 'damaged')").
+		Where("id NOT IN (?)", db.Model(&models.Hold{}).Select("copy_id").Where("status = 'ready' AND copy_id IS NOT NULL"))
+}
+
+// OpenStockTake starts a session and records every copy expected in its location range
+// as missing until it is scanned
+func OpenStockTake(db *gorm.DB, name, from, to string, staffID uint) (*models.StockTake, error) {
+	session := models.StockTake{
+		Name:         name,
+		LocationFrom: from,
+		LocationTo:   to,
+		Status:       "open",
+		OpenedBy:     staffID,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var copies []models.Copy
+		if err := shelvedCopies(tx, from, to).Select("id", "barcode").Find(&copies).Error; err != nil {
+			return err
+		}
+		if len(copies) == 0 {
+			return nil
+		}
+		items := make([]models.StockTakeItem, 0, len(copies))
+		for _, bookCopy := range copies {
+			copyID := bookCopy.ID
+			items = append(items, models.StockTakeItem{
+				StockTakeID: session.ID,
+				Barcode:     bookCopy.Barcode,
+				CopyID:      &copyID,
+				Expected:    true,
+				Result:      "missing",
+			})
+		}
+		return tx.CreateInBatches(&items, 500).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RecordScans records a batch of scanned barcodes. Blank and repeated barcodes are
+// skipped; barcodes scanned in an earlier batch are reported as duplicates.
+func RecordScans(db *gorm.DB, session *models.StockTake, barcodes []string, staffID uint) ([]ScanResult, error) {
+	if session.Status != "open" {
+		return nil, ErrStockTakeClosed
+	}
+
+	seen := make(map[string]bool, len(barcodes))
+	unique := make([]string, 0, len(barcodes))
+	for _, barcode := range barcodes {
+		barcode = strings.TrimSpace(barcode)
+		if barcode == "" || seen[barcode] {
+			continue
+		}
+		seen[barcode] = true
+		unique = append(unique, barcode)
+	}
+	results := make([]ScanResult, 0, len(unique))
+	if len(unique) == 0 {
+		return results, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.StockTakeItem
+		if err := tx.Where("stock_take_id = ? AND barcode IN ?", session.ID, unique).Find(&existing).Error; err != nil {
+			return err
+		}
+		items := make(map[string]models.StockTakeItem, len(existing))
+		for _, item := range existing {
+			items[item.Barcode] = item
+		}
+
+		var copies []models.Copy
+		if err := tx.Select("id", "barcode").Where("barcode IN ?", unique).Find(&copies).Error; err != nil {
+			return err
+		}
+		copyIDs := make(map[string]uint, len(copies))
+		for _, bookCopy := range copies {
+			copyIDs[bookCopy.Barcode] = bookCopy.ID
+		}
+		var inRangeIDs []uint
+		if err := tx.Model(&models.Copy{}).Where("barcode IN ? AND location BETWEEN ? AND ?", unique, session.LocationFrom, session.LocationTo).
+			Pluck("id", &inRangeIDs).Error; err != nil {
+			return err
+		}
+		inRange := make(map[uint]bool, len(inRangeIDs))
+		for _, id := range inRangeIDs {
+			inRange[id] = true
+		}
+
+		now := time.Now()
+		for _, barcode := range unique {
+			if item, ok := items[barcode]; ok {
+				if item.ScannedAt != nil {
+					results = append(results, ScanResult{Barcode: barcode, Result: item.Result, Duplicate: true})
+					continue
+				}
+				// An expected copy turning up
+				if err := tx.Model(&item).Updates(map[string]interface{}{
+					"result":     "found",
+					"scanned_by": staffID,
+					"scanned_at": now,
+				}).Error; err != nil {
+					return err
+				}
+				results = append(results, ScanResult{Barcode: barcode, Result: "found"})
+				continue
+			}
+
+			item := models.StockTakeItem{
+				StockTakeID: session.ID,
+				Barcode:     barcode,
+				Result:      "unknown",
+				ScannedBy:   &staffID,
+				ScannedAt:   &now,
+			}
+			if copyID, ok := copyIDs[barcode]; ok {
+				item.CopyID = &copyID
+				item.Result = "wrong_location"
+				if inRange[copyID] {
+					item.Result = "found"
+				}
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+			results = append(results, ScanResult{Barcode: barcode, Result: item.Result})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SummarizeStockTake counts a session's items by result
+func SummarizeStockTake(db *gorm.DB, sessionID uint) (StockTakeSummary, error) {
+	var rows []struct {
+		Result   string
+		Expected bool
+		Count    int
+	}
+	if err := db.Model(&models.StockTakeItem{}).Select("result, expected, COUNT(*) AS count").
+		Where("stock_take_id = ?", sessionID).Group("result, expected").Scan(&rows).Error; err != nil {
+		return StockTakeSummary{}, err
+	}
+
+	var summary StockTakeSummary
+	for _, row := range rows {
+		if row.Expected {
+			summary.Expected += row.Count
+		}
+		switch row.Result {
+		case "found":
+			summary.Found += row.Count
+		case "missing":
+			summary.Missing += row.Count
+		case "wrong_location":
+			summary.WrongLocation += row.Count
+		case "unknown":
+			summary.Unknown += row.Count
+		}
+	}
+	return summary, nil
+}
+
+// CloseStockTake closes a session. With markLost, copies still missing are marked lost
+// and holds that can no longer be filled are cancelled.
+func CloseStockTake(db *gorm.DB, session *models.StockTake, markLost bool, staffID uint) error {
+	if session.Status != "open" {
+		return ErrStockTakeClosed
+	}
+
+	bookIDs := make(map[uint]bool)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		markedLost := 0
+		if markLost {
+			// Copies lent or put on the hold shelf since the session opened are accounted for
+			var lost []models.Copy
+			if err := shelvedCopies(tx, session.LocationFrom, session.LocationTo).Select("id", "book_id").
+				Where("id IN (?)", tx.Model(&models.StockTakeItem{}).Select("copy_id").
+					Where("stock_take_id = ? AND result = 'missing' AND copy_id IS NOT NULL", session.ID)).
+				Find(&lost).Error; err != nil {
+				return err
+			}
+			if len(lost) > 0 {
+				ids := make([]uint, 0, len(lost))
+				for _, bookCopy := range lost {
+					ids = append(ids, bookCopy.ID)
+					bookIDs[bookCopy.BookID] = true
+				}
+				if err := tx.Model(&models.Copy{}).Where("id IN ?", ids).Update("status", "lost").Error; err != nil {
+					return err
+				}
+				markedLost = len(ids)
+			}
+		}
+
+		now := time.Now()
+		result := tx.Model(session).Where("status = 'open'").Updates(map[string]interface{}{
+			"status":      "closed",
+			"marked_lost": markedLost,
+			"closed_by":   staffID,
+			"closed_at":   now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStockTakeClosed
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for bookID := range bookIDs {
+		if err := CancelOrphanedHolds(db, bookID); err != nil {
+			log.Printf("[CloseStockTake] Failed to cancel holds of book %d: %v", bookID, err)
+		}
+	}
+	return nil
+}
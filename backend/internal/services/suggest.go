@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SuggestMinPrefix is the shortest prefix completions are looked up for
+const SuggestMinPrefix = 2
+
+// Suggestion is a completion of what the user is typing
+type Suggestion struct {
+	Text       string `json:"text"`
+	ItemType   string `json:"item_type,omitempty"` // titles only
+	ID         uint   `json:"id,omitempty"`        // the book, paper or author
+	Popularity int64  `json:"popularity"`
+}
+
+// Suggestions are the completions of a prefix, grouped by what they complete
+type Suggestions struct {
+	Titles   []Suggestion `json:"titles"`
+	Authors  []Suggestion `json:"authors"`
+	Keywords []Suggestion `json:"keywords"`
+}
+
+// Suggest completes titles, author names and keywords that start with prefix or have a
+// word starting with it. Titles rank by downloads, authors by their number of works and
+// keywords by the number of items tagged with them.
+func Suggest(db *gorm.DB, prefix string, limit int) (*Suggestions, error) {
+	result := &Suggestions{Titles: []Suggestion{}, Authors: []Suggestion{}, Keywords: []Suggestion{}}
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if len([]rune(prefix)) < SuggestMinPrefix {
+		return result, nil
+	}
+	start := escapeLike(prefix) + "%"
+	word := "% " + start
+
+	// Titles of books and papers
+	var parts []string
+	var args []interface{}
+	for _, source := range searchSources {
+		parts = append(parts, "SELECT "+source.column("title")+" AS text, '"+source.itemType+"' AS item_type, "+source.column("id")+" AS id, "+
+			"(SELECT COUNT(*) FROM downloads WHERE downloads.item_type = '"+source.itemType+"' AND downloads.item_id = "+source.column("id")+") AS popularity "+
+			"FROM "+source.table+" WHERE LOWER("+source.column("title")+") LIKE ? OR LOWER("+source.column("title")+") LIKE ?")
+		args = append(args, start, word)
+	}
+	args = append(args, limit)
+	if err := db.Raw(strings.Join(parts, " UNION ALL ")+" ORDER BY popularity DESC, text ASC LIMIT ?", args...).
+		Scan(&result.Titles).Error; err != nil {
+		return nil, err
+	}
+
+	// Authority records matched by their preferred name or any variant
+	var works []string
+	for _, source := range searchSources {
+		works = append(works, "(SELECT COUNT(*) FROM "+source.authors+" WHERE "+source.authors+".author_id = authors.id)")
+	}
+	if err := db.Table("authors").
+		Select("authors.preferred_name AS text, authors.id AS id, "+strings.Join(works, " + ")+" AS popularity").
+		Where("LOWER(authors.preferred_name) LIKE ? OR LOWER(authors.preferred_name) LIKE ? OR authors.id IN (?)", start, word,
+			db.Table("author_variants").Select("author_id").Where("LOWER(name) LIKE ? OR LOWER(name) LIKE ?", start, word)).
+		Order("popularity DESC, text ASC").Limit(limit).
+		Scan(&result.Authors).Error; err != nil {
+		return nil, err
+	}
+
+	// Keywords of the controlled vocabulary
+	normalized := escapeLike(NormalizeKeyword(prefix))
+	if normalized == "" {
+		return result, nil
+	}
+	var tagged []string
+	for _, itemType := range []string{"book", "paper"} {
+		join := keywordJoinTables[itemType]
+		tagged = append(tagged, "(SELECT COUNT(*) FROM "+join[0]+" WHERE "+join[0]+".keyword_id = keywords.id)")
+	}
+	if err := db.Table("keywords").
+		Select("keywords.term AS text, keywords.id AS id, "+strings.Join(tagged, " + ")+" AS popularity").
+		Where("keywords.normalized LIKE ? OR keywords.normalized LIKE ?", normalized+"%", "% "+normalized+"%").
+		Having("popularity > 0").
+		Order("popularity DESC, text ASC").Limit(limit).
+		Scan(&result.Keywords).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
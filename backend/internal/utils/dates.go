@@ -0,0 +1,25 @@
+package utils
+
+import "time"
+
+// DateLayout is the format of calendar dates in requests and responses
+const DateLayout = "2006-01-02"
+
+// ParseOptionalDate parses an optional YYYY-MM-DD date, returning nil for nil or blank input
+func ParseOptionalDate(s *string) (*time.Time, error) {
+	value := StringValue(s)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation(DateLayout, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// StartOfDay returns midnight of the day t falls on, in t's location
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -87,94 +87,151 @@ func SendVerificationEmail(to, token string, config *configs.EmailConfig) error
 		return fmt.Errorf("failed to render email template: %v", err)
 	}
 
-	// Set up email headers
-	headers := make(map[string]string)
-	headers["From"] = fmt.Sprintf("%s <%s>", config.FromName, config.FromEmail)
-	headers["To"] = to
-	headers["Subject"] = "Verify Your Email Address - E-Repository"
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
-	headers["Date"] = time.Now().Format(time.RFC1123Z)
+	return sendHTMLEmail(to, "Verify Your Email Address - E-Repository", body.Bytes(), config)
+}
 
-	// Build email message
-	var message bytes.Buffer
-	for key, value := range headers {
-		message.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
+// SendPasswordResetEmail sends a password reset email to the user
+func SendPasswordResetEmail(to, token string, config *configs.EmailConfig) error {
+	// Validate recipient email domain
+	if err := ValidateReceiverEmail(to); err != nil {
+		return fmt.Errorf("invalid recipient email: %v", err)
 	}
-	message.WriteString("\r\n")
-	message.Write(body.Bytes())
 
-	// Configure TLS
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: false,
-		ServerName:         config.SMTPHost,
-	}
+	resetLink := fmt.Sprintf("http://localhost:3000/reset-password?token=%s", token)
 
-	// Create SMTP connection
-	conn, err := tls.Dial("tcp", fmt.Sprintf("%s:%s", config.SMTPHost, config.SMTPPort), tlsConfig)
+	// Load email template
+	tmpl, err := template.ParseFiles("templates/password_reset_email.html")
 	if err != nil {
-		return fmt.Errorf("failed to establish TLS connection: %v", err)
+		return fmt.Errorf("failed to parse email template: %v", err)
 	}
-	defer conn.Close()
 
-	// Create SMTP client
-	client, err := smtp.NewClient(conn, config.SMTPHost)
+	// Prepare email data
+	data := struct {
+		ResetLink  string
+		ExpiryTime string
+	}{
+		ResetLink:  resetLink,
+		ExpiryTime: time.Now().Add(24 * time.Hour).Format("January 2, 2006 15:04:05"),
+	}
+
+	// Render email body
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to render email template: %v", err)
+	}
+
+	return sendHTMLEmail(to, "Reset Your Password - E-Repository", body.Bytes(), config)
+}
+
+// OverdueLoanNotice is one overdue loan listed in a reminder email
+type OverdueLoanNotice struct {
+	Title       string
+	Barcode     string
+	DueDate     string
+	DaysOverdue int
+	Fine        string
+}
+
+// SendOverdueReminderEmail reminds a library member to return their overdue loans
+func SendOverdueReminderEmail(to, name string, loans []OverdueLoanNotice, config *configs.EmailConfig) error {
+	// Validate recipient email domain
+	if err := ValidateReceiverEmail(to); err != nil {
+		return fmt.Errorf("invalid recipient email: %v", err)
+	}
+
+	// Load email template
+	tmpl, err := template.ParseFiles("templates/overdue_reminder_email.html")
 	if err != nil {
-		return fmt.Errorf("failed to create SMTP client: %v", err)
+		return fmt.Errorf("failed to parse email template: %v", err)
 	}
-	defer client.Close()
 
-	// Authenticate
-	auth := smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
-	if err := client.Auth(auth); err != nil {
-		return fmt.Errorf("failed to authenticate: %v", err)
+	// Prepare email data
+	data := struct {
+		Name  string
+		Loans []OverdueLoanNotice
+	}{
+		Name:  name,
+		Loans: loans,
 	}
 
-	// Set sender and recipient
-	if err := client.Mail(config.FromEmail); err != nil {
-		return fmt.Errorf("failed to set sender: %v", err)
+	// Render email body
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to render email template: %v", err)
 	}
-	if err := client.Rcpt(to); err != nil {
-		return fmt.Errorf("failed to set recipient: %v", err)
+
+	return sendHTMLEmail(to, "Overdue Library Loans - E-Repository", body.Bytes(), config)
+}
+
+// SendHoldReadyEmail tells a library member that a book they reserved is waiting for pickup
+func SendHoldReadyEmail(to, name, title, location, pickupDeadline string, config *configs.EmailConfig) error {
+	// Validate recipient email domain
+	if err := ValidateReceiverEmail(to); err != nil {
+		return fmt.Errorf("invalid recipient email: %v", err)
 	}
 
-	// Send email data
-	w, err := client.Data()
+	// Load email template
+	tmpl, err := template.ParseFiles("templates/hold_ready_email.html")
 	if err != nil {
-		return fmt.Errorf("failed to prepare email data: %v", err)
+		return fmt.Errorf("failed to parse email template: %v", err)
 	}
-	defer w.Close()
 
-	_, err = w.Write(message.Bytes())
-	if err != nil {
-		return fmt.Errorf("failed to write email data: %v", err)
+	// Prepare email data
+	data := struct {
+		Name           string
+		Title          string
+		Location       string
+		PickupDeadline string
+	}{
+		Name:           name,
+		Title:          title,
+		Location:       location,
+		PickupDeadline: pickupDeadline,
 	}
 
-	return nil
+	// Render email body
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return fmt.Errorf("failed to render email template: %v", err)
+	}
+
+	return sendHTMLEmail(to, "Your Reserved Book Is Ready - E-Repository", body.Bytes(), config)
 }
 
-// SendPasswordResetEmail sends a password reset email to the user
-func SendPasswordResetEmail(to, token string, config *configs.EmailConfig) error {
+// SavedSearchDigest lists the new items found by one saved search
+type SavedSearchDigest struct {
+	Name            string
+	Total           int64
+	Items           []SavedSearchDigestItem
+	UnsubscribeLink string
+}
+
+// SavedSearchDigestItem is a new book or paper listed in a saved search digest
+type SavedSearchDigestItem struct {
+	Type  string
+	Title string
+}
+
+// SendSavedSearchDigestEmail sends a user the new items found by their saved searches
+func SendSavedSearchDigestEmail(to, name string, searches []SavedSearchDigest, config *configs.EmailConfig) error {
 	// Validate recipient email domain
 	if err := ValidateReceiverEmail(to); err != nil {
 		return fmt.Errorf("invalid recipient email: %v", err)
 	}
 
-	resetLink := fmt.Sprintf("http://localhost:3000/reset-password?token=%s", token)
-
 	// Load email template
-	tmpl, err := template.ParseFiles("templates/password_reset_email.html")
+	tmpl, err := template.ParseFiles("templates/saved_search_digest_email.html")
 	if err != nil {
 		return fmt.Errorf("failed to parse email template: %v", err)
 	}
 
 	// Prepare email data
 	data := struct {
-		ResetLink  string
-		ExpiryTime string
+		Name     string
+		Searches []SavedSearchDigest
 	}{
-		ResetLink:  resetLink,
-		ExpiryTime: time.Now().Add(24 * time.Hour).Format("January 2, 2006 15:04:05"),
+		Name:     name,
+		Searches: searches,
 	}
 
 	// Render email body
@@ -183,11 +240,16 @@ func SendPasswordResetEmail(to, token string, config *configs.EmailConfig) error
 		return fmt.Errorf("failed to render email template: %v", err)
 	}
 
+	return sendHTMLEmail(to, "New Items for Your Saved Searches - E-Repository", body.Bytes(), config)
+}
+
+// sendHTMLEmail sends an HTML email over SMTP with TLS
+func sendHTMLEmail(to, subject string, body []byte, config *configs.EmailConfig) error {
 	// Set up email headers
 	headers := make(map[string]string)
 	headers["From"] = fmt.Sprintf("%s <%s>", config.FromName, config.FromEmail)
 	headers["To"] = to
-	headers["Subject"] = "Reset Your Password - E-Repository"
+	headers["Subject"] = subject
 	headers["MIME-Version"] = "1.0"
 	headers["Content-Type"] = "text/html; charset=UTF-8"
 	headers["Date"] = time.Now().Format(time.RFC1123Z)
@@ -198,7 +260,7 @@ func SendPasswordResetEmail(to, token string, config *configs.EmailConfig) error
 		message.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
 	}
 	message.WriteString("\r\n")
-	message.Write(body.Bytes())
+	message.Write(body)
 
 	// Configure TLS
 	tlsConfig := &tls.Config{
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// academicTitles are honorifics and Indonesian/English degree abbreviations that
+// are dropped before names are compared
+var academicTitles = map[string]bool{
+	"prof": true, "dr": true, "drs": true, "dra": true, "ir": true, "hj": true,
+	"st": true, "skom": true, "si": true, "se": true, "sh": true, "spd": true, "ssi": true, "sag": true,
+	"mt": true, "mkom": true, "msi": true, "mm": true, "mh": true, "mpd": true, "msc": true, "ma": true, "mba": true,
+	"phd": true, "bsc": true, "ba": true, "mag": true, "mcs": true,
+}
+
+// NameParts splits a person's name into words in reading order, keeping their case.
+// It drops academic titles and punctuation and turns inverted "Santoso, Budi" into
+// "Budi Santoso".
+func NameParts(name string) []string {
+	var segments [][]string
+	for _, segment := range strings.Split(name, ",") {
+		var parts []string
+		for _, field := range strings.Fields(segment) {
+			part := strings.Map(func(r rune) rune {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					return r
+				}
+				return -1
+			}, field)
+			if part == "" || (academicTitles[strings.ToLower(part)] && (strings.Contains(field, ".") || len(part) > 2)) {
+				continue
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) > 0 {
+			segments = append(segments, parts)
+		}
+	}
+
+	switch len(segments) {
+	case 0:
+		return nil
+	case 2:
+		// "Santoso, Budi" -> "Budi Santoso"
+		return append(segments[1], segments[0]...)
+	default:
+		var parts []string
+		for _, segment := range segments {
+			parts = append(parts, segment...)
+		}
+		return parts
+	}
+}
+
+// NameTokens splits a person's name into comparable lowercase tokens (see NameParts)
+func NameTokens(name string) []string {
+	parts := NameParts(name)
+	for i, part := range parts {
+		parts[i] = strings.ToLower(part)
+	}
+	return parts
+}
+
+// NameKey returns the normalized form of a person's name used to match name variants
+func NameKey(name string) string {
+	return strings.Join(NameTokens(name), " ")
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameTokens(t *testing.T) {
+	assert.Equal(t, []string{"budi", "santoso"}, NameTokens("Budi Santoso"))
+	assert.Equal(t, []string{"budi", "santoso"}, NameTokens("Santoso, Budi"))
+	assert.Equal(t, []string{"budi", "santoso"}, NameTokens("Dr. Budi Santoso, S.Kom., M.T."))
+	assert.Equal(t, []string{"b", "santoso"}, NameTokens("B. Santoso"))
+	assert.Empty(t, NameTokens(" , "))
+}
+
+func TestNameKey(t *testing.T) {
+	assert.Equal(t, "budi santoso", NameKey("Santoso, Budi"))
+	assert.Equal(t, NameKey("BUDI SANTOSO"), NameKey("Budi Santoso, S.Kom."))
+	assert.Equal(t, "", NameKey(""))
+}
+
+func TestNameParts(t *testing.T) {
+	assert.Equal(t, []string{"Budi", "Santoso"}, NameParts("Santoso, Budi"))
+	assert.Equal(t, []string{"B", "Santoso"}, NameParts("Dr. B. Santoso, M.T."))
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "strings"
+
 // StringPtr returns a pointer to the given string
 func StringPtr(s string) *string {
 	return &s
@@ -14,3 +16,19 @@ func IntPtr(i int) *int {
 func UintPtr(u uint) *uint {
 	return &u
 }
+
+// StringValue returns the trimmed value of an optional string, or "" when it is nil
+func StringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimSpace(*s)
+}
+
+// IntValue returns the value of an optional int, or 0 when it is nil
+func IntValue(i *int) int {
+	if i == nil {
+		return 0
+	}
+	return *i
+}